@@ -7,12 +7,15 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/ahrdadan/scrq/internal/api"
 	"github.com/ahrdadan/scrq/internal/browser"
 	"github.com/ahrdadan/scrq/internal/config"
 	"github.com/ahrdadan/scrq/internal/nats"
 	"github.com/ahrdadan/scrq/internal/queue"
+	"github.com/ahrdadan/scrq/internal/security"
+	"github.com/ahrdadan/scrq/internal/tracing"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -39,6 +42,15 @@ func main() {
 	}
 	lightpandaAvailable = available
 
+	// Trace spans are exported only when a collector is configured
+	tracer := tracing.NewTracer(tracing.Config{Endpoint: cfg.OTLPEndpoint})
+	if tracer != nil {
+		log.Printf("Exporting traces to %s", cfg.OTLPEndpoint)
+	}
+
+	// Shared cap on open pages across both engines
+	pageLimiter := browser.NewPageLimiter(cfg.MaxConcurrentPages, cfg.PageWaitTimeout)
+
 	if lightpandaAvailable {
 		// Start Lightpanda browser
 		browserManager, err = browser.NewManagerWithPath(lightpandaPath, cfg.BrowserHost, cfg.BrowserPort)
@@ -46,6 +58,7 @@ func main() {
 			log.Printf("Warning: Failed to initialize browser manager: %v", err)
 			lightpandaAvailable = false
 		} else {
+			browserManager.SetPageLimiter(pageLimiter)
 			if err := browserManager.Start(); err != nil {
 				log.Printf("Warning: Failed to start Lightpanda browser: %v", err)
 				lightpandaAvailable = false
@@ -55,6 +68,12 @@ func main() {
 						log.Printf("Failed to stop Lightpanda browser: %v", err)
 					}
 				}()
+				if cfg.BrowserMaxRSS > 0 {
+					defer browserManager.StartMemoryWatchdog(uint64(cfg.BrowserMaxRSS)<<20, cfg.BrowserMemoryCheck)()
+				}
+				if cfg.BrowserMaxPages > 0 {
+					defer browserManager.StartPageRecycler(cfg.BrowserMaxPages)()
+				}
 			}
 		}
 	}
@@ -72,6 +91,8 @@ func main() {
 		}
 
 		chromeManager = browser.NewChromeManager(chromeBin)
+		chromeManager.SetPageLimiter(pageLimiter)
+		chromeManager.SetSessionTTL(cfg.ChromeSessionTTL)
 		if err := chromeManager.Start(); err != nil {
 			log.Fatalf("Failed to start Chrome: %v", err)
 		}
@@ -80,6 +101,15 @@ func main() {
 				log.Printf("Failed to stop Chrome: %v", err)
 			}
 		}()
+		if cfg.BrowserMaxRSS > 0 {
+			defer chromeManager.StartMemoryWatchdog(uint64(cfg.BrowserMaxRSS)<<20, cfg.BrowserMemoryCheck)()
+		}
+		if cfg.BrowserMaxPages > 0 {
+			defer chromeManager.StartPageRecycler(cfg.BrowserMaxPages)()
+		}
+		if cfg.ChromePoolSize > 0 {
+			defer chromeManager.StartPagePool(cfg.ChromePoolSize, cfg.ChromePoolMaxIdle)()
+		}
 	}
 
 	// NATS + JetStream setup
@@ -94,6 +124,8 @@ func main() {
 			StoreDir: cfg.NatsStore,
 			URL:      cfg.NatsURL,
 			AutoDL:   cfg.NatsAutoDL,
+
+			ConfigFile: cfg.NatsConfig,
 		})
 		if err != nil {
 			log.Fatalf("Failed to create NATS server: %v", err)
@@ -105,9 +137,25 @@ func main() {
 		}
 		defer func() { _ = natsServer.Stop() }()
 
+		// A single embedded node cannot hold more than one replica
+		if cfg.NatsReplicas > 1 && natsServer.IsEmbedded() {
+			log.Fatalf("--nats-replicas=%d requires an external NATS cluster; the embedded server is a single node", cfg.NatsReplicas)
+		}
+
 		// Create queue manager
 		js := natsServer.GetJetStream()
-		queueManager, err = queue.NewManager(js)
+		queueManager, err = queue.NewManagerWithConfig(js, queue.ManagerConfig{
+			EventBufferSize: cfg.EventBufferSize,
+			QueueConfig: queue.QueueConfig{
+				MaxAge:     cfg.QueueMaxAge,
+				MaxMsgs:    cfg.QueueMaxMsgs,
+				MaxBytes:   cfg.QueueMaxBytes,
+				Storage:    cfg.QueueStorage,
+				Replicas:   cfg.NatsReplicas,
+				MaxDeliver: cfg.QueueMaxDeliver,
+			},
+			Tracer: tracer,
+		})
 		if err != nil {
 			log.Fatalf("Failed to create queue manager: %v", err)
 		}
@@ -124,7 +172,7 @@ func main() {
 		}
 
 		processor := queue.NewScrapeProcessor(lightpandaClient, chromeClient)
-		if err := queueManager.Start(processor); err != nil {
+		if err := queueManager.StartN(processor, cfg.Workers); err != nil {
 			log.Fatalf("Failed to start queue processor: %v", err)
 		}
 		defer queueManager.Stop()
@@ -134,6 +182,7 @@ func main() {
 	app := fiber.New(fiber.Config{
 		AppName:      config.AppName,
 		ErrorHandler: api.ErrorHandler,
+		BodyLimit:    cfg.MaxBodyBytes,
 	})
 
 	// Middleware
@@ -141,34 +190,82 @@ func main() {
 	app.Use(logger.New())
 	app.Use(cors.New())
 
+	if cfg.Compress {
+		api.SetupCompression(app)
+	}
+
+	// Version info (always available, unauthenticated)
+	app.Get("/scrq/version", api.Version)
+
+	// Batch scrapes share one cap, never above the global page cap
+	batchPages := cfg.MaxBatchPages
+	if cfg.MaxConcurrentPages > 0 && (batchPages <= 0 || batchPages > cfg.MaxConcurrentPages) {
+		batchPages = cfg.MaxConcurrentPages
+	}
+
+	routeConfig := api.RouteConfig{
+		RateLimitRequests: cfg.RateLimitRequests,
+		RateLimitWindow:   cfg.RateLimitWindow,
+		IdempotencyTTL:    cfg.IdempotencyTTL,
+		BaseURL:           cfg.BaseURL,
+		PathPrefix:        cfg.PathPrefix,
+		AdminToken:        cfg.AdminToken,
+
+		RequireIdempotencyKey: cfg.RequireIdempotencyKey,
+		ContentDedupWindow:    cfg.ContentDedupWindow,
+		PollInterval:          cfg.PollInterval,
+		SSEKeepalive:          cfg.SSEKeepalive,
+		WSPingInterval:        cfg.WSPing,
+		MaxSyncTimeout:        cfg.MaxSyncTimeout,
+		MaxJobTimeout:         cfg.MaxJobTimeout,
+		BatchLimiter:          browser.NewPageLimiter(batchPages, cfg.PageWaitTimeout),
+		MaxBodyBytes:          cfg.MaxBodyBytes,
+		AllowedIPs:            cfg.AllowedIPs,
+		TrustProxy:            cfg.TrustProxy,
+		Tracer:                tracer,
+	}
+	if _, err := security.ParseAllowedIPs(cfg.AllowedIPs); err != nil {
+		log.Fatalf("Invalid --allowed-ips: %v", err)
+	}
+	routeContentTypes, err := security.ParseRouteContentTypes(cfg.RouteContentTypes)
+	if err != nil {
+		log.Fatalf("Invalid --route-content-types: %v", err)
+	}
+	routeConfig.RouteContentTypes = routeContentTypes
+
+	if cfg.RecipesFile != "" {
+		recipes, err := queue.LoadRecipeFile(cfg.RecipesFile)
+		if err != nil {
+			log.Fatalf("Failed to load recipes: %v", err)
+		}
+		routeConfig.Recipes = recipes
+		log.Printf("Loaded %d recipes from %s", len(recipes.List()), cfg.RecipesFile)
+	}
+
+	// Liveness and readiness probe every enabled dependency
+	var probes []api.Probe
+	if lightpandaAvailable && browserManager != nil {
+		probes = append(probes, api.BrowserProbe("lightpanda", browserManager))
+	}
+	if chromeManager != nil {
+		probes = append(probes, api.BrowserProbe("chrome", chromeManager))
+	}
+	if natsServer != nil {
+		probes = append(probes, api.NATSProbe(natsServer.GetConnection()), api.JetStreamProbe(natsServer.GetJetStream()))
+	}
+	api.SetupHealthRoutes(app, probes...)
+
 	// Setup routes
 	if lightpandaAvailable && browserManager != nil {
-		api.SetupRoutes(app, browserManager)
-	} else {
-		// Setup health check only if no browser
-		app.Get("/health", func(c *fiber.Ctx) error {
-			return c.JSON(fiber.Map{
-				"success": true,
-				"data": fiber.Map{
-					"status":     "ok",
-					"lightpanda": false,
-				},
-			})
-		})
+		api.SetupRoutesWithConfig(app, browserManager, routeConfig)
 	}
 
 	if chromeManager != nil {
-		api.SetupChromeRoutes(app, chromeManager)
+		api.SetupChromeRoutesWithConfig(app, chromeManager, routeConfig)
 	}
 
 	if queueManager != nil {
 		// Setup job routes with security configuration
-		routeConfig := api.RouteConfig{
-			RateLimitRequests: cfg.RateLimitRequests,
-			RateLimitWindow:   cfg.RateLimitWindow,
-			IdempotencyTTL:    cfg.IdempotencyTTL,
-			BaseURL:           cfg.BaseURL,
-		}
 		api.SetupJobRoutesWithConfig(app, queueManager, routeConfig)
 	}
 
@@ -176,17 +273,47 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
+	shutdownDone := make(chan struct{})
 	go func() {
 		<-quit
-		log.Println("Shutting down server...")
+		defer close(shutdownDone)
+		log.Printf("Shutting down server (timeout %s)...", cfg.ShutdownTimeout)
+
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+
+		// Force exit if graceful shutdown does not finish in time
+		go func() {
+			<-ctx.Done()
+			if ctx.Err() != context.DeadlineExceeded {
+				return
+			}
+			if queueManager != nil {
+				log.Printf("Jobs still in flight: %v", queueManager.InFlightJobs())
+			}
+			log.Printf("Open connections: %d", app.Server().GetOpenConnectionsCount())
+			log.Fatalf("Shutdown timed out after %s, forcing exit", cfg.ShutdownTimeout)
+		}()
+
+		// Stop accepting requests first so no job is enqueued mid-drain
+		if err := app.ShutdownWithContext(ctx); err != nil {
+			log.Printf("Error during shutdown: %v", err)
+		}
+
+		// Let running jobs finish, or requeue them once the grace period
+		// is over, before tearing down the browser
+		if queueManager != nil {
+			drainCtx, cancelDrain := context.WithTimeout(ctx, cfg.DrainTimeout)
+			if err := queueManager.Drain(drainCtx); err != nil {
+				log.Printf("Queue drain incomplete: %v", err)
+			}
+			cancelDrain()
+		}
 		if browserManager != nil {
 			if err := browserManager.Stop(); err != nil {
 				log.Printf("Failed to stop Lightpanda browser: %v", err)
 			}
 		}
-		if err := app.Shutdown(); err != nil {
-			log.Printf("Error during shutdown: %v", err)
-		}
 	}()
 
 	// Start server
@@ -203,4 +330,14 @@ func main() {
 	if err := app.Listen(addr); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
+
+	// Listen returns as soon as the listener closes; wait for the drain
+	<-shutdownDone
+
+	// Export the spans of the last requests and jobs before exiting
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracer.Shutdown(ctx); err != nil {
+		log.Printf("Failed to flush traces: %v", err)
+	}
 }
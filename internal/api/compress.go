@@ -0,0 +1,96 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/compress"
+	"github.com/gofiber/websocket/v2"
+)
+
+// compressedHTMLFields are the result fields gzipped by ?compressed=1
+var compressedHTMLFields = []string{"html", "initial_html"}
+
+// SetupCompression compresses /scrq responses for clients sending
+// Accept-Encoding (br, gzip or deflate). Event streams and WebSockets are
+// left alone so events are not held back by the compressor.
+func SetupCompression(app *fiber.App) {
+	app.Use("/scrq", compress.New(compress.Config{
+		Level: compress.LevelBestSpeed,
+		Next: func(c *fiber.Ctx) bool {
+			return strings.HasSuffix(c.Path(), "/events") || websocket.IsWebSocketUpgrade(c)
+		},
+	}))
+}
+
+// compressResultHTML returns result with every html and initial_html field,
+// including those of batch items, replaced by its base64 gzip encoding
+func compressResultHTML(result interface{}) (interface{}, error) {
+	if result == nil {
+		return nil, nil
+	}
+
+	// Results are structs while the job is in memory and maps once loaded
+	// from the KV store, so work on the JSON form of either
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil, err
+	}
+	return generic, gzipHTMLFields(generic)
+}
+
+func gzipHTMLFields(value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if html, ok := field.(string); ok && isCompressedHTMLField(key) {
+				encoded, err := gzipBase64(html)
+				if err != nil {
+					return err
+				}
+				v[key] = encoded
+				continue
+			}
+			if err := gzipHTMLFields(field); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := gzipHTMLFields(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isCompressedHTMLField(key string) bool {
+	for _, field := range compressedHTMLFields {
+		if key == field {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipBase64 returns s gzipped and base64 encoded
+func gzipBase64(s string) (string, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,90 @@
+package api_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ahrdadan/scrq/internal/api"
+	"github.com/ahrdadan/scrq/internal/browser"
+	"github.com/ahrdadan/scrq/internal/queue"
+	"github.com/gofiber/fiber/v2"
+)
+
+func gunzip(t *testing.T, data []byte) []byte {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to open gzip: %v", err)
+	}
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("Failed to gunzip: %v", err)
+	}
+	return out
+}
+
+func TestJobResultCompression(t *testing.T) {
+	qm, _ := newTestQueueManager(t)
+	app := fiber.New(fiber.Config{ErrorHandler: api.ErrorHandler})
+	api.SetupCompression(app)
+	api.SetupJobRoutesWithConfig(app, qm, api.DefaultRouteConfig())
+
+	html := "<html><body>" + strings.Repeat("<p>scraped</p>", 1000) + "</body></html>"
+	job := queue.NewJob(queue.JobRequest{Type: queue.JobTypeScrape, URL: "https://example.com"})
+	job.SetResult(&browser.PageResult{URL: "https://example.com", HTML: html})
+	if err := qm.GetStore().Save(job); err != nil {
+		t.Fatalf("Failed to store job: %v", err)
+	}
+
+	// Clients sending Accept-Encoding get a gzipped body
+	req := httptest.NewRequest("GET", "/scrq/jobs/"+job.ID+"/result", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.Header.Get("Content-Encoding") != "gzip" || len(body) >= len(html) {
+		t.Fatalf("Expected a gzipped response smaller than the HTML, got %q with %d bytes", resp.Header.Get("Content-Encoding"), len(body))
+	}
+	if !strings.Contains(string(gunzip(t, body)), "scraped") {
+		t.Error("Expected the gzipped body to hold the result")
+	}
+
+	// Clients without it get plain JSON
+	resp, _ = app.Test(httptest.NewRequest("GET", "/scrq/jobs/"+job.ID+"/result", nil))
+	if resp.Header.Get("Content-Encoding") != "" {
+		t.Errorf("Expected no compression without Accept-Encoding, got %q", resp.Header.Get("Content-Encoding"))
+	}
+
+	// ?compressed=1 returns the HTML itself as base64 gzip
+	resp, _ = app.Test(httptest.NewRequest("GET", "/scrq/jobs/"+job.ID+"/result?compressed=1", nil))
+	var response struct {
+		Data struct {
+			Compressed bool `json:"compressed"`
+			Result     struct {
+				URL  string `json:"url"`
+				HTML string `json:"html"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if !response.Data.Compressed || response.Data.Result.URL != "https://example.com" {
+		t.Fatalf("Expected a compressed result keeping its other fields, got %+v", response.Data)
+	}
+	raw, err := base64.StdEncoding.DecodeString(response.Data.Result.HTML)
+	if err != nil {
+		t.Fatalf("Expected base64 HTML: %v", err)
+	}
+	if string(gunzip(t, raw)) != html {
+		t.Error("Expected the HTML to round-trip through gzip")
+	}
+}
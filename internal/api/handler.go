@@ -1,18 +1,32 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ahrdadan/scrq/internal/browser"
+	"github.com/ahrdadan/scrq/internal/config"
 	"github.com/gofiber/fiber/v2"
 )
 
 // Handler handles API requests
 type Handler struct {
 	browserManager browser.Client
+
+	// maxTimeout caps the page timeout clients may request (0 = no cap)
+	maxTimeout time.Duration
+
+	// batchLimiter bounds pages in flight across all concurrent batch
+	// scrapes, whatever each request's concurrency (nil = no cap)
+	batchLimiter *browser.PageLimiter
 }
 
 // NewHandler creates a new handler
@@ -42,25 +56,67 @@ func ErrorHandler(c *fiber.Ctx, err error) error {
 	})
 }
 
-// HealthCheck returns health status
-func (h *Handler) HealthCheck(c *fiber.Ctx) error {
+// browserError maps a browser operation failure to an HTTP error
+func browserError(err error) error {
+	if errors.Is(err, browser.ErrTooManyPages) || errors.Is(err, browser.ErrTooManySessions) {
+		return fiber.NewError(fiber.StatusServiceUnavailable, err.Error())
+	}
+	if errors.Is(err, browser.ErrInvalidOptions) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if errors.Is(err, browser.ErrUnsupported) {
+		return fiber.NewError(fiber.StatusNotImplemented, err.Error())
+	}
+	if errors.Is(err, browser.ErrElementNotFound) || errors.Is(err, browser.ErrSessionNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	if errors.Is(err, browser.ErrScriptTimeout) {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+	}
+	return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+}
+
+// consoleError responds like ErrorHandler, adding the console messages the
+// page logged before it failed
+func consoleError(c *fiber.Ctx, err error, console []browser.ConsoleMessage) error {
+	code := fiber.StatusInternalServerError
+	if e, ok := err.(*fiber.Error); ok {
+		code = e.Code
+	}
+
+	return c.Status(code).JSON(Response{
+		Success: false,
+		Error:   err.Error(),
+		Data:    map[string]interface{}{"console": console},
+	})
+}
+
+// Version returns the server name, version and build info
+func Version(c *fiber.Ctx) error {
 	return c.JSON(Response{
 		Success: true,
 		Data: map[string]interface{}{
-			"status":    "ok",
-			"timestamp": time.Now().UTC().Format(time.RFC3339),
+			"name":       config.AppName,
+			"version":    config.Version,
+			"commit":     config.Commit,
+			"build_date": config.BuildDate,
 		},
 	})
 }
 
 // BrowserStatus returns browser status
 func (h *Handler) BrowserStatus(c *fiber.Ctx) error {
+	data := map[string]interface{}{
+		"running":  h.browserManager.IsRunning(),
+		"endpoint": h.browserManager.GetEndpoint(),
+	}
+	if usage, err := h.browserManager.Usage(); err == nil {
+		data["usage"] = usage
+	}
+
 	return c.JSON(Response{
 		Success: true,
-		Data: map[string]interface{}{
-			"running":  h.browserManager.IsRunning(),
-			"endpoint": h.browserManager.GetEndpoint(),
-		},
+		Data:    data,
 	})
 }
 
@@ -72,6 +128,35 @@ type RequestOptions struct {
 	Headers     map[string]string     `json:"headers,omitempty"`
 	Cookies     []browser.CookieParam `json:"cookies,omitempty"`
 	Proxy       string                `json:"proxy,omitempty"`
+
+	AcceptLanguage   string `json:"accept_language,omitempty"`
+	WaitForFunction  string `json:"wait_for_function,omitempty"`
+	WaitPollInterval int    `json:"wait_poll_interval,omitempty"` // milliseconds
+
+	WaitForSelectors []string         `json:"wait_for_selectors,omitempty"`
+	WaitMode         browser.WaitMode `json:"wait_mode,omitempty"` // any or all (default)
+	DismissConsent   bool             `json:"dismiss_consent,omitempty"`
+	PierceShadow     bool             `json:"pierce_shadow,omitempty"`
+	IncludeCookies   bool             `json:"include_cookies,omitempty"` // Return cookies after navigation (fetch only)
+	CaptureConsole   bool             `json:"capture_console,omitempty"` // Return console messages and page errors (fetch only)
+
+	WaitForSelector        string `json:"wait_for_selector,omitempty"`
+	WaitForSelectorTimeout int    `json:"wait_for_selector_timeout,omitempty"` // milliseconds
+
+	AutoScroll  bool `json:"auto_scroll,omitempty"`
+	ScrollSteps int  `json:"scroll_steps,omitempty"`
+	ScrollDelay int  `json:"scroll_delay,omitempty"` // milliseconds
+
+	BlockResources []string `json:"block_resources,omitempty"` // image, font, stylesheet, media
+
+	Viewport *browser.Viewport `json:"viewport,omitempty"`
+	Device   string            `json:"device,omitempty"` // iphone-13, pixel-7
+
+	BasicAuth *browser.Credentials `json:"basic_auth,omitempty"` // Site HTTP basic auth
+
+	SessionID string `json:"session_id,omitempty"` // Reuse a session's cookies and storage (chrome only)
+
+	ScriptTimeout int `json:"script_timeout,omitempty"` // milliseconds a script may run (evaluate only)
 }
 
 func buildPageOptions(req RequestOptions, defaultWait bool) browser.PageOptions {
@@ -88,16 +173,90 @@ func buildPageOptions(req RequestOptions, defaultWait bool) browser.PageOptions
 	opts.Headers = req.Headers
 	opts.Cookies = req.Cookies
 	opts.Proxy = req.Proxy
+	opts.AcceptLanguage = req.AcceptLanguage
+	opts.WaitForFunction = req.WaitForFunction
+	opts.WaitPollInterval = time.Duration(req.WaitPollInterval) * time.Millisecond
+	opts.WaitForSelectors = req.WaitForSelectors
+	opts.WaitMode = req.WaitMode
+	opts.WaitForSelector = req.WaitForSelector
+	opts.WaitForSelectorTimeout = time.Duration(req.WaitForSelectorTimeout) * time.Millisecond
+	opts.AutoScroll = req.AutoScroll
+	opts.ScrollSteps = req.ScrollSteps
+	opts.ScrollDelay = time.Duration(req.ScrollDelay) * time.Millisecond
+	opts.BlockResources = req.BlockResources
+	opts.Viewport = req.Viewport
+	opts.Device = req.Device
+	opts.DismissConsent = req.DismissConsent
+	opts.PierceShadow = req.PierceShadow
+	opts.IncludeCookies = req.IncludeCookies
+	opts.CaptureConsole = req.CaptureConsole
+	opts.BasicAuth = req.BasicAuth
+	opts.SessionID = req.SessionID
+	opts.ScriptTimeout = time.Duration(req.ScriptTimeout) * time.Millisecond
+	return opts
+}
+
+// pageOptions builds the page options for a request, clamping the timeout to
+// the server's ceiling for synchronous endpoints
+func (h *Handler) pageOptions(c *fiber.Ctx, req RequestOptions, defaultWait bool) browser.PageOptions {
+	opts := buildPageOptions(req, defaultWait)
+	opts.Timeout = clampTimeout(c, opts.Timeout, h.maxTimeout)
 	return opts
 }
 
+// clampTimeout caps a requested timeout at max (0 = no cap). A clamped
+// timeout is reported in the X-Timeout-Clamped header (seconds).
+func clampTimeout(c *fiber.Ctx, timeout, max time.Duration) time.Duration {
+	if max > 0 && timeout > max {
+		c.Set("X-Timeout-Clamped", strconv.Itoa(int(max/time.Second)))
+		return max
+	}
+	return timeout
+}
+
+// timeoutCeiling returns the lowest of the configured timeout limits,
+// ignoring unset (zero) ones
+func timeoutCeiling(limits ...time.Duration) time.Duration {
+	var ceiling time.Duration
+	for _, limit := range limits {
+		if limit > 0 && (ceiling == 0 || limit < ceiling) {
+			ceiling = limit
+		}
+	}
+	return ceiling
+}
+
 // FetchRequest represents a fetch request
 type FetchRequest struct {
-	URL        string `json:"url" validate:"required"`
-	Screenshot bool   `json:"screenshot"`
+	URL         string   `json:"url" validate:"required"`
+	Screenshot  bool     `json:"screenshot"`
+	InitialHTML bool     `json:"initial_html"`     // Also return the pre-JavaScript server HTML
+	Render      *bool    `json:"render,omitempty"` // false fetches over plain HTTP without a browser
+	Fields      []string `json:"fields,omitempty"` // Response fields to keep (default: all)
 	RequestOptions
 }
 
+// selectFields drops response keys not listed in fields; no fields keeps
+// them all. screenshot_format follows screenshot, and rendered is always kept.
+func selectFields(response map[string]interface{}, fields []string) map[string]interface{} {
+	if len(fields) == 0 {
+		return response
+	}
+	keep := map[string]bool{"rendered": true}
+	for _, field := range fields {
+		keep[field] = true
+	}
+	if keep["screenshot"] {
+		keep["screenshot_format"] = true
+	}
+	for key := range response {
+		if !keep[key] {
+			delete(response, key)
+		}
+	}
+	return response
+}
+
 // FetchPage fetches a page and returns its content
 func (h *Handler) FetchPage(c *fiber.Ctx) error {
 	var req FetchRequest
@@ -108,22 +267,87 @@ func (h *Handler) FetchPage(c *fiber.Ctx) error {
 	if req.URL == "" {
 		return fiber.NewError(fiber.StatusBadRequest, "URL is required")
 	}
+	if err := browser.ValidateResultFields(req.Fields); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
 
-	opts := buildPageOptions(req.RequestOptions, false)
+	opts := h.pageOptions(c, req.RequestOptions, false)
 	opts.Screenshot = req.Screenshot
+	opts.CaptureInitialHTML = req.InitialHTML
 
 	ctx := context.Background()
+	if req.Render != nil && !*req.Render {
+		if req.Screenshot || req.InitialHTML || req.CaptureConsole || req.SessionID != "" {
+			return fiber.NewError(fiber.StatusBadRequest, "screenshot, initial_html, capture_console and session_id require render")
+		}
+		result, err := browser.FetchStatic(ctx, req.URL, opts)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadGateway, err.Error())
+		}
+		response := map[string]interface{}{
+			"url":           result.URL,
+			"requested_url": result.RequestedURL,
+			"title":         result.Title,
+			"html":          result.HTML,
+			"text":          result.Text,
+			"links":         result.Links,
+			"status_code":   result.StatusCode,
+			"headers":       result.Headers,
+			"final_url":     result.FinalURL,
+			"rendered":      false,
+		}
+		if req.IncludeCookies {
+			response["cookies"] = result.Cookies
+		}
+		return c.JSON(Response{
+			Success: true,
+			Data:    selectFields(response, req.Fields),
+		})
+	}
+
 	result, err := h.browserManager.FetchPage(ctx, req.URL, opts)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		if console := browser.ConsoleMessages(err); len(console) > 0 {
+			return consoleError(c, browserError(err), console)
+		}
+		return browserError(err)
 	}
 
 	response := map[string]interface{}{
-		"url":   result.URL,
-		"title": result.Title,
-		"html":  result.HTML,
-		"text":  result.Text,
-		"links": result.Links,
+		"url":           result.URL,
+		"requested_url": result.RequestedURL,
+		"title":         result.Title,
+		"html":          result.HTML,
+		"text":          result.Text,
+		"links":         result.Links,
+	}
+	if result.StatusCode != 0 {
+		response["status_code"] = result.StatusCode
+		response["headers"] = result.Headers
+		response["final_url"] = result.FinalURL
+	}
+	if len(result.MatchedSelectors) > 0 {
+		response["matched_selectors"] = result.MatchedSelectors
+	}
+	if req.InitialHTML {
+		response["initial_html"] = result.InitialHTML
+	}
+	if req.IncludeCookies {
+		cookies := result.Cookies
+		if cookies == nil {
+			cookies = []browser.CookieInfo{}
+		}
+		response["cookies"] = cookies
+	}
+	if len(req.BlockResources) > 0 {
+		response["blocked_requests"] = result.BlockedRequests
+	}
+	if req.CaptureConsole {
+		console := result.Console
+		if console == nil {
+			console = []browser.ConsoleMessage{}
+		}
+		response["console"] = console
 	}
 
 	if len(result.Screenshot) > 0 {
@@ -133,7 +357,7 @@ func (h *Handler) FetchPage(c *fiber.Ctx) error {
 
 	return c.JSON(Response{
 		Success: true,
-		Data:    response,
+		Data:    selectFields(response, req.Fields),
 	})
 }
 
@@ -141,6 +365,7 @@ func (h *Handler) FetchPage(c *fiber.Ctx) error {
 type ScreenshotRequest struct {
 	URL      string `json:"url" validate:"required"`
 	FullPage bool   `json:"full_page"`
+	browser.ScreenshotOptions
 	RequestOptions
 }
 
@@ -156,15 +381,50 @@ func (h *Handler) Screenshot(c *fiber.Ctx) error {
 	}
 
 	ctx := context.Background()
-	opts := buildPageOptions(req.RequestOptions, false)
-	screenshot, err := h.browserManager.TakeScreenshot(ctx, req.URL, req.FullPage, opts)
+	opts := h.pageOptions(c, req.RequestOptions, false)
+	screenshot, err := h.browserManager.TakeScreenshot(ctx, req.URL, req.FullPage, req.ScreenshotOptions, opts)
+	if err != nil {
+		return browserError(err)
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"screenshot": base64.StdEncoding.EncodeToString(screenshot),
+			"format":     req.ImageFormat(),
+		},
+	})
+}
+
+// ElementScreenshotRequest represents an element screenshot request
+type ElementScreenshotRequest struct {
+	URL      string `json:"url" validate:"required"`
+	Selector string `json:"selector" validate:"required"`
+	RequestOptions
+}
+
+// ScreenshotElement takes a screenshot of one element on a page
+func (h *Handler) ScreenshotElement(c *fiber.Ctx) error {
+	var req ElementScreenshotRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.URL == "" || req.Selector == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "URL and selector are required")
+	}
+
+	ctx := context.Background()
+	opts := h.pageOptions(c, req.RequestOptions, false)
+	screenshot, err := h.browserManager.ScreenshotElement(ctx, req.URL, req.Selector, opts)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return browserError(err)
 	}
 
 	return c.JSON(Response{
 		Success: true,
 		Data: map[string]interface{}{
+			"selector":   req.Selector,
 			"screenshot": base64.StdEncoding.EncodeToString(screenshot),
 			"format":     "png",
 		},
@@ -190,10 +450,10 @@ func (h *Handler) EvaluateScript(c *fiber.Ctx) error {
 	}
 
 	ctx := context.Background()
-	opts := buildPageOptions(req.RequestOptions, false)
+	opts := h.pageOptions(c, req.RequestOptions, false)
 	result, err := h.browserManager.EvaluateScript(ctx, req.URL, req.Script, opts)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return browserError(err)
 	}
 
 	return c.JSON(Response{
@@ -223,10 +483,10 @@ func (h *Handler) ClickElement(c *fiber.Ctx) error {
 	}
 
 	ctx := context.Background()
-	opts := buildPageOptions(req.RequestOptions, false)
+	opts := h.pageOptions(c, req.RequestOptions, false)
 	err := h.browserManager.ClickElement(ctx, req.URL, req.Selector, opts)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return browserError(err)
 	}
 
 	return c.JSON(Response{
@@ -256,10 +516,10 @@ func (h *Handler) FillForm(c *fiber.Ctx) error {
 	}
 
 	ctx := context.Background()
-	opts := buildPageOptions(req.RequestOptions, false)
+	opts := h.pageOptions(c, req.RequestOptions, false)
 	err := h.browserManager.FillForm(ctx, req.URL, req.Inputs, opts)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return browserError(err)
 	}
 
 	return c.JSON(Response{
@@ -270,6 +530,167 @@ func (h *Handler) FillForm(c *fiber.Ctx) error {
 	})
 }
 
+// HoverRequest represents a hover request
+type HoverRequest struct {
+	URL      string `json:"url" validate:"required"`
+	Selector string `json:"selector" validate:"required"`
+	RequestOptions
+}
+
+// Hover moves the mouse over an element on a page
+func (h *Handler) Hover(c *fiber.Ctx) error {
+	var req HoverRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.URL == "" || req.Selector == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "URL and selector are required")
+	}
+
+	ctx := context.Background()
+	opts := h.pageOptions(c, req.RequestOptions, false)
+	err := h.browserManager.HoverElement(ctx, req.URL, req.Selector, opts)
+	if err != nil {
+		return browserError(err)
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"hovered": true,
+		},
+	})
+}
+
+// SelectRequest represents a dropdown selection request
+type SelectRequest struct {
+	URL      string `json:"url" validate:"required"`
+	Selector string `json:"selector" validate:"required"`
+	browser.OptionQuery
+	RequestOptions
+}
+
+// SelectOption selects an option of a <select> element by value or label
+func (h *Handler) SelectOption(c *fiber.Ctx) error {
+	var req SelectRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.URL == "" || req.Selector == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "URL and selector are required")
+	}
+	if err := req.OptionQuery.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	ctx := context.Background()
+	opts := h.pageOptions(c, req.RequestOptions, false)
+	err := h.browserManager.SelectOption(ctx, req.URL, req.Selector, req.OptionQuery, opts)
+	if err != nil {
+		return browserError(err)
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"selected": true,
+		},
+	})
+}
+
+// KeyRequest represents a key press request
+type KeyRequest struct {
+	URL      string `json:"url" validate:"required"`
+	Selector string `json:"selector,omitempty"`
+	Key      string `json:"key" validate:"required"`
+	RequestOptions
+}
+
+// PressKey presses a key on an element, or on the focused element when no
+// selector is given
+func (h *Handler) PressKey(c *fiber.Ctx) error {
+	var req KeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.URL == "" || req.Key == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "URL and key are required")
+	}
+	if err := browser.ValidateKey(req.Key); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	ctx := context.Background()
+	opts := h.pageOptions(c, req.RequestOptions, false)
+	err := h.browserManager.PressKey(ctx, req.URL, req.Selector, req.Key, opts)
+	if err != nil {
+		return browserError(err)
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"pressed": req.Key,
+		},
+	})
+}
+
+// ActionsRequest represents an action sequence request
+type ActionsRequest struct {
+	Actions []browser.Action `json:"actions" validate:"required"`
+	RequestOptions
+}
+
+// RunActions applies a sequence of actions to one page in one session
+func (h *Handler) RunActions(c *fiber.Ctx) error {
+	var req ActionsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := browser.ValidateActions(req.Actions); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	ctx := context.Background()
+	opts := h.pageOptions(c, req.RequestOptions, true)
+	results, err := h.browserManager.RunActions(ctx, req.Actions, opts)
+	if err != nil {
+		if step := browser.FailedAction(err); step >= 0 {
+			return actionsError(c, browserError(err), step, results)
+		}
+		return browserError(err)
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"results": results,
+		},
+	})
+}
+
+// actionsError responds like ErrorHandler, adding the failed step index and
+// the results of the steps completed before it
+func actionsError(c *fiber.Ctx, err error, step int, results []browser.ActionResult) error {
+	code := fiber.StatusInternalServerError
+	if e, ok := err.(*fiber.Error); ok {
+		code = e.Code
+	}
+
+	return c.Status(code).JSON(Response{
+		Success: false,
+		Error:   err.Error(),
+		Data: map[string]interface{}{
+			"failed_step": step,
+			"results":     results,
+		},
+	})
+}
+
 // LinksRequest represents a links extraction request
 type LinksRequest struct {
 	URL string `json:"url" validate:"required"`
@@ -287,11 +708,11 @@ func (h *Handler) ExtractLinks(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "URL is required")
 	}
 
-	opts := buildPageOptions(req.RequestOptions, false)
+	opts := h.pageOptions(c, req.RequestOptions, false)
 	ctx := context.Background()
 	result, err := h.browserManager.FetchPage(ctx, req.URL, opts)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return browserError(err)
 	}
 
 	return c.JSON(Response{
@@ -316,10 +737,10 @@ func (h *Handler) GetPageInfo(c *fiber.Ctx) error {
 	}
 
 	ctx := context.Background()
-	opts := buildPageOptions(req.RequestOptions, false)
+	opts := h.pageOptions(c, req.RequestOptions, false)
 	result, err := h.browserManager.GetPageInfo(ctx, req.URL, opts)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return browserError(err)
 	}
 
 	return c.JSON(Response{
@@ -328,17 +749,16 @@ func (h *Handler) GetPageInfo(c *fiber.Ctx) error {
 	})
 }
 
-// ScrapeRequest represents a scraping request
-type ScrapeRequest struct {
-	URL       string   `json:"url" validate:"required"`
-	Selectors []string `json:"selectors"`
-	Script    string   `json:"script"`
+// MarkdownRequest represents a Markdown conversion request
+type MarkdownRequest struct {
+	URL      string `json:"url" validate:"required"`
+	Selector string `json:"selector"`
 	RequestOptions
 }
 
-// Scrape scrapes data from a page
-func (h *Handler) Scrape(c *fiber.Ctx) error {
-	var req ScrapeRequest
+// GetMarkdown returns the page content converted to Markdown
+func (h *Handler) GetMarkdown(c *fiber.Ctx) error {
+	var req MarkdownRequest
 	if err := c.BodyParser(&req); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
@@ -348,102 +768,824 @@ func (h *Handler) Scrape(c *fiber.Ctx) error {
 	}
 
 	ctx := context.Background()
-	opts := buildPageOptions(req.RequestOptions, false)
-
-	// If custom script provided, use it
-	if req.Script != "" {
-		result, err := h.browserManager.EvaluateScript(ctx, req.URL, req.Script, opts)
-		if err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, err.Error())
-		}
-
-		return c.JSON(Response{
-			Success: true,
-			Data: map[string]interface{}{
-				"url":    req.URL,
-				"result": result,
-			},
-		})
-	}
-
-	// Otherwise fetch page content
-	result, err := h.browserManager.FetchPage(ctx, req.URL, opts)
+	opts := h.pageOptions(c, req.RequestOptions, false)
+	result, err := h.browserManager.GetMarkdown(ctx, req.URL, req.Selector, opts)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return browserError(err)
 	}
 
 	return c.JSON(Response{
 		Success: true,
 		Data: map[string]interface{}{
-			"url":   result.URL,
-			"title": result.Title,
-			"html":  result.HTML,
-			"text":  result.Text,
+			"url":           result.URL,
+			"requested_url": result.RequestedURL,
+			"title":         result.Title,
+			"markdown":      result.Markdown,
 		},
 	})
 }
 
-// BatchScrapeRequest represents a batch scraping request
-type BatchScrapeRequest struct {
-	URLs       []string `json:"urls" validate:"required"`
-	Script     string   `json:"script"`
-	Concurrent int      `json:"concurrent"`
+// CountRequest represents an element count request
+type CountRequest struct {
+	URL       string   `json:"url" validate:"required"`
+	Selector  string   `json:"selector"`
+	Selectors []string `json:"selectors"`
 	RequestOptions
 }
 
-// BatchScrapeResult represents a single result in batch scraping
-type BatchScrapeResult struct {
-	URL   string      `json:"url"`
-	Data  interface{} `json:"data,omitempty"`
-	Error string      `json:"error,omitempty"`
-}
-
-// BatchScrape scrapes multiple pages concurrently
-func (h *Handler) BatchScrape(c *fiber.Ctx) error {
-	var req BatchScrapeRequest
+// CountElements returns how many elements match one or more selectors
+func (h *Handler) CountElements(c *fiber.Ctx) error {
+	var req CountRequest
 	if err := c.BodyParser(&req); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	if len(req.URLs) == 0 {
-		return fiber.NewError(fiber.StatusBadRequest, "URLs are required")
+	selectors := req.Selectors
+	if req.Selector != "" {
+		selectors = append([]string{req.Selector}, selectors...)
 	}
 
-	concurrent := req.Concurrent
-	if concurrent <= 0 {
-		concurrent = 3
+	if req.URL == "" || len(selectors) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "URL and selector are required")
+	}
+
+	ctx := context.Background()
+	opts := h.pageOptions(c, req.RequestOptions, false)
+	counts, err := h.browserManager.CountElements(ctx, req.URL, selectors, opts)
+	if err != nil {
+		return browserError(err)
+	}
+
+	data := map[string]interface{}{
+		"url":    req.URL,
+		"counts": counts,
+	}
+	if req.Selector != "" {
+		data["count"] = counts[req.Selector]
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data:    data,
+	})
+}
+
+// AttributesRequest represents an attribute extraction request
+type AttributesRequest struct {
+	URL       string                   `json:"url" validate:"required"`
+	Selector  string                   `json:"selector"`
+	Attribute string                   `json:"attribute"`
+	Queries   []browser.AttributeQuery `json:"queries"`
+	RequestOptions
+}
+
+// ExtractAttributes returns attribute values of elements matching selectors
+func (h *Handler) ExtractAttributes(c *fiber.Ctx) error {
+	var req AttributesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	queries := req.Queries
+	if req.Selector != "" || req.Attribute != "" {
+		queries = append([]browser.AttributeQuery{{Selector: req.Selector, Attribute: req.Attribute}}, queries...)
+	}
+
+	if req.URL == "" || len(queries) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "URL and at least one selector/attribute pair are required")
+	}
+	for _, query := range queries {
+		if query.Selector == "" || query.Attribute == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Each query requires a selector and an attribute")
+		}
+	}
+
+	ctx := context.Background()
+	opts := h.pageOptions(c, req.RequestOptions, false)
+	results, err := h.browserManager.ExtractAttributes(ctx, req.URL, queries, opts)
+	if err != nil {
+		return browserError(err)
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"url":     req.URL,
+			"results": results,
+		},
+	})
+}
+
+// BoxesRequest represents an element bounding box request
+type BoxesRequest struct {
+	URL         string   `json:"url" validate:"required"`
+	Selector    string   `json:"selector"`
+	Selectors   []string `json:"selectors"`
+	Screenshots bool     `json:"screenshots"` // Include a PNG of each element
+	RequestOptions
+}
+
+// ElementBoxes returns the bounding boxes of elements matching selectors
+func (h *Handler) ElementBoxes(c *fiber.Ctx) error {
+	var req BoxesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	selectors := req.Selectors
+	if req.Selector != "" {
+		selectors = append([]string{req.Selector}, selectors...)
+	}
+
+	if req.URL == "" || len(selectors) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "URL and selector are required")
+	}
+
+	ctx := context.Background()
+	opts := h.pageOptions(c, req.RequestOptions, false)
+	results, err := h.browserManager.ElementBoxes(ctx, req.URL, selectors, req.Screenshots, opts)
+	if err != nil {
+		return browserError(err)
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"url":     req.URL,
+			"results": results,
+		},
+	})
+}
+
+// FramesRequest represents a progressive screenshot request
+type FramesRequest struct {
+	URL       string `json:"url" validate:"required"`
+	Interval  int    `json:"interval"`   // Milliseconds between frames (default 500, min 100)
+	MaxFrames int    `json:"max_frames"` // Default 10, max 30
+	RequestOptions
+}
+
+// CaptureFrames returns screenshots taken at intervals while a page loads
+func (h *Handler) CaptureFrames(c *fiber.Ctx) error {
+	var req FramesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.URL == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "URL is required")
+	}
+
+	interval := browser.DefaultFrameInterval
+	if req.Interval > 0 {
+		interval = time.Duration(req.Interval) * time.Millisecond
+	}
+	maxFrames := req.MaxFrames
+	if maxFrames <= 0 {
+		maxFrames = browser.DefaultMaxFrames
+	}
+	if maxFrames > browser.MaxFrames {
+		maxFrames = browser.MaxFrames
+	}
+
+	ctx := context.Background()
+	opts := h.pageOptions(c, req.RequestOptions, false)
+	frames, err := h.browserManager.CaptureFrames(ctx, req.URL, interval, maxFrames, opts)
+	if err != nil {
+		return browserError(err)
+	}
+
+	encoded := make([]map[string]interface{}, 0, len(frames))
+	for _, frame := range frames {
+		encoded = append(encoded, map[string]interface{}{
+			"offset_ms": frame.OffsetMS,
+			"loaded":    frame.Loaded,
+			"image":     base64.StdEncoding.EncodeToString(frame.Image),
+		})
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"url":    req.URL,
+			"format": "jpeg",
+			"frames": encoded,
+			"count":  len(encoded),
+		},
+	})
+}
+
+// FeedsRequest represents a feed discovery request
+type FeedsRequest struct {
+	URL   string `json:"url" validate:"required"`
+	Fetch bool   `json:"fetch"` // Fetch and parse each discovered feed
+	RequestOptions
+}
+
+// FeedResult is a discovered feed, with its parsed content when fetched
+type FeedResult struct {
+	browser.FeedLink
+	Feed  *browser.Feed `json:"feed,omitempty"`
+	Error string        `json:"error,omitempty"`
+}
+
+// maxFetchedFeeds caps how many discovered feeds are fetched per request
+const maxFetchedFeeds = 5
+
+// DiscoverFeeds finds the RSS/Atom feeds a page links to and optionally
+// parses them
+func (h *Handler) DiscoverFeeds(c *fiber.Ctx) error {
+	var req FeedsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.URL == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "URL is required")
+	}
+
+	ctx := context.Background()
+	opts := h.pageOptions(c, req.RequestOptions, false)
+	links, err := h.browserManager.DiscoverFeeds(ctx, req.URL, opts)
+	if err != nil {
+		return browserError(err)
+	}
+
+	feeds := make([]FeedResult, 0, len(links))
+	for i, link := range links {
+		result := FeedResult{FeedLink: link}
+		if req.Fetch && i < maxFetchedFeeds {
+			fetchCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			result.Feed, err = browser.FetchFeed(fetchCtx, link.URL)
+			cancel()
+			if err != nil {
+				result.Error = err.Error()
+			}
+		}
+		feeds = append(feeds, result)
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"url":   req.URL,
+			"feeds": feeds,
+		},
+	})
+}
+
+// MetadataRequest represents a page metadata request
+type MetadataRequest struct {
+	URL string `json:"url" validate:"required"`
+	RequestOptions
+}
+
+// ExtractMetadata returns the JSON-LD blocks, OpenGraph properties and meta
+// tags of a page
+func (h *Handler) ExtractMetadata(c *fiber.Ctx) error {
+	var req MetadataRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.URL == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "URL is required")
+	}
+
+	ctx := context.Background()
+	opts := h.pageOptions(c, req.RequestOptions, false)
+	metadata, err := h.browserManager.ExtractMetadata(ctx, req.URL, opts)
+	if err != nil {
+		return browserError(err)
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data:    metadata,
+	})
+}
+
+// TablesRequest represents a table extraction request
+type TablesRequest struct {
+	URL      string `json:"url" validate:"required"`
+	Selector string `json:"selector,omitempty"` // Only tables matching it (all tables if empty)
+	RequestOptions
+}
+
+// ExtractTables returns the page's HTML tables as header and row cells
+func (h *Handler) ExtractTables(c *fiber.Ctx) error {
+	var req TablesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.URL == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "URL is required")
+	}
+
+	ctx := context.Background()
+	opts := h.pageOptions(c, req.RequestOptions, false)
+	tables, err := h.browserManager.ExtractTables(ctx, req.URL, req.Selector, opts)
+	if err != nil {
+		return browserError(err)
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"url":    req.URL,
+			"count":  len(tables),
+			"tables": tables,
+		},
+	})
+}
+
+// DownloadRequest represents a file download request
+type DownloadRequest struct {
+	URL      string `json:"url" validate:"required"`
+	Selector string `json:"selector" validate:"required"`
+	RequestOptions
+}
+
+// DownloadFile clicks an element and returns the file it downloads
+func (h *Handler) DownloadFile(c *fiber.Ctx) error {
+	var req DownloadRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.URL == "" || req.Selector == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "URL and selector are required")
+	}
+
+	ctx := context.Background()
+	opts := h.pageOptions(c, req.RequestOptions, true)
+	result, err := h.browserManager.DownloadFile(ctx, req.URL, req.Selector, opts)
+	if err != nil {
+		return browserError(err)
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"url":          req.URL,
+			"filename":     result.Filename,
+			"download_url": result.URL,
+			"size":         result.Size,
+			"data":         base64.StdEncoding.EncodeToString(result.Data),
+		},
+	})
+}
+
+// PDFRequest represents a PDF rendering request
+type PDFRequest struct {
+	URL string `json:"url" validate:"required"`
+	browser.PDFOptions
+	RequestOptions
+}
+
+// RenderPDF prints a page to PDF (chrome only)
+func (h *Handler) RenderPDF(c *fiber.Ctx) error {
+	var req PDFRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.URL == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "URL is required")
+	}
+
+	ctx := context.Background()
+	opts := h.pageOptions(c, req.RequestOptions, true)
+	pdf, err := h.browserManager.RenderPDF(ctx, req.URL, req.PDFOptions, opts)
+	if err != nil {
+		return browserError(err)
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"url":          req.URL,
+			"content_type": "application/pdf",
+			"size":         len(pdf),
+			"data":         base64.StdEncoding.EncodeToString(pdf),
+		},
+	})
+}
+
+// CDPRequest represents a raw CDP command request
+type CDPRequest struct {
+	URL    string          `json:"url" validate:"required"`
+	Method string          `json:"method" validate:"required"`
+	Params json.RawMessage `json:"params,omitempty"`
+	RequestOptions
+}
+
+// ExecuteCDP runs an allow-listed raw CDP method against a page
+func (h *Handler) ExecuteCDP(c *fiber.Ctx) error {
+	var req CDPRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.URL == "" || req.Method == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "URL and method are required")
+	}
+
+	if !browser.IsCDPMethodAllowed(req.Method) {
+		return fiber.NewError(fiber.StatusForbidden, "CDP method not allowed: "+req.Method)
+	}
+
+	params := bytes.TrimSpace(req.Params)
+	if len(params) > 0 && string(params) != "null" && params[0] != '{' {
+		return fiber.NewError(fiber.StatusBadRequest, "params must be a JSON object")
+	}
+
+	ctx := context.Background()
+	opts := h.pageOptions(c, req.RequestOptions, false)
+	result, err := h.browserManager.ExecuteCDP(ctx, req.URL, req.Method, req.Params, opts)
+	if err != nil {
+		if errors.Is(err, browser.ErrCDPCommand) {
+			return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+		}
+		return browserError(err)
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"method": req.Method,
+			"result": result,
+		},
+	})
+}
+
+// CreateSession starts a browser session whose cookies and storage persist
+// across requests passing its session_id (chrome only)
+// POST /scrq/sessions
+func (h *Handler) CreateSession(c *fiber.Ctx) error {
+	session, err := h.browserManager.CreateSession()
+	if err != nil {
+		return browserError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(Response{
+		Success: true,
+		Data:    session,
+	})
+}
+
+// CloseSession closes a browser session
+// DELETE /scrq/sessions/:id
+func (h *Handler) CloseSession(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.browserManager.CloseSession(id); err != nil {
+		return browserError(err)
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"session_id": id,
+			"closed":     true,
+		},
+	})
+}
+
+// ScrapeRequest represents a scraping request
+type ScrapeRequest struct {
+	URL       string   `json:"url" validate:"required"`
+	Selectors []string `json:"selectors"`
+	Script    string   `json:"script"`
+	RequestOptions
+
+	// JSONScriptSelector selects a <script> holding JSON (e.g.
+	// "script#__NEXT_DATA__"); JSONPath picks data out of it (default "$").
+	JSONScriptSelector string `json:"json_script_selector,omitempty"`
+	JSONPath           string `json:"json_path,omitempty"`
+}
+
+// Scrape scrapes data from a page
+func (h *Handler) Scrape(c *fiber.Ctx) error {
+	var req ScrapeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.URL == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "URL is required")
+	}
+
+	ctx := context.Background()
+	opts := h.pageOptions(c, req.RequestOptions, false)
+
+	// Extract embedded JSON if requested
+	if req.JSONScriptSelector != "" || req.JSONPath != "" {
+		return h.scrapeJSONScript(c, ctx, req, opts)
+	}
+
+	// Extract element text per selector if requested
+	if len(req.Selectors) > 0 {
+		if req.Script != "" {
+			return fiber.NewError(fiber.StatusBadRequest, "selectors cannot be combined with script")
+		}
+		values, err := h.browserManager.ExtractBySelectors(ctx, req.URL, req.Selectors, opts)
+		if err != nil {
+			return browserError(err)
+		}
+
+		return c.JSON(Response{
+			Success: true,
+			Data: map[string]interface{}{
+				"url":  req.URL,
+				"data": values,
+			},
+		})
+	}
+
+	// If custom script provided, use it
+	if req.Script != "" {
+		result, err := h.browserManager.EvaluateScript(ctx, req.URL, req.Script, opts)
+		if err != nil {
+			return browserError(err)
+		}
+
+		return c.JSON(Response{
+			Success: true,
+			Data: map[string]interface{}{
+				"url":    req.URL,
+				"result": result,
+			},
+		})
+	}
+
+	// Otherwise fetch page content
+	result, err := h.browserManager.FetchPage(ctx, req.URL, opts)
+	if err != nil {
+		return browserError(err)
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"url":   result.URL,
+			"title": result.Title,
+			"html":  result.HTML,
+			"text":  result.Text,
+		},
+	})
+}
+
+// scrapeJSONScript handles Scrape requests for embedded JSON
+func (h *Handler) scrapeJSONScript(c *fiber.Ctx, ctx context.Context, req ScrapeRequest, opts browser.PageOptions) error {
+	if req.JSONScriptSelector == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "json_script_selector is required with json_path")
+	}
+	if req.Script != "" {
+		return fiber.NewError(fiber.StatusBadRequest, "script cannot be combined with json_script_selector")
+	}
+	if req.JSONPath != "" {
+		if _, err := browser.ParseJSONPath(req.JSONPath); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+
+	result, err := h.browserManager.ExtractJSONScript(ctx, req.URL, req.JSONScriptSelector, req.JSONPath, opts)
+	if err != nil {
+		if errors.Is(err, browser.ErrJSONPathNoMatch) {
+			return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+		}
+		return browserError(err)
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"url":    req.URL,
+			"result": result,
+		},
+	})
+}
+
+// BatchScrapeRequest represents a batch scraping request
+type BatchScrapeRequest struct {
+	URLs       []string `json:"urls" validate:"required"`
+	Script     string   `json:"script"`
+	Selectors  []string `json:"selectors"`
+	Concurrent int      `json:"concurrent"`
+	Aggregate  string   `json:"aggregate,omitempty"` // list (default), flat or by_url
+	Reducer    string   `json:"reducer,omitempty"`   // flat only: unique, count or sum
+	// MaxDuration time-boxes the whole batch in seconds: URLs not started
+	// by then are skipped and the completed results returned
+	MaxDuration int `json:"max_duration,omitempty"`
+	RequestOptions
+}
+
+// errBatchDeadline marks the URLs a batch skipped once its max_duration
+// passed
+var errBatchDeadline = errors.New("skipped: batch deadline exceeded")
+
+// Batch aggregation modes
+const (
+	AggregateList  = "list"
+	AggregateFlat  = "flat"
+	AggregateByURL = "by_url"
+)
+
+// batchReducers reduce the merged items of a flat aggregation
+var batchReducers = map[string]func(items []interface{}) (interface{}, error){
+	"unique": func(items []interface{}) (interface{}, error) {
+		seen := make(map[string]bool, len(items))
+		unique := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			key, err := json.Marshal(item)
+			if err != nil {
+				return nil, err
+			}
+			if !seen[string(key)] {
+				seen[string(key)] = true
+				unique = append(unique, item)
+			}
+		}
+		return unique, nil
+	},
+	"count": func(items []interface{}) (interface{}, error) {
+		return len(items), nil
+	},
+	"sum": func(items []interface{}) (interface{}, error) {
+		var sum float64
+		for _, item := range items {
+			n, ok := item.(float64)
+			if !ok {
+				return nil, fmt.Errorf("sum reducer needs numbers, got %T", item)
+			}
+			sum += n
+		}
+		return sum, nil
+	},
+}
+
+// aggregateBatch merges the successful results of a batch. Flat spreads
+// array results into one list (other values are appended as-is) and applies
+// the reducer, if any; by_url keys results by URL. Failed URLs are returned
+// separately so errors stay visible.
+func aggregateBatch(results []BatchScrapeResult, mode string, reducer string) (interface{}, []BatchScrapeResult, error) {
+	var failed []BatchScrapeResult
+	for _, result := range results {
+		if result.Error != "" {
+			failed = append(failed, result)
+		}
+	}
+
+	switch mode {
+	case AggregateByURL:
+		byURL := make(map[string]interface{}, len(results))
+		for _, result := range results {
+			if result.Error == "" {
+				byURL[result.URL] = result.Data
+			}
+		}
+		return byURL, failed, nil
+	case AggregateFlat:
+		items := []interface{}{}
+		for _, result := range results {
+			if result.Error != "" {
+				continue
+			}
+			// Round-trip through JSON so script values and page maps share one shape
+			data, err := json.Marshal(result.Data)
+			if err != nil {
+				return nil, nil, err
+			}
+			var value interface{}
+			if err := json.Unmarshal(data, &value); err != nil {
+				return nil, nil, err
+			}
+			if list, ok := value.([]interface{}); ok {
+				items = append(items, list...)
+			} else if value != nil {
+				items = append(items, value)
+			}
+		}
+		if reducer == "" {
+			return items, failed, nil
+		}
+		reduced, err := batchReducers[reducer](items)
+		return reduced, failed, err
+	default:
+		return results, nil, nil
+	}
+}
+
+// BatchScrapeResult represents a single result in batch scraping
+type BatchScrapeResult struct {
+	URL   string      `json:"url"`
+	Data  interface{} `json:"data,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// BatchScrape scrapes multiple pages concurrently
+func (h *Handler) BatchScrape(c *fiber.Ctx) error {
+	var req BatchScrapeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if len(req.URLs) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "URLs are required")
+	}
+	if len(req.Selectors) > 0 && req.Script != "" {
+		return fiber.NewError(fiber.StatusBadRequest, "selectors cannot be combined with script")
+	}
+	switch req.Aggregate {
+	case "", AggregateList, AggregateFlat, AggregateByURL:
+	default:
+		return fiber.NewError(fiber.StatusBadRequest, "aggregate must be list, flat or by_url")
+	}
+	if req.Reducer != "" {
+		if req.Aggregate != AggregateFlat {
+			return fiber.NewError(fiber.StatusBadRequest, "reducer requires aggregate flat")
+		}
+		if batchReducers[req.Reducer] == nil {
+			return fiber.NewError(fiber.StatusBadRequest, "reducer must be unique, count or sum")
+		}
+	}
+	if req.MaxDuration < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "max_duration must not be negative")
+	}
+
+	concurrent := req.Concurrent
+	if concurrent <= 0 {
+		concurrent = 3
 	}
 	if concurrent > 10 {
 		concurrent = 10
 	}
 
+	ctx := context.Background()
+	if req.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(req.MaxDuration)*time.Second)
+		defer cancel()
+	}
+
 	results := make([]BatchScrapeResult, len(req.URLs))
-	opts := buildPageOptions(req.RequestOptions, false)
+	opts := h.pageOptions(c, req.RequestOptions, false)
 	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, concurrent)
 
+	// Back off when the browser starts timing out, ramp up on success
+	limiter := browser.NewAdaptiveLimiter(concurrent)
+
+	var saturated, skipped int32
 	for i, url := range req.URLs {
 		wg.Add(1)
 		go func(idx int, targetURL string) {
 			defer wg.Done()
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
+			limiter.Acquire()
 
-			ctx := context.Background()
 			result := BatchScrapeResult{URL: targetURL}
+			skip := func() {
+				atomic.AddInt32(&skipped, 1)
+				result.Error = errBatchDeadline.Error()
+				limiter.Release(errBatchDeadline)
+				results[idx] = result
+			}
+			if ctx.Err() != nil {
+				skip()
+				return
+			}
+
+			// Wait for a slot in the cap shared by all batch requests
+			release, err := h.batchLimiter.Acquire(ctx)
+			if err != nil && ctx.Err() != nil {
+				skip()
+				return
+			}
+			if err != nil {
+				atomic.AddInt32(&saturated, 1)
+				result.Error = err.Error()
+				limiter.Release(err)
+				results[idx] = result
+				return
+			}
+			defer release()
 
-			if req.Script != "" {
-				data, err := h.browserManager.EvaluateScript(ctx, targetURL, req.Script, opts)
-				if err != nil {
-					result.Error = err.Error()
-				} else {
+			if len(req.Selectors) > 0 {
+				var values map[string][]string
+				values, err = h.browserManager.ExtractBySelectors(ctx, targetURL, req.Selectors, opts)
+				if err == nil {
+					result.Data = values
+				}
+			} else if req.Script != "" {
+				var data interface{}
+				data, err = h.browserManager.EvaluateScript(ctx, targetURL, req.Script, opts)
+				if err == nil {
 					result.Data = data
 				}
 			} else {
-				pageResult, err := h.browserManager.FetchPage(ctx, targetURL, opts)
-				if err != nil {
-					result.Error = err.Error()
-				} else {
+				var pageResult *browser.PageResult
+				pageResult, err = h.browserManager.FetchPage(ctx, targetURL, opts)
+				if err == nil {
 					result.Data = map[string]interface{}{
 						"title": pageResult.Title,
 						"text":  pageResult.Text,
@@ -452,17 +1594,64 @@ func (h *Handler) BatchScrape(c *fiber.Ctx) error {
 				}
 			}
 
+			if err != nil && ctx.Err() != nil {
+				// Cut short by the batch deadline, which says nothing
+				// about browser health
+				result.Error = err.Error()
+				limiter.Release(errBatchDeadline)
+				results[idx] = result
+				return
+			}
+			if err != nil {
+				result.Error = err.Error()
+				// A failure while the browser is down means it crashed or is restarting
+				if !h.browserManager.IsRunning() {
+					limiter.Backoff()
+				}
+			}
+			limiter.Release(err)
+
 			results[idx] = result
 		}(i, url)
 	}
 
 	wg.Wait()
 
+	// Nothing ran: every URL timed out waiting for the shared batch cap
+	if int(saturated) == len(req.URLs) {
+		return browserError(fmt.Errorf("%w: batch capacity saturated", browser.ErrTooManyPages))
+	}
+
+	data := map[string]interface{}{
+		"results": results,
+		"total":   len(results),
+		"concurrency": map[string]int{
+			"requested": concurrent,
+			"lowest":    limiter.Lowest(),
+			"final":     limiter.Limit(),
+		},
+	}
+	if skipped > 0 {
+		data["partial"] = true
+		data["skipped"] = int(skipped)
+	}
+
+	if req.Aggregate == AggregateFlat || req.Aggregate == AggregateByURL {
+		aggregated, failed, err := aggregateBatch(results, req.Aggregate, req.Reducer)
+		if err != nil {
+			return fiber.NewError(fiber.StatusUnprocessableEntity, fmt.Sprintf("Failed to aggregate results: %v", err))
+		}
+		delete(data, "results")
+		data["aggregate"] = req.Aggregate
+		data["data"] = aggregated
+		if failed == nil {
+			failed = []BatchScrapeResult{}
+		}
+		data["errors"] = failed
+	}
+
 	return c.JSON(Response{
 		Success: true,
-		Data: map[string]interface{}{
-			"results": results,
-			"total":   len(results),
-		},
+		Data:    data,
 	})
 }
@@ -1,13 +1,20 @@
 package api_test
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ahrdadan/scrq/internal/api"
+	"github.com/ahrdadan/scrq/internal/browser"
+	"github.com/ahrdadan/scrq/internal/browser/browsertest"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -187,6 +194,47 @@ func TestFetchPageMissingURL(t *testing.T) {
 	}
 }
 
+func TestFetchPageFields(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: api.ErrorHandler,
+	})
+	api.SetupRoutesWithConfig(app, newTestClient(), api.DefaultRouteConfig())
+
+	reqBody := `{"url": "https://example.com", "fields": ["url", "title"]}`
+	req := httptest.NewRequest("POST", "/scrq/page/fetch", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	var response api.Response
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	data := response.Data.(map[string]interface{})
+	if len(data) != 2 || data["url"] != "https://example.com" {
+		t.Errorf("Expected only url and title, got %v", data)
+	}
+
+	reqBody = `{"url": "https://example.com", "fields": ["nope"]}`
+	req = httptest.NewRequest("POST", "/scrq/page/fetch", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	if resp.StatusCode != 400 {
+		t.Errorf("Expected status 400 for unknown field, got %d", resp.StatusCode)
+	}
+}
+
 func TestScreenshot(t *testing.T) {
 	app := setupTestApp()
 
@@ -236,3 +284,956 @@ func TestInvalidJSON(t *testing.T) {
 	}
 }
 
+// newTestClient returns a MockClient with the canned responses the handler
+// tests expect. Like the real clients it rejects invalid options; "slow"
+// URLs block until the request gives up and "fail" URLs fail scripts.
+func newTestClient() *browsertest.MockClient {
+	value := "value"
+	client := browsertest.NewMockClient()
+	client.ResourceUsage = &browser.ResourceUsage{PID: 42, Processes: 1, RSSBytes: 1 << 20, OpenPages: 1}
+	client.Screenshot = []byte{0x89, 'P', 'N', 'G'}
+	client.ScriptResult = []interface{}{"first", "second", "shared"}
+	client.Counts = map[string]int{"a": 2, ".item": 2}
+	client.Texts = map[string][]string{"h1": {"Example"}}
+	client.Attributes = []browser.AttributeResult{
+		{Selector: "a", Attribute: "href", Values: []*string{&value, nil}},
+		{Selector: "img", Attribute: "src", Values: []*string{&value, nil}},
+	}
+	client.Boxes = []browser.BoxResult{{Selector: "h1", Boxes: []*browser.ElementBox{{X: 1, Y: 2, Width: 30, Height: 40}, nil}}}
+	for i := 0; i < browser.MaxFrames; i++ {
+		client.Frames = append(client.Frames, browser.ScreenshotFrame{OffsetMS: int64(i) * 250, Loaded: i == browser.MaxFrames-1, Image: []byte{0xff, 0xd8}})
+	}
+	client.Metadata = &browser.PageMetadata{
+		URL:       "https://example.com/widget",
+		Title:     "Widget",
+		JSONLD:    []json.RawMessage{json.RawMessage(`{"@type":"Product","name":"Widget"}`)},
+		OpenGraph: map[string]string{"og:title": "Widget"},
+		Meta:      map[string]string{"description": "A widget"},
+	}
+	client.Tables = []browser.Table{{Headers: []string{"Team", "Points"}, Rows: [][]string{{"Reds", "42"}, {"Blues", ""}}}}
+	client.JSONResult = map[string]interface{}{"props": map[string]interface{}{}}
+	client.Download = &browser.DownloadResult{Filename: "export.csv", Size: 3, Data: []byte("a,b")}
+	client.PDF = []byte("%PDF-1.4")
+	client.CDPResult = json.RawMessage(`{}`)
+	client.Session = &browser.SessionInfo{ID: "sess_1"}
+
+	client.CallFunc = func(ctx context.Context, call browsertest.Call) error {
+		if strings.Contains(call.URL, "slow") {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		if call.Opts.SessionID != "" && call.Opts.SessionID != "sess_1" {
+			return browser.ErrSessionNotFound
+		}
+
+		switch call.Method {
+		case browsertest.MethodTakeScreenshot:
+			return call.Args[1].(browser.ScreenshotOptions).Validate()
+		case browsertest.MethodScreenshotElement, browsertest.MethodExtractTables:
+			if call.Args[0] == "#missing" {
+				return browser.ErrElementNotFound
+			}
+		case browsertest.MethodEvaluateScript:
+			if strings.Contains(call.URL, "fail") {
+				return errors.New("evaluation failed")
+			}
+			if call.Opts.ScriptTimeout > 0 && strings.Contains(call.Args[0].(string), "while (true)") {
+				return fmt.Errorf("%w of %s", browser.ErrScriptTimeout, call.Opts.ScriptTimeout)
+			}
+		case browsertest.MethodSelectOption:
+			return call.Args[1].(browser.OptionQuery).Validate()
+		case browsertest.MethodRunActions:
+			for i, action := range call.Args[0].([]browser.Action) {
+				if action.Selector == "#missing" {
+					return &browser.ActionError{Index: i, Type: action.Type, Err: errors.New("element not found: #missing")}
+				}
+			}
+		case browsertest.MethodRenderPDF:
+			return call.Args[0].(browser.PDFOptions).Validate()
+		case browsertest.MethodCloseSession:
+			if call.Args[0] != "sess_1" {
+				return browser.ErrSessionNotFound
+			}
+		}
+		return nil
+	}
+	return client
+}
+
+func setupCDPTestApp() *fiber.App {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: api.ErrorHandler,
+	})
+
+	config := api.DefaultRouteConfig()
+	config.AdminToken = "secret"
+	api.SetupRoutesWithConfig(app, newTestClient(), config)
+
+	return app
+}
+
+func doCDPRequest(t *testing.T, body, token string) int {
+	t.Helper()
+	app := setupCDPTestApp()
+
+	req := httptest.NewRequest("POST", "/scrq/page/cdp", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("X-Admin-Token", token)
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	return resp.StatusCode
+}
+
+func TestExecuteCDP(t *testing.T) {
+	status := doCDPRequest(t, `{"url": "https://example.com", "method": "Page.getLayoutMetrics"}`, "secret")
+	if status != 200 {
+		t.Errorf("Expected status 200, got %d", status)
+	}
+}
+
+func TestExecuteCDPMethodNotAllowed(t *testing.T) {
+	status := doCDPRequest(t, `{"url": "https://example.com", "method": "Browser.close"}`, "secret")
+	if status != 403 {
+		t.Errorf("Expected status 403, got %d", status)
+	}
+}
+
+func TestExecuteCDPMissingFields(t *testing.T) {
+	status := doCDPRequest(t, `{"url": "https://example.com"}`, "secret")
+	if status != 400 {
+		t.Errorf("Expected status 400 for missing method, got %d", status)
+	}
+
+	status = doCDPRequest(t, `{"method": "Page.getLayoutMetrics"}`, "secret")
+	if status != 400 {
+		t.Errorf("Expected status 400 for missing url, got %d", status)
+	}
+}
+
+func TestExecuteCDPInvalidParams(t *testing.T) {
+	status := doCDPRequest(t, `{"url": "https://example.com", "method": "DOM.getDocument", "params": [1]}`, "secret")
+	if status != 400 {
+		t.Errorf("Expected status 400, got %d", status)
+	}
+}
+
+func TestExecuteCDPAdminToken(t *testing.T) {
+	body := `{"url": "https://example.com", "method": "Page.getLayoutMetrics"}`
+
+	if status := doCDPRequest(t, body, ""); status != 401 {
+		t.Errorf("Expected status 401 without token, got %d", status)
+	}
+
+	if status := doCDPRequest(t, body, "wrong"); status != 401 {
+		t.Errorf("Expected status 401 with wrong token, got %d", status)
+	}
+}
+
+func TestCountElements(t *testing.T) {
+	app := setupCDPTestApp()
+
+	reqBody := `{"url": "https://example.com", "selectors": ["a", ".item"]}`
+	req := httptest.NewRequest("POST", "/scrq/page/count", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	var response api.Response
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	data := response.Data.(map[string]interface{})
+	counts := data["counts"].(map[string]interface{})
+	if len(counts) != 2 || counts[".item"] != float64(2) {
+		t.Errorf("Unexpected counts: %v", counts)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	app := fiber.New()
+	app.Get("/scrq/version", api.Version)
+
+	req := httptest.NewRequest("GET", "/scrq/version", nil)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	var response api.Response
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	data := response.Data.(map[string]interface{})
+	if data["version"] == "" || data["name"] == "" {
+		t.Errorf("Expected name and version, got %v", data)
+	}
+}
+
+func TestExtractAttributes(t *testing.T) {
+	app := setupCDPTestApp()
+
+	reqBody := `{"url": "https://example.com", "selector": "a", "attribute": "href", "queries": [{"selector": "img", "attribute": "src"}]}`
+	req := httptest.NewRequest("POST", "/scrq/page/attributes", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	var response api.Response
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	data := response.Data.(map[string]interface{})
+	results := data["results"].([]interface{})
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	first := results[0].(map[string]interface{})
+	values := first["values"].([]interface{})
+	if first["attribute"] != "href" || values[0] != "value" || values[1] != nil {
+		t.Errorf("Unexpected result: %v", first)
+	}
+}
+
+func TestSyncTimeoutClamped(t *testing.T) {
+	app := setupCDPTestApp()
+
+	reqBody := `{"url": "https://example.com", "timeout": 600}`
+	req := httptest.NewRequest("POST", "/scrq/page/fetch", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("X-Timeout-Clamped"); got != "120" {
+		t.Errorf("Expected timeout clamped to 120s, got %q", got)
+	}
+}
+
+func TestSyncTimeoutClampedToJobMax(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: api.ErrorHandler,
+	})
+	config := api.DefaultRouteConfig()
+	config.MaxSyncTimeout = 0
+	config.MaxJobTimeout = 5 * time.Minute
+	api.SetupRoutesWithConfig(app, newTestClient(), config)
+
+	reqBody := `{"url": "https://example.com", "timeout": 600}`
+	req := httptest.NewRequest("POST", "/scrq/page/fetch", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("X-Timeout-Clamped"); got != "300" {
+		t.Errorf("Expected timeout clamped to 300s, got %q", got)
+	}
+}
+
+func TestElementBoxes(t *testing.T) {
+	app := setupCDPTestApp()
+
+	reqBody := `{"url": "https://example.com", "selector": "h1"}`
+	req := httptest.NewRequest("POST", "/scrq/page/boxes", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	var response api.Response
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	data := response.Data.(map[string]interface{})
+	results := data["results"].([]interface{})
+	boxes := results[0].(map[string]interface{})["boxes"].([]interface{})
+	first := boxes[0].(map[string]interface{})
+	if first["width"] != float64(30) || boxes[1] != nil {
+		t.Errorf("Unexpected boxes: %v", boxes)
+	}
+}
+
+func TestScreenshotFormat(t *testing.T) {
+	app := setupCDPTestApp()
+
+	post := func(body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest("POST", "/scrq/page/screenshot", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		var response api.Response
+		raw, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(raw, &response)
+		data, _ := response.Data.(map[string]interface{})
+		return resp.StatusCode, data
+	}
+
+	for body, want := range map[string]string{
+		`{"url": "https://example.com"}`:                                 "png",
+		`{"url": "https://example.com", "format": "jpg", "quality": 60}`: "jpeg",
+		`{"url": "https://example.com", "format": "webp"}`:               "webp",
+	} {
+		status, data := post(body)
+		if status != 200 || data["format"] != want {
+			t.Errorf("%s: expected %s, got %d %v", body, want, status, data["format"])
+		}
+	}
+
+	if status, _ := post(`{"url": "https://example.com", "format": "gif"}`); status != 400 {
+		t.Errorf("Expected status 400 for an unknown format, got %d", status)
+	}
+}
+
+func TestScreenshotElement(t *testing.T) {
+	app := setupCDPTestApp()
+
+	post := func(body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest("POST", "/scrq/page/screenshot/element", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		var response api.Response
+		raw, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(raw, &response)
+		data, _ := response.Data.(map[string]interface{})
+		return resp.StatusCode, data
+	}
+
+	status, data := post(`{"url": "https://example.com", "selector": ".chart"}`)
+	if status != 200 {
+		t.Fatalf("Expected status 200, got %d", status)
+	}
+	if data["screenshot"] != "iVBORw==" || data["format"] != "png" || data["selector"] != ".chart" {
+		t.Errorf("Unexpected response: %v", data)
+	}
+
+	if status, _ := post(`{"url": "https://example.com", "selector": "#missing"}`); status != 404 {
+		t.Errorf("Expected status 404 for a missing element, got %d", status)
+	}
+	if status, _ := post(`{"url": "https://example.com"}`); status != 400 {
+		t.Errorf("Expected status 400 without a selector, got %d", status)
+	}
+}
+
+func TestCaptureFrames(t *testing.T) {
+	app := setupCDPTestApp()
+
+	reqBody := `{"url": "https://example.com", "interval": 250, "max_frames": 50}`
+	req := httptest.NewRequest("POST", "/scrq/page/frames", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	var response api.Response
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	data := response.Data.(map[string]interface{})
+	frames := data["frames"].([]interface{})
+	if len(frames) != browser.MaxFrames || data["count"] != float64(browser.MaxFrames) {
+		t.Fatalf("Expected max_frames clamped to %d, got %d", browser.MaxFrames, len(frames))
+	}
+	second := frames[1].(map[string]interface{})
+	if second["offset_ms"] != float64(250) || second["image"] != "/9g=" {
+		t.Errorf("Unexpected frame: %v", second)
+	}
+	if frames[len(frames)-1].(map[string]interface{})["loaded"] != true {
+		t.Error("Expected last frame to be marked loaded")
+	}
+}
+
+func TestDiscoverFeeds(t *testing.T) {
+	feedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = io.WriteString(w, `<rss version="2.0"><channel><title>News</title><item><title>First</title><link>https://example.com/1</link></item></channel></rss>`)
+	}))
+	defer feedServer.Close()
+
+	app := fiber.New(fiber.Config{
+		ErrorHandler: api.ErrorHandler,
+	})
+	client := newTestClient()
+	client.Feeds = []browser.FeedLink{{URL: feedServer.URL, Type: "application/rss+xml"}}
+	api.SetupRoutesWithConfig(app, client, api.DefaultRouteConfig())
+
+	reqBody := `{"url": "https://example.com", "fetch": true}`
+	req := httptest.NewRequest("POST", "/scrq/page/feeds", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	var response api.Response
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	data := response.Data.(map[string]interface{})
+	feeds := data["feeds"].([]interface{})
+	if len(feeds) != 1 {
+		t.Fatalf("Expected 1 feed, got %d", len(feeds))
+	}
+	feed := feeds[0].(map[string]interface{})
+	if feed["url"] != feedServer.URL || feed["feed"] == nil {
+		t.Fatalf("Unexpected feed: %v", feed)
+	}
+	items := feed["feed"].(map[string]interface{})["items"].([]interface{})
+	if len(items) != 1 || items[0].(map[string]interface{})["title"] != "First" {
+		t.Errorf("Unexpected items: %v", items)
+	}
+}
+
+func TestExtractMetadata(t *testing.T) {
+	app := setupCDPTestApp()
+
+	req := httptest.NewRequest("POST", "/scrq/page/metadata", strings.NewReader(`{"url": "https://example.com/widget"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Data struct {
+			URL       string            `json:"url"`
+			JSONLD    []json.RawMessage `json:"json_ld"`
+			OpenGraph map[string]string `json:"open_graph"`
+			Meta      map[string]string `json:"meta"`
+		} `json:"data"`
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	data := response.Data
+	if data.URL != "https://example.com/widget" || len(data.JSONLD) != 1 || string(data.JSONLD[0]) != `{"@type":"Product","name":"Widget"}` {
+		t.Errorf("Unexpected response: %s", body)
+	}
+	if data.OpenGraph["og:title"] != "Widget" || data.Meta["description"] != "A widget" {
+		t.Errorf("Unexpected tags: %s", body)
+	}
+}
+
+func TestExtractTables(t *testing.T) {
+	app := setupCDPTestApp()
+
+	post := func(body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest("POST", "/scrq/page/tables", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		var response api.Response
+		raw, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(raw, &response)
+		data, _ := response.Data.(map[string]interface{})
+		return resp.StatusCode, data
+	}
+
+	status, data := post(`{"url": "https://example.com/stats"}`)
+	if status != 200 {
+		t.Fatalf("Expected status 200, got %d", status)
+	}
+	tables := data["tables"].([]interface{})
+	first := tables[0].(map[string]interface{})
+	if data["count"] != float64(1) || len(first["headers"].([]interface{})) != 2 || len(first["rows"].([]interface{})) != 2 {
+		t.Errorf("Unexpected response: %v", data)
+	}
+
+	if status, _ := post(`{"url": "https://example.com/stats", "selector": "#missing"}`); status != 404 {
+		t.Errorf("Expected status 404 when no table matches, got %d", status)
+	}
+}
+
+func TestExtractAttributesMissingAttribute(t *testing.T) {
+	app := setupCDPTestApp()
+
+	reqBody := `{"url": "https://example.com", "queries": [{"selector": "a"}]}`
+	req := httptest.NewRequest("POST", "/scrq/page/attributes", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+
+	if resp.StatusCode != 400 {
+		t.Errorf("Expected status 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestScrapeJSONScript(t *testing.T) {
+	app := setupCDPTestApp()
+
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{"url": "https://example.com", "json_script_selector": "script#__NEXT_DATA__", "json_path": "$.props.pageProps"}`, 200},
+		{`{"url": "https://example.com", "json_path": "$.props"}`, 400},
+		{`{"url": "https://example.com", "json_script_selector": "script#data", "json_path": "props"}`, 400},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/scrq/scrape", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.body, tt.status, resp.StatusCode)
+		}
+	}
+}
+
+func TestFetchPageConsole(t *testing.T) {
+	post := func(client *browsertest.MockClient, body string) (int, api.Response) {
+		app := fiber.New(fiber.Config{
+			ErrorHandler: api.ErrorHandler,
+		})
+		api.SetupRoutesWithConfig(app, client, api.DefaultRouteConfig())
+
+		req := httptest.NewRequest("POST", "/scrq/page/fetch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		var response api.Response
+		raw, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(raw, &response)
+		return resp.StatusCode, response
+	}
+
+	status, response := post(newTestClient(), `{"url": "https://example.com", "capture_console": true}`)
+	if status != 200 {
+		t.Fatalf("Expected status 200, got %d", status)
+	}
+	data, _ := response.Data.(map[string]interface{})
+	if console, ok := data["console"].([]interface{}); !ok || len(console) != 0 {
+		t.Errorf("Expected an empty console list, got %v", data["console"])
+	}
+
+	failing := newTestClient()
+	failing.Errors[browsertest.MethodFetchPage] = &browser.PageError{
+		Err:     errors.New("failed to wait for page load"),
+		Console: []browser.ConsoleMessage{{Type: "exception", Text: "TypeError: x is undefined", Timestamp: 1710000000000}},
+	}
+	status, response = post(failing, `{"url": "https://example.com", "capture_console": true}`)
+	if status != 500 || response.Success {
+		t.Fatalf("Expected a failed response with status 500, got %d", status)
+	}
+	data, _ = response.Data.(map[string]interface{})
+	if console, ok := data["console"].([]interface{}); !ok || len(console) != 1 {
+		t.Errorf("Expected the console messages on failure, got %v", response.Data)
+	}
+
+	if status, _ := post(newTestClient(), `{"url": "https://example.com", "capture_console": true, "render": false}`); status != 400 {
+		t.Errorf("Expected capture_console without render to be rejected, got %d", status)
+	}
+}
+
+func TestBatchScrapeAggregate(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: api.ErrorHandler,
+	})
+	api.SetupRoutesWithConfig(app, newTestClient(), api.DefaultRouteConfig())
+
+	post := func(body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest("POST", "/scrq/scrape/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		var response api.Response
+		raw, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(raw, &response)
+		data, _ := response.Data.(map[string]interface{})
+		return resp.StatusCode, data
+	}
+
+	status, data := post(`{"urls": ["https://a.example", "https://b.example", "https://fail.example"], "script": "x", "aggregate": "flat", "reducer": "unique"}`)
+	if status != 200 {
+		t.Fatalf("Expected status 200, got %d", status)
+	}
+	if items := data["data"].([]interface{}); len(items) != 3 {
+		t.Errorf("Expected 3 unique merged items, got %v", items)
+	}
+	if errs := data["errors"].([]interface{}); len(errs) != 1 {
+		t.Errorf("Expected the failed URL to be reported, got %v", errs)
+	}
+
+	status, data = post(`{"urls": ["https://a.example"], "script": "x", "aggregate": "by_url"}`)
+	if status != 200 {
+		t.Fatalf("Expected status 200, got %d", status)
+	}
+	if byURL := data["data"].(map[string]interface{}); byURL["https://a.example"] == nil {
+		t.Errorf("Expected results keyed by URL, got %v", byURL)
+	}
+
+	if status, _ := post(`{"urls": ["https://a.example"], "reducer": "count"}`); status != 400 {
+		t.Errorf("Expected reducer without flat aggregation to be rejected, got %d", status)
+	}
+}
+
+func TestBatchScrapeSharedLimit(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: api.ErrorHandler,
+	})
+	config := api.DefaultRouteConfig()
+	config.BatchLimiter = browser.NewPageLimiter(1, 10*time.Millisecond)
+	api.SetupRoutesWithConfig(app, newTestClient(), config)
+
+	post := func() int {
+		req := httptest.NewRequest("POST", "/scrq/scrape/batch", strings.NewReader(`{"urls": ["https://a.example", "https://b.example"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	// Another batch holds the only slot
+	release, err := config.BatchLimiter.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to acquire slot: %v", err)
+	}
+	if status := post(); status != 503 {
+		t.Errorf("Expected status 503 while the batch cap is saturated, got %d", status)
+	}
+
+	release()
+	if status := post(); status != 200 {
+		t.Errorf("Expected status 200 once a slot is free, got %d", status)
+	}
+	if inUse := config.BatchLimiter.InUse(); inUse != 0 {
+		t.Errorf("Expected all batch slots released, got %d in use", inUse)
+	}
+}
+
+func TestBatchScrapeMaxDuration(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: api.ErrorHandler,
+	})
+	api.SetupRoutesWithConfig(app, newTestClient(), api.DefaultRouteConfig())
+
+	// Two slow URLs run into the deadline; the third never starts
+	body := `{"urls": ["https://slow.example/1", "https://slow.example/2", "https://slow.example/3"], "concurrent": 2, "max_duration": 1}`
+	req := httptest.NewRequest("POST", "/scrq/scrape/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, 5000)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	var response api.Response
+	raw, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(raw, &response)
+	data, _ := response.Data.(map[string]interface{})
+
+	if resp.StatusCode != 200 || data["partial"] != true || data["skipped"] != float64(1) {
+		t.Fatalf("Expected partial results with 1 URL skipped, got %d: %v", resp.StatusCode, data)
+	}
+	skipped := 0
+	for _, result := range data["results"].([]interface{}) {
+		if result.(map[string]interface{})["error"] == "skipped: batch deadline exceeded" {
+			skipped++
+		}
+	}
+	if skipped != 1 {
+		t.Errorf("Expected 1 result marked skipped, got %v", data["results"])
+	}
+	if concurrency := data["concurrency"].(map[string]interface{}); concurrency["lowest"] != float64(2) {
+		t.Errorf("Expected the deadline not to count as overload, got %v", concurrency)
+	}
+
+	req = httptest.NewRequest("POST", "/scrq/scrape/batch", strings.NewReader(`{"urls": ["https://a.example"], "max_duration": -1}`))
+	req.Header.Set("Content-Type", "application/json")
+	if resp, err := app.Test(req); err != nil || resp.StatusCode != 400 {
+		t.Errorf("Expected a negative max_duration to be rejected, got %v", resp.StatusCode)
+	}
+}
+
+func TestScrapeSelectors(t *testing.T) {
+	app := setupCDPTestApp()
+
+	reqBody := `{"url": "https://example.com", "selectors": ["h1", ".missing"]}`
+	req := httptest.NewRequest("POST", "/scrq/scrape", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	var response api.Response
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	values := response.Data.(map[string]interface{})["data"].(map[string]interface{})
+	if h1 := values["h1"].([]interface{}); len(h1) != 1 || h1[0] != "Example" {
+		t.Errorf("Unexpected h1 values: %v", values["h1"])
+	}
+	missing, ok := values[".missing"].([]interface{})
+	if !ok || len(missing) != 0 {
+		t.Errorf("Expected an empty list for an unmatched selector, got %v", values[".missing"])
+	}
+
+	req = httptest.NewRequest("POST", "/scrq/scrape", strings.NewReader(`{"url": "https://example.com", "selectors": ["h1"], "script": "1"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	if resp.StatusCode != 400 {
+		t.Errorf("Expected status 400 for selectors with script, got %d", resp.StatusCode)
+	}
+}
+
+func TestRenderPDF(t *testing.T) {
+	app := setupCDPTestApp()
+
+	post := func(body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest("POST", "/scrq/page/pdf", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		var response api.Response
+		raw, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(raw, &response)
+		data, _ := response.Data.(map[string]interface{})
+		return resp.StatusCode, data
+	}
+
+	status, data := post(`{"url": "https://example.com", "format": "Letter", "landscape": true, "margins": {"top": 0.5}}`)
+	if status != 200 {
+		t.Fatalf("Expected status 200, got %d", status)
+	}
+	if data["data"] != "JVBERi0xLjQ=" || data["content_type"] != "application/pdf" {
+		t.Errorf("Unexpected response: %v", data)
+	}
+
+	if status, _ := post(`{"url": "https://example.com", "format": "A3"}`); status != 400 {
+		t.Errorf("Expected status 400 for an unknown format, got %d", status)
+	}
+}
+
+func TestPageInteractions(t *testing.T) {
+	app := setupCDPTestApp()
+
+	tests := []struct {
+		path   string
+		body   string
+		status int
+	}{
+		{"/scrq/page/hover", `{"url": "https://example.com", "selector": "nav .menu"}`, 200},
+		{"/scrq/page/hover", `{"url": "https://example.com"}`, 400},
+		{"/scrq/page/select", `{"url": "https://example.com", "selector": "select#size", "value": "m"}`, 200},
+		{"/scrq/page/select", `{"url": "https://example.com", "selector": "select#size", "label": "Medium"}`, 200},
+		{"/scrq/page/select", `{"url": "https://example.com", "selector": "select#size"}`, 400},
+		{"/scrq/page/select", `{"url": "https://example.com", "selector": "select#size", "value": "m", "label": "Medium"}`, 400},
+		{"/scrq/page/press", `{"url": "https://example.com", "selector": "input", "key": "Enter"}`, 200},
+		{"/scrq/page/press", `{"url": "https://example.com", "key": "a"}`, 200},
+		{"/scrq/page/press", `{"url": "https://example.com", "key": "Hyper"}`, 400},
+		{"/scrq/page/press", `{"url": "https://example.com"}`, 400},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d", tt.path, tt.body, tt.status, resp.StatusCode)
+		}
+	}
+}
+
+func TestRunActions(t *testing.T) {
+	app := setupCDPTestApp()
+
+	post := func(body string) (int, api.Response) {
+		req := httptest.NewRequest("POST", "/scrq/page/actions", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		var response api.Response
+		respBody, _ := io.ReadAll(resp.Body)
+		if err := json.Unmarshal(respBody, &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		return resp.StatusCode, response
+	}
+
+	status, response := post(`{"actions": [
+		{"type": "navigate", "url": "https://example.com/login"},
+		{"type": "fill", "selector": "#user", "value": "me"},
+		{"type": "click", "selector": "#go"}
+	]}`)
+	if status != 200 {
+		t.Fatalf("Expected status 200, got %d: %s", status, response.Error)
+	}
+	results := response.Data.(map[string]interface{})["results"].([]interface{})
+	if len(results) != 3 || results[2].(map[string]interface{})["type"] != "click" {
+		t.Errorf("Unexpected results: %v", results)
+	}
+
+	if status, _ := post(`{"actions": [{"type": "click", "selector": "#go"}]}`); status != 400 {
+		t.Errorf("Expected 400 when the first action is not navigate, got %d", status)
+	}
+
+	status, response = post(`{"actions": [
+		{"type": "navigate", "url": "https://example.com"},
+		{"type": "click", "selector": "#missing"}
+	]}`)
+	if status != 500 || !strings.Contains(response.Error, "action 1 (click)") {
+		t.Fatalf("Expected the failed step in a 500, got %d: %s", status, response.Error)
+	}
+	data := response.Data.(map[string]interface{})
+	if data["failed_step"] != float64(1) || len(data["results"].([]interface{})) != 1 {
+		t.Errorf("Expected the failed step and the completed results, got %v", data)
+	}
+}
+
+func TestBrowserSessions(t *testing.T) {
+	app := setupCDPTestApp()
+
+	do := func(method, path, body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		var response api.Response
+		raw, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(raw, &response)
+		data, _ := response.Data.(map[string]interface{})
+		return resp.StatusCode, data
+	}
+
+	status, data := do("POST", "/scrq/sessions", "")
+	if status != 201 || data["session_id"] != "sess_1" {
+		t.Fatalf("Expected a created session, got %d: %v", status, data)
+	}
+
+	if status, _ := do("POST", "/scrq/page/fetch", `{"url": "https://example.com", "session_id": "sess_1"}`); status != 200 {
+		t.Errorf("Expected status 200 in the session, got %d", status)
+	}
+	if status, _ := do("POST", "/scrq/page/fetch", `{"url": "https://example.com", "session_id": "sess_gone"}`); status != 404 {
+		t.Errorf("Expected status 404 for an unknown session, got %d", status)
+	}
+
+	if status, data := do("DELETE", "/scrq/sessions/sess_1", ""); status != 200 || data["closed"] != true {
+		t.Errorf("Expected the session to be closed, got %d: %v", status, data)
+	}
+	if status, _ := do("DELETE", "/scrq/sessions/sess_gone", ""); status != 404 {
+		t.Errorf("Expected status 404 closing an unknown session, got %d", status)
+	}
+}
+
+func TestEvaluateScriptTimeout(t *testing.T) {
+	app := setupCDPTestApp()
+
+	body := `{"url": "https://example.com", "script": "while (true) {}", "script_timeout": 50}`
+	req := httptest.NewRequest("POST", "/scrq/page/evaluate", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+
+	var response api.Response
+	raw, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(raw, &response)
+	if resp.StatusCode != 422 || response.Error != "script execution exceeded budget of 50ms" {
+		t.Errorf("Expected 422 for a script over budget, got %d: %s", resp.StatusCode, response.Error)
+	}
+}
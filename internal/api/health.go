@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ahrdadan/scrq/internal/browser"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// probeTimeout bounds each health probe
+const probeTimeout = 2 * time.Second
+
+// Probe checks one dependency of the server; a nil error means it is up
+type Probe struct {
+	Name string
+	// Liveness probes also fail /health. Leave it unset for dependencies a
+	// server restart cannot fix, such as an external NATS cluster.
+	Liveness bool
+	Check    func(ctx context.Context) error
+}
+
+// BrowserProbe reports whether a browser process is running
+func BrowserProbe(name string, client browser.Client) Probe {
+	return Probe{
+		Name:     name,
+		Liveness: true,
+		Check: func(ctx context.Context) error {
+			if !client.IsRunning() {
+				return errors.New("browser is not running")
+			}
+			return nil
+		},
+	}
+}
+
+// NATSProbe reports whether the NATS connection is established
+func NATSProbe(nc *nats.Conn) Probe {
+	return Probe{
+		Name: "nats",
+		Check: func(ctx context.Context) error {
+			if nc == nil {
+				return errors.New("not connected")
+			}
+			if status := nc.Status(); status != nats.CONNECTED {
+				return fmt.Errorf("connection %s", status)
+			}
+			return nil
+		},
+	}
+}
+
+// JetStreamProbe reports whether JetStream answers requests
+func JetStreamProbe(js jetstream.JetStream) Probe {
+	return Probe{
+		Name: "jetstream",
+		Check: func(ctx context.Context) error {
+			if js == nil {
+				return errors.New("not available")
+			}
+			_, err := js.AccountInfo(ctx)
+			return err
+		},
+	}
+}
+
+// HealthHandler serves the liveness and readiness endpoints
+type HealthHandler struct {
+	probes []Probe
+}
+
+// NewHealthHandler creates a health handler running the given probes
+func NewHealthHandler(probes ...Probe) *HealthHandler {
+	return &HealthHandler{probes: probes}
+}
+
+// SetupHealthRoutes registers GET /health (liveness) and GET /ready
+// (readiness)
+func SetupHealthRoutes(app *fiber.App, probes ...Probe) {
+	handler := NewHealthHandler(probes...)
+	app.Get("/health", handler.Health)
+	app.Get("/ready", handler.Ready)
+}
+
+// Health reports whether the server is alive: 503 when a liveness probe,
+// such as a browser process, fails
+// GET /health
+func (h *HealthHandler) Health(c *fiber.Ctx) error {
+	return h.respond(c, true)
+}
+
+// Ready reports whether the server can take traffic: 503 when any
+// dependency, including NATS and JetStream, is down
+// GET /ready
+func (h *HealthHandler) Ready(c *fiber.Ctx) error {
+	return h.respond(c, false)
+}
+
+func (h *HealthHandler) respond(c *fiber.Ctx, liveness bool) error {
+	status, code := "ok", fiber.StatusOK
+	checks := make(map[string]interface{}, len(h.probes))
+	for _, probe := range h.probes {
+		if liveness && !probe.Liveness {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(c.UserContext(), probeTimeout)
+		err := probe.Check(ctx)
+		cancel()
+
+		if err != nil {
+			status, code = "unavailable", fiber.StatusServiceUnavailable
+			checks[probe.Name] = map[string]interface{}{"status": "down", "error": err.Error()}
+		} else {
+			checks[probe.Name] = map[string]interface{}{"status": "up"}
+		}
+	}
+
+	return c.Status(code).JSON(Response{
+		Success: code == fiber.StatusOK,
+		Data: map[string]interface{}{
+			"status":    status,
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+			"checks":    checks,
+		},
+	})
+}
@@ -0,0 +1,79 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ahrdadan/scrq/internal/api"
+	"github.com/ahrdadan/scrq/internal/browser/browsertest"
+	"github.com/gofiber/fiber/v2"
+)
+
+func getHealth(t *testing.T, app *fiber.App, path string) (int, map[string]interface{}) {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	var response api.Response
+	raw, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(raw, &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	data, _ := response.Data.(map[string]interface{})
+	return resp.StatusCode, data
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	client := browsertest.NewMockClient()
+	natsUp := true
+	app := fiber.New()
+	api.SetupHealthRoutes(app,
+		api.BrowserProbe("lightpanda", client),
+		api.Probe{Name: "nats", Check: func(ctx context.Context) error {
+			if !natsUp {
+				return errors.New("connection RECONNECTING")
+			}
+			return nil
+		}},
+	)
+
+	for _, path := range []string{"/health", "/ready"} {
+		if status, data := getHealth(t, app, path); status != 200 || data["status"] != "ok" {
+			t.Errorf("%s: expected 200 ok, got %d: %v", path, status, data)
+		}
+	}
+
+	// NATS is down: not ready, but restarting would not help
+	natsUp = false
+	status, data := getHealth(t, app, "/ready")
+	nats, _ := data["checks"].(map[string]interface{})["nats"].(map[string]interface{})
+	if status != 503 || nats["status"] != "down" || nats["error"] != "connection RECONNECTING" {
+		t.Errorf("Expected /ready to report NATS down with 503, got %d: %v", status, data)
+	}
+	status, data = getHealth(t, app, "/health")
+	if _, ok := data["checks"].(map[string]interface{})["nats"]; status != 200 || ok {
+		t.Errorf("Expected /health to skip the NATS probe, got %d: %v", status, data)
+	}
+
+	// The browser died: neither alive nor ready
+	client.Running = false
+	for _, path := range []string{"/health", "/ready"} {
+		if status, _ := getHealth(t, app, path); status != 503 {
+			t.Errorf("%s: expected 503 with the browser down, got %d", path, status)
+		}
+	}
+}
+
+func TestNATSProbeWithoutConnection(t *testing.T) {
+	if err := api.NATSProbe(nil).Check(context.Background()); err == nil {
+		t.Error("Expected a missing connection to be reported down")
+	}
+	if err := api.JetStreamProbe(nil).Check(context.Background()); err == nil {
+		t.Error("Expected missing JetStream to be reported down")
+	}
+}
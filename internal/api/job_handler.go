@@ -2,12 +2,18 @@ package api
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ahrdadan/scrq/internal/queue"
 	"github.com/ahrdadan/scrq/internal/security"
+	"github.com/ahrdadan/scrq/internal/tracing"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/websocket/v2"
 )
@@ -17,8 +23,52 @@ type JobHandler struct {
 	queueManager     *queue.Manager
 	idempotencyStore *security.IdempotencyStore
 	baseURL          string
+
+	// requireIdempotencyKey rejects job submissions without a key
+	requireIdempotencyKey bool
+
+	// pollInterval is the status polling interval suggested to clients
+	pollInterval time.Duration
+
+	// sseKeepalive is how often idle SSE streams get a keepalive comment
+	sseKeepalive time.Duration
+
+	// wsPingInterval is how often WebSocket peers are pinged
+	wsPingInterval time.Duration
+
+	// recipes holds the named extraction templates
+	recipes *queue.RecipeStore
+
+	// pathPrefix is prepended to returned paths for clients behind a
+	// gateway that mounts scrq below a path
+	pathPrefix string
+
+	// contentDedup remembers request content hashes so identical keyless
+	// submissions within its TTL return the first job (nil = disabled)
+	contentDedup *security.IdempotencyStore
+
+	// maxTimeout caps the job timeout clients may request (0 = no cap)
+	maxTimeout time.Duration
 }
 
+const (
+	// defaultPollInterval is suggested when no poll interval is configured
+	defaultPollInterval = 2 * time.Second
+	// maxLongPollWait caps the ?wait= parameter on job status requests
+	maxLongPollWait = 30 * time.Second
+	// defaultSSEKeepalive is used when no SSE keepalive interval is configured
+	defaultSSEKeepalive = 15 * time.Second
+	// defaultWSPingInterval is used when no WebSocket ping interval is
+	// configured; a peer is dropped after two intervals without a pong
+	defaultWSPingInterval = 30 * time.Second
+	// wsWriteWait bounds writing a WebSocket ping
+	wsWriteWait = 10 * time.Second
+
+	// defaultListLimit and maxListLimit bound the page size of job listings
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
 // NewJobHandler creates a new job handler
 func NewJobHandler(qm *queue.Manager) *JobHandler {
 	return &JobHandler{
@@ -63,27 +113,67 @@ func (h *JobHandler) CreateJob(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	if req.JobRequest.URL == "" {
-		return fiber.NewError(fiber.StatusBadRequest, "URL is required")
+	applyTraceHeaders(c, &req.JobRequest)
+	if err := req.JobRequest.Normalize(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	if req.JobRequest.Type == "" {
-		req.JobRequest.Type = queue.JobTypeScrape
+	return h.submitJob(c, req)
+}
+
+// applyTraceHeaders fills the request's trace context from the traceparent
+// and X-Trace-Id headers when the body does not set it. A malformed
+// traceparent header is ignored, as W3C Trace Context requires.
+func applyTraceHeaders(c *fiber.Ctx, req *queue.JobRequest) {
+	if req.Traceparent == "" {
+		if value := c.Get(queue.HeaderTraceparent); value != "" {
+			if _, ok := queue.ParseTraceparent(value); ok {
+				req.Traceparent = value
+			}
+		}
 	}
+	if req.TraceID == "" {
+		req.TraceID = c.Get(queue.HeaderTraceID)
+	}
+}
 
+// submitJob enqueues a normalized job request, honoring idempotency keys
+func (h *JobHandler) submitJob(c *fiber.Ctx, req CreateJobRequest) error {
 	// Check idempotency key from header or body
 	idempotencyKey := c.Get("X-Idempotency-Key")
 	if idempotencyKey == "" {
 		idempotencyKey = req.IdempotencyKey
 	}
+	if idempotencyKey == "" && h.requireIdempotencyKey {
+		return fiber.NewError(fiber.StatusBadRequest, "Idempotency key is required (X-Idempotency-Key header or idempotency_key field)")
+	}
+
+	// Without a key, fall back to the request content hash when enabled
+	store := h.idempotencyStore
+	dedupKey := idempotencyKey
+	if dedupKey == "" && h.contentDedup != nil {
+		store = h.contentDedup
+		dedupKey = req.JobRequest.ContentHash()
+	}
+
+	// If a dedup key is available, check for cached response
+	if dedupKey != "" && store != nil {
+		if entry, exists := store.Check(dedupKey); exists {
+			var response queue.JobCreatedResponse
+			if existing, err := h.queueManager.GetJob(entry.JobID); err == nil {
+				response = h.jobCreatedResponse(existing)
+			} else if cached, ok := entry.Response.(queue.JobCreatedResponse); ok {
+				response = cached
+			} else {
+				response = queue.JobCreatedResponse{JobID: entry.JobID, CreatedAt: entry.CreatedAt.Unix()}
+			}
+			response.Duplicate = true
 
-	// If idempotency key provided, check for cached response
-	if idempotencyKey != "" && h.idempotencyStore != nil {
-		if cachedResponse, exists := h.idempotencyStore.Check(idempotencyKey); exists {
 			c.Set("X-Idempotency-Hit", "true")
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(h.pollIntervalSeconds()))
 			return c.Status(fiber.StatusAccepted).JSON(Response{
 				Success: true,
-				Data:    cachedResponse,
+				Data:    response,
 			})
 		}
 	}
@@ -102,13 +192,11 @@ func (h *JobHandler) CreateJob(c *fiber.Ctx) error {
 		job.Priority = 5
 	}
 
-	// Set timeout (default 30s, max 5min)
+	// Set timeout (default 30s), clamped to the configured maximum
 	if req.Timeout > 0 {
-		if req.Timeout > 300 {
-			req.Timeout = 300
-		}
 		job.Timeout = req.Timeout
 	}
+	job.Timeout = int(clampTimeout(c, job.GetTimeoutDuration(), h.maxTimeout) / time.Second)
 
 	// Set max retries (default 3, max 5)
 	if req.MaxRetries > 0 {
@@ -118,33 +206,36 @@ func (h *JobHandler) CreateJob(c *fiber.Ctx) error {
 		job.MaxRetries = req.MaxRetries
 	}
 
+	// The job carries the enqueue span's traceparent so its processing
+	// span joins this request's trace
+	_, span := tracing.Start(c.UserContext(), "scrq.job.enqueue")
+	span.SetAttribute("scrq.job.id", job.ID)
+	job.Traceparent = span.Traceparent()
+
 	// Enqueue with idempotency check
 	enqueuedJob, wasDuplicate, err := h.queueManager.EnqueueWithIdempotency(job)
+	span.SetAttribute("scrq.job.duplicate", wasDuplicate)
+	span.RecordError(err)
+	span.End()
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Failed to enqueue job: %v", err))
 	}
 
-	response := queue.JobCreatedResponse{
-		JobID:         enqueuedJob.ID,
-		Status:        enqueuedJob.Status,
-		StatusURL:     fmt.Sprintf("/scrq/jobs/%s", enqueuedJob.ID),
-		StatusURLFull: fmt.Sprintf("%s/scrq/jobs/%s", h.baseURL, enqueuedJob.ID),
-		ResultURL:     fmt.Sprintf("/scrq/jobs/%s/result", enqueuedJob.ID),
-		ResultURLFull: fmt.Sprintf("%s/scrq/jobs/%s/result", h.baseURL, enqueuedJob.ID),
-	}
-	response.Events.SSEURL = fmt.Sprintf("/scrq/jobs/%s/events", enqueuedJob.ID)
-	response.Events.SSEURLFull = fmt.Sprintf("%s/scrq/jobs/%s/events", h.baseURL, enqueuedJob.ID)
-	response.Events.WSURL = fmt.Sprintf("/scrq/ws?job_id=%s", enqueuedJob.ID)
-	response.Events.WSURLFull = fmt.Sprintf("%s/scrq/ws?job_id=%s", h.baseURL, enqueuedJob.ID)
+	response := h.jobCreatedResponse(enqueuedJob)
 
 	// Cache response for idempotency
-	if idempotencyKey != "" && h.idempotencyStore != nil && !wasDuplicate {
-		h.idempotencyStore.Store(idempotencyKey, enqueuedJob.ID, response)
+	if dedupKey != "" && store != nil && !wasDuplicate {
+		store.Store(dedupKey, enqueuedJob.ID, response)
 	}
 
 	if wasDuplicate {
+		response.Duplicate = true
 		c.Set("X-Idempotency-Hit", "true")
 	}
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(response.PollInterval))
+	if response.TraceID != "" {
+		c.Set(queue.HeaderTraceID, response.TraceID)
+	}
 
 	return c.Status(fiber.StatusAccepted).JSON(Response{
 		Success: true,
@@ -154,17 +245,75 @@ func (h *JobHandler) CreateJob(c *fiber.Ctx) error {
 
 // GetJobStatus returns the status of a job
 // GET /scrq/jobs/:job_id
+//
+// Responses carry an ETag; a matching If-None-Match returns 304. With
+// ?wait=N (seconds) the request long-polls until the job changes from the
+// state identified by If-None-Match (or until the next event without it),
+// for up to N seconds.
 func (h *JobHandler) GetJobStatus(c *fiber.Ctx) error {
 	jobID := c.Params("job_id")
 	if jobID == "" {
 		return fiber.NewError(fiber.StatusBadRequest, "Job ID is required")
 	}
 
+	wait := time.Duration(c.QueryInt("wait", 0)) * time.Second
+	if wait > maxLongPollWait {
+		wait = maxLongPollWait
+	}
+
+	// Subscribe before reading the job so no update is missed while waiting
+	var events <-chan queue.Event
+	if wait > 0 {
+		events = h.queueManager.Subscribe(jobID)
+		defer h.queueManager.Unsubscribe(jobID, events)
+	}
+
 	job, err := h.queueManager.GetJob(jobID)
 	if err != nil {
 		return fiber.NewError(fiber.StatusNotFound, "Job not found")
 	}
 
+	body, etag, err := jobStatusBody(job, h.jobStatusData(job))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	ifNoneMatch := c.Get(fiber.HeaderIfNoneMatch)
+	if wait > 0 && !job.Status.IsTerminal() && (ifNoneMatch == "" || ifNoneMatch == etag) {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-events:
+		case <-timer.C:
+		}
+
+		job, err = h.queueManager.GetJob(jobID)
+		if err != nil {
+			return fiber.NewError(fiber.StatusNotFound, "Job not found")
+		}
+		body, etag, err = jobStatusBody(job, h.jobStatusData(job))
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		}
+	}
+
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	if !job.Status.IsTerminal() {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(h.pollIntervalSeconds()))
+	}
+
+	if ifNoneMatch == etag {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(body)
+}
+
+// jobStatusData builds the status payload for a job
+func (h *JobHandler) jobStatusData(job *queue.Job) map[string]interface{} {
 	response := map[string]interface{}{
 		"job_id":     job.ID,
 		"status":     job.Status,
@@ -198,18 +347,88 @@ func (h *JobHandler) GetJobStatus(c *fiber.Ctx) error {
 		}
 	}
 
+	if job.Status == queue.JobStatusScheduled {
+		response["run_at"] = time.Unix(job.RunAt, 0).Format(time.RFC3339)
+	}
+
+	// Flag incomplete results
+	if job.Partial {
+		response["partial"] = true
+		response["warning"] = job.Warning
+	}
+
 	// Add TTL info
 	if job.ExpiresAt > 0 {
 		response["expires_at"] = time.Unix(job.ExpiresAt, 0).Format(time.RFC3339)
 	}
 
-	return c.JSON(Response{
+	if !job.Status.IsTerminal() {
+		response["poll_interval"] = h.pollIntervalSeconds()
+	}
+
+	return response
+}
+
+// jobCreatedResponse builds the job creation response with its status and
+// event URLs
+func (h *JobHandler) jobCreatedResponse(job *queue.Job) queue.JobCreatedResponse {
+	response := queue.JobCreatedResponse{
+		JobID:        job.ID,
+		Status:       job.Status,
+		PollInterval: h.pollIntervalSeconds(),
+		CreatedAt:    job.CreatedAt,
+		TraceID:      job.Request.TraceID,
+	}
+	response.StatusURL, response.StatusURLFull = h.jobURL(fmt.Sprintf("/scrq/jobs/%s", job.ID))
+	response.ResultURL, response.ResultURLFull = h.jobURL(fmt.Sprintf("/scrq/jobs/%s/result", job.ID))
+	response.Events.SSEURL, response.Events.SSEURLFull = h.jobURL(fmt.Sprintf("/scrq/jobs/%s/events", job.ID))
+	response.Events.WSURL, response.Events.WSURLFull = h.jobURL(fmt.Sprintf("/scrq/ws?job_id=%s", job.ID))
+
+	// WebSocket clients need a ws:// or wss:// URL
+	if strings.HasPrefix(response.Events.WSURLFull, "https://") {
+		response.Events.WSURLFull = "wss://" + strings.TrimPrefix(response.Events.WSURLFull, "https://")
+	} else if strings.HasPrefix(response.Events.WSURLFull, "http://") {
+		response.Events.WSURLFull = "ws://" + strings.TrimPrefix(response.Events.WSURLFull, "http://")
+	}
+
+	return response
+}
+
+// jobURL returns the client-facing path (with the gateway prefix) and the
+// full URL (base URL plus that path) for a scrq path
+func (h *JobHandler) jobURL(path string) (string, string) {
+	path = h.pathPrefix + path
+	return path, strings.TrimRight(h.baseURL, "/") + path
+}
+
+// pollIntervalSeconds returns the suggested polling interval, at least one second
+func (h *JobHandler) pollIntervalSeconds() int {
+	interval := h.pollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	if interval < time.Second {
+		return 1
+	}
+	return int(interval / time.Second)
+}
+
+// jobStatusBody encodes a status response and derives its ETag
+func jobStatusBody(job *queue.Job, data map[string]interface{}) ([]byte, string, error) {
+	body, err := json.Marshal(Response{
 		Success: true,
-		Data:    response,
+		Data:    data,
 	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	sum := sha256.Sum256(body)
+	return body, `W/"` + hex.EncodeToString(sum[:8]) + `"`, nil
 }
 
-// GetJobResult returns the result of a completed job
+// GetJobResult returns the result of a completed job. With ?compressed=1
+// its HTML fields are returned gzipped and base64 encoded.
 // GET /scrq/jobs/:job_id/result
 func (h *JobHandler) GetJobResult(c *fiber.Ctx) error {
 	jobID := c.Params("job_id")
@@ -226,18 +445,30 @@ func (h *JobHandler) GetJobResult(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusConflict, "Job not completed yet")
 	}
 
+	result := queue.JobResultResponse{
+		JobID:           job.ID,
+		Status:          job.Status,
+		Result:          job.Result,
+		Error:           job.Error,
+		ErrorScreenshot: job.ErrorScreenshot,
+		Partial:         job.Partial,
+		Warning:         job.Warning,
+		ErrorConsole:    job.ErrorConsole,
+	}
+	if c.QueryBool("compressed") {
+		if result.Result, err = compressResultHTML(job.Result); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to compress result: "+err.Error())
+		}
+		result.Compressed = true
+	}
+
 	return c.JSON(Response{
 		Success: true,
-		Data: queue.JobResultResponse{
-			JobID:  job.ID,
-			Status: job.Status,
-			Result: job.Result,
-			Error:  job.Error,
-		},
+		Data:    result,
 	})
 }
 
-// CancelJob cancels a queued or running job
+// CancelJob cancels a queued, scheduled, running or retrying job
 // POST /scrq/jobs/:job_id/cancel
 func (h *JobHandler) CancelJob(c *fiber.Ctx) error {
 	jobID := c.Params("job_id")
@@ -245,16 +476,53 @@ func (h *JobHandler) CancelJob(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Job ID is required")
 	}
 
-	job, err := h.queueManager.CancelJob(jobID)
+	job, canceled, err := h.queueManager.CancelJob(jobID)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		if errors.Is(err, queue.ErrNotCancelable) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return fiber.NewError(fiber.StatusNotFound, "Job not found")
+	}
+
+	data := map[string]interface{}{
+		"job_id":   job.ID,
+		"status":   job.Status,
+		"canceled": canceled,
+	}
+	if !canceled {
+		// Already finished: report the outcome instead of failing, so
+		// clients can cancel defensively after a timeout
+		data["message"] = fmt.Sprintf("Job already %s; nothing to cancel", job.Status)
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data:    data,
+	})
+}
+
+// DeleteJob removes a finished job and its result before the result TTL
+// DELETE /scrq/jobs/:job_id
+func (h *JobHandler) DeleteJob(c *fiber.Ctx) error {
+	jobID := c.Params("job_id")
+	if jobID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Job ID is required")
+	}
+
+	job, err := h.queueManager.DeleteJob(jobID)
+	if err != nil {
+		if errors.Is(err, queue.ErrJobNotFinished) {
+			return fiber.NewError(fiber.StatusConflict, err.Error()+"; cancel it first")
+		}
+		return fiber.NewError(fiber.StatusNotFound, "Job not found")
 	}
 
 	return c.JSON(Response{
 		Success: true,
 		Data: map[string]interface{}{
-			"job_id": job.ID,
-			"status": job.Status,
+			"job_id":  job.ID,
+			"status":  job.Status,
+			"deleted": true,
 		},
 	})
 }
@@ -278,7 +546,11 @@ func (h *JobHandler) StreamEvents(c *fiber.Ctx) error {
 	c.Set("Connection", "keep-alive")
 	c.Set("Transfer-Encoding", "chunked")
 
-	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+	// fasthttp cannot signal a client disconnect without a write, so a
+	// gone client is noticed when an event or keepalive fails to flush;
+	// reqCtx.Done only fires on server shutdown
+	reqCtx := c.Context()
+	reqCtx.SetBodyStreamWriter(func(w *bufio.Writer) {
 		// Send initial status
 		eventData, _ := json.Marshal(queue.Event{
 			JobID:    job.ID,
@@ -290,7 +562,7 @@ func (h *JobHandler) StreamEvents(c *fiber.Ctx) error {
 		w.Flush()
 
 		// If job is already completed, close the stream
-		if job.Status == queue.JobStatusSucceeded || job.Status == queue.JobStatusFailed || job.Status == queue.JobStatusCanceled {
+		if job.Status.IsTerminal() {
 			return
 		}
 
@@ -298,14 +570,39 @@ func (h *JobHandler) StreamEvents(c *fiber.Ctx) error {
 		events := h.queueManager.Subscribe(jobID)
 		defer h.queueManager.Unsubscribe(jobID, events)
 
-		for event := range events {
-			eventData, _ := json.Marshal(event)
-			fmt.Fprintf(w, "data: %s\n\n", eventData)
-			w.Flush()
+		// Keepalive comments stop proxies from closing idle streams and
+		// bound how long a disconnected client keeps its subscription.
+		// Events and keepalives are written from this goroutine only.
+		interval := h.sseKeepalive
+		if interval <= 0 {
+			interval = defaultSSEKeepalive
+		}
+		keepalive := time.NewTicker(interval)
+		defer keepalive.Stop()
 
-			// Close stream when job completes
-			if event.Status == queue.JobStatusSucceeded || event.Status == queue.JobStatusFailed || event.Status == queue.JobStatusCanceled {
+		for {
+			select {
+			case <-reqCtx.Done():
 				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				eventData, _ := json.Marshal(event)
+				fmt.Fprintf(w, "data: %s\n\n", eventData)
+				if err := w.Flush(); err != nil {
+					return
+				}
+
+				// Close stream when job completes
+				if event.Status.IsTerminal() {
+					return
+				}
+			case <-keepalive.C:
+				fmt.Fprint(w, ": keepalive\n\n")
+				if err := w.Flush(); err != nil {
+					return
+				}
 			}
 		}
 	})
@@ -343,7 +640,7 @@ func (h *JobHandler) HandleWebSocket(c *websocket.Conn) {
 	})
 
 	// If job is already completed, close the connection
-	if job.Status == queue.JobStatusSucceeded || job.Status == queue.JobStatusFailed || job.Status == queue.JobStatusCanceled {
+	if job.Status.IsTerminal() {
 		c.Close()
 		return
 	}
@@ -352,16 +649,361 @@ func (h *JobHandler) HandleWebSocket(c *websocket.Conn) {
 	events := h.queueManager.Subscribe(jobID)
 	defer h.queueManager.Unsubscribe(jobID, events)
 
-	// Send events to client
-	for event := range events {
-		if err := c.WriteJSON(event); err != nil {
-			return
+	// Every pong extends the read deadline; a peer that misses two pings
+	// fails the read below
+	interval := h.wsPingInterval
+	if interval <= 0 {
+		interval = defaultWSPingInterval
+	}
+	pongWait := 2 * interval
+	_ = c.SetReadDeadline(time.Now().Add(pongWait))
+	c.SetPongHandler(func(string) error {
+		return c.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	// Pongs and close frames are only handled while reading, so a reader
+	// runs until the peer closes or goes silent and then closes done.
+	// Returning from the handler closes the conn, which ends the reader.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				return
+			}
 		}
+	}()
+
+	ping := time.NewTicker(interval)
+	defer ping.Stop()
 
-		// Close connection when job completes
-		if event.Status == queue.JobStatusSucceeded || event.Status == queue.JobStatusFailed || event.Status == queue.JobStatusCanceled {
-			time.Sleep(100 * time.Millisecond)
+	// Send events to client; all writes happen here
+	for {
+		select {
+		case <-done:
 			return
+		case <-ping.C:
+			if err := c.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := c.WriteJSON(event); err != nil {
+				return
+			}
+
+			// Close connection when job completes
+			if event.Status.IsTerminal() {
+				time.Sleep(100 * time.Millisecond)
+				return
+			}
 		}
 	}
 }
+
+// ListJobs returns stored jobs, newest first, with optional filters
+// GET /scrq/jobs?status=running&type=scrape&limit=50&offset=0
+func (h *JobHandler) ListJobs(c *fiber.Ctx) error {
+	filter := queue.ListFilter{
+		Status: queue.JobStatus(c.Query("status")),
+		Type:   queue.JobType(c.Query("type")),
+		Limit:  c.QueryInt("limit", defaultListLimit),
+		Offset: c.QueryInt("offset", 0),
+	}
+
+	switch filter.Status {
+	case "", queue.JobStatusQueued, queue.JobStatusScheduled, queue.JobStatusRunning, queue.JobStatusSucceeded,
+		queue.JobStatusFailed, queue.JobStatusCanceled, queue.JobStatusRetrying:
+	default:
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Unknown status %q", filter.Status))
+	}
+	switch filter.Type {
+	case "", queue.JobTypeScrape, queue.JobTypeBatch, queue.JobTypePDF:
+	default:
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Unknown type %q", filter.Type))
+	}
+	if filter.Limit < 1 || filter.Limit > maxListLimit {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxListLimit))
+	}
+	if filter.Offset < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "offset must not be negative")
+	}
+
+	jobs, total, err := h.queueManager.ListJobs(filter)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	response := queue.JobListResponse{
+		Jobs:   make([]queue.JobSummary, 0, len(jobs)),
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}
+	for _, job := range jobs {
+		response.Jobs = append(response.Jobs, h.jobSummary(job))
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data:    response,
+	})
+}
+
+// jobSummary describes a job for a listing
+func (h *JobHandler) jobSummary(job *queue.Job) queue.JobSummary {
+	statusURL, _ := h.jobURL(fmt.Sprintf("/scrq/jobs/%s", job.ID))
+	return queue.JobSummary{
+		JobID:     job.ID,
+		Type:      job.Type,
+		Status:    job.Status,
+		Progress:  job.Progress,
+		URL:       job.Request.URL,
+		URLCount:  len(job.Request.URLs),
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+		StatusURL: statusURL,
+	}
+}
+
+// ListDeadLetters returns jobs that exhausted their retries, most recently
+// failed first
+// GET /scrq/jobs/dlq?limit=50&offset=0
+func (h *JobHandler) ListDeadLetters(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", defaultListLimit)
+	offset := c.QueryInt("offset", 0)
+	if limit < 1 || limit > maxListLimit {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxListLimit))
+	}
+	if offset < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "offset must not be negative")
+	}
+
+	jobs, total, err := h.queueManager.DeadLetters(limit, offset)
+	if err != nil {
+		return fiber.NewError(fiber.StatusServiceUnavailable, err.Error())
+	}
+
+	response := queue.JobListResponse{
+		Jobs:   make([]queue.JobSummary, 0, len(jobs)),
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}
+	for _, job := range jobs {
+		response.Jobs = append(response.Jobs, h.jobSummary(job))
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data:    response,
+	})
+}
+
+// RequeueJob moves a dead-lettered job back into the job queue
+// POST /scrq/jobs/:job_id/requeue
+func (h *JobHandler) RequeueJob(c *fiber.Ctx) error {
+	jobID := c.Params("job_id")
+	if jobID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Job ID is required")
+	}
+
+	job, err := h.queueManager.Requeue(jobID)
+	if err != nil {
+		if errors.Is(err, queue.ErrNotDeadLettered) {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		return fiber.NewError(fiber.StatusServiceUnavailable, err.Error())
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(Response{
+		Success: true,
+		Data:    h.jobCreatedResponse(job),
+	})
+}
+
+// GetStats returns queue-level statistics
+// GET /scrq/stats
+func (h *JobHandler) GetStats(c *fiber.Ctx) error {
+	return c.JSON(Response{
+		Success: true,
+		Data:    h.queueManager.Stats(),
+	})
+}
+
+// PauseQueue stops the worker from fetching new jobs
+// POST /scrq/admin/queue/pause
+func (h *JobHandler) PauseQueue(c *fiber.Ctx) error {
+	h.queueManager.StopFetching()
+
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"paused": true,
+		},
+	})
+}
+
+// ListSubscriptions returns the number of SSE/WS subscribers per job
+// GET /scrq/admin/subscriptions
+func (h *JobHandler) ListSubscriptions(c *fiber.Ctx) error {
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"subscriptions": h.queueManager.SubscriberCounts(),
+		},
+	})
+}
+
+// GetJobSubscribers returns the number of SSE/WS subscribers for a job
+// GET /scrq/admin/jobs/:job_id/subscribers
+func (h *JobHandler) GetJobSubscribers(c *fiber.Ctx) error {
+	jobID := c.Params("job_id")
+	if jobID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Job ID is required")
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"job_id":      jobID,
+			"subscribers": h.queueManager.SubscriberCount(jobID),
+		},
+	})
+}
+
+// ResumeQueue resumes fetching jobs
+// POST /scrq/admin/queue/resume
+func (h *JobHandler) ResumeQueue(c *fiber.Ctx) error {
+	h.queueManager.ResumeFetching()
+
+	// A drain in progress keeps the worker paused
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"paused": h.queueManager.IsPaused(),
+		},
+	})
+}
+
+// RunRecipeRequest represents a request to run a recipe
+type RunRecipeRequest struct {
+	URL            string   `json:"url"`
+	URLs           []string `json:"urls,omitempty"`
+	Version        int      `json:"version,omitempty"` // Recipe version (default: latest)
+	IdempotencyKey string   `json:"idempotency_key,omitempty"`
+	Priority       int      `json:"priority,omitempty"`
+}
+
+// RunRecipe creates a job from a named recipe
+// POST /scrq/recipes/:name/run
+func (h *JobHandler) RunRecipe(c *fiber.Ctx) error {
+	var req RunRecipeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	recipe, err := h.recipes.Get(c.Params("name"), req.Version)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	urls := req.URLs
+	if req.URL != "" {
+		urls = append([]string{req.URL}, urls...)
+	}
+	if len(urls) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "url or urls is required")
+	}
+
+	jobReq := CreateJobRequest{
+		JobRequest:     recipe.Build(urls),
+		IdempotencyKey: req.IdempotencyKey,
+		Priority:       req.Priority,
+	}
+	applyTraceHeaders(c, &jobReq.JobRequest)
+	if err := jobReq.JobRequest.Normalize(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	c.Set("X-Recipe-Version", strconv.Itoa(recipe.Version))
+	return h.submitJob(c, jobReq)
+}
+
+// ListRecipes returns the latest version of every recipe
+// GET /scrq/recipes
+func (h *JobHandler) ListRecipes(c *fiber.Ctx) error {
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"recipes": h.recipes.List(),
+		},
+	})
+}
+
+// GetRecipe returns a recipe and its version history
+// GET /scrq/recipes/:name
+func (h *JobHandler) GetRecipe(c *fiber.Ctx) error {
+	name := c.Params("name")
+	recipe, err := h.recipes.Get(name, c.QueryInt("version", 0))
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	versions := h.recipes.Versions(name)
+	history := make([]map[string]interface{}, 0, len(versions))
+	for _, v := range versions {
+		history = append(history, map[string]interface{}{
+			"version":    v.Version,
+			"created_at": v.CreatedAt,
+		})
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"recipe":   recipe,
+			"versions": history,
+		},
+	})
+}
+
+// PutRecipe stores a new version of a recipe (admin)
+// PUT /scrq/admin/recipes/:name
+func (h *JobHandler) PutRecipe(c *fiber.Ctx) error {
+	var recipe queue.Recipe
+	if err := c.BodyParser(&recipe); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	recipe.Name = c.Params("name")
+
+	stored, err := h.recipes.Put(recipe)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(Response{
+		Success: true,
+		Data:    stored,
+	})
+}
+
+// DeleteRecipe removes a recipe and all its versions (admin)
+// DELETE /scrq/admin/recipes/:name
+func (h *JobHandler) DeleteRecipe(c *fiber.Ctx) error {
+	name := c.Params("name")
+	if !h.recipes.Delete(name) {
+		return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("%v: %s", queue.ErrRecipeNotFound, name))
+	}
+
+	return c.JSON(Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"name":    name,
+			"deleted": true,
+		},
+	})
+}
@@ -0,0 +1,428 @@
+package api_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ahrdadan/scrq/internal/api"
+	"github.com/ahrdadan/scrq/internal/queue"
+	"github.com/ahrdadan/scrq/internal/tracing"
+	fastws "github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// fakeJetStream is just enough JetStream for queue.NewManager: streams and
+// consumers are accepted, jobs are kept in memory and publishes recorded
+type fakeJetStream struct {
+	jetstream.JetStream
+	mu        sync.Mutex
+	published []*queue.Job
+}
+
+func (f *fakeJetStream) CreateOrUpdateStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error) {
+	return nil, nil
+}
+
+func (f *fakeJetStream) CreateOrUpdateConsumer(ctx context.Context, stream string, cfg jetstream.ConsumerConfig) (jetstream.Consumer, error) {
+	return nil, nil
+}
+
+func (f *fakeJetStream) CreateOrUpdateKeyValue(ctx context.Context, cfg jetstream.KeyValueConfig) (jetstream.KeyValue, error) {
+	return &fakeKV{}, nil
+}
+
+func (f *fakeJetStream) PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	job := &queue.Job{}
+	if err := json.Unmarshal(msg.Data, job); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	f.published = append(f.published, job)
+	f.mu.Unlock()
+	return &jetstream.PubAck{}, nil
+}
+
+// fakeKV is an always-empty job store bucket
+type fakeKV struct{ jetstream.KeyValue }
+
+type fakeLister struct{ keys chan string }
+
+func (l fakeLister) Keys() <-chan string { return l.keys }
+func (l fakeLister) Stop() error         { return nil }
+
+func (kv *fakeKV) ListKeys(ctx context.Context, opts ...jetstream.WatchOpt) (jetstream.KeyLister, error) {
+	keys := make(chan string)
+	close(keys)
+	return fakeLister{keys: keys}, nil
+}
+
+func (kv *fakeKV) Get(ctx context.Context, key string) (jetstream.KeyValueEntry, error) {
+	return nil, jetstream.ErrKeyNotFound
+}
+
+func (kv *fakeKV) Put(ctx context.Context, key string, value []byte) (uint64, error) {
+	return 1, nil
+}
+
+func (kv *fakeKV) Delete(ctx context.Context, key string, opts ...jetstream.KVDeleteOpt) error {
+	return nil
+}
+
+// newTestQueueManager returns a queue manager backed by fakeJetStream
+func newTestQueueManager(t *testing.T) (*queue.Manager, *fakeJetStream) {
+	t.Helper()
+	js := &fakeJetStream{}
+	qm, err := queue.NewManager(js)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	t.Cleanup(qm.GetStore().Stop)
+	return qm, js
+}
+
+func TestCreateJobRequiresIdempotencyKey(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: api.ErrorHandler,
+	})
+	config := api.DefaultRouteConfig()
+	config.RequireIdempotencyKey = true
+	api.SetupJobRoutesWithConfig(app, nil, config)
+
+	req := httptest.NewRequest("POST", "/scrq/jobs", bytes.NewReader([]byte(`{"url":"https://example.com"}`)))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+
+	if resp.StatusCode != 400 {
+		t.Errorf("Expected status 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestRecipeAdminLifecycle(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: api.ErrorHandler,
+	})
+	config := api.DefaultRouteConfig()
+	config.AdminToken = "secret"
+	api.SetupJobRoutesWithConfig(app, nil, config)
+
+	for _, script := range []string{"() => document.title", "() => location.href"} {
+		body, _ := json.Marshal(map[string]interface{}{"request": map[string]string{"script": script}})
+		req := httptest.NewRequest("PUT", "/scrq/admin/recipes/title", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Admin-Token", "secret")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		if resp.StatusCode != 201 {
+			t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+		}
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/scrq/recipes/title", nil))
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	var response api.Response
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	data := response.Data.(map[string]interface{})
+	recipe := data["recipe"].(map[string]interface{})
+	if recipe["version"] != float64(2) || len(data["versions"].([]interface{})) != 2 {
+		t.Errorf("Expected latest version 2 with 2 versions, got %v", data)
+	}
+
+	req := httptest.NewRequest("POST", "/scrq/recipes/missing/run", bytes.NewReader([]byte(`{"url":"https://example.com"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	if resp.StatusCode != 404 {
+		t.Errorf("Expected status 404 for unknown recipe, got %d", resp.StatusCode)
+	}
+}
+
+func TestCreateJobClampsTimeout(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: api.ErrorHandler,
+	})
+	qm, js := newTestQueueManager(t)
+	config := api.DefaultRouteConfig()
+	config.MaxJobTimeout = 5 * time.Minute
+	api.SetupJobRoutesWithConfig(app, qm, config)
+
+	for _, tc := range []struct {
+		timeout int
+		want    int
+		clamped string
+	}{
+		{timeout: 600, want: 300, clamped: "300"},
+		{timeout: 60, want: 60},
+	} {
+		body, _ := json.Marshal(map[string]interface{}{"url": "https://example.com", "timeout": tc.timeout})
+		req := httptest.NewRequest("POST", "/scrq/jobs", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		if resp.StatusCode != 202 {
+			t.Fatalf("Expected status 202, got %d", resp.StatusCode)
+		}
+		if got := resp.Header.Get("X-Timeout-Clamped"); got != tc.clamped {
+			t.Errorf("timeout %d: expected X-Timeout-Clamped %q, got %q", tc.timeout, tc.clamped, got)
+		}
+
+		job := js.published[len(js.published)-1]
+		if job.Timeout != tc.want {
+			t.Errorf("timeout %d: expected job timeout %d, got %d", tc.timeout, tc.want, job.Timeout)
+		}
+	}
+}
+
+func TestCreateJobCarriesTraceparent(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer collector.Close()
+
+	app := fiber.New(fiber.Config{
+		ErrorHandler: api.ErrorHandler,
+	})
+	qm, js := newTestQueueManager(t)
+	config := api.DefaultRouteConfig()
+	config.Tracer = tracing.NewTracer(tracing.Config{Endpoint: collector.URL})
+	defer func() { _ = config.Tracer.Shutdown(context.Background()) }()
+	api.SetupJobRoutesWithConfig(app, qm, config)
+
+	incoming := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest("POST", "/scrq/jobs", strings.NewReader(`{"url":"https://example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", incoming)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	if resp.StatusCode != 202 {
+		t.Fatalf("Expected status 202, got %d", resp.StatusCode)
+	}
+
+	// The job links to the enqueue span in the caller's trace, not to the caller
+	job := js.published[len(js.published)-1]
+	if !strings.HasPrefix(job.Traceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-") || job.Traceparent == incoming {
+		t.Errorf("Expected the job to carry the enqueue span's traceparent, got %q", job.Traceparent)
+	}
+	if job.Request.Traceparent != incoming {
+		t.Errorf("Expected the request traceparent to be kept, got %q", job.Request.Traceparent)
+	}
+}
+
+func TestJobRoutesValidateRequests(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: api.ErrorHandler,
+	})
+	qm, _ := newTestQueueManager(t)
+	config := api.DefaultRouteConfig()
+	config.MaxBodyBytes = 64
+	config.RouteContentTypes = map[string][]string{"/scrq/jobs": {"text/csv"}}
+	api.SetupJobRoutesWithConfig(app, qm, config)
+
+	for _, tc := range []struct {
+		contentType string
+		body        string
+		want        int
+	}{
+		{contentType: "text/plain", body: `{"url":"https://example.com"}`, want: 415},
+		{contentType: "text/csv", body: `https://example.com`, want: 400}, // Accepted type, rejected by the handler
+		{contentType: "application/json", body: `{"url":"https://example.com/` + strings.Repeat("a", 64) + `"}`, want: 413},
+		{contentType: "application/json", body: `{"url":"https://example.com"}`, want: 202},
+	} {
+		req := httptest.NewRequest("POST", "/scrq/jobs", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", tc.contentType)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s body of %d bytes: expected status %d, got %d", tc.contentType, len(tc.body), tc.want, resp.StatusCode)
+		}
+	}
+}
+
+func TestStreamEventsKeepalive(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: api.ErrorHandler,
+	})
+	qm, _ := newTestQueueManager(t)
+	config := api.DefaultRouteConfig()
+	config.SSEKeepalive = 10 * time.Millisecond
+	api.SetupJobRoutesWithConfig(app, qm, config)
+
+	job := queue.NewJob(queue.JobRequest{Type: queue.JobTypeScrape, URL: "https://example.com"})
+	if err := qm.Enqueue(job); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	// Finish the job once a few keepalives have gone out, ending the stream
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		done := *job
+		done.SetResult("ok")
+		_ = qm.UpdateJob(&done)
+	}()
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/scrq/jobs/"+job.ID+"/events", nil), 5000)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	stream := string(body)
+
+	if !strings.Contains(stream, ": keepalive\n\n") {
+		t.Errorf("Expected keepalive comments in the stream, got %q", stream)
+	}
+	if !strings.Contains(stream, `"status":"succeeded"`) {
+		t.Errorf("Expected the stream to end with the terminal event, got %q", stream)
+	}
+	if n := qm.SubscriberCount(job.ID); n != 0 {
+		t.Errorf("Expected the subscription to be released, got %d subscribers", n)
+	}
+}
+
+func TestStreamEventsAbandonedClientsUnsubscribe(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		ErrorHandler:          api.ErrorHandler,
+		DisableStartupMessage: true,
+	})
+	qm, _ := newTestQueueManager(t)
+	config := api.DefaultRouteConfig()
+	config.SSEKeepalive = 10 * time.Millisecond
+	api.SetupJobRoutesWithConfig(app, qm, config)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	job := queue.NewJob(queue.JobRequest{Type: queue.JobTypeScrape, URL: "https://example.com"})
+	if err := qm.Enqueue(job); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	// Open streams, read the initial event and walk away mid-job
+	const streams = 20
+	for i := 0; i < streams; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		req, _ := http.NewRequestWithContext(ctx, "GET", "http://"+ln.Addr().String()+"/scrq/jobs/"+job.ID+"/events", nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			cancel()
+			t.Fatalf("Failed to open stream: %v", err)
+		}
+		line, _ := bufio.NewReader(resp.Body).ReadString('\n')
+		if !strings.HasPrefix(line, "data: ") {
+			t.Errorf("Expected the initial event, got %q", line)
+		}
+		cancel()
+		resp.Body.Close()
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for qm.SubscriberCount(job.ID) != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := qm.SubscriberCount(job.ID); n != 0 {
+		t.Errorf("Expected abandoned streams to unsubscribe, got %d subscribers", n)
+	}
+}
+
+func TestWebSocketDropsSilentPeers(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		ErrorHandler:          api.ErrorHandler,
+		DisableStartupMessage: true,
+	})
+	qm, _ := newTestQueueManager(t)
+	config := api.DefaultRouteConfig()
+	config.WSPingInterval = 20 * time.Millisecond
+	api.SetupJobRoutesWithConfig(app, qm, config)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	job := queue.NewJob(queue.JobRequest{Type: queue.JobTypeScrape, URL: "https://example.com"})
+	if err := qm.Enqueue(job); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	conn, _, err := fastws.DefaultDialer.Dial("ws://"+ln.Addr().String()+"/scrq/ws?job_id="+job.ID, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	// Read pings without answering them, like a peer whose network died
+	pings := 0
+	conn.SetPingHandler(func(string) error {
+		pings++
+		return nil
+	})
+
+	closed := make(chan error, 1)
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				closed <- err
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the server to close a connection that stopped answering pings")
+	}
+	if pings == 0 {
+		t.Error("Expected the server to send pings")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for qm.SubscriberCount(job.ID) != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := qm.SubscriberCount(job.ID); n != 0 {
+		t.Errorf("Expected the subscription to be released, got %d subscribers", n)
+	}
+}
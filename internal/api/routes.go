@@ -6,25 +6,34 @@ import (
 	"github.com/ahrdadan/scrq/internal/browser"
 	"github.com/ahrdadan/scrq/internal/queue"
 	"github.com/ahrdadan/scrq/internal/security"
+	"github.com/ahrdadan/scrq/internal/tracing"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/websocket/v2"
 )
 
 // SetupRoutes configures all API routes
 func SetupRoutes(app *fiber.App, browserManager browser.Client) {
-	handler := NewHandler(browserManager)
+	SetupRoutesWithConfig(app, browserManager, DefaultRouteConfig())
+}
 
-	// Health check (simple path)
-	app.Get("/health", handler.HealthCheck)
+// SetupRoutesWithConfig configures all API routes with custom config. Health
+// routes are registered separately with SetupHealthRoutes.
+func SetupRoutesWithConfig(app *fiber.App, browserManager browser.Client, config RouteConfig) {
+	handler := NewHandler(browserManager)
 
 	// Scrq routes
-	registerRoutes(app.Group("/scrq"), handler)
+	registerRoutes(app.Group("/scrq"), handler, config)
 }
 
 // SetupChromeRoutes registers routes that use the Chrome backend.
 func SetupChromeRoutes(app *fiber.App, chromeManager browser.Client) {
+	SetupChromeRoutesWithConfig(app, chromeManager, DefaultRouteConfig())
+}
+
+// SetupChromeRoutesWithConfig registers Chrome-backed routes with custom config.
+func SetupChromeRoutesWithConfig(app *fiber.App, chromeManager browser.Client, config RouteConfig) {
 	handler := NewHandler(chromeManager)
-	registerRoutes(app.Group("/scrq/chrome"), handler)
+	registerRoutes(app.Group("/scrq/chrome"), handler, config)
 }
 
 // RouteConfig holds configuration for routes
@@ -33,6 +42,32 @@ type RouteConfig struct {
 	RateLimitWindow   time.Duration // time window
 	IdempotencyTTL    time.Duration // TTL for idempotency keys
 	BaseURL           string        // Base URL for full URLs in responses
+	PathPrefix        string        // Gateway path in front of /scrq in returned URLs
+	AdminToken        string        // Token for admin endpoints (disabled if empty)
+
+	RequireIdempotencyKey bool          // Reject job submissions without an idempotency key
+	ContentDedupWindow    time.Duration // Treat identical keyless submissions within this window as duplicates (0 = disabled)
+
+	PollInterval time.Duration // Status polling interval suggested to clients (default 2s)
+	SSEKeepalive time.Duration // Keepalive comment interval on idle SSE streams (default 15s)
+
+	WSPingInterval time.Duration // Ping interval on job WebSockets; peers missing two pongs are dropped (default 30s)
+
+	MaxSyncTimeout time.Duration // Ceiling for page timeouts on synchronous endpoints (0 = none)
+	MaxJobTimeout  time.Duration // Ceiling for job timeouts, also bounding synchronous endpoints (0 = none)
+
+	BatchLimiter *browser.PageLimiter // Shared cap on pages in flight across all batch scrapes (nil = none)
+
+	Recipes *queue.RecipeStore // Named extraction recipes (empty store if nil)
+
+	MaxBodyBytes int // Larger request bodies are rejected with 413 (default 10MB)
+
+	RouteContentTypes map[string][]string // Content types accepted besides JSON on routes under a path prefix
+
+	AllowedIPs []string // Client IPs or CIDR ranges allowed on the job routes (all if empty)
+	TrustProxy bool     // Take the client IP from X-Forwarded-For for AllowedIPs
+
+	Tracer *tracing.Tracer // Records a span per job route request (nil = no tracing)
 }
 
 // DefaultRouteConfig returns default route configuration
@@ -42,9 +77,23 @@ func DefaultRouteConfig() RouteConfig {
 		RateLimitWindow:   time.Minute,
 		IdempotencyTTL:    24 * time.Hour,
 		BaseURL:           "http://localhost:8000",
+		MaxSyncTimeout:    2 * time.Minute,
+		MaxJobTimeout:     5 * time.Minute,
+		MaxBodyBytes:      security.DefaultMaxBodyBytes,
 	}
 }
 
+// validationMiddleware accepts JSON bodies, and the types configured for
+// each route prefix, up to config.MaxBodyBytes
+func validationMiddleware(config RouteConfig) fiber.Handler {
+	validation := security.DefaultValidationConfig()
+	validation.RouteContentTypes = config.RouteContentTypes
+	if config.MaxBodyBytes > 0 {
+		validation.MaxBodyBytes = config.MaxBodyBytes
+	}
+	return security.RequestValidationMiddlewareWithConfig(validation)
+}
+
 // SetupJobRoutes configures job queue routes
 func SetupJobRoutes(app *fiber.App, queueManager *queue.Manager) {
 	SetupJobRoutesWithConfig(app, queueManager, DefaultRouteConfig())
@@ -61,25 +110,74 @@ func SetupJobRoutesWithConfig(app *fiber.App, queueManager *queue.Manager, confi
 	idempotencyStore := security.NewIdempotencyStore(config.IdempotencyTTL)
 
 	jobHandler := NewJobHandlerWithConfig(queueManager, idempotencyStore, config.BaseURL)
+	jobHandler.requireIdempotencyKey = config.RequireIdempotencyKey
+	jobHandler.pollInterval = config.PollInterval
+	jobHandler.sseKeepalive = config.SSEKeepalive
+	jobHandler.wsPingInterval = config.WSPingInterval
+	jobHandler.recipes = config.Recipes
+	jobHandler.pathPrefix = config.PathPrefix
+	jobHandler.maxTimeout = config.MaxJobTimeout
+	if config.ContentDedupWindow > 0 {
+		jobHandler.contentDedup = security.NewIdempotencyStore(config.ContentDedupWindow)
+	}
+	if jobHandler.recipes == nil {
+		jobHandler.recipes = queue.NewRecipeStore()
+	}
 
 	// Create security middleware
 	secMiddleware := security.NewMiddleware(rateLimiter, idempotencyStore)
 
 	scrq := app.Group("/scrq")
 
-	// Apply security headers to all scrq routes
+	// Turn away clients outside the allowlist before anything else
+	if len(config.AllowedIPs) > 0 {
+		scrq.Use(security.IPWhitelistMiddlewareWithConfig(security.IPWhitelistConfig{
+			AllowedIPs: config.AllowedIPs,
+			TrustProxy: config.TrustProxy,
+		}))
+	}
+
+	// Trace requests that pass the allowlist; enqueued jobs continue the trace
+	if config.Tracer != nil {
+		scrq.Use(tracing.Middleware(config.Tracer))
+	}
+
+	// Apply security headers and request validation to all scrq routes
 	scrq.Use(security.SecurityHeadersMiddleware())
+	scrq.Use(validationMiddleware(config))
 
 	// Job queue endpoints with rate limiting
 	jobsGroup := scrq.Group("/jobs")
 	jobsGroup.Use(secMiddleware.RateLimitMiddleware())
 
 	jobsGroup.Post("", jobHandler.CreateJob)
+	jobsGroup.Get("", jobHandler.ListJobs)
+	jobsGroup.Get("/dlq", jobHandler.ListDeadLetters)
 	jobsGroup.Get("/:job_id", jobHandler.GetJobStatus)
+	jobsGroup.Delete("/:job_id", jobHandler.DeleteJob)
 	jobsGroup.Get("/:job_id/result", jobHandler.GetJobResult)
 	jobsGroup.Post("/:job_id/cancel", jobHandler.CancelJob)
+	jobsGroup.Post("/:job_id/requeue", jobHandler.RequeueJob)
 	jobsGroup.Get("/:job_id/events", jobHandler.StreamEvents)
 
+	// Recipes (running one creates a job, so it shares the job rate limit)
+	recipesGroup := scrq.Group("/recipes")
+	recipesGroup.Get("", jobHandler.ListRecipes)
+	recipesGroup.Get("/:name", jobHandler.GetRecipe)
+	recipesGroup.Post("/:name/run", secMiddleware.RateLimitMiddleware(), jobHandler.RunRecipe)
+
+	// Queue statistics
+	scrq.Get("/stats", jobHandler.GetStats)
+
+	// Admin endpoints
+	admin := scrq.Group("/admin", security.AdminAuthMiddleware(config.AdminToken))
+	admin.Post("/queue/pause", jobHandler.PauseQueue)
+	admin.Post("/queue/resume", jobHandler.ResumeQueue)
+	admin.Get("/subscriptions", jobHandler.ListSubscriptions)
+	admin.Get("/jobs/:job_id/subscribers", jobHandler.GetJobSubscribers)
+	admin.Put("/recipes/:name", jobHandler.PutRecipe)
+	admin.Delete("/recipes/:name", jobHandler.DeleteRecipe)
+
 	// WebSocket endpoint for job events
 	app.Use("/scrq/ws", func(c *fiber.Ctx) error {
 		if websocket.IsWebSocketUpgrade(c) {
@@ -104,29 +202,55 @@ func SetupSecureRoutes(app *fiber.App, browserManager browser.Client, config Rou
 	// Create security middleware
 	secMiddleware := security.NewMiddleware(rateLimiter, nil)
 
-	// Health check (no rate limit)
-	app.Get("/health", handler.HealthCheck)
+	// Health checks (no rate limit)
+	SetupHealthRoutes(app, BrowserProbe("browser", browserManager))
 
 	// Scrq routes with security
 	scrq := app.Group("/scrq")
 	scrq.Use(security.SecurityHeadersMiddleware())
+	scrq.Use(validationMiddleware(config))
 	scrq.Use(secMiddleware.RateLimitMiddleware())
 
-	registerRoutes(scrq, handler)
+	registerRoutes(scrq, handler, config)
 }
 
-func registerRoutes(scrq fiber.Router, handler *Handler) {
+func registerRoutes(scrq fiber.Router, handler *Handler, config RouteConfig) {
+	handler.maxTimeout = timeoutCeiling(config.MaxSyncTimeout, config.MaxJobTimeout)
+	handler.batchLimiter = config.BatchLimiter
+
 	// Browser status
 	scrq.Get("/browser/status", handler.BrowserStatus)
 
 	// Page operations
 	scrq.Post("/page/fetch", handler.FetchPage)
 	scrq.Post("/page/screenshot", handler.Screenshot)
+	scrq.Post("/page/screenshot/element", handler.ScreenshotElement)
+	scrq.Post("/page/frames", handler.CaptureFrames)
 	scrq.Post("/page/evaluate", handler.EvaluateScript)
 	scrq.Post("/page/click", handler.ClickElement)
 	scrq.Post("/page/fill", handler.FillForm)
+	scrq.Post("/page/hover", handler.Hover)
+	scrq.Post("/page/select", handler.SelectOption)
+	scrq.Post("/page/press", handler.PressKey)
+	scrq.Post("/page/actions", handler.RunActions)
 	scrq.Post("/page/links", handler.ExtractLinks)
 	scrq.Post("/page/info", handler.GetPageInfo)
+	scrq.Post("/page/count", handler.CountElements)
+	scrq.Post("/page/markdown", handler.GetMarkdown)
+	scrq.Post("/page/attributes", handler.ExtractAttributes)
+	scrq.Post("/page/boxes", handler.ElementBoxes)
+	scrq.Post("/page/feeds", handler.DiscoverFeeds)
+	scrq.Post("/page/metadata", handler.ExtractMetadata)
+	scrq.Post("/page/tables", handler.ExtractTables)
+	scrq.Post("/page/download", handler.DownloadFile)
+	scrq.Post("/page/pdf", handler.RenderPDF)
+
+	// Browser sessions
+	scrq.Post("/sessions", handler.CreateSession)
+	scrq.Delete("/sessions/:id", handler.CloseSession)
+
+	// Admin-only page operations
+	scrq.Post("/page/cdp", security.AdminAuthMiddleware(config.AdminToken), handler.ExecuteCDP)
 
 	// Scraping operations
 	scrq.Post("/scrape", handler.Scrape)
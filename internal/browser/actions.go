@@ -0,0 +1,241 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Bounds for action sequences
+const (
+	MaxActions    = 50
+	MaxActionWait = 30 * time.Second
+)
+
+// ActionType names a step of an action sequence
+type ActionType string
+
+const (
+	// ActionNavigate loads URL in the page
+	ActionNavigate ActionType = "navigate"
+	// ActionClick clicks the element matching Selector
+	ActionClick ActionType = "click"
+	// ActionFill types Value into the element matching Selector
+	ActionFill ActionType = "fill"
+	// ActionWait waits until Selector matches an element, or for Duration
+	// when there is no selector
+	ActionWait ActionType = "wait"
+	// ActionScroll scrolls the element matching Selector into view, or
+	// scrolls to the bottom of the page until it stops growing
+	ActionScroll ActionType = "scroll"
+	// ActionScreenshot captures the viewport, or the whole page with FullPage
+	ActionScreenshot ActionType = "screenshot"
+	// ActionEval evaluates Script and returns its value
+	ActionEval ActionType = "eval"
+)
+
+// Action is one step of an action sequence run against a single page
+type Action struct {
+	Type     ActionType `json:"type"`
+	URL      string     `json:"url,omitempty"`       // navigate
+	Selector string     `json:"selector,omitempty"`  // click, fill, wait, scroll
+	Value    string     `json:"value,omitempty"`     // fill
+	Script   string     `json:"script,omitempty"`    // eval
+	Duration int        `json:"duration,omitempty"`  // wait, milliseconds
+	FullPage bool       `json:"full_page,omitempty"` // screenshot
+}
+
+// ActionResult is the outcome of one step. URL is the page URL after the
+// step; Result holds an eval value and Screenshot a PNG capture.
+type ActionResult struct {
+	Index      int         `json:"index"`
+	Type       ActionType  `json:"type"`
+	URL        string      `json:"url"`
+	Result     interface{} `json:"result,omitempty"`
+	Screenshot []byte      `json:"screenshot,omitempty"` // base64 in JSON
+}
+
+// ActionError reports the step of an action sequence that failed
+type ActionError struct {
+	Index int
+	Type  ActionType
+	Err   error
+}
+
+func (e *ActionError) Error() string {
+	return fmt.Sprintf("action %d (%s) failed: %v", e.Index, e.Type, e.Err)
+}
+
+func (e *ActionError) Unwrap() error {
+	return e.Err
+}
+
+// FailedAction returns the index of the failed step attached to err, or -1
+func FailedAction(err error) int {
+	var actionErr *ActionError
+	if errors.As(err, &actionErr) {
+		return actionErr.Index
+	}
+	return -1
+}
+
+// ValidateActions checks an action sequence. The first step must be a
+// navigate, which opens the page.
+func ValidateActions(actions []Action) error {
+	if len(actions) == 0 || len(actions) > MaxActions {
+		return fmt.Errorf("%w: between 1 and %d actions are required", ErrInvalidOptions, MaxActions)
+	}
+	if actions[0].Type != ActionNavigate {
+		return fmt.Errorf("%w: the first action must be navigate", ErrInvalidOptions)
+	}
+
+	for i, action := range actions {
+		if err := action.validate(); err != nil {
+			return fmt.Errorf("%w: action %d (%s): %s", ErrInvalidOptions, i, action.Type, err)
+		}
+	}
+	return nil
+}
+
+func (a Action) validate() error {
+	switch a.Type {
+	case ActionNavigate:
+		if a.URL == "" {
+			return errors.New("url is required")
+		}
+	case ActionClick:
+		if a.Selector == "" {
+			return errors.New("selector is required")
+		}
+	case ActionFill:
+		if a.Selector == "" {
+			return errors.New("selector is required")
+		}
+	case ActionWait:
+		if a.Duration < 0 || time.Duration(a.Duration)*time.Millisecond > MaxActionWait {
+			return fmt.Errorf("duration must be between 0 and %d", MaxActionWait.Milliseconds())
+		}
+		if a.Selector == "" && a.Duration == 0 {
+			return errors.New("selector or duration is required")
+		}
+	case ActionScroll, ActionScreenshot:
+	case ActionEval:
+		if a.Script == "" {
+			return errors.New("script is required")
+		}
+	default:
+		return fmt.Errorf("unknown action type (use navigate, click, fill, wait, scroll, screenshot or eval)")
+	}
+	return nil
+}
+
+// runActions opens a page at the first action's URL and applies each step
+// in order. On failure the results of the completed steps are returned with
+// an *ActionError.
+func runActions(opener pageOpener, ctx context.Context, actions []Action, opts PageOptions) ([]ActionResult, error) {
+	if err := ValidateActions(actions); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	page, cleanup, err := opener.OpenPage(ctx, actions[0].URL, opts)
+	if err != nil {
+		return nil, &ActionError{Index: 0, Type: ActionNavigate, Err: err}
+	}
+	defer cleanup()
+
+	results := make([]ActionResult, 0, len(actions))
+	for i, action := range actions {
+		result := ActionResult{Index: i, Type: action.Type}
+		// The first navigate already happened when the page was opened
+		if i > 0 {
+			if err := runAction(page, action, opts, &result); err != nil {
+				return results, withFailureScreenshot(page, opts, &ActionError{Index: i, Type: action.Type, Err: err})
+			}
+		}
+
+		if info, err := page.Info(); err == nil {
+			result.URL = info.URL
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// runAction applies one step to the page, recording its output in result
+func runAction(page *rod.Page, action Action, opts PageOptions, result *ActionResult) error {
+	switch action.Type {
+	case ActionNavigate:
+		if err := page.Navigate(action.URL); err != nil {
+			return fmt.Errorf("failed to navigate to %s: %w", action.URL, err)
+		}
+		if opts.WaitForLoad {
+			if err := page.WaitLoad(); err != nil {
+				return fmt.Errorf("failed to wait for page load: %w", err)
+			}
+		}
+
+	case ActionClick:
+		element, err := queryElement(page, action.Selector, opts.PierceShadow)
+		if err != nil {
+			return fmt.Errorf("element not found: %s", action.Selector)
+		}
+		if err := element.Click(proto.InputMouseButtonLeft, 1); err != nil {
+			return fmt.Errorf("failed to click element: %w", err)
+		}
+
+	case ActionFill:
+		element, err := queryElement(page, action.Selector, opts.PierceShadow)
+		if err != nil {
+			return fmt.Errorf("element not found: %s", action.Selector)
+		}
+		if err := element.Input(action.Value); err != nil {
+			return fmt.Errorf("failed to input value for %s: %w", action.Selector, err)
+		}
+
+	case ActionWait:
+		duration := time.Duration(action.Duration) * time.Millisecond
+		if action.Selector != "" {
+			return waitForSelector(page, action.Selector, duration)
+		}
+		select {
+		case <-page.GetContext().Done():
+			return fmt.Errorf("timed out waiting %s: %w", duration, page.GetContext().Err())
+		case <-time.After(duration):
+		}
+
+	case ActionScroll:
+		if action.Selector == "" {
+			return autoScroll(page, 0, 0)
+		}
+		element, err := queryElement(page, action.Selector, opts.PierceShadow)
+		if err != nil {
+			return fmt.Errorf("element not found: %s", action.Selector)
+		}
+		if err := element.ScrollIntoView(); err != nil {
+			return fmt.Errorf("failed to scroll to element: %w", err)
+		}
+
+	case ActionScreenshot:
+		screenshot, err := page.Screenshot(action.FullPage, nil)
+		if err != nil {
+			return fmt.Errorf("failed to take screenshot: %w", err)
+		}
+		result.Screenshot = screenshot
+
+	case ActionEval:
+		value, err := page.Eval(action.Script)
+		if err != nil {
+			return fmt.Errorf("failed to evaluate script: %w", err)
+		}
+		result.Result = value.Value.Raw()
+	}
+	return nil
+}
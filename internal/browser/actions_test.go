@@ -0,0 +1,57 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestValidateActions(t *testing.T) {
+	valid := []Action{
+		{Type: ActionNavigate, URL: "https://example.com/login"},
+		{Type: ActionFill, Selector: "#user", Value: "me"},
+		{Type: ActionClick, Selector: "button[type=submit]"},
+		{Type: ActionWait, Selector: ".dashboard"},
+		{Type: ActionWait, Duration: 500},
+		{Type: ActionScroll},
+		{Type: ActionScreenshot, FullPage: true},
+		{Type: ActionEval, Script: "() => document.title"},
+	}
+	if err := ValidateActions(valid); err != nil {
+		t.Fatalf("Expected a valid sequence, got %v", err)
+	}
+
+	navigate := Action{Type: ActionNavigate, URL: "https://example.com"}
+	tests := map[string][]Action{
+		"empty":             nil,
+		"no navigate first": {{Type: ActionClick, Selector: "a"}},
+		"navigate no url":   {{Type: ActionNavigate}},
+		"click no selector": {navigate, {Type: ActionClick}},
+		"fill no selector":  {navigate, {Type: ActionFill, Value: "x"}},
+		"wait nothing":      {navigate, {Type: ActionWait}},
+		"wait too long":     {navigate, {Type: ActionWait, Duration: 31000}},
+		"eval no script":    {navigate, {Type: ActionEval}},
+		"unknown type":      {navigate, {Type: "hover"}},
+		"too many actions":  make([]Action, MaxActions+1),
+	}
+	for name, actions := range tests {
+		if err := ValidateActions(actions); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("%s: expected ErrInvalidOptions, got %v", name, err)
+		}
+	}
+}
+
+func TestFailedAction(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", &PageError{Err: &ActionError{Index: 2, Type: ActionClick, Err: errors.New("element not found: #go")}})
+	if got := FailedAction(err); got != 2 {
+		t.Errorf("Expected step 2, got %d", got)
+	}
+	if got := FailedAction(errors.New("boom")); got != -1 {
+		t.Errorf("Expected -1 without an action error, got %d", got)
+	}
+
+	actionErr := &ActionError{Index: 1, Type: ActionEval, Err: errors.New("syntax error")}
+	if actionErr.Error() != "action 1 (eval) failed: syntax error" {
+		t.Errorf("Unexpected message %q", actionErr.Error())
+	}
+}
@@ -0,0 +1,99 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// AdaptiveLimiter bounds concurrent operations with a limit that adapts to
+// browser health: it halves on overload errors (timeouts, no free page slot)
+// and grows by one after a full window of successes, up to the initial max.
+type AdaptiveLimiter struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	max       int
+	limit     int
+	lowest    int
+	inFlight  int
+	successes int
+}
+
+// NewAdaptiveLimiter creates a limiter starting at max concurrent operations
+func NewAdaptiveLimiter(max int) *AdaptiveLimiter {
+	if max < 1 {
+		max = 1
+	}
+	l := &AdaptiveLimiter{max: max, limit: max, lowest: max}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// Acquire blocks until an operation may start under the current limit
+func (l *AdaptiveLimiter) Acquire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.inFlight >= l.limit {
+		l.cond.Wait()
+	}
+	l.inFlight++
+}
+
+// Release ends an operation and adjusts the limit from its outcome
+func (l *AdaptiveLimiter) Release(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	switch {
+	case IsOverloadError(err):
+		l.backoffLocked()
+	case err == nil:
+		l.successes++
+		if l.successes >= l.limit && l.limit < l.max {
+			l.limit++
+			l.successes = 0
+		}
+	}
+	l.cond.Broadcast()
+}
+
+// Backoff halves the limit, e.g. after the browser was seen restarting
+func (l *AdaptiveLimiter) Backoff() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.backoffLocked()
+	l.cond.Broadcast()
+}
+
+func (l *AdaptiveLimiter) backoffLocked() {
+	l.limit /= 2
+	if l.limit < 1 {
+		l.limit = 1
+	}
+	if l.limit < l.lowest {
+		l.lowest = l.limit
+	}
+	l.successes = 0
+}
+
+// Limit returns the current concurrency limit
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// Lowest returns the lowest limit reached so far
+func (l *AdaptiveLimiter) Lowest() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lowest
+}
+
+// IsOverloadError reports whether err suggests the browser is overwhelmed
+func IsOverloadError(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrTooManyPages)
+}
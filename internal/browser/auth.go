@@ -0,0 +1,94 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Credentials answer an HTTP basic auth challenge from a site or a proxy
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Validate checks that a username is set
+func (c *Credentials) Validate() error {
+	if c != nil && c.Username == "" {
+		return fmt.Errorf("%w: basic_auth requires a username", ErrInvalidOptions)
+	}
+	return nil
+}
+
+// handleAuth pauses every request so auth challenges can be answered:
+// server challenges with opts.BasicAuth and proxy challenges with the
+// proxy's credentials. Each request is answered once; a repeated challenge
+// means the credentials were rejected, so it is canceled rather than
+// retried forever. Fetch interception is exclusive per page, so this also
+// aborts the opts.BlockResources types. The returned func stops it.
+func handleAuth(page *rod.Page, opts PageOptions) (func(), error) {
+	if err := opts.BasicAuth.Validate(); err != nil {
+		return noopCleanup, err
+	}
+	if err := ValidateBlockResources(opts.BlockResources); err != nil {
+		return noopCleanup, fmt.Errorf("%w: %w", ErrInvalidOptions, err)
+	}
+	blockTypes := make(map[proto.NetworkResourceType]bool, len(opts.BlockResources))
+	for _, name := range opts.BlockResources {
+		blockTypes[blockableResources[name]] = true
+	}
+
+	enable := proto.FetchEnable{
+		Patterns:           []*proto.FetchRequestPattern{{URLPattern: "*"}},
+		HandleAuthRequests: true,
+	}
+	if err := enable.Call(page); err != nil {
+		return noopCleanup, fmt.Errorf("failed to enable auth handling: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(page.GetContext())
+	events := page.Context(ctx)
+
+	// Auth events are handled in order on one goroutine
+	answered := map[proto.FetchRequestID]bool{}
+
+	go events.EachEvent(func(e *proto.FetchRequestPaused) {
+		go func() {
+			if blockTypes[e.ResourceType] {
+				if opts.blocked != nil {
+					opts.blocked.Add(1)
+				}
+				_ = proto.FetchFailRequest{RequestID: e.RequestID, ErrorReason: proto.NetworkErrorReasonBlockedByClient}.Call(events)
+				return
+			}
+			_ = proto.FetchContinueRequest{RequestID: e.RequestID}.Call(events)
+		}()
+	}, func(e *proto.FetchAuthRequired) {
+		creds := opts.BasicAuth
+		if e.AuthChallenge.Source == proto.FetchAuthChallengeSourceProxy {
+			creds = opts.proxyAuth
+		}
+
+		retry := answered[e.RequestID]
+		answered[e.RequestID] = true
+
+		response := &proto.FetchAuthChallengeResponse{Response: proto.FetchAuthChallengeResponseResponseCancelAuth}
+		if creds != nil && !retry {
+			response = &proto.FetchAuthChallengeResponse{
+				Response: proto.FetchAuthChallengeResponseResponseProvideCredentials,
+				Username: creds.Username,
+				Password: creds.Password,
+			}
+		}
+		go func() {
+			_ = proto.FetchContinueWithAuth{RequestID: e.RequestID, AuthChallengeResponse: response}.Call(events)
+		}()
+	})()
+
+	return func() {
+		cancel()
+		_ = proto.FetchDisable{}.Call(page)
+	}, nil
+}
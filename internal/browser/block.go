@@ -0,0 +1,89 @@
+package browser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// blockableResources maps the BlockResources values to CDP resource types
+var blockableResources = map[string]proto.NetworkResourceType{
+	"image":      proto.NetworkResourceTypeImage,
+	"font":       proto.NetworkResourceTypeFont,
+	"stylesheet": proto.NetworkResourceTypeStylesheet,
+	"media":      proto.NetworkResourceTypeMedia,
+}
+
+// ValidateBlockResources checks that every entry names a blockable
+// resource type
+func ValidateBlockResources(types []string) error {
+	for _, name := range types {
+		if _, ok := blockableResources[name]; !ok {
+			names := make([]string, 0, len(blockableResources))
+			for name := range blockableResources {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("unknown block_resources type %q (use %s)", name, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// blockResources intercepts requests for the given resource types and
+// aborts them, counting each in blocked when set. Only those types are
+// paused, so other requests are not slowed down. The returned func removes
+// the interception.
+func blockResources(page *rod.Page, types []string, blocked *atomic.Int64) (func(), error) {
+	if len(types) == 0 {
+		return noopCleanup, nil
+	}
+	if err := ValidateBlockResources(types); err != nil {
+		return noopCleanup, fmt.Errorf("%w: %w", ErrInvalidOptions, err)
+	}
+
+	router := page.HijackRequests()
+	for _, name := range types {
+		err := router.Add("*", blockableResources[name], func(h *rod.Hijack) {
+			if blocked != nil {
+				blocked.Add(1)
+			}
+			h.Response.Fail(proto.NetworkErrorReasonBlockedByClient)
+		})
+		if err != nil {
+			_ = router.Stop()
+			return noopCleanup, fmt.Errorf("failed to block %s requests: %w", name, err)
+		}
+	}
+	go router.Run()
+
+	return func() { _ = router.Stop() }, nil
+}
+
+// setupPage blocks the requested resource types, handles auth challenges
+// and prepares the page. The returned func removes the request interception
+// and must be called once the page is no longer used.
+func setupPage(page *rod.Page, url string, opts PageOptions) (func(), error) {
+	intercept := func() (func(), error) {
+		return blockResources(page, opts.BlockResources, opts.blocked)
+	}
+	if opts.BasicAuth != nil || opts.proxyAuth != nil {
+		intercept = func() (func(), error) {
+			return handleAuth(page, opts)
+		}
+	}
+
+	unblock, err := intercept()
+	if err != nil {
+		return noopCleanup, err
+	}
+	if err := preparePage(page, url, opts); err != nil {
+		unblock()
+		return noopCleanup, err
+	}
+	return unblock, nil
+}
@@ -0,0 +1,15 @@
+package browser
+
+import "testing"
+
+func TestValidateBlockResources(t *testing.T) {
+	if err := ValidateBlockResources([]string{"image", "font", "stylesheet", "media"}); err != nil {
+		t.Errorf("Expected all documented types to be accepted: %v", err)
+	}
+	if err := ValidateBlockResources(nil); err != nil {
+		t.Errorf("Expected no types to be accepted: %v", err)
+	}
+	if err := ValidateBlockResources([]string{"image", "document"}); err == nil {
+		t.Error("Expected the main document to be rejected")
+	}
+}
@@ -0,0 +1,419 @@
+// Package browsertest provides a browser.Client for tests that need no real
+// browser. MockClient returns canned responses, injects errors per method,
+// URL or argument, and records every call so tests can assert on the options
+// passed.
+package browsertest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ahrdadan/scrq/internal/browser"
+)
+
+// Method names used by MockClient for Errors and call lookups
+const (
+	MethodUsage              = "Usage"
+	MethodFetchPage          = "FetchPage"
+	MethodTakeScreenshot     = "TakeScreenshot"
+	MethodScreenshotElement  = "ScreenshotElement"
+	MethodEvaluateScript     = "EvaluateScript"
+	MethodClickElement       = "ClickElement"
+	MethodFillForm           = "FillForm"
+	MethodHoverElement       = "HoverElement"
+	MethodSelectOption       = "SelectOption"
+	MethodPressKey           = "PressKey"
+	MethodRunActions         = "RunActions"
+	MethodGetPageInfo        = "GetPageInfo"
+	MethodGetMarkdown        = "GetMarkdown"
+	MethodCountElements      = "CountElements"
+	MethodExtractBySelectors = "ExtractBySelectors"
+	MethodExtractAttributes  = "ExtractAttributes"
+	MethodElementBoxes       = "ElementBoxes"
+	MethodCaptureFrames      = "CaptureFrames"
+	MethodDiscoverFeeds      = "DiscoverFeeds"
+	MethodExtractMetadata    = "ExtractMetadata"
+	MethodExtractTables      = "ExtractTables"
+	MethodExtractJSONScript  = "ExtractJSONScript"
+	MethodDownloadFile       = "DownloadFile"
+	MethodRenderPDF          = "RenderPDF"
+	MethodExecuteCDP         = "ExecuteCDP"
+	MethodCreateSession      = "CreateSession"
+	MethodCloseSession       = "CloseSession"
+)
+
+// Call records one method invocation on a MockClient
+type Call struct {
+	Method string
+	URL    string
+	Opts   browser.PageOptions
+	Args   []interface{} // Method-specific arguments after the URL, in order
+}
+
+// MockClient is a browser.Client with canned responses. Set the response
+// fields before use; a nil page result makes FetchPage, GetPageInfo and
+// GetMarkdown return a result holding only the URL. It is safe for
+// concurrent use.
+type MockClient struct {
+	Running  bool
+	Endpoint string
+
+	ResourceUsage *browser.ResourceUsage
+	Page          *browser.PageResult
+	Screenshot    []byte
+	ScriptResult  interface{}
+	Counts        map[string]int
+	Texts         map[string][]string
+	Attributes    []browser.AttributeResult
+	Boxes         []browser.BoxResult
+	Frames        []browser.ScreenshotFrame
+	Feeds         []browser.FeedLink
+	Metadata      *browser.PageMetadata
+	Tables        []browser.Table
+	JSONResult    interface{}
+	Download      *browser.DownloadResult
+	PDF           []byte
+	CDPResult     json.RawMessage
+	Session       *browser.SessionInfo
+
+	// Errors maps a method name (see the Method constants) to the error it
+	// returns. ErrorFunc, when set, is consulted first and may fail only
+	// some URLs; returning nil falls through to Errors.
+	Errors    map[string]error
+	ErrorFunc func(method, url string) error
+
+	// CallFunc, when set, is consulted before ErrorFunc with the whole call,
+	// so it can fail calls by their arguments or block on ctx to stand in
+	// for a slow page. Returning nil falls through to ErrorFunc.
+	CallFunc func(ctx context.Context, call Call) error
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+// NewMockClient returns a running MockClient with empty responses
+func NewMockClient() *MockClient {
+	return &MockClient{
+		Running:  true,
+		Endpoint: "ws://127.0.0.1:9222",
+		Errors:   map[string]error{},
+	}
+}
+
+// Calls returns every recorded call in order
+func (m *MockClient) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// CallsTo returns the recorded calls to one method in order
+func (m *MockClient) CallsTo(method string) []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var calls []Call
+	for _, call := range m.calls {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Called reports whether the method was called at least once
+func (m *MockClient) Called(method string) bool {
+	return len(m.CallsTo(method)) > 0
+}
+
+// LastCall returns the most recent call to the method
+func (m *MockClient) LastCall(method string) (Call, bool) {
+	calls := m.CallsTo(method)
+	if len(calls) == 0 {
+		return Call{}, false
+	}
+	return calls[len(calls)-1], true
+}
+
+// Reset clears the recorded calls
+func (m *MockClient) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = nil
+}
+
+// record stores the call and returns the error injected for it, if any
+func (m *MockClient) record(ctx context.Context, method, url string, opts browser.PageOptions, args ...interface{}) error {
+	call := Call{Method: method, URL: url, Opts: opts, Args: args}
+	m.mu.Lock()
+	m.calls = append(m.calls, call)
+	callFunc, errorFunc, err := m.CallFunc, m.ErrorFunc, m.Errors[method]
+	m.mu.Unlock()
+
+	if callFunc != nil {
+		if injected := callFunc(ctx, call); injected != nil {
+			return injected
+		}
+	}
+	if errorFunc != nil {
+		if injected := errorFunc(method, url); injected != nil {
+			return injected
+		}
+	}
+	return err
+}
+
+func (m *MockClient) page(url string) *browser.PageResult {
+	if m.Page == nil {
+		return &browser.PageResult{URL: url, RequestedURL: url}
+	}
+	page := *m.Page
+	return &page
+}
+
+// IsRunning returns Running
+func (m *MockClient) IsRunning() bool { return m.Running }
+
+// GetEndpoint returns Endpoint
+func (m *MockClient) GetEndpoint() string { return m.Endpoint }
+
+// Usage returns ResourceUsage (zero usage if unset)
+func (m *MockClient) Usage() (*browser.ResourceUsage, error) {
+	if err := m.record(context.Background(), MethodUsage, "", browser.PageOptions{}); err != nil {
+		return nil, err
+	}
+	if m.ResourceUsage == nil {
+		return &browser.ResourceUsage{}, nil
+	}
+	return m.ResourceUsage, nil
+}
+
+// FetchPage returns a copy of Page
+func (m *MockClient) FetchPage(ctx context.Context, url string, opts browser.PageOptions) (*browser.PageResult, error) {
+	if err := m.record(ctx, MethodFetchPage, url, opts); err != nil {
+		return nil, err
+	}
+	return m.page(url), nil
+}
+
+// TakeScreenshot returns Screenshot
+func (m *MockClient) TakeScreenshot(ctx context.Context, url string, fullPage bool, shot browser.ScreenshotOptions, opts browser.PageOptions) ([]byte, error) {
+	if err := m.record(ctx, MethodTakeScreenshot, url, opts, fullPage, shot); err != nil {
+		return nil, err
+	}
+	return m.Screenshot, nil
+}
+
+// ScreenshotElement returns Screenshot
+func (m *MockClient) ScreenshotElement(ctx context.Context, url string, selector string, opts browser.PageOptions) ([]byte, error) {
+	if err := m.record(ctx, MethodScreenshotElement, url, opts, selector); err != nil {
+		return nil, err
+	}
+	return m.Screenshot, nil
+}
+
+// EvaluateScript returns ScriptResult
+func (m *MockClient) EvaluateScript(ctx context.Context, url string, script string, opts browser.PageOptions) (interface{}, error) {
+	if err := m.record(ctx, MethodEvaluateScript, url, opts, script); err != nil {
+		return nil, err
+	}
+	return m.ScriptResult, nil
+}
+
+// ClickElement records the click
+func (m *MockClient) ClickElement(ctx context.Context, url string, selector string, opts browser.PageOptions) error {
+	return m.record(ctx, MethodClickElement, url, opts, selector)
+}
+
+// FillForm records the inputs
+func (m *MockClient) FillForm(ctx context.Context, url string, inputs map[string]string, opts browser.PageOptions) error {
+	return m.record(ctx, MethodFillForm, url, opts, inputs)
+}
+
+// HoverElement records the hover
+func (m *MockClient) HoverElement(ctx context.Context, url string, selector string, opts browser.PageOptions) error {
+	return m.record(ctx, MethodHoverElement, url, opts, selector)
+}
+
+// SelectOption records the selection
+func (m *MockClient) SelectOption(ctx context.Context, url string, selector string, option browser.OptionQuery, opts browser.PageOptions) error {
+	return m.record(ctx, MethodSelectOption, url, opts, selector, option)
+}
+
+// PressKey records the key press
+func (m *MockClient) PressKey(ctx context.Context, url string, selector string, key string, opts browser.PageOptions) error {
+	return m.record(ctx, MethodPressKey, url, opts, selector, key)
+}
+
+// RunActions records the actions and returns one empty result per action,
+// with the URL of the last navigate. Like a real client, an injected
+// *browser.ActionError comes with the results of the steps before it.
+func (m *MockClient) RunActions(ctx context.Context, actions []browser.Action, opts browser.PageOptions) ([]browser.ActionResult, error) {
+	var url string
+	if len(actions) > 0 {
+		url = actions[0].URL
+	}
+	err := m.record(ctx, MethodRunActions, url, opts, actions)
+
+	results := make([]browser.ActionResult, 0, len(actions))
+	for i, action := range actions {
+		if action.Type == browser.ActionNavigate {
+			url = action.URL
+		}
+		results = append(results, browser.ActionResult{Index: i, Type: action.Type, URL: url})
+	}
+
+	var actionErr *browser.ActionError
+	switch {
+	case errors.As(err, &actionErr) && actionErr.Index <= len(results):
+		return results[:actionErr.Index], err
+	case err != nil:
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetPageInfo returns a copy of Page
+func (m *MockClient) GetPageInfo(ctx context.Context, url string, opts browser.PageOptions) (*browser.PageResult, error) {
+	if err := m.record(ctx, MethodGetPageInfo, url, opts); err != nil {
+		return nil, err
+	}
+	return m.page(url), nil
+}
+
+// GetMarkdown returns a copy of Page
+func (m *MockClient) GetMarkdown(ctx context.Context, url string, selector string, opts browser.PageOptions) (*browser.PageResult, error) {
+	if err := m.record(ctx, MethodGetMarkdown, url, opts, selector); err != nil {
+		return nil, err
+	}
+	return m.page(url), nil
+}
+
+// CountElements returns Counts, reporting 0 for selectors it does not list
+func (m *MockClient) CountElements(ctx context.Context, url string, selectors []string, opts browser.PageOptions) (map[string]int, error) {
+	if err := m.record(ctx, MethodCountElements, url, opts, selectors); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(selectors))
+	for _, selector := range selectors {
+		counts[selector] = m.Counts[selector]
+	}
+	return counts, nil
+}
+
+// ExtractBySelectors returns Texts, reporting an empty slice for selectors
+// it does not list
+func (m *MockClient) ExtractBySelectors(ctx context.Context, url string, selectors []string, opts browser.PageOptions) (map[string][]string, error) {
+	if err := m.record(ctx, MethodExtractBySelectors, url, opts, selectors); err != nil {
+		return nil, err
+	}
+	texts := make(map[string][]string, len(selectors))
+	for _, selector := range selectors {
+		texts[selector] = append([]string{}, m.Texts[selector]...)
+	}
+	return texts, nil
+}
+
+// ExtractAttributes returns Attributes
+func (m *MockClient) ExtractAttributes(ctx context.Context, url string, queries []browser.AttributeQuery, opts browser.PageOptions) ([]browser.AttributeResult, error) {
+	if err := m.record(ctx, MethodExtractAttributes, url, opts, queries); err != nil {
+		return nil, err
+	}
+	return m.Attributes, nil
+}
+
+// ElementBoxes returns Boxes
+func (m *MockClient) ElementBoxes(ctx context.Context, url string, selectors []string, screenshots bool, opts browser.PageOptions) ([]browser.BoxResult, error) {
+	if err := m.record(ctx, MethodElementBoxes, url, opts, selectors, screenshots); err != nil {
+		return nil, err
+	}
+	return m.Boxes, nil
+}
+
+// CaptureFrames returns Frames
+func (m *MockClient) CaptureFrames(ctx context.Context, url string, interval time.Duration, maxFrames int, opts browser.PageOptions) ([]browser.ScreenshotFrame, error) {
+	if err := m.record(ctx, MethodCaptureFrames, url, opts, interval, maxFrames); err != nil {
+		return nil, err
+	}
+	return m.Frames, nil
+}
+
+// DiscoverFeeds returns Feeds
+func (m *MockClient) DiscoverFeeds(ctx context.Context, url string, opts browser.PageOptions) ([]browser.FeedLink, error) {
+	if err := m.record(ctx, MethodDiscoverFeeds, url, opts); err != nil {
+		return nil, err
+	}
+	return m.Feeds, nil
+}
+
+// ExtractMetadata returns a copy of Metadata, or metadata holding only the
+// URL when unset
+func (m *MockClient) ExtractMetadata(ctx context.Context, url string, opts browser.PageOptions) (*browser.PageMetadata, error) {
+	if err := m.record(ctx, MethodExtractMetadata, url, opts); err != nil {
+		return nil, err
+	}
+	if m.Metadata == nil {
+		return &browser.PageMetadata{URL: url}, nil
+	}
+	metadata := *m.Metadata
+	return &metadata, nil
+}
+
+// ExtractTables returns Tables
+func (m *MockClient) ExtractTables(ctx context.Context, url string, selector string, opts browser.PageOptions) ([]browser.Table, error) {
+	if err := m.record(ctx, MethodExtractTables, url, opts, selector); err != nil {
+		return nil, err
+	}
+	return m.Tables, nil
+}
+
+// ExtractJSONScript returns JSONResult
+func (m *MockClient) ExtractJSONScript(ctx context.Context, url string, selector string, jsonPath string, opts browser.PageOptions) (interface{}, error) {
+	if err := m.record(ctx, MethodExtractJSONScript, url, opts, selector, jsonPath); err != nil {
+		return nil, err
+	}
+	return m.JSONResult, nil
+}
+
+// DownloadFile returns Download
+func (m *MockClient) DownloadFile(ctx context.Context, url string, selector string, opts browser.PageOptions) (*browser.DownloadResult, error) {
+	if err := m.record(ctx, MethodDownloadFile, url, opts, selector); err != nil {
+		return nil, err
+	}
+	return m.Download, nil
+}
+
+// RenderPDF returns PDF
+func (m *MockClient) RenderPDF(ctx context.Context, url string, pdfOpts browser.PDFOptions, opts browser.PageOptions) ([]byte, error) {
+	if err := m.record(ctx, MethodRenderPDF, url, opts, pdfOpts); err != nil {
+		return nil, err
+	}
+	return m.PDF, nil
+}
+
+// ExecuteCDP returns CDPResult
+func (m *MockClient) ExecuteCDP(ctx context.Context, url string, method string, params json.RawMessage, opts browser.PageOptions) (json.RawMessage, error) {
+	if err := m.record(ctx, MethodExecuteCDP, url, opts, method, params); err != nil {
+		return nil, err
+	}
+	return m.CDPResult, nil
+}
+
+// CreateSession returns Session (a session with ID "sess_test" if unset)
+func (m *MockClient) CreateSession() (*browser.SessionInfo, error) {
+	if err := m.record(context.Background(), MethodCreateSession, "", browser.PageOptions{}); err != nil {
+		return nil, err
+	}
+	if m.Session == nil {
+		return &browser.SessionInfo{ID: "sess_test"}, nil
+	}
+	return m.Session, nil
+}
+
+// CloseSession records the session ID as its argument
+func (m *MockClient) CloseSession(id string) error {
+	return m.record(context.Background(), MethodCloseSession, "", browser.PageOptions{}, id)
+}
@@ -0,0 +1,101 @@
+package browser
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// initialHTMLGrace bounds how long the buffered network events are scanned
+// for the document response once the page has loaded
+const initialHTMLGrace = 2 * time.Second
+
+// captureInitialHTML starts recording the main document response. The
+// returned func, called once the page has loaded, yields the HTML as sent by
+// the server before any script ran, or "" if it could not be captured. The
+// events are buffered from the moment of the call, so it must run before
+// navigating.
+func captureInitialHTML(page *rod.Page) func() string {
+	ctx, cancel := context.WithCancel(page.GetContext())
+
+	var documentID proto.NetworkRequestID
+	var body string
+	wait := page.Context(ctx).EachEvent(
+		func(e *proto.NetworkResponseReceived) {
+			// Redirects do not produce a response event, so the first main
+			// frame document is the final one
+			if documentID == "" && e.Type == proto.NetworkResourceTypeDocument && e.FrameID == page.FrameID {
+				documentID = e.RequestID
+			}
+		},
+		func(e *proto.NetworkLoadingFinished) bool {
+			if documentID == "" || e.RequestID != documentID {
+				return false
+			}
+			// Read the body before the wait ends and the domain is restored
+			res, err := proto.NetworkGetResponseBody{RequestID: documentID}.Call(page)
+			if err == nil && !res.Base64Encoded {
+				body = res.Body
+			}
+			return true
+		},
+	)
+
+	return func() string {
+		timer := time.AfterFunc(initialHTMLGrace, cancel)
+		defer timer.Stop()
+		defer cancel()
+
+		wait()
+		return body
+	}
+}
+
+// documentResponseGrace bounds how long the buffered network events are
+// scanned for the main document response after navigation. The response
+// normally arrives before navigation completes, so this only matters for
+// pages without one, such as about:blank.
+const documentResponseGrace = 500 * time.Millisecond
+
+// documentResponse is the HTTP response of the main document
+type documentResponse struct {
+	URL        string
+	StatusCode int
+	Headers    map[string]string
+}
+
+// captureDocumentResponse starts recording the main document response. The
+// returned func, called after navigation, yields it, or nil if none was
+// seen. Like captureInitialHTML it must run before navigating.
+func captureDocumentResponse(page *rod.Page) func() *documentResponse {
+	ctx, cancel := context.WithCancel(page.GetContext())
+
+	var response *documentResponse
+	wait := page.Context(ctx).EachEvent(func(e *proto.NetworkResponseReceived) bool {
+		// Redirects do not produce a response event, so the first main frame
+		// document is the final one
+		if e.Type != proto.NetworkResourceTypeDocument || e.FrameID != page.FrameID || e.Response == nil {
+			return false
+		}
+		response = &documentResponse{
+			URL:        e.Response.URL,
+			StatusCode: e.Response.Status,
+			Headers:    make(map[string]string, len(e.Response.Headers)),
+		}
+		for key, value := range e.Response.Headers {
+			response.Headers[key] = value.Str()
+		}
+		return true
+	})
+
+	return func() *documentResponse {
+		timer := time.AfterFunc(documentResponseGrace, cancel)
+		defer timer.Stop()
+		defer cancel()
+
+		wait()
+		return response
+	}
+}
@@ -0,0 +1,76 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/go-rod/rod/lib/cdp"
+)
+
+// ErrCDPCommand is returned when the browser rejects a raw CDP command, e.g.
+// because of invalid params. It distinguishes protocol errors from
+// navigation or browser failures.
+var ErrCDPCommand = errors.New("cdp command failed")
+
+// allowedCDPMethods lists the raw CDP methods that may be executed through
+// ExecuteCDP. Methods that control the browser process or targets are
+// intentionally excluded.
+var allowedCDPMethods = map[string]bool{
+	"Accessibility.getFullAXTree": true,
+	"CSS.getComputedStyleForNode": true,
+	"DOM.describeNode":            true,
+	"DOM.getBoxModel":             true,
+	"DOM.getDocument":             true,
+	"DOM.getOuterHTML":            true,
+	"DOM.querySelector":           true,
+	"DOM.querySelectorAll":        true,
+	"Network.getCookies":          true,
+	"Page.captureScreenshot":      true,
+	"Page.getFrameTree":           true,
+	"Page.getLayoutMetrics":       true,
+	"Page.getNavigationHistory":   true,
+	"Page.getResourceTree":        true,
+	"Performance.getMetrics":      true,
+	"Runtime.evaluate":            true,
+	"Runtime.getHeapUsage":        true,
+}
+
+// IsCDPMethodAllowed reports whether a raw CDP method may be executed.
+func IsCDPMethodAllowed(method string) bool {
+	return allowedCDPMethods[method]
+}
+
+func executeCDP(opener pageOpener, ctx context.Context, url string, method string, params json.RawMessage, opts PageOptions) (json.RawMessage, error) {
+	if !IsCDPMethodAllowed(method) {
+		return nil, fmt.Errorf("cdp method not allowed: %s", method)
+	}
+
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	page, cleanup, err := opener.OpenPage(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	var payload interface{}
+	if len(params) == 0 || string(params) == "null" {
+		payload = struct{}{}
+	} else {
+		payload = params
+	}
+
+	res, err := page.Call(ctx, string(page.SessionID), method, payload)
+	if err != nil {
+		var cdpErr *cdp.Error
+		if errors.As(err, &cdpErr) {
+			return nil, fmt.Errorf("%w: %s: %s", ErrCDPCommand, method, cdpErr.Error())
+		}
+		return nil, fmt.Errorf("failed to execute %s: %w", method, err)
+	}
+
+	return json.RawMessage(res), nil
+}
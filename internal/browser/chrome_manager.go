@@ -2,9 +2,11 @@ package browser
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/launcher"
@@ -20,13 +22,22 @@ type ChromeManager struct {
 	browser   *rod.Browser
 	wsURL     string
 	running   bool
+
+	pageLimiter *PageLimiter
+	downloadMu  sync.Mutex
+	usage       usageCache
+	watchdog    browserWatchdog
+	pool        *pagePool
+	sessions    *SessionManager
 }
 
 // NewChromeManager creates a new Chrome manager.
 func NewChromeManager(binPath string) *ChromeManager {
-	return &ChromeManager{
+	m := &ChromeManager{
 		binPath: binPath,
 	}
+	m.sessions = NewSessionManager(DefaultSessionTTL, DefaultMaxSessions, m.newSessionContext)
+	return m
 }
 
 // Start launches Chrome and connects via CDP.
@@ -72,6 +83,13 @@ func (m *ChromeManager) Stop() error {
 		return nil
 	}
 
+	// Pooled pages die with the browser
+	if m.pool != nil {
+		m.pool.drain(false)
+	}
+	// So are sessions
+	m.sessions.CloseAll()
+
 	if m.browser != nil {
 		if err := m.browser.Close(); err != nil {
 			log.Printf("Warning: failed to close chrome: %v", err)
@@ -99,6 +117,32 @@ func (m *ChromeManager) IsRunning() bool {
 	return m.running
 }
 
+// Usage returns the memory and CPU used by Chrome and its child processes
+// and its open page count, sampled at most every 10 seconds.
+func (m *ChromeManager) Usage() (*ResourceUsage, error) {
+	m.mu.Lock()
+	pid := 0
+	if m.running && m.launcher != nil {
+		pid = m.launcher.PID()
+	}
+	browser := m.browser
+	m.mu.Unlock()
+
+	usage, err := m.usage.get(pid, browser)
+	if err != nil {
+		return nil, err
+	}
+
+	sample := *usage
+	sample.MemoryRestarts = m.watchdog.restarts.Load()
+	sample.PagesServed = m.watchdog.served.Load()
+	sample.PageRecycles = m.watchdog.recycles.Load()
+	if m.pool != nil {
+		sample.Pool = m.pool.stats()
+	}
+	return &sample, nil
+}
+
 // GetEndpoint returns the Chrome DevTools endpoint.
 func (m *ChromeManager) GetEndpoint() string {
 	m.mu.Lock()
@@ -133,39 +177,213 @@ func (m *ChromeManager) NewPage(ctx context.Context) (*rod.Page, error) {
 
 // OpenPage creates a page, applies options, and navigates to the URL.
 func (m *ChromeManager) OpenPage(ctx context.Context, url string, opts PageOptions) (*rod.Page, func(), error) {
+	return m.watchdog.track(m.pageLimiter.open(ctx, func() (*rod.Page, func(), error) {
+		return m.openPage(ctx, url, opts)
+	}))
+}
+
+// StartMemoryWatchdog restarts the browser, once no page is open, whenever
+// its resident memory exceeds limitBytes. Usage is checked every interval.
+// The returned func stops the watchdog.
+func (m *ChromeManager) StartMemoryWatchdog(limitBytes uint64, interval time.Duration) func() {
+	return m.watchdog.start("Chrome", limitBytes, interval, m.Usage, m.restartBrowser)
+}
+
+// StartPageRecycler restarts the browser, once no page is open, after it
+// has served maxPages pages, bounding slow leaks. The returned func stops
+// the recycler.
+func (m *ChromeManager) StartPageRecycler(maxPages int) func() {
+	return m.watchdog.startRecycle("Chrome", int64(maxPages), m.restartBrowser)
+}
+
+// SetPageLimiter bounds the number of pages this manager opens at once.
+func (m *ChromeManager) SetPageLimiter(limiter *PageLimiter) {
+	m.pageLimiter = limiter
+}
+
+// StartPagePool keeps up to size pages warm for reuse instead of creating a
+// page per request. Pages are reset between uses and closed once idle for
+// maxIdle. Requests with a proxy never use the pool. The returned func stops
+// the pool and closes its pages.
+func (m *ChromeManager) StartPagePool(size int, maxIdle time.Duration) func() {
+	pool := newPagePool(size, maxIdle)
+	stopJanitor := pool.startJanitor()
+	m.pool = pool
+
+	return func() {
+		stopJanitor()
+		pool.drain(true)
+	}
+}
+
+// SetSessionTTL sets how long a session may stay idle before it is closed.
+func (m *ChromeManager) SetSessionTTL(ttl time.Duration) {
+	m.sessions.SetTTL(ttl)
+}
+
+// CreateSession creates a browser context whose cookies and storage persist
+// across the requests passing its ID in PageOptions.SessionID.
+func (m *ChromeManager) CreateSession() (*SessionInfo, error) {
+	return m.sessions.Create()
+}
+
+// CloseSession closes a session and its browser context.
+func (m *ChromeManager) CloseSession(id string) error {
+	return m.sessions.Close(id)
+}
+
+func (m *ChromeManager) openPage(ctx context.Context, url string, opts PageOptions) (*rod.Page, func(), error) {
+	if opts.SessionID != "" {
+		return m.openSessionPage(ctx, url, opts)
+	}
 	if opts.Proxy != "" {
 		return m.openPageWithProxy(ctx, url, opts)
 	}
 
-	page, err := m.NewPage(ctx)
+	page, release, err := m.acquirePage(ctx, url, opts)
 	if err != nil {
 		return nil, noopCleanup, err
 	}
 
-	if err := applyPageOptions(page, url, opts); err != nil {
-		page.Close()
+	unblock, err := setupPage(page, url, opts)
+	if err != nil {
+		// A page that failed to load may be stuck, so it is not reused.
+		release(false)
 		return nil, noopCleanup, err
 	}
 
-	if err := page.Navigate(url); err != nil {
-		page.Close()
-		return nil, noopCleanup, fmt.Errorf("failed to navigate to %s: %w", url, err)
+	return page, func() {
+		unblock()
+		release(true)
+	}, nil
+}
+
+// acquirePage returns a page for url, taken from the pool when one is
+// running. The returned func closes the page, or returns it to the pool
+// when reuse is set.
+func (m *ChromeManager) acquirePage(ctx context.Context, url string, opts PageOptions) (*rod.Page, func(reuse bool), error) {
+	pool := m.pool
+	if pool == nil {
+		page, err := m.NewPage(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		return page, func(bool) { page.Close() }, nil
+	}
+
+	entry := pool.get()
+	if entry == nil {
+		var err error
+		if entry, err = m.newPooledPage(); err != nil {
+			return nil, nil, err
+		}
+	}
+	entry.addOrigin(url)
+	entry.emulated = opts.Viewport != nil || opts.Device != "" || opts.UserAgent != "" || opts.AcceptLanguage != ""
+
+	return entry.page.Context(ctx), func(reuse bool) {
+		if reuse {
+			pool.put(entry)
+		} else {
+			pool.discard(entry)
+		}
+	}, nil
+}
+
+// newPooledPage creates a page in a new incognito context. The page is not
+// bound to a request context, so it can outlive the request.
+func (m *ChromeManager) newPooledPage() (*pooledPage, error) {
+	if err := m.ensureStarted(); err != nil {
+		return nil, fmt.Errorf("failed to start chrome: %w", err)
 	}
 
-	if opts.WaitForLoad {
-		if err := page.WaitLoad(); err != nil {
-			page.Close()
-			return nil, noopCleanup, fmt.Errorf("failed to wait for page load: %w", err)
+	entry, err := m.createPooledPage()
+	if err != nil {
+		if !isConnectionError(err) {
+			return nil, fmt.Errorf("failed to create new page: %w", err)
+		}
+
+		if restartErr := m.restartBrowser(); restartErr != nil {
+			return nil, fmt.Errorf("failed to restart chrome after connection error: %w", restartErr)
+		}
+
+		entry, err = m.createPooledPage()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create new page: %w", err)
 		}
 	}
 
-	return page, noopCleanup, nil
+	return entry, nil
+}
+
+func (m *ChromeManager) createPooledPage() (*pooledPage, error) {
+	incognito, err := m.browser.Incognito()
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := incognito.Page(proto.TargetCreateTarget{})
+	if err != nil {
+		_ = incognito.Close()
+		return nil, err
+	}
+
+	return &pooledPage{page: page, context: incognito}, nil
+}
+
+// newSessionContext creates the incognito context backing a session
+func (m *ChromeManager) newSessionContext() (*rod.Browser, error) {
+	if err := m.ensureStarted(); err != nil {
+		return nil, fmt.Errorf("failed to start chrome: %w", err)
+	}
+	return m.browser.Incognito()
+}
+
+// openSessionPage opens a page in a session's context. Session pages bypass
+// the pool, which would clear the cookies the session exists to keep.
+func (m *ChromeManager) openSessionPage(ctx context.Context, url string, opts PageOptions) (*rod.Page, func(), error) {
+	if opts.Proxy != "" {
+		return nil, noopCleanup, fmt.Errorf("%w: proxy cannot be combined with a session", ErrInvalidOptions)
+	}
+
+	incognito, release, err := m.sessions.acquire(opts.SessionID)
+	if err != nil {
+		return nil, noopCleanup, err
+	}
+
+	page, err := incognito.Context(ctx).Page(proto.TargetCreateTarget{})
+	if err != nil {
+		release()
+		return nil, noopCleanup, fmt.Errorf("failed to create new page: %w", err)
+	}
+
+	unblock, err := setupPage(page, url, opts)
+	if err != nil {
+		page.Close()
+		release()
+		return nil, noopCleanup, err
+	}
+
+	return page, func() {
+		unblock()
+		page.Close()
+		release()
+	}, nil
 }
 
 // Navigate navigates to a URL and returns the page.
 func (m *ChromeManager) Navigate(ctx context.Context, url string) (*rod.Page, error) {
-	page, _, err := m.OpenPage(ctx, url, DefaultPageOptions())
-	return page, err
+	// The caller owns the page, so it bypasses the pool and is not counted
+	// against the limiter.
+	page, err := m.NewPage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := setupPage(page, url, DefaultPageOptions()); err != nil {
+		page.Close()
+		return nil, err
+	}
+	return page, nil
 }
 
 // FetchPage fetches a page and returns its content.
@@ -188,9 +406,35 @@ func (m *ChromeManager) FillForm(ctx context.Context, url string, inputs map[str
 	return fillForm(m, ctx, url, inputs, opts)
 }
 
-// TakeScreenshot takes a screenshot of a page.
-func (m *ChromeManager) TakeScreenshot(ctx context.Context, url string, fullPage bool, opts PageOptions) ([]byte, error) {
-	return takeScreenshot(m, ctx, url, fullPage, opts)
+// HoverElement moves the mouse over an element on the page.
+func (m *ChromeManager) HoverElement(ctx context.Context, url string, selector string, opts PageOptions) error {
+	return hoverElement(m, ctx, url, selector, opts)
+}
+
+// SelectOption selects an option of a <select> element by value or label.
+func (m *ChromeManager) SelectOption(ctx context.Context, url string, selector string, option OptionQuery, opts PageOptions) error {
+	return selectOption(m, ctx, url, selector, option, opts)
+}
+
+// PressKey presses a key on the element, or on the focused element if the
+// selector is empty.
+func (m *ChromeManager) PressKey(ctx context.Context, url string, selector string, key string, opts PageOptions) error {
+	return pressKey(m, ctx, url, selector, key, opts)
+}
+
+// RunActions applies a sequence of actions to a single page.
+func (m *ChromeManager) RunActions(ctx context.Context, actions []Action, opts PageOptions) ([]ActionResult, error) {
+	return runActions(m, ctx, actions, opts)
+}
+
+// TakeScreenshot takes a screenshot of a page in the format of shot.
+func (m *ChromeManager) TakeScreenshot(ctx context.Context, url string, fullPage bool, shot ScreenshotOptions, opts PageOptions) ([]byte, error) {
+	return takeScreenshot(m, ctx, url, fullPage, shot, opts)
+}
+
+// ScreenshotElement takes a screenshot of the first element matching selector.
+func (m *ChromeManager) ScreenshotElement(ctx context.Context, url string, selector string, opts PageOptions) ([]byte, error) {
+	return screenshotElement(m, ctx, url, selector, opts)
 }
 
 // GetPageInfo returns basic page information.
@@ -198,6 +442,74 @@ func (m *ChromeManager) GetPageInfo(ctx context.Context, url string, opts PageOp
 	return getPageInfo(m, ctx, url, opts)
 }
 
+// GetMarkdown converts the page (or the element matching selector) to Markdown.
+func (m *ChromeManager) GetMarkdown(ctx context.Context, url string, selector string, opts PageOptions) (*PageResult, error) {
+	return getMarkdown(m, ctx, url, selector, opts)
+}
+
+// ExtractBySelectors returns the text of every element matching each selector.
+func (m *ChromeManager) ExtractBySelectors(ctx context.Context, url string, selectors []string, opts PageOptions) (map[string][]string, error) {
+	return extractBySelectors(m, ctx, url, selectors, opts)
+}
+
+// CountElements returns the number of elements matching each selector.
+func (m *ChromeManager) CountElements(ctx context.Context, url string, selectors []string, opts PageOptions) (map[string]int, error) {
+	return countElements(m, ctx, url, selectors, opts)
+}
+
+// ElementBoxes returns the bounding boxes of the elements matching each selector.
+func (m *ChromeManager) ElementBoxes(ctx context.Context, url string, selectors []string, screenshots bool, opts PageOptions) ([]BoxResult, error) {
+	return elementBoxes(m, ctx, url, selectors, screenshots, opts)
+}
+
+// CaptureFrames takes timed screenshots of the page while it loads.
+func (m *ChromeManager) CaptureFrames(ctx context.Context, url string, interval time.Duration, maxFrames int, opts PageOptions) ([]ScreenshotFrame, error) {
+	return captureFrames(m, ctx, url, interval, maxFrames, opts)
+}
+
+// DiscoverFeeds returns the RSS/Atom feeds advertised by the page.
+func (m *ChromeManager) DiscoverFeeds(ctx context.Context, url string, opts PageOptions) ([]FeedLink, error) {
+	return discoverFeeds(m, ctx, url, opts)
+}
+
+// ExtractMetadata returns the page's JSON-LD, OpenGraph and meta tags.
+func (m *ChromeManager) ExtractMetadata(ctx context.Context, url string, opts PageOptions) (*PageMetadata, error) {
+	return extractMetadata(m, ctx, url, opts)
+}
+
+// ExtractTables returns the tables matching selector (all tables if empty).
+func (m *ChromeManager) ExtractTables(ctx context.Context, url string, selector string, opts PageOptions) ([]Table, error) {
+	return extractTables(m, ctx, url, selector, opts)
+}
+
+// ExtractAttributes returns attribute values of the elements matching each query.
+func (m *ChromeManager) ExtractAttributes(ctx context.Context, url string, queries []AttributeQuery, opts PageOptions) ([]AttributeResult, error) {
+	return extractAttributes(m, ctx, url, queries, opts)
+}
+
+// ExtractJSONScript parses the JSON in a script element and applies a JSONPath.
+func (m *ChromeManager) ExtractJSONScript(ctx context.Context, url string, selector string, jsonPath string, opts PageOptions) (interface{}, error) {
+	return extractJSONScript(m, ctx, url, selector, jsonPath, opts)
+}
+
+// DownloadFile clicks an element and captures the file it downloads.
+// Download behavior is browser-wide, so downloads are serialized.
+func (m *ChromeManager) DownloadFile(ctx context.Context, url string, selector string, opts PageOptions) (*DownloadResult, error) {
+	m.downloadMu.Lock()
+	defer m.downloadMu.Unlock()
+	return downloadFile(m, ctx, url, selector, opts)
+}
+
+// RenderPDF prints a page to PDF.
+func (m *ChromeManager) RenderPDF(ctx context.Context, url string, pdfOpts PDFOptions, opts PageOptions) ([]byte, error) {
+	return renderPDF(m, ctx, url, pdfOpts, opts)
+}
+
+// ExecuteCDP executes an allow-listed raw CDP method against a page.
+func (m *ChromeManager) ExecuteCDP(ctx context.Context, url string, method string, params json.RawMessage, opts PageOptions) (json.RawMessage, error) {
+	return executeCDP(m, ctx, url, method, params, opts)
+}
+
 func (m *ChromeManager) ensureStarted() error {
 	if m.IsRunning() {
 		return nil
@@ -225,7 +537,17 @@ func (m *ChromeManager) restartBrowser() error {
 }
 
 func (m *ChromeManager) openPageWithProxy(ctx context.Context, url string, opts PageOptions) (*rod.Page, func(), error) {
-	l := launcher.New().Proxy(opts.Proxy)
+	proxy, err := parseProxy(opts.Proxy)
+	if err != nil {
+		return nil, noopCleanup, err
+	}
+	// Chrome cannot authenticate to SOCKS proxies
+	if proxy.User != nil && proxy.Scheme == "socks5" {
+		return nil, noopCleanup, fmt.Errorf("%w: socks5 proxy credentials are not supported by chrome", ErrInvalidOptions)
+	}
+	opts.proxyAuth = proxyCredentials(proxy)
+
+	l := launcher.New().Proxy(proxyServer(proxy))
 	if m.binPath != "" {
 		l.Bin(m.binPath)
 	}
@@ -258,25 +580,16 @@ func (m *ChromeManager) openPageWithProxy(ctx context.Context, url string, opts
 		l.Cleanup()
 	}
 
-	if err := applyPageOptions(page, url, opts); err != nil {
+	unblock, err := setupPage(page, url, opts)
+	if err != nil {
 		page.Close()
 		cleanup()
 		return nil, noopCleanup, err
 	}
 
-	if err := page.Navigate(url); err != nil {
+	return page, func() {
+		unblock()
 		page.Close()
 		cleanup()
-		return nil, noopCleanup, fmt.Errorf("failed to navigate to %s: %w", url, err)
-	}
-
-	if opts.WaitForLoad {
-		if err := page.WaitLoad(); err != nil {
-			page.Close()
-			cleanup()
-			return nil, noopCleanup, fmt.Errorf("failed to wait for page load: %w", err)
-		}
-	}
-
-	return page, cleanup, nil
+	}, nil
 }
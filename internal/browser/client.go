@@ -1,15 +1,40 @@
 package browser
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
 
 // Client defines the browser operations used by the API handlers.
 type Client interface {
 	IsRunning() bool
 	GetEndpoint() string
+	Usage() (*ResourceUsage, error)
 	FetchPage(ctx context.Context, url string, opts PageOptions) (*PageResult, error)
-	TakeScreenshot(ctx context.Context, url string, fullPage bool, opts PageOptions) ([]byte, error)
+	TakeScreenshot(ctx context.Context, url string, fullPage bool, shot ScreenshotOptions, opts PageOptions) ([]byte, error)
+	ScreenshotElement(ctx context.Context, url string, selector string, opts PageOptions) ([]byte, error)
 	EvaluateScript(ctx context.Context, url string, script string, opts PageOptions) (interface{}, error)
 	ClickElement(ctx context.Context, url string, selector string, opts PageOptions) error
 	FillForm(ctx context.Context, url string, inputs map[string]string, opts PageOptions) error
+	HoverElement(ctx context.Context, url string, selector string, opts PageOptions) error
+	SelectOption(ctx context.Context, url string, selector string, option OptionQuery, opts PageOptions) error
+	PressKey(ctx context.Context, url string, selector string, key string, opts PageOptions) error
+	RunActions(ctx context.Context, actions []Action, opts PageOptions) ([]ActionResult, error)
 	GetPageInfo(ctx context.Context, url string, opts PageOptions) (*PageResult, error)
+	GetMarkdown(ctx context.Context, url string, selector string, opts PageOptions) (*PageResult, error)
+	CountElements(ctx context.Context, url string, selectors []string, opts PageOptions) (map[string]int, error)
+	ExtractBySelectors(ctx context.Context, url string, selectors []string, opts PageOptions) (map[string][]string, error)
+	ExtractAttributes(ctx context.Context, url string, queries []AttributeQuery, opts PageOptions) ([]AttributeResult, error)
+	ElementBoxes(ctx context.Context, url string, selectors []string, screenshots bool, opts PageOptions) ([]BoxResult, error)
+	CaptureFrames(ctx context.Context, url string, interval time.Duration, maxFrames int, opts PageOptions) ([]ScreenshotFrame, error)
+	DiscoverFeeds(ctx context.Context, url string, opts PageOptions) ([]FeedLink, error)
+	ExtractMetadata(ctx context.Context, url string, opts PageOptions) (*PageMetadata, error)
+	ExtractTables(ctx context.Context, url string, selector string, opts PageOptions) ([]Table, error)
+	ExtractJSONScript(ctx context.Context, url string, selector string, jsonPath string, opts PageOptions) (interface{}, error)
+	DownloadFile(ctx context.Context, url string, selector string, opts PageOptions) (*DownloadResult, error)
+	RenderPDF(ctx context.Context, url string, pdfOpts PDFOptions, opts PageOptions) ([]byte, error)
+	ExecuteCDP(ctx context.Context, url string, method string, params json.RawMessage, opts PageOptions) (json.RawMessage, error)
+	CreateSession() (*SessionInfo, error)
+	CloseSession(id string) error
 }
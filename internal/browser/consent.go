@@ -0,0 +1,83 @@
+package browser
+
+import (
+	"log"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+// consentSelectors are accept buttons of common consent management platforms
+var consentSelectors = []string{
+	"#onetrust-accept-btn-handler",                           // OneTrust
+	"#CybotCookiebotDialogBodyLevelButtonLevelOptinAllowAll", // Cookiebot
+	"#CybotCookiebotDialogBodyButtonAccept",                  // Cookiebot (legacy)
+	"#didomi-notice-agree-button",                            // Didomi
+	"#truste-consent-button",                                 // TrustArc
+	".qc-cmp2-summary-buttons button[mode=primary]",          // Quantcast
+	".fc-cta-consent",                                        // Google Funding Choices
+	".osano-cm-accept-all",                                   // Osano
+	".cky-btn-accept",                                        // CookieYes
+	".cmplz-accept",                                          // Complianz
+	"[data-testid=uc-accept-all-button]",                     // Usercentrics
+	"#axeptio_btn_acceptAll",                                 // Axeptio
+	".iubenda-cs-accept-btn",                                 // iubenda
+	"button[data-cookiefirst-action=accept]",                 // CookieFirst
+}
+
+// consentTextPattern matches accept-button labels in common languages
+const consentTextPattern = `^(accept( all)?( cookies)?|allow( all)?( cookies)?|agree|i agree|i accept|ok|got it|` +
+	`alle akzeptieren|akzeptieren|zustimmen|alle zulassen|tout accepter|accepter|j'accepte|` +
+	`aceptar( todo)?|accetta( tutto)?|accetto|alles accepteren|accepteren|aceitar( tudo)?|zaakceptuj|akceptuj)$`
+
+const (
+	consentAttempts = 3
+	consentInterval = 500 * time.Millisecond
+)
+
+// dismissConsentScript clicks the first visible consent accept button found
+// by known selectors, then by button text or aria-label. It returns what was
+// clicked, or an empty string.
+const dismissConsentScript = `(selectors, pattern) => {
+	const visible = (el) => {
+		const rect = el.getBoundingClientRect()
+		const style = getComputedStyle(el)
+		return rect.width > 0 && rect.height > 0 && style.visibility !== 'hidden' && style.display !== 'none'
+	}
+	for (const sel of selectors) {
+		const el = document.querySelector(sel)
+		if (el && visible(el)) { el.click(); return sel }
+	}
+	const re = new RegExp(pattern, 'i')
+	const candidates = document.querySelectorAll('button, a[role=button], [role=button], input[type=button], input[type=submit]')
+	for (const el of candidates) {
+		const label = (el.getAttribute('aria-label') || el.innerText || el.value || '').trim().replace(/\s+/g, ' ')
+		if (label.length <= 40 && re.test(label) && visible(el)) { el.click(); return label }
+	}
+	return ''
+}`
+
+// dismissConsent makes a best-effort attempt to accept cookie consent
+// banners. Banners are often injected shortly after load, so it retries a few
+// times. Failures are logged and never fail the page.
+func dismissConsent(page *rod.Page) {
+	for attempt := 0; attempt < consentAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-page.GetContext().Done():
+				return
+			case <-time.After(consentInterval):
+			}
+		}
+
+		res, err := page.Eval(dismissConsentScript, consentSelectors, consentTextPattern)
+		if err != nil {
+			log.Printf("Warning: consent dismissal failed: %v", err)
+			return
+		}
+
+		if res.Value.Str() != "" {
+			return
+		}
+	}
+}
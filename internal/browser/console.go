@@ -0,0 +1,116 @@
+package browser
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// MaxConsoleMessages caps the console messages kept per page
+const MaxConsoleMessages = 200
+
+// ConsoleMessage is a console API call or an uncaught exception on a page
+type ConsoleMessage struct {
+	Type      string `json:"type"` // log, info, warning, error, debug, ... or exception
+	Text      string `json:"text"`
+	Timestamp int64  `json:"timestamp"` // Unix milliseconds
+}
+
+// consoleLog collects the console messages of one page
+type consoleLog struct {
+	mu       sync.Mutex
+	messages []ConsoleMessage
+}
+
+func (l *consoleLog) add(msg ConsoleMessage) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.messages) < MaxConsoleMessages {
+		l.messages = append(l.messages, msg)
+	}
+}
+
+// list returns a copy of the messages collected so far
+func (l *consoleLog) list() []ConsoleMessage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]ConsoleMessage(nil), l.messages...)
+}
+
+// captureConsole records console API calls and uncaught exceptions into log
+// until the page context ends. The listener is attached on call, so it must
+// run before navigating.
+func captureConsole(page *rod.Page, log *consoleLog) {
+	wait := page.EachEvent(
+		func(e *proto.RuntimeConsoleAPICalled) {
+			log.add(ConsoleMessage{
+				Type:      string(e.Type),
+				Text:      consoleText(e.Args),
+				Timestamp: int64(e.Timestamp),
+			})
+		},
+		func(e *proto.RuntimeExceptionThrown) {
+			log.add(ConsoleMessage{
+				Type:      "exception",
+				Text:      exceptionText(e.ExceptionDetails),
+				Timestamp: int64(e.Timestamp),
+			})
+		},
+	)
+	go wait()
+}
+
+// consoleText joins console call arguments the way DevTools prints them
+func consoleText(args []*proto.RuntimeRemoteObject) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch {
+		case arg.Type == proto.RuntimeRemoteObjectTypeString:
+			parts = append(parts, arg.Value.Str())
+		case !arg.Value.Nil():
+			parts = append(parts, arg.Value.JSON("", ""))
+		case arg.UnserializableValue != "":
+			parts = append(parts, string(arg.UnserializableValue))
+		default:
+			parts = append(parts, arg.Description)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// exceptionText describes an uncaught exception, preferring the error's
+// message and stack over the generic "Uncaught" text
+func exceptionText(details *proto.RuntimeExceptionDetails) string {
+	if details == nil {
+		return ""
+	}
+	if details.Exception != nil && details.Exception.Description != "" {
+		return details.Exception.Description
+	}
+	return details.Text
+}
+
+// withConsole attaches the collected console messages to err
+func withConsole(err error, log *consoleLog) error {
+	if err == nil || log == nil {
+		return err
+	}
+	var pageErr *PageError
+	if errors.As(err, &pageErr) {
+		pageErr.Console = log.list()
+		return err
+	}
+	return &PageError{Err: err, Console: log.list()}
+}
+
+// ConsoleMessages returns the console messages attached to err, if any
+func ConsoleMessages(err error) []ConsoleMessage {
+	var pageErr *PageError
+	if errors.As(err, &pageErr) {
+		return pageErr.Console
+	}
+	return nil
+}
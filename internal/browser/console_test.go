@@ -0,0 +1,45 @@
+package browser
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+func TestConsoleText(t *testing.T) {
+	var args []*proto.RuntimeRemoteObject
+	_ = json.Unmarshal([]byte(`[
+		{"type": "string", "value": "loaded"},
+		{"type": "number", "value": 3},
+		{"type": "number", "unserializableValue": "NaN"},
+		{"type": "object", "description": "HTMLDivElement"}
+	]`), &args)
+	if got := consoleText(args); got != "loaded 3 NaN HTMLDivElement" {
+		t.Errorf("Unexpected console text %q", got)
+	}
+}
+
+func TestWithConsole(t *testing.T) {
+	log := &consoleLog{}
+	log.add(ConsoleMessage{Type: "error", Text: "boom"})
+
+	err := withConsole(errors.New("navigation failed"), log)
+	if msgs := ConsoleMessages(err); len(msgs) != 1 || msgs[0].Text != "boom" {
+		t.Errorf("Expected console messages on the error, got %v", msgs)
+	}
+	if err.Error() != "navigation failed" {
+		t.Errorf("Expected the original message, got %q", err.Error())
+	}
+
+	shot := &PageError{Err: errors.New("click failed"), Screenshot: []byte{1}}
+	err = withConsole(shot, log)
+	if err != shot || len(shot.Console) != 1 || len(FailureScreenshot(err)) != 1 {
+		t.Errorf("Expected the console to be added to the existing PageError")
+	}
+
+	if withConsole(nil, log) != nil {
+		t.Error("Expected nil error to stay nil")
+	}
+}
@@ -0,0 +1,116 @@
+package browser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Bounds for emulated viewports
+const (
+	MaxViewportSize        = 10000
+	MaxDeviceScaleFactor   = 4
+	defaultMobileUserAgent = "Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0.0.0 Mobile Safari/537.36"
+)
+
+// Viewport is an emulated screen size and device type
+type Viewport struct {
+	Width             int     `json:"width"`
+	Height            int     `json:"height"`
+	DeviceScaleFactor float64 `json:"device_scale_factor,omitempty"` // 1 if unset
+	Mobile            bool    `json:"mobile,omitempty"`
+}
+
+// Validate checks the viewport bounds
+func (v Viewport) Validate() error {
+	if v.Width < 1 || v.Width > MaxViewportSize || v.Height < 1 || v.Height > MaxViewportSize {
+		return fmt.Errorf("%w: viewport width and height must be between 1 and %d", ErrInvalidOptions, MaxViewportSize)
+	}
+	if v.DeviceScaleFactor < 0 || v.DeviceScaleFactor > MaxDeviceScaleFactor {
+		return fmt.Errorf("%w: device_scale_factor must be between 0 and %d", ErrInvalidOptions, MaxDeviceScaleFactor)
+	}
+	return nil
+}
+
+// devicePreset is a named device: its viewport and user agent
+type devicePreset struct {
+	Viewport  Viewport
+	UserAgent string
+}
+
+// devicePresets holds the devices selectable by PageOptions.Device
+var devicePresets = map[string]devicePreset{
+	"iphone-13": {
+		Viewport:  Viewport{Width: 390, Height: 844, DeviceScaleFactor: 3, Mobile: true},
+		UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 15_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.0 Mobile/15E148 Safari/604.1",
+	},
+	"pixel-7": {
+		Viewport:  Viewport{Width: 412, Height: 915, DeviceScaleFactor: 2.625, Mobile: true},
+		UserAgent: defaultMobileUserAgent,
+	},
+}
+
+// resolveEmulation returns the viewport and user agent to emulate. A device
+// preset supplies both; an explicit Viewport or UserAgent wins over it, and
+// a mobile viewport without a user agent gets a mobile one.
+func resolveEmulation(opts PageOptions) (*Viewport, string, error) {
+	viewport := opts.Viewport
+	userAgent := opts.UserAgent
+
+	if opts.Device != "" {
+		preset, ok := devicePresets[strings.ToLower(opts.Device)]
+		if !ok {
+			names := make([]string, 0, len(devicePresets))
+			for name := range devicePresets {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, "", fmt.Errorf("%w: unknown device %q (use %s)", ErrInvalidOptions, opts.Device, strings.Join(names, ", "))
+		}
+		if viewport == nil {
+			viewport = &preset.Viewport
+		}
+		if userAgent == "" {
+			userAgent = preset.UserAgent
+		}
+	}
+
+	if viewport == nil {
+		return nil, userAgent, nil
+	}
+	if err := viewport.Validate(); err != nil {
+		return nil, "", err
+	}
+	if viewport.Mobile && userAgent == "" {
+		userAgent = defaultMobileUserAgent
+	}
+	return viewport, userAgent, nil
+}
+
+// emulateViewport applies the viewport through rod, which remembers it so
+// full-page screenshots keep the scale factor and mobile mode
+func emulateViewport(page *rod.Page, viewport *Viewport) error {
+	scale := viewport.DeviceScaleFactor
+	if scale == 0 {
+		scale = 1
+	}
+	err := page.SetViewport(&proto.EmulationSetDeviceMetricsOverride{
+		Width:             viewport.Width,
+		Height:            viewport.Height,
+		DeviceScaleFactor: scale,
+		Mobile:            viewport.Mobile,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set viewport: %w", err)
+	}
+
+	if viewport.Mobile {
+		if err := (proto.EmulationSetTouchEmulationEnabled{Enabled: true}).Call(page); err != nil {
+			return fmt.Errorf("failed to enable touch emulation: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package browser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResolveEmulation(t *testing.T) {
+	viewport, userAgent, err := resolveEmulation(PageOptions{Device: "iPhone-13"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if viewport == nil || viewport.Width != 390 || !viewport.Mobile || userAgent != devicePresets["iphone-13"].UserAgent {
+		t.Errorf("Expected the iphone-13 preset, got %+v %q", viewport, userAgent)
+	}
+
+	custom := &Viewport{Width: 800, Height: 600}
+	viewport, userAgent, _ = resolveEmulation(PageOptions{Device: "pixel-7", Viewport: custom, UserAgent: "scrq-test"})
+	if viewport != custom || userAgent != "scrq-test" {
+		t.Errorf("Expected explicit viewport and user agent to win, got %+v %q", viewport, userAgent)
+	}
+
+	_, userAgent, _ = resolveEmulation(PageOptions{Viewport: &Viewport{Width: 360, Height: 640, Mobile: true}})
+	if userAgent != defaultMobileUserAgent {
+		t.Errorf("Expected a mobile user agent, got %q", userAgent)
+	}
+
+	viewport, userAgent, _ = resolveEmulation(PageOptions{})
+	if viewport != nil || userAgent != "" {
+		t.Errorf("Expected no emulation, got %+v %q", viewport, userAgent)
+	}
+
+	for _, opts := range []PageOptions{
+		{Device: "nokia-3310"},
+		{Viewport: &Viewport{Width: 0, Height: 600}},
+		{Viewport: &Viewport{Width: 800, Height: MaxViewportSize + 1}},
+		{Viewport: &Viewport{Width: 800, Height: 600, DeviceScaleFactor: 8}},
+	} {
+		if _, _, err := resolveEmulation(opts); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("Expected ErrInvalidOptions for %+v, got %v", opts, err)
+		}
+	}
+}
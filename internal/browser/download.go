@@ -0,0 +1,84 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// ErrUnsupported is returned when an engine cannot perform an operation
+var ErrUnsupported = errors.New("operation not supported by this engine")
+
+// maxDownloadBytes caps the size of a captured download
+const maxDownloadBytes = 50 << 20
+
+// DownloadResult is a file downloaded by the browser
+type DownloadResult struct {
+	Filename string `json:"filename"`
+	URL      string `json:"url"`
+	Size     int    `json:"size"`
+	Data     []byte `json:"data"`
+}
+
+// downloadFile clicks the element matching selector and captures the file
+// the browser downloads as a result. The file is written to a temporary
+// directory that is removed before returning.
+func downloadFile(opener pageOpener, ctx context.Context, url string, selector string, opts PageOptions) (*DownloadResult, error) {
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	page, cleanup, err := opener.OpenPage(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	dir, err := os.MkdirTemp("", "scrq-download-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create download directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	wait := page.Browser().Context(ctx).WaitDownload(dir)
+
+	element, err := page.Element(selector)
+	if err != nil {
+		return nil, withFailureScreenshot(page, opts, fmt.Errorf("failed to find element %s: %w", selector, err))
+	}
+	if err := element.Click(proto.InputMouseButtonLeft, 1); err != nil {
+		return nil, withFailureScreenshot(page, opts, fmt.Errorf("failed to click element %s: %w", selector, err))
+	}
+
+	info := wait()
+	if ctx.Err() != nil {
+		return nil, withFailureScreenshot(page, opts, fmt.Errorf("download did not complete: %w", ctx.Err()))
+	}
+	if info == nil {
+		return nil, withFailureScreenshot(page, opts, errors.New("no download was started"))
+	}
+
+	path := filepath.Join(dir, info.GUID)
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate downloaded file: %w", err)
+	}
+	if stat.Size() > maxDownloadBytes {
+		return nil, fmt.Errorf("downloaded file is too large (%d bytes, max %d)", stat.Size(), maxDownloadBytes)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read downloaded file: %w", err)
+	}
+
+	return &DownloadResult{
+		Filename: info.SuggestedFilename,
+		URL:      info.URL,
+		Size:     len(data),
+		Data:     data,
+	}, nil
+}
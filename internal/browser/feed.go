@@ -0,0 +1,197 @@
+package browser
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxFeedBytes caps the size of a fetched feed document
+const maxFeedBytes = 10 << 20
+
+// ErrNotAFeed is returned when a document is neither RSS nor Atom
+var ErrNotAFeed = errors.New("document is not an RSS or Atom feed")
+
+// FeedLink is a feed advertised by a page via <link rel="alternate">
+type FeedLink struct {
+	URL   string `json:"url"`
+	Type  string `json:"type"`
+	Title string `json:"title,omitempty"`
+}
+
+// FeedItem is a single entry of an RSS or Atom feed
+type FeedItem struct {
+	Title     string `json:"title"`
+	Link      string `json:"link"`
+	Published string `json:"published,omitempty"`
+	Summary   string `json:"summary,omitempty"`
+	ID        string `json:"id,omitempty"`
+}
+
+// Feed is a parsed RSS or Atom feed
+type Feed struct {
+	Format string     `json:"format"` // rss or atom
+	Title  string     `json:"title"`
+	Link   string     `json:"link,omitempty"`
+	Items  []FeedItem `json:"items"`
+}
+
+// feedLinksScript returns the page's feed links with absolute URLs
+const feedLinksScript = `() => Array.from(document.querySelectorAll('link[rel~="alternate"]'))
+	.filter(l => /^application\/(rss|atom)\+xml$/i.test((l.type || '').trim()))
+	.map(l => ({url: l.href, type: l.type.trim().toLowerCase(), title: l.title || ''}))`
+
+func discoverFeeds(opener pageOpener, ctx context.Context, url string, opts PageOptions) ([]FeedLink, error) {
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	page, cleanup, err := opener.OpenPage(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	res, err := page.Eval(feedLinksScript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover feeds: %w", err)
+	}
+
+	links := []FeedLink{}
+	if err := res.Value.Unmarshal(&links); err != nil {
+		return nil, fmt.Errorf("failed to read feed links: %w", err)
+	}
+	return links, nil
+}
+
+// FetchFeed downloads and parses the feed at feedURL
+func FetchFeed(ctx context.Context, feedURL string) (*Feed, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid feed URL: %w", err)
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch feed: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed: %w", err)
+	}
+	return ParseFeed(data)
+}
+
+type rssDocument struct {
+	Channel struct {
+		Title string `xml:"title"`
+		Link  string `xml:"link"`
+		Items []struct {
+			Title       string `xml:"title"`
+			Link        string `xml:"link"`
+			PubDate     string `xml:"pubDate"`
+			Description string `xml:"description"`
+			GUID        string `xml:"guid"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+type atomDocument struct {
+	Title   string     `xml:"title"`
+	Links   []atomLink `xml:"link"`
+	Entries []struct {
+		Title     string     `xml:"title"`
+		Links     []atomLink `xml:"link"`
+		Published string     `xml:"published"`
+		Updated   string     `xml:"updated"`
+		Summary   string     `xml:"summary"`
+		Content   string     `xml:"content"`
+		ID        string     `xml:"id"`
+	} `xml:"entry"`
+}
+
+// ParseFeed parses an RSS 2.0 or Atom document
+func ParseFeed(data []byte) (*Feed, error) {
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotAFeed, err)
+	}
+
+	switch strings.ToLower(root.XMLName.Local) {
+	case "rss":
+		var doc rssDocument
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid RSS feed: %w", err)
+		}
+
+		feed := &Feed{Format: "rss", Title: strings.TrimSpace(doc.Channel.Title), Link: strings.TrimSpace(doc.Channel.Link), Items: []FeedItem{}}
+		for _, item := range doc.Channel.Items {
+			feed.Items = append(feed.Items, FeedItem{
+				Title:     strings.TrimSpace(item.Title),
+				Link:      strings.TrimSpace(item.Link),
+				Published: strings.TrimSpace(item.PubDate),
+				Summary:   strings.TrimSpace(item.Description),
+				ID:        strings.TrimSpace(item.GUID),
+			})
+		}
+		return feed, nil
+
+	case "feed":
+		var doc atomDocument
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid Atom feed: %w", err)
+		}
+
+		feed := &Feed{Format: "atom", Title: strings.TrimSpace(doc.Title), Link: atomHref(doc.Links), Items: []FeedItem{}}
+		for _, entry := range doc.Entries {
+			published := entry.Published
+			if published == "" {
+				published = entry.Updated
+			}
+			summary := entry.Summary
+			if summary == "" {
+				summary = entry.Content
+			}
+			feed.Items = append(feed.Items, FeedItem{
+				Title:     strings.TrimSpace(entry.Title),
+				Link:      atomHref(entry.Links),
+				Published: strings.TrimSpace(published),
+				Summary:   strings.TrimSpace(summary),
+				ID:        strings.TrimSpace(entry.ID),
+			})
+		}
+		return feed, nil
+	}
+
+	return nil, ErrNotAFeed
+}
+
+// atomHref returns the alternate link, or the first link if none is marked
+func atomHref(links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	if len(links) > 0 {
+		return links[0].Href
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+package browser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseFeed(t *testing.T) {
+	rss := `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>News</title><link>https://example.com/</link>
+<item><title>First</title><link>https://example.com/1</link><pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate><guid>1</guid></item>
+</channel></rss>`
+
+	feed, err := ParseFeed([]byte(rss))
+	if err != nil {
+		t.Fatalf("Failed to parse RSS: %v", err)
+	}
+	if feed.Format != "rss" || feed.Title != "News" || len(feed.Items) != 1 || feed.Items[0].Link != "https://example.com/1" {
+		t.Errorf("Unexpected RSS feed: %+v", feed)
+	}
+
+	atom := `<?xml version="1.0"?>
+<feed xmlns="http://www.w3.org/2005/Atom"><title>Blog</title><link rel="self" href="https://example.com/feed"/><link href="https://example.com/"/>
+<entry><title>Post</title><link rel="alternate" href="https://example.com/post"/><updated>2006-01-02T15:04:05Z</updated><summary>Hi</summary></entry>
+</feed>`
+
+	feed, err = ParseFeed([]byte(atom))
+	if err != nil {
+		t.Fatalf("Failed to parse Atom: %v", err)
+	}
+	if feed.Format != "atom" || feed.Link != "https://example.com/" || len(feed.Items) != 1 {
+		t.Fatalf("Unexpected Atom feed: %+v", feed)
+	}
+	if item := feed.Items[0]; item.Link != "https://example.com/post" || item.Published != "2006-01-02T15:04:05Z" || item.Summary != "Hi" {
+		t.Errorf("Unexpected Atom entry: %+v", item)
+	}
+
+	if _, err := ParseFeed([]byte(`<html><body>nope</body></html>`)); !errors.Is(err, ErrNotAFeed) {
+		t.Errorf("Expected ErrNotAFeed, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// resultFields holds the JSON names of PageResult's fields
+var resultFields = func() map[string]bool {
+	fields := map[string]bool{}
+	t := reflect.TypeOf(PageResult{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}()
+
+// ValidateResultFields checks that every entry names a page result field
+func ValidateResultFields(fields []string) error {
+	for _, field := range fields {
+		if !resultFields[field] {
+			names := make([]string, 0, len(resultFields))
+			for name := range resultFields {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("unknown field %q (use %s)", field, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// Project returns only the named fields of the result, keyed by their JSON
+// names, so unwanted large fields (e.g. html) are dropped. With no fields the
+// result is returned unchanged.
+func (r *PageResult) Project(fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return r, nil
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]interface{}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+
+	projected := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			projected[field] = value
+		}
+	}
+	return projected, nil
+}
@@ -0,0 +1,112 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Bounds for progressive screenshot capture
+const (
+	DefaultFrameInterval = 500 * time.Millisecond
+	MinFrameInterval     = 100 * time.Millisecond
+	DefaultMaxFrames     = 10
+	MaxFrames            = 30
+
+	// frameQuality keeps individual JPEG frames small
+	frameQuality = 60
+)
+
+// ScreenshotFrame is one screenshot taken while a page loads
+type ScreenshotFrame struct {
+	OffsetMS int64  `json:"offset_ms"` // Time since navigation started
+	Loaded   bool   `json:"loaded"`    // Whether the load event had fired
+	Image    []byte `json:"image"`     // JPEG
+}
+
+// captureFrames navigates to url and takes a viewport screenshot every
+// interval until maxFrames are taken or the page timeout ends. Capture stops
+// one frame after the page has loaded, so fast pages return early.
+func captureFrames(opener pageOpener, ctx context.Context, url string, interval time.Duration, maxFrames int, opts PageOptions) ([]ScreenshotFrame, error) {
+	if interval < MinFrameInterval {
+		interval = MinFrameInterval
+	}
+	if maxFrames <= 0 {
+		maxFrames = DefaultMaxFrames
+	}
+	if maxFrames > MaxFrames {
+		maxFrames = MaxFrames
+	}
+
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	// Open a blank page with the request options applied, then navigate
+	// here so frames can be taken before the load completes
+	blank := opts
+	blank.WaitForLoad = false
+	blank.WaitForFunction = ""
+	blank.WaitForSelectors = nil
+	blank.WaitForSelector = ""
+	blank.AutoScroll = false
+	blank.DismissConsent = false
+	blank.OnPhase = nil
+
+	page, cleanup, err := opener.OpenPage(ctx, "about:blank", blank)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	start := time.Now()
+	loaded := make(chan error, 1)
+	go func() {
+		if err := page.Navigate(url); err != nil {
+			loaded <- err
+			return
+		}
+		loaded <- page.WaitLoad()
+	}()
+
+	quality := frameQuality
+	frames := make([]ScreenshotFrame, 0, maxFrames)
+	isLoaded := false
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for len(frames) < maxFrames {
+		image, err := page.Screenshot(false, &proto.PageCaptureScreenshot{
+			Format:  proto.PageCaptureScreenshotFormatJpeg,
+			Quality: &quality,
+		})
+		// Frames can fail mid-navigation; skip them rather than abort
+		if err == nil {
+			frames = append(frames, ScreenshotFrame{
+				OffsetMS: time.Since(start).Milliseconds(),
+				Loaded:   isLoaded,
+				Image:    image,
+			})
+		}
+		if isLoaded {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			if len(frames) == 0 {
+				return nil, fmt.Errorf("no frames captured: %w", ctx.Err())
+			}
+			return frames, nil
+		case err := <-loaded:
+			if err != nil {
+				return nil, fmt.Errorf("failed to load page: %w", err)
+			}
+			isLoaded = true
+		case <-ticker.C:
+		}
+	}
+
+	return frames, nil
+}
@@ -0,0 +1,165 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/input"
+)
+
+// namedKeys are the non-character keys PressKey accepts, by their
+// KeyboardEvent.key name
+var namedKeys = map[string]input.Key{
+	"Enter":      input.Enter,
+	"Escape":     input.Escape,
+	"Tab":        input.Tab,
+	"Backspace":  input.Backspace,
+	"Delete":     input.Delete,
+	"Space":      input.Space,
+	"ArrowUp":    input.ArrowUp,
+	"ArrowDown":  input.ArrowDown,
+	"ArrowLeft":  input.ArrowLeft,
+	"ArrowRight": input.ArrowRight,
+	"Home":       input.Home,
+	"End":        input.End,
+	"PageUp":     input.PageUp,
+	"PageDown":   input.PageDown,
+}
+
+// parseKey returns the key for a KeyboardEvent.key name such as "Enter" or
+// a single printable ASCII character such as "a"
+func parseKey(name string) (input.Key, error) {
+	if key, ok := namedKeys[name]; ok {
+		return key, nil
+	}
+	if len(name) == 1 && name[0] >= ' ' && name[0] <= '~' {
+		return input.Key(name[0]), nil
+	}
+
+	names := make([]string, 0, len(namedKeys))
+	for n := range namedKeys {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return 0, fmt.Errorf("%w: unknown key %q (use a single character or %s)", ErrInvalidOptions, name, strings.Join(names, ", "))
+}
+
+// ValidateKey checks that PressKey accepts the key name
+func ValidateKey(name string) error {
+	_, err := parseKey(name)
+	return err
+}
+
+// OptionQuery selects an option of a <select> element by its value
+// attribute or its visible label; exactly one must be set
+type OptionQuery struct {
+	Value string `json:"value,omitempty"`
+	Label string `json:"label,omitempty"`
+}
+
+// Validate checks that exactly one of Value and Label is set
+func (q OptionQuery) Validate() error {
+	if (q.Value == "") == (q.Label == "") {
+		return fmt.Errorf("%w: exactly one of value and label is required", ErrInvalidOptions)
+	}
+	return nil
+}
+
+// matcher returns the rod selector matching the option exactly
+func (q OptionQuery) matcher() (string, rod.SelectorType) {
+	if q.Value != "" {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(q.Value)
+		return `option[value="` + value + `"]`, rod.SelectorTypeCSSSector
+	}
+	return `^\s*` + regexp.QuoteMeta(q.Label) + `\s*$`, rod.SelectorTypeRegex
+}
+
+func hoverElement(opener pageOpener, ctx context.Context, url string, selector string, opts PageOptions) error {
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	page, cleanup, err := opener.OpenPage(ctx, url, opts)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	element, err := queryElement(page, selector, opts.PierceShadow)
+	if err != nil {
+		return withFailureScreenshot(page, opts, fmt.Errorf("element not found: %s", selector))
+	}
+
+	if err := element.Hover(); err != nil {
+		return withFailureScreenshot(page, opts, fmt.Errorf("failed to hover element: %w", err))
+	}
+
+	return nil
+}
+
+func selectOption(opener pageOpener, ctx context.Context, url string, selector string, option OptionQuery, opts PageOptions) error {
+	if err := option.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	page, cleanup, err := opener.OpenPage(ctx, url, opts)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	element, err := queryElement(page, selector, opts.PierceShadow)
+	if err != nil {
+		return withFailureScreenshot(page, opts, fmt.Errorf("element not found: %s", selector))
+	}
+
+	match, selectorType := option.matcher()
+	if err := element.Select([]string{match}, true, selectorType); err != nil {
+		if _, ok := err.(*rod.ElementNotFoundError); ok {
+			return withFailureScreenshot(page, opts, fmt.Errorf("%w: no option with value %q or label %q in %s", ErrInvalidOptions, option.Value, option.Label, selector))
+		}
+		return withFailureScreenshot(page, opts, fmt.Errorf("failed to select option: %w", err))
+	}
+
+	return nil
+}
+
+func pressKey(opener pageOpener, ctx context.Context, url string, selector string, key string, opts PageOptions) error {
+	k, err := parseKey(key)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	page, cleanup, err := opener.OpenPage(ctx, url, opts)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// Without a selector the key goes to whatever has focus
+	actions := page.KeyActions()
+	if selector != "" {
+		element, err := queryElement(page, selector, opts.PierceShadow)
+		if err != nil {
+			return withFailureScreenshot(page, opts, fmt.Errorf("element not found: %s", selector))
+		}
+		if actions, err = element.KeyActions(); err != nil {
+			return withFailureScreenshot(page, opts, fmt.Errorf("failed to focus element: %w", err))
+		}
+	}
+
+	if err := actions.Type(k).Do(); err != nil {
+		return withFailureScreenshot(page, opts, fmt.Errorf("failed to press %s: %w", key, err))
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package browser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/input"
+)
+
+func TestParseKey(t *testing.T) {
+	for name, want := range map[string]input.Key{
+		"Enter":     input.Enter,
+		"Escape":    input.Escape,
+		"ArrowDown": input.ArrowDown,
+		"a":         input.KeyA,
+		"/":         input.Slash,
+	} {
+		got, err := parseKey(name)
+		if err != nil || got != want {
+			t.Errorf("%q: got %v, %v", name, got, err)
+		}
+	}
+
+	for _, name := range []string{"", "enter", "Hyper", "ab", "é"} {
+		if _, err := parseKey(name); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("%q: expected ErrInvalidOptions, got %v", name, err)
+		}
+	}
+}
+
+func TestOptionQuery(t *testing.T) {
+	if err := (OptionQuery{}).Validate(); err == nil {
+		t.Error("Expected an empty query to be rejected")
+	}
+	if err := (OptionQuery{Value: "m", Label: "Medium"}).Validate(); err == nil {
+		t.Error("Expected a query with both value and label to be rejected")
+	}
+
+	match, selectorType := OptionQuery{Value: `a"b\c`}.matcher()
+	if match != `option[value="a\"b\\c"]` || selectorType != rod.SelectorTypeCSSSector {
+		t.Errorf("Unexpected value matcher %q (%s)", match, selectorType)
+	}
+	match, selectorType = OptionQuery{Label: "Size (M)"}.matcher()
+	if match != `^\s*Size \(M\)\s*$` || selectorType != rod.SelectorTypeRegex {
+		t.Errorf("Unexpected label matcher %q (%s)", match, selectorType)
+	}
+}
@@ -0,0 +1,175 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrJSONPathNoMatch is returned when a JSONPath expression selects nothing
+var ErrJSONPathNoMatch = errors.New("json path matched nothing")
+
+// JSONPath is a parsed JSONPath expression. It supports the common subset
+// used to pick data out of embedded JSON: the root "$", dot and bracket
+// member access ($.props.items, $['page-data']), array indexes including
+// negative ones ($.items[0], $.items[-1]) and the wildcard ($.items[*].id).
+type JSONPath struct {
+	expr  string
+	steps []jsonPathStep
+}
+
+type jsonPathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// ParseJSONPath parses a JSONPath expression
+func ParseJSONPath(expr string) (*JSONPath, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("invalid json path %q: must start with $", expr)
+	}
+
+	path := &JSONPath{expr: expr}
+	rest := expr[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			if strings.HasPrefix(rest, "*") {
+				path.steps = append(path.steps, jsonPathStep{wildcard: true})
+				rest = rest[1:]
+				continue
+			}
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid json path %q: empty member name", expr)
+			}
+			path.steps = append(path.steps, jsonPathStep{key: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid json path %q: unclosed bracket", expr)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+
+			switch {
+			case inner == "*":
+				path.steps = append(path.steps, jsonPathStep{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				path.steps = append(path.steps, jsonPathStep{key: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid json path %q: bad index %q", expr, inner)
+				}
+				path.steps = append(path.steps, jsonPathStep{index: index, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("invalid json path %q: unexpected %q", expr, rest[0])
+		}
+	}
+
+	return path, nil
+}
+
+// Apply evaluates the path against decoded JSON. Paths containing a wildcard
+// return a slice of all matches; other paths return the single match.
+func (p *JSONPath) Apply(data interface{}) (interface{}, error) {
+	values := []interface{}{data}
+	multi := false
+
+	for _, step := range p.steps {
+		if step.wildcard {
+			multi = true
+		}
+
+		next := make([]interface{}, 0, len(values))
+		for _, value := range values {
+			switch v := value.(type) {
+			case map[string]interface{}:
+				if step.wildcard {
+					keys := make([]string, 0, len(v))
+					for key := range v {
+						keys = append(keys, key)
+					}
+					sort.Strings(keys)
+					for _, key := range keys {
+						next = append(next, v[key])
+					}
+				} else if child, ok := v[step.key]; ok && !step.isIndex {
+					next = append(next, child)
+				}
+			case []interface{}:
+				if step.wildcard {
+					next = append(next, v...)
+				} else if step.isIndex {
+					index := step.index
+					if index < 0 {
+						index += len(v)
+					}
+					if index >= 0 && index < len(v) {
+						next = append(next, v[index])
+					}
+				}
+			}
+		}
+		values = next
+	}
+
+	if multi {
+		return values, nil
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrJSONPathNoMatch, p.expr)
+	}
+	return values[0], nil
+}
+
+func extractJSONScript(opener pageOpener, ctx context.Context, url string, selector string, jsonPath string, opts PageOptions) (interface{}, error) {
+	if jsonPath == "" {
+		jsonPath = "$"
+	}
+	path, err := ParseJSONPath(jsonPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	page, cleanup, err := opener.OpenPage(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	element, err := page.Element(selector)
+	if err != nil {
+		return nil, withFailureScreenshot(page, opts, fmt.Errorf("failed to find %s: %w", selector, err))
+	}
+
+	// innerText is empty for <script> elements, so read textContent
+	content, err := element.Eval(`() => this.textContent`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", selector, err)
+	}
+
+	var data interface{}
+	if err := json.Unmarshal([]byte(content.Value.Str()), &data); err != nil {
+		return nil, fmt.Errorf("%s does not contain valid JSON: %w", selector, err)
+	}
+
+	return path.Apply(data)
+}
@@ -0,0 +1,52 @@
+package browser
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	var data interface{}
+	doc := `{"props": {"pageProps": {"items": [{"id": 1, "name": "a"}, {"id": 2, "name": "b"}]}}, "build-id": "x1"}`
+	if err := json.Unmarshal([]byte(doc), &data); err != nil {
+		t.Fatalf("Failed to parse fixture: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{"$", data},
+		{"$.props.pageProps.items[0].name", "a"},
+		{"$.props.pageProps.items[-1].id", float64(2)},
+		{"$['build-id']", "x1"},
+		{"$.props.pageProps.items[*].id", []interface{}{float64(1), float64(2)}},
+	}
+
+	for _, tt := range tests {
+		path, err := ParseJSONPath(tt.path)
+		if err != nil {
+			t.Fatalf("ParseJSONPath(%q) failed: %v", tt.path, err)
+		}
+		got, err := path.Apply(data)
+		if err != nil {
+			t.Fatalf("Apply(%q) failed: %v", tt.path, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Apply(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	path, _ := ParseJSONPath("$.props.missing")
+	if _, err := path.Apply(data); !errors.Is(err, ErrJSONPathNoMatch) {
+		t.Errorf("Expected ErrJSONPathNoMatch, got %v", err)
+	}
+
+	for _, invalid := range []string{"props", "$.", "$[0", "$[abc]"} {
+		if _, err := ParseJSONPath(invalid); err == nil {
+			t.Errorf("Expected ParseJSONPath(%q) to fail", invalid)
+		}
+	}
+}
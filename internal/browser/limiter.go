@@ -0,0 +1,86 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+// ErrTooManyPages is returned when no page slot frees up within the
+// limiter's wait timeout.
+var ErrTooManyPages = errors.New("too many concurrent pages")
+
+// PageLimiter caps the number of pages open at once. A single limiter can be
+// shared by several managers to bound pages across engines.
+type PageLimiter struct {
+	slots   chan struct{}
+	maxWait time.Duration
+}
+
+// NewPageLimiter creates a limiter allowing max concurrent pages. Callers
+// beyond the limit wait up to maxWait for a free slot. It returns nil (no
+// limit) if max is not positive.
+func NewPageLimiter(max int, maxWait time.Duration) *PageLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &PageLimiter{
+		slots:   make(chan struct{}, max),
+		maxWait: maxWait,
+	}
+}
+
+// Acquire waits for a free page slot and returns a func that releases it. A
+// nil limiter never blocks.
+func (l *PageLimiter) Acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return noopCleanup, nil
+	}
+
+	var timeout <-chan time.Time
+	if l.maxWait > 0 {
+		timer := time.NewTimer(l.maxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-timeout:
+		return noopCleanup, fmt.Errorf("%w: no slot free after %s", ErrTooManyPages, l.maxWait)
+	case <-ctx.Done():
+		return noopCleanup, ctx.Err()
+	}
+}
+
+// InUse returns the number of pages currently holding a slot
+func (l *PageLimiter) InUse() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.slots)
+}
+
+// open acquires a slot, runs open and releases the slot when the returned
+// cleanup is called or open fails
+func (l *PageLimiter) open(ctx context.Context, open func() (*rod.Page, func(), error)) (*rod.Page, func(), error) {
+	release, err := l.Acquire(ctx)
+	if err != nil {
+		return nil, noopCleanup, err
+	}
+
+	page, cleanup, err := open()
+	if err != nil {
+		release()
+		return nil, noopCleanup, err
+	}
+
+	return page, func() {
+		cleanup()
+		release()
+	}, nil
+}
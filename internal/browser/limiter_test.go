@@ -0,0 +1,78 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPageLimiter(t *testing.T) {
+	limiter := NewPageLimiter(1, 10*time.Millisecond)
+
+	release, err := limiter.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Expected first acquire to succeed: %v", err)
+	}
+
+	if _, err := limiter.Acquire(context.Background()); !errors.Is(err, ErrTooManyPages) {
+		t.Errorf("Expected ErrTooManyPages, got %v", err)
+	}
+
+	release()
+	if limiter.InUse() != 0 {
+		t.Errorf("Expected slot to be released, %d in use", limiter.InUse())
+	}
+
+	release, err = limiter.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Expected acquire after release to succeed: %v", err)
+	}
+	release()
+}
+
+func TestNilPageLimiter(t *testing.T) {
+	limiter := NewPageLimiter(0, time.Second)
+	if limiter != nil {
+		t.Fatalf("Expected nil limiter for max 0")
+	}
+
+	release, err := limiter.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Expected nil limiter to never block: %v", err)
+	}
+	release()
+}
+
+func TestAdaptiveLimiter(t *testing.T) {
+	limiter := NewAdaptiveLimiter(4)
+
+	limiter.Acquire()
+	limiter.Release(context.DeadlineExceeded)
+	if limiter.Limit() != 2 {
+		t.Fatalf("Expected limit to halve to 2, got %d", limiter.Limit())
+	}
+
+	limiter.Acquire()
+	limiter.Release(fmt.Errorf("open page: %w", ErrTooManyPages))
+	limiter.Backoff()
+	if limiter.Limit() != 1 || limiter.Lowest() != 1 {
+		t.Fatalf("Expected limit to floor at 1, got %d (lowest %d)", limiter.Limit(), limiter.Lowest())
+	}
+
+	// A non-overload failure leaves the limit alone
+	limiter.Acquire()
+	limiter.Release(errors.New("selector not found"))
+	if limiter.Limit() != 1 {
+		t.Fatalf("Expected limit to stay at 1, got %d", limiter.Limit())
+	}
+
+	for i := 0; i < 1+2+3; i++ {
+		limiter.Acquire()
+		limiter.Release(nil)
+	}
+	if limiter.Limit() != 4 {
+		t.Errorf("Expected limit to ramp back to 4, got %d", limiter.Limit())
+	}
+}
@@ -29,6 +29,10 @@ type Manager struct {
 	restartMu  sync.Mutex
 	isRunning  bool
 	binaryPath string
+
+	pageLimiter *PageLimiter
+	usage       usageCache
+	watchdog    browserWatchdog
 }
 
 // NewManager creates a new browser manager
@@ -181,6 +185,29 @@ func (m *Manager) IsRunning() bool {
 	return m.isRunning
 }
 
+// Usage returns the memory and CPU used by the Lightpanda process and its
+// open page count, sampled at most every 10 seconds
+func (m *Manager) Usage() (*ResourceUsage, error) {
+	m.mu.Lock()
+	pid := 0
+	if m.isRunning && m.cmd != nil && m.cmd.Process != nil {
+		pid = m.cmd.Process.Pid
+	}
+	browser := m.browser
+	m.mu.Unlock()
+
+	usage, err := m.usage.get(pid, browser)
+	if err != nil {
+		return nil, err
+	}
+
+	sample := *usage
+	sample.MemoryRestarts = m.watchdog.restarts.Load()
+	sample.PagesServed = m.watchdog.served.Load()
+	sample.PageRecycles = m.watchdog.recycles.Load()
+	return &sample, nil
+}
+
 // GetEndpoint returns the WebSocket endpoint URL
 func (m *Manager) GetEndpoint() string {
 	return fmt.Sprintf("ws://%s:%d", m.host, m.port)
@@ -214,33 +241,56 @@ func (m *Manager) NewPage(ctx context.Context) (*rod.Page, error) {
 
 // OpenPage creates a page, applies options, and navigates to the URL.
 func (m *Manager) OpenPage(ctx context.Context, url string, opts PageOptions) (*rod.Page, func(), error) {
+	return m.watchdog.track(m.pageLimiter.open(ctx, func() (*rod.Page, func(), error) {
+		return m.openPage(ctx, url, opts)
+	}))
+}
+
+// StartMemoryWatchdog restarts the browser, once no page is open, whenever
+// its resident memory exceeds limitBytes. Usage is checked every interval.
+// The returned func stops the watchdog
+func (m *Manager) StartMemoryWatchdog(limitBytes uint64, interval time.Duration) func() {
+	return m.watchdog.start("Lightpanda", limitBytes, interval, m.Usage, m.restart)
+}
+
+// StartPageRecycler restarts the browser, once no page is open, after it
+// has served maxPages pages, bounding slow leaks. The returned func stops
+// the recycler
+func (m *Manager) StartPageRecycler(maxPages int) func() {
+	return m.watchdog.startRecycle("Lightpanda", int64(maxPages), m.restart)
+}
+
+// SetPageLimiter bounds the number of pages this manager opens at once
+func (m *Manager) SetPageLimiter(limiter *PageLimiter) {
+	m.pageLimiter = limiter
+}
+
+func (m *Manager) openPage(ctx context.Context, url string, opts PageOptions) (*rod.Page, func(), error) {
+	if opts.Proxy != "" && HasProxyCredentials(opts.Proxy) {
+		return nil, noopCleanup, fmt.Errorf("%w: proxy authentication is not supported by lightpanda, use a chrome endpoint", ErrUnsupported)
+	}
 	if opts.Proxy != "" {
 		return nil, noopCleanup, fmt.Errorf("proxy is only supported on chrome endpoints")
 	}
+	if opts.SessionID != "" {
+		return nil, noopCleanup, fmt.Errorf("%w: sessions are only supported on chrome endpoints", ErrUnsupported)
+	}
 
 	page, err := m.NewPage(ctx)
 	if err != nil {
 		return nil, noopCleanup, err
 	}
 
-	if err := applyPageOptions(page, url, opts); err != nil {
+	unblock, err := setupPage(page, url, opts)
+	if err != nil {
 		page.Close()
 		return nil, noopCleanup, err
 	}
 
-	if err := page.Navigate(url); err != nil {
+	return page, func() {
+		unblock()
 		page.Close()
-		return nil, noopCleanup, fmt.Errorf("failed to navigate to %s: %w", url, err)
-	}
-
-	if opts.WaitForLoad {
-		if err := page.WaitLoad(); err != nil {
-			page.Close()
-			return nil, noopCleanup, fmt.Errorf("failed to wait for page load: %w", err)
-		}
-	}
-
-	return page, noopCleanup, nil
+	}, nil
 }
 
 func (m *Manager) ensureStarted() error {
@@ -289,6 +339,8 @@ func isConnectionError(err error) bool {
 
 // Navigate navigates to a URL and returns the page
 func (m *Manager) Navigate(ctx context.Context, url string) (*rod.Page, error) {
-	page, _, err := m.OpenPage(ctx, url, DefaultPageOptions())
+	// The caller owns the page, so it is not counted against the limiter
+	// and the cleanup, which would close it, is dropped
+	page, _, err := m.openPage(ctx, url, DefaultPageOptions())
 	return page, err
 }
@@ -0,0 +1,83 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+)
+
+func getMarkdown(opener pageOpener, ctx context.Context, targetURL string, selector string, opts PageOptions) (*PageResult, error) {
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	page, cleanup, err := opener.OpenPage(ctx, targetURL, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	info := page.MustInfo()
+	result := &PageResult{
+		URL:          info.URL,
+		RequestedURL: targetURL,
+		Title:        info.Title,
+	}
+
+	var html string
+	if selector != "" {
+		element, err := queryElement(page, selector, opts.PierceShadow)
+		if err != nil {
+			return nil, fmt.Errorf("element not found: %s", selector)
+		}
+		html, err = elementHTML(element, opts.PierceShadow)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get element html: %w", err)
+		}
+	} else {
+		html, err = pageHTML(page, opts.PierceShadow)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get page html: %w", err)
+		}
+	}
+
+	markdown, err := htmlToMarkdown(html, info.URL)
+	if err != nil {
+		return nil, err
+	}
+	result.Markdown = markdown
+
+	return result, nil
+}
+
+// htmlToMarkdown converts HTML to Markdown, resolving relative links and
+// images against the page URL.
+func htmlToMarkdown(html string, pageURL string) (string, error) {
+	converter := md.NewConverter(pageURL, true, &md.Options{
+		GetAbsoluteURL: resolveURL,
+	})
+	converter.Remove("script", "style", "noscript")
+
+	markdown, err := converter.ConvertString(html)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert html to markdown: %w", err)
+	}
+
+	return markdown, nil
+}
+
+func resolveURL(_ *goquery.Selection, rawURL string, base string) string {
+	baseURL, err := url.Parse(base)
+	if err != nil || baseURL.Host == "" {
+		return rawURL
+	}
+
+	ref, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	return baseURL.ResolveReference(ref).String()
+}
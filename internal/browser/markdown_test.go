@@ -0,0 +1,25 @@
+package browser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	html := `<article><h1>Title</h1><p>Hello <a href="/about">world</a></p><script>alert(1)</script></article>`
+
+	markdown, err := htmlToMarkdown(html, "https://example.com/post")
+	if err != nil {
+		t.Fatalf("Failed to convert: %v", err)
+	}
+
+	if !strings.Contains(markdown, "# Title") {
+		t.Errorf("Expected heading in markdown, got %q", markdown)
+	}
+	if !strings.Contains(markdown, "[world](https://example.com/about)") {
+		t.Errorf("Expected absolute link in markdown, got %q", markdown)
+	}
+	if strings.Contains(markdown, "alert") {
+		t.Errorf("Expected script to be removed, got %q", markdown)
+	}
+}
@@ -0,0 +1,103 @@
+package browser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// PageMetadata is the structured metadata a page declares for search engines
+// and link previews
+type PageMetadata struct {
+	URL       string            `json:"url"`
+	Title     string            `json:"title"`
+	Canonical string            `json:"canonical,omitempty"`
+	JSONLD    []json.RawMessage `json:"json_ld"`    // One entry per valid <script type="application/ld+json">
+	OpenGraph map[string]string `json:"open_graph"` // og:* properties, e.g. "og:title"
+	Meta      map[string]string `json:"meta"`       // <meta name> tags, e.g. "description"
+}
+
+// metadataScript collects the raw metadata; JSON-LD is parsed in Go so
+// malformed blocks can be skipped
+const metadataScript = `() => {
+	const canonical = document.querySelector('link[rel~="canonical"]');
+	const metas = Array.from(document.querySelectorAll('meta[content]'));
+	return {
+		url: location.href,
+		title: document.title || '',
+		canonical: canonical ? canonical.href : '',
+		json_ld: Array.from(document.querySelectorAll('script[type="application/ld+json" i]')).map(s => s.textContent || ''),
+		properties: metas.filter(m => m.getAttribute('property')).map(m => [m.getAttribute('property').trim(), m.content]),
+		names: metas.filter(m => m.name).map(m => [m.name.trim().toLowerCase(), m.content]),
+	};
+}`
+
+// rawMetadata is what metadataScript returns
+type rawMetadata struct {
+	URL        string      `json:"url"`
+	Title      string      `json:"title"`
+	Canonical  string      `json:"canonical"`
+	JSONLD     []string    `json:"json_ld"`
+	Properties [][2]string `json:"properties"`
+	Names      [][2]string `json:"names"`
+}
+
+func extractMetadata(opener pageOpener, ctx context.Context, url string, opts PageOptions) (*PageMetadata, error) {
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	page, cleanup, err := opener.OpenPage(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	res, err := page.Eval(metadataScript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract metadata: %w", err)
+	}
+
+	var raw rawMetadata
+	if err := res.Value.Unmarshal(&raw); err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+	return raw.parse(), nil
+}
+
+// parse keeps the valid JSON-LD blocks and the og:* properties and named
+// meta tags. The first tag wins when a name repeats.
+func (r rawMetadata) parse() *PageMetadata {
+	meta := &PageMetadata{
+		URL:       r.URL,
+		Title:     strings.TrimSpace(r.Title),
+		Canonical: r.Canonical,
+		JSONLD:    []json.RawMessage{},
+		OpenGraph: map[string]string{},
+		Meta:      map[string]string{},
+	}
+
+	for _, block := range r.JSONLD {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, []byte(strings.TrimSpace(block))); err != nil || compact.Len() == 0 {
+			continue // Malformed blocks are common and skipped
+		}
+		meta.JSONLD = append(meta.JSONLD, json.RawMessage(compact.Bytes()))
+	}
+
+	for _, tag := range r.Properties {
+		if property := strings.ToLower(tag[0]); strings.HasPrefix(property, "og:") {
+			if _, ok := meta.OpenGraph[property]; !ok {
+				meta.OpenGraph[property] = tag[1]
+			}
+		}
+	}
+	for _, tag := range r.Names {
+		if _, ok := meta.Meta[tag[0]]; !ok && tag[0] != "" {
+			meta.Meta[tag[0]] = tag[1]
+		}
+	}
+
+	return meta
+}
@@ -0,0 +1,38 @@
+package browser
+
+import (
+	"testing"
+)
+
+func TestParseMetadata(t *testing.T) {
+	raw := rawMetadata{
+		URL:       "https://example.com/widget",
+		Title:     "  Widget  ",
+		Canonical: "https://example.com/widget",
+		JSONLD: []string{
+			"\n{\"@context\": \"https://schema.org\",\n \"@type\": \"Product\", \"name\": \"Widget\"}\n",
+			`{"@type": "BreadcrumbList",`,
+			"   ",
+			`[{"@type": "Organization"}]`,
+		},
+		Properties: [][2]string{{"og:title", "Widget"}, {"OG:Image", "a.png"}, {"og:image", "b.png"}, {"article:author", "Ann"}},
+		Names:      [][2]string{{"description", "A widget"}, {"twitter:card", "summary"}, {"", "ignored"}},
+	}
+
+	meta := raw.parse()
+	if meta.Title != "Widget" || meta.Canonical != raw.Canonical {
+		t.Errorf("Unexpected title or canonical: %q %q", meta.Title, meta.Canonical)
+	}
+	if len(meta.JSONLD) != 2 {
+		t.Fatalf("Expected the two valid JSON-LD blocks, got %d", len(meta.JSONLD))
+	}
+	if string(meta.JSONLD[0]) != `{"@context":"https://schema.org","@type":"Product","name":"Widget"}` || string(meta.JSONLD[1]) != `[{"@type":"Organization"}]` {
+		t.Errorf("Unexpected JSON-LD: %s %s", meta.JSONLD[0], meta.JSONLD[1])
+	}
+	if len(meta.OpenGraph) != 2 || meta.OpenGraph["og:title"] != "Widget" || meta.OpenGraph["og:image"] != "a.png" {
+		t.Errorf("Unexpected OpenGraph: %v", meta.OpenGraph)
+	}
+	if len(meta.Meta) != 2 || meta.Meta["description"] != "A widget" || meta.Meta["twitter:card"] != "summary" {
+		t.Errorf("Unexpected meta: %v", meta.Meta)
+	}
+}
@@ -2,8 +2,13 @@ package browser
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/url"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-rod/rod"
@@ -19,6 +24,144 @@ type PageOptions struct {
 	Headers     map[string]string `json:"headers,omitempty"`
 	Cookies     []CookieParam `json:"cookies,omitempty"`
 	Proxy       string        `json:"proxy,omitempty"`
+
+	// ScreenshotOnFailure captures the page state when an operation fails.
+	// The image is attached to the returned *PageError.
+	ScreenshotOnFailure bool `json:"screenshot_on_failure,omitempty"`
+
+	// AcceptLanguage sets both the Accept-Language header and
+	// navigator.languages, e.g. "de-DE,de;q=0.9".
+	AcceptLanguage string `json:"accept_language,omitempty"`
+
+	// WaitForFunction is a JS function polled after load until it returns a
+	// truthy value, every WaitPollInterval (default 100ms).
+	WaitForFunction  string        `json:"wait_for_function,omitempty"`
+	WaitPollInterval time.Duration `json:"wait_poll_interval,omitempty"`
+
+	// DismissConsent clicks common cookie consent accept buttons after load
+	// (best effort).
+	DismissConsent bool `json:"dismiss_consent,omitempty"`
+
+	// WaitForSelectors waits after load until any or all (WaitMode) of the
+	// selectors match an element.
+	WaitForSelectors []string `json:"wait_for_selectors,omitempty"`
+	WaitMode         WaitMode `json:"wait_mode,omitempty"`
+
+	// WaitForSelector waits after load until an element matches, for at
+	// most WaitForSelectorTimeout (the page timeout if zero).
+	WaitForSelector        string        `json:"wait_for_selector,omitempty"`
+	WaitForSelectorTimeout time.Duration `json:"wait_for_selector_timeout,omitempty"`
+
+	// AutoScroll scrolls to the bottom after load, up to ScrollSteps times
+	// (default 10) with ScrollDelay (default 500ms) between scrolls, until
+	// the page stops growing. For lazy-loaded and infinite feeds.
+	AutoScroll  bool          `json:"auto_scroll,omitempty"`
+	ScrollSteps int           `json:"scroll_steps,omitempty"`
+	ScrollDelay time.Duration `json:"scroll_delay,omitempty"`
+
+	// OnPhase, if set, is called as the page reaches each loading phase.
+	OnPhase func(PagePhase) `json:"-"`
+
+	// OnProgress, if set, is called when an evaluated script reports
+	// progress via window.__scrqProgress(current, total, message).
+	OnProgress func(current, total int, message string) `json:"-"`
+
+	// CaptureInitialHTML records the document as sent by the server, before
+	// scripts ran, in PageResult.InitialHTML (FetchPage only).
+	CaptureInitialHTML bool `json:"capture_initial_html,omitempty"`
+
+	// PierceShadow makes selectors, HTML and text extraction descend into
+	// open shadow roots.
+	PierceShadow bool `json:"pierce_shadow,omitempty"`
+
+	// IncludeCookies returns the cookies set for the page's URL after
+	// navigation in PageResult.Cookies (FetchPage only).
+	IncludeCookies bool `json:"include_cookies,omitempty"`
+
+	// Viewport emulates a screen size and device type; Device selects a
+	// preset (iphone-13, pixel-7) with a matching user agent instead.
+	Viewport *Viewport `json:"viewport,omitempty"`
+	Device   string    `json:"device,omitempty"`
+
+	// BlockResources aborts requests for these resource types (image, font,
+	// stylesheet, media) to speed up text scraping.
+	BlockResources []string `json:"block_resources,omitempty"`
+
+	// CaptureConsole records console calls and uncaught exceptions from
+	// before navigation in PageResult.Console (FetchPage only). On failure
+	// they are attached to the returned *PageError.
+	CaptureConsole bool `json:"capture_console,omitempty"`
+
+	// BasicAuth answers HTTP basic auth challenges from the site. Proxy
+	// credentials go in the Proxy URL (user:pass@host:port) instead.
+	BasicAuth *Credentials `json:"basic_auth,omitempty"`
+
+	// SessionID opens the page in a session created with CreateSession, so
+	// cookies and storage carry over between requests (Chrome only).
+	SessionID string `json:"session_id,omitempty"`
+
+	// ScriptTimeout bounds how long an evaluated script may run, separately
+	// from Timeout. A script over budget is terminated and its page closed
+	// (EvaluateScript only).
+	ScriptTimeout time.Duration `json:"script_timeout,omitempty"`
+
+	// proxyAuth answers the proxy's auth challenges; set from the Proxy URL
+	// when Chrome is launched with it
+	proxyAuth *Credentials
+
+	// initialHTML receives the captured document during preparePage
+	initialHTML *string
+
+	// console receives the page's console messages from preparePage on
+	console *consoleLog
+
+	// response receives the main document response during preparePage
+	response *documentResponse
+
+	// blocked counts the requests aborted by BlockResources
+	blocked *atomic.Int64
+}
+
+// PagePhase identifies a page loading milestone reported via OnPhase
+type PagePhase string
+
+const (
+	// PhaseNavigated is reported once navigation to the URL has completed
+	PhaseNavigated PagePhase = "navigated"
+	// PhaseLoaded is reported once the page load event has fired
+	PhaseLoaded PagePhase = "loaded"
+)
+
+// WaitMode selects how WaitForSelectors is satisfied
+type WaitMode string
+
+const (
+	// WaitAll waits until every selector matches (default)
+	WaitAll WaitMode = "all"
+	// WaitAny waits until at least one selector matches
+	WaitAny WaitMode = "any"
+)
+
+// ErrInvalidOptions is returned when page options are malformed
+var ErrInvalidOptions = errors.New("invalid page options")
+
+// ErrScriptTimeout is returned when a script runs longer than
+// PageOptions.ScriptTimeout
+var ErrScriptTimeout = errors.New("script execution exceeded budget")
+
+// ErrElementNotFound is returned when a selector matches nothing on the page
+var ErrElementNotFound = errors.New("element not found")
+
+// ValidWaitMode reports whether mode is a known wait mode or empty
+func ValidWaitMode(mode WaitMode) bool {
+	return mode == "" || mode == WaitAll || mode == WaitAny
+}
+
+// reportPhase notifies the OnPhase callback, if any
+func (o PageOptions) reportPhase(phase PagePhase) {
+	if o.OnPhase != nil {
+		o.OnPhase(phase)
+	}
 }
 
 // DefaultPageOptions returns default page options
@@ -32,14 +175,118 @@ func DefaultPageOptions() PageOptions {
 
 // PageResult represents the result of a page operation
 type PageResult struct {
-	URL        string            `json:"url"`
+	URL        string            `json:"url"` // Where the page is when the result is taken, after redirects and client-side navigation
 	Title      string            `json:"title"`
 	HTML       string            `json:"html,omitempty"`
 	Text       string            `json:"text,omitempty"`
+	Markdown   string            `json:"markdown,omitempty"`
 	Links      []string          `json:"links,omitempty"`
 	Screenshot []byte            `json:"screenshot,omitempty"`
 	Cookies    []CookieInfo      `json:"cookies,omitempty"`
 	Headers    map[string]string `json:"headers,omitempty"`
+
+	// MatchedSelectors lists the WaitForSelectors entries present on the page
+	MatchedSelectors []string `json:"matched_selectors,omitempty"`
+
+	// InitialHTML is the server response before JavaScript ran, when
+	// CaptureInitialHTML is set; HTML is the rendered DOM
+	InitialHTML string `json:"initial_html,omitempty"`
+
+	// StatusCode is the HTTP status of the main document response, and
+	// Headers its headers. FinalURL is its URL after HTTP redirects; it
+	// differs from URL when scripts or a meta refresh navigated further or
+	// changed the address, e.g. with history.pushState.
+	StatusCode int    `json:"status_code,omitempty"`
+	FinalURL   string `json:"final_url,omitempty"`
+
+	// RequestedURL is the URL asked for, which differs from FinalURL when
+	// the server redirected
+	RequestedURL string `json:"requested_url,omitempty"`
+
+	// BlockedRequests counts the requests aborted by BlockResources
+	BlockedRequests int64 `json:"blocked_requests,omitempty"`
+
+	// Console lists console messages and page errors (CaptureConsole)
+	Console []ConsoleMessage `json:"console,omitempty"`
+}
+
+// maxFailureScreenshotBytes bounds the size of screenshots attached to errors
+const maxFailureScreenshotBytes = 1 << 20
+
+// PageError wraps a page operation failure with a screenshot of the page
+// state at the time of failure and the console messages logged until then.
+type PageError struct {
+	Err        error
+	Screenshot []byte
+	Console    []ConsoleMessage
+}
+
+func (e *PageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PageError) Unwrap() error {
+	return e.Err
+}
+
+// FailureScreenshot returns the screenshot attached to err, if any
+func FailureScreenshot(err error) []byte {
+	var pageErr *PageError
+	if errors.As(err, &pageErr) {
+		return pageErr.Screenshot
+	}
+	return nil
+}
+
+// withFailureScreenshot attaches a screenshot of the page to err when
+// ScreenshotOnFailure is enabled. Oversized images are dropped.
+func withFailureScreenshot(page *rod.Page, opts PageOptions, err error) error {
+	if err == nil || !opts.ScreenshotOnFailure || page == nil {
+		return err
+	}
+
+	// The operation context may already be done, so capture on a fresh one
+	quality := 60
+	screenshot, shotErr := page.Context(context.Background()).Timeout(5*time.Second).Screenshot(false, &proto.PageCaptureScreenshot{
+		Format:  proto.PageCaptureScreenshotFormatJpeg,
+		Quality: &quality,
+	})
+	if shotErr != nil || len(screenshot) > maxFailureScreenshotBytes {
+		return err
+	}
+
+	return &PageError{Err: err, Screenshot: screenshot}
+}
+
+// AttributeQuery selects an attribute of the elements matching a selector
+type AttributeQuery struct {
+	Selector  string `json:"selector"`
+	Attribute string `json:"attribute"`
+}
+
+// AttributeResult holds the attribute values of the matched elements, in
+// document order. Elements without the attribute yield a nil value.
+type AttributeResult struct {
+	Selector  string    `json:"selector"`
+	Attribute string    `json:"attribute"`
+	Values    []*string `json:"values"`
+}
+
+// ElementBox is the layout box of an element in CSS pixels, relative to
+// the viewport
+type ElementBox struct {
+	X          float64 `json:"x"`
+	Y          float64 `json:"y"`
+	Width      float64 `json:"width"`
+	Height     float64 `json:"height"`
+	Screenshot []byte  `json:"screenshot,omitempty"`
+}
+
+// BoxResult holds the boxes of the elements matching a selector, in
+// document order. Elements that are not rendered yield a nil box.
+type BoxResult struct {
+	Selector string        `json:"selector"`
+	Boxes    []*ElementBox `json:"boxes"`
 }
 
 // CookieInfo represents cookie information
@@ -53,6 +300,32 @@ type CookieInfo struct {
 	Secure   bool   `json:"secure"`
 }
 
+// pageCookies returns the cookies the browser holds for the page's current
+// URL. Session cookies report an Expires of 0.
+func pageCookies(page *rod.Page) ([]CookieInfo, error) {
+	cookies, err := page.Cookies(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cookies: %w", err)
+	}
+
+	infos := make([]CookieInfo, 0, len(cookies))
+	for _, cookie := range cookies {
+		info := CookieInfo{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Domain:   cookie.Domain,
+			Path:     cookie.Path,
+			HTTPOnly: cookie.HTTPOnly,
+			Secure:   cookie.Secure,
+		}
+		if !cookie.Session && cookie.Expires > 0 {
+			info.Expires = int64(cookie.Expires)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
 // CookieParam represents cookie parameters sent in requests.
 type CookieParam struct {
 	Name     string `json:"name"`
@@ -85,9 +358,35 @@ func (m *Manager) FillForm(ctx context.Context, url string, inputs map[string]st
 	return fillForm(m, ctx, url, inputs, opts)
 }
 
-// TakeScreenshot takes a screenshot of a page
-func (m *Manager) TakeScreenshot(ctx context.Context, url string, fullPage bool, opts PageOptions) ([]byte, error) {
-	return takeScreenshot(m, ctx, url, fullPage, opts)
+// HoverElement moves the mouse over an element on the page
+func (m *Manager) HoverElement(ctx context.Context, url string, selector string, opts PageOptions) error {
+	return hoverElement(m, ctx, url, selector, opts)
+}
+
+// SelectOption selects an option of a <select> element by value or label
+func (m *Manager) SelectOption(ctx context.Context, url string, selector string, option OptionQuery, opts PageOptions) error {
+	return selectOption(m, ctx, url, selector, option, opts)
+}
+
+// PressKey presses a key on the element, or on the focused element if the
+// selector is empty
+func (m *Manager) PressKey(ctx context.Context, url string, selector string, key string, opts PageOptions) error {
+	return pressKey(m, ctx, url, selector, key, opts)
+}
+
+// RunActions applies a sequence of actions to a single page
+func (m *Manager) RunActions(ctx context.Context, actions []Action, opts PageOptions) ([]ActionResult, error) {
+	return runActions(m, ctx, actions, opts)
+}
+
+// TakeScreenshot takes a screenshot of a page in the format of shot
+func (m *Manager) TakeScreenshot(ctx context.Context, url string, fullPage bool, shot ScreenshotOptions, opts PageOptions) ([]byte, error) {
+	return takeScreenshot(m, ctx, url, fullPage, shot, opts)
+}
+
+// ScreenshotElement takes a screenshot of the first element matching selector
+func (m *Manager) ScreenshotElement(ctx context.Context, url string, selector string, opts PageOptions) ([]byte, error) {
+	return screenshotElement(m, ctx, url, selector, opts)
 }
 
 // GetPageInfo returns basic page information
@@ -95,6 +394,84 @@ func (m *Manager) GetPageInfo(ctx context.Context, url string, opts PageOptions)
 	return getPageInfo(m, ctx, url, opts)
 }
 
+// GetMarkdown converts the page (or the element matching selector) to Markdown
+func (m *Manager) GetMarkdown(ctx context.Context, url string, selector string, opts PageOptions) (*PageResult, error) {
+	return getMarkdown(m, ctx, url, selector, opts)
+}
+
+// ExtractBySelectors returns the text of every element matching each selector
+func (m *Manager) ExtractBySelectors(ctx context.Context, url string, selectors []string, opts PageOptions) (map[string][]string, error) {
+	return extractBySelectors(m, ctx, url, selectors, opts)
+}
+
+// CountElements returns the number of elements matching each selector
+func (m *Manager) CountElements(ctx context.Context, url string, selectors []string, opts PageOptions) (map[string]int, error) {
+	return countElements(m, ctx, url, selectors, opts)
+}
+
+// ExtractAttributes returns attribute values of the elements matching each query
+func (m *Manager) ExtractAttributes(ctx context.Context, url string, queries []AttributeQuery, opts PageOptions) ([]AttributeResult, error) {
+	return extractAttributes(m, ctx, url, queries, opts)
+}
+
+// ElementBoxes returns the bounding boxes of the elements matching each selector
+func (m *Manager) ElementBoxes(ctx context.Context, url string, selectors []string, screenshots bool, opts PageOptions) ([]BoxResult, error) {
+	return elementBoxes(m, ctx, url, selectors, screenshots, opts)
+}
+
+// CaptureFrames takes timed screenshots of the page while it loads
+func (m *Manager) CaptureFrames(ctx context.Context, url string, interval time.Duration, maxFrames int, opts PageOptions) ([]ScreenshotFrame, error) {
+	return captureFrames(m, ctx, url, interval, maxFrames, opts)
+}
+
+// DiscoverFeeds returns the RSS/Atom feeds advertised by the page
+func (m *Manager) DiscoverFeeds(ctx context.Context, url string, opts PageOptions) ([]FeedLink, error) {
+	return discoverFeeds(m, ctx, url, opts)
+}
+
+// ExtractMetadata returns the page's JSON-LD, OpenGraph and meta tags
+func (m *Manager) ExtractMetadata(ctx context.Context, url string, opts PageOptions) (*PageMetadata, error) {
+	return extractMetadata(m, ctx, url, opts)
+}
+
+// ExtractTables returns the tables matching selector (all tables if empty)
+func (m *Manager) ExtractTables(ctx context.Context, url string, selector string, opts PageOptions) ([]Table, error) {
+	return extractTables(m, ctx, url, selector, opts)
+}
+
+// ExtractJSONScript parses the JSON in a script element and applies a JSONPath
+func (m *Manager) ExtractJSONScript(ctx context.Context, url string, selector string, jsonPath string, opts PageOptions) (interface{}, error) {
+	return extractJSONScript(m, ctx, url, selector, jsonPath, opts)
+}
+
+// DownloadFile is not supported by Lightpanda
+func (m *Manager) DownloadFile(ctx context.Context, url string, selector string, opts PageOptions) (*DownloadResult, error) {
+	return nil, fmt.Errorf("%w: downloads are only supported on chrome endpoints", ErrUnsupported)
+}
+
+// RenderPDF is not supported by Lightpanda
+func (m *Manager) RenderPDF(ctx context.Context, url string, pdfOpts PDFOptions, opts PageOptions) ([]byte, error) {
+	return nil, fmt.Errorf("%w: PDF rendering is only supported on chrome endpoints", ErrUnsupported)
+}
+
+// CreateSession is not supported by Lightpanda
+func (m *Manager) CreateSession() (*SessionInfo, error) {
+	return nil, fmt.Errorf("%w: sessions are only supported on chrome endpoints", ErrUnsupported)
+}
+
+// CloseSession is not supported by Lightpanda
+func (m *Manager) CloseSession(id string) error {
+	return fmt.Errorf("%w: sessions are only supported on chrome endpoints", ErrUnsupported)
+}
+
+// ExecuteCDP executes an allow-listed raw CDP method against a page
+func (m *Manager) ExecuteCDP(ctx context.Context, url string, method string, params json.RawMessage, opts PageOptions) (json.RawMessage, error) {
+	return executeCDP(m, ctx, url, method, params, opts)
+}
+
+// pageOpener opens a prepared page. The returned cleanup releases the page,
+// closing it or returning it to a pool, so callers must not close it
+// themselves.
 type pageOpener interface {
 	OpenPage(ctx context.Context, url string, opts PageOptions) (*rod.Page, func(), error)
 }
@@ -103,28 +480,48 @@ func fetchPage(opener pageOpener, ctx context.Context, url string, opts PageOpti
 	ctx, cancel := withTimeout(ctx, opts.Timeout)
 	defer cancel()
 
+	if opts.CaptureInitialHTML {
+		opts.initialHTML = new(string)
+	}
+	if opts.CaptureConsole {
+		opts.console = &consoleLog{}
+	}
+	opts.response = &documentResponse{}
+	if len(opts.BlockResources) > 0 {
+		opts.blocked = new(atomic.Int64)
+	}
+
 	page, cleanup, err := opener.OpenPage(ctx, url, opts)
 	if err != nil {
-		return nil, err
+		return nil, withConsole(err, opts.console)
 	}
 	defer cleanup()
-	defer page.Close()
 
+	// Redirects and client-side navigation may land elsewhere
+	info, err := page.Info()
+	if err != nil {
+		return nil, withConsole(fmt.Errorf("failed to get page info: %w", err), opts.console)
+	}
 	result := &PageResult{
-		URL: url,
+		URL:          info.URL,
+		RequestedURL: url,
+		Title:        info.Title,
 	}
+	if opts.initialHTML != nil {
+		result.InitialHTML = *opts.initialHTML
+	}
+	result.StatusCode = opts.response.StatusCode
+	result.Headers = opts.response.Headers
+	result.FinalURL = opts.response.URL
 
-	title := page.MustInfo().Title
-	result.Title = title
-
-	html, err := page.HTML()
+	html, err := pageHTML(page, opts.PierceShadow)
 	if err == nil {
 		result.HTML = html
 	}
 
-	text, err := page.Eval(`() => document.body.innerText`)
-	if err == nil && text.Value.Str() != "" {
-		result.Text = text.Value.Str()
+	text, err := pageText(page, opts.PierceShadow)
+	if err == nil && text != "" {
+		result.Text = text
 	}
 
 	links, err := extractLinks(page)
@@ -132,6 +529,21 @@ func fetchPage(opener pageOpener, ctx context.Context, url string, opts PageOpti
 		result.Links = links
 	}
 
+	if opts.IncludeCookies {
+		cookies, err := pageCookies(page)
+		if err != nil {
+			return nil, withConsole(err, opts.console)
+		}
+		result.Cookies = cookies
+	}
+
+	if len(opts.WaitForSelectors) > 0 {
+		matched, err := matchingSelectors(page, opts.WaitForSelectors)
+		if err == nil {
+			result.MatchedSelectors = matched
+		}
+	}
+
 	if opts.Screenshot {
 		screenshot, err := page.Screenshot(true, nil)
 		if err == nil {
@@ -139,6 +551,13 @@ func fetchPage(opener pageOpener, ctx context.Context, url string, opts PageOpti
 		}
 	}
 
+	if opts.console != nil {
+		result.Console = opts.console.list()
+	}
+	if opts.blocked != nil {
+		result.BlockedRequests = opts.blocked.Load()
+	}
+
 	return result, nil
 }
 
@@ -170,16 +589,53 @@ func evaluateScript(opener pageOpener, ctx context.Context, url string, script s
 		return nil, err
 	}
 	defer cleanup()
-	defer page.Close()
 
-	result, err := page.Eval(script)
+	if opts.OnProgress != nil {
+		stop, err := exposeProgress(page, opts.OnProgress)
+		if err != nil {
+			return nil, withFailureScreenshot(page, opts, err)
+		}
+		defer stop()
+	}
+
+	result, err := evalWithBudget(page, script, opts.ScriptTimeout)
+	if errors.Is(err, ErrScriptTimeout) {
+		return nil, err
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to evaluate script: %w", err)
+		return nil, withFailureScreenshot(page, opts, fmt.Errorf("failed to evaluate script: %w", err))
 	}
 
 	return result.Value.Raw(), nil
 }
 
+// evalWithBudget evaluates script, giving up once it has run for budget
+// (no limit if zero). A script over budget may still be spinning in the
+// renderer, so it is terminated and the page closed rather than reused.
+func evalWithBudget(page *rod.Page, script string, budget time.Duration) (*proto.RuntimeRemoteObject, error) {
+	if budget <= 0 {
+		return page.Eval(script)
+	}
+
+	ctx, cancel := context.WithTimeout(page.GetContext(), budget)
+	defer cancel()
+
+	result, err := page.Context(ctx).Eval(script)
+	if err == nil || ctx.Err() != context.DeadlineExceeded || page.GetContext().Err() != nil {
+		// Errors once the page's own timeout has passed stay navigation
+		// timeouts
+		return result, err
+	}
+
+	if err := (proto.RuntimeTerminateExecution{}).Call(page); err != nil {
+		log.Printf("Warning: failed to terminate script over budget: %v", err)
+	}
+	if err := page.Close(); err != nil {
+		log.Printf("Warning: failed to close page after script over budget: %v", err)
+	}
+	return nil, fmt.Errorf("%w of %s", ErrScriptTimeout, budget)
+}
+
 func clickElement(opener pageOpener, ctx context.Context, url string, selector string, opts PageOptions) error {
 	ctx, cancel := withTimeout(ctx, opts.Timeout)
 	defer cancel()
@@ -189,15 +645,14 @@ func clickElement(opener pageOpener, ctx context.Context, url string, selector s
 		return err
 	}
 	defer cleanup()
-	defer page.Close()
 
-	element, err := page.Element(selector)
+	element, err := queryElement(page, selector, opts.PierceShadow)
 	if err != nil {
-		return fmt.Errorf("element not found: %s", selector)
+		return withFailureScreenshot(page, opts, fmt.Errorf("element not found: %s", selector))
 	}
 
 	if err := element.Click(proto.InputMouseButtonLeft, 1); err != nil {
-		return fmt.Errorf("failed to click element: %w", err)
+		return withFailureScreenshot(page, opts, fmt.Errorf("failed to click element: %w", err))
 	}
 
 	return nil
@@ -212,23 +667,26 @@ func fillForm(opener pageOpener, ctx context.Context, url string, inputs map[str
 		return err
 	}
 	defer cleanup()
-	defer page.Close()
 
 	for selector, value := range inputs {
-		element, err := page.Element(selector)
+		element, err := queryElement(page, selector, opts.PierceShadow)
 		if err != nil {
-			return fmt.Errorf("element not found: %s", selector)
+			return withFailureScreenshot(page, opts, fmt.Errorf("element not found: %s", selector))
 		}
 
 		if err := element.Input(value); err != nil {
-			return fmt.Errorf("failed to input value for %s: %w", selector, err)
+			return withFailureScreenshot(page, opts, fmt.Errorf("failed to input value for %s: %w", selector, err))
 		}
 	}
 
 	return nil
 }
 
-func takeScreenshot(opener pageOpener, ctx context.Context, url string, fullPage bool, opts PageOptions) ([]byte, error) {
+func takeScreenshot(opener pageOpener, ctx context.Context, url string, fullPage bool, shot ScreenshotOptions, opts PageOptions) ([]byte, error) {
+	if err := shot.Validate(); err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := withTimeout(ctx, opts.Timeout)
 	defer cancel()
 
@@ -237,9 +695,8 @@ func takeScreenshot(opener pageOpener, ctx context.Context, url string, fullPage
 		return nil, err
 	}
 	defer cleanup()
-	defer page.Close()
 
-	screenshot, err := page.Screenshot(fullPage, nil)
+	screenshot, err := page.Screenshot(fullPage, shot.capture())
 	if err != nil {
 		return nil, fmt.Errorf("failed to take screenshot: %w", err)
 	}
@@ -247,6 +704,179 @@ func takeScreenshot(opener pageOpener, ctx context.Context, url string, fullPage
 	return screenshot, nil
 }
 
+// screenshotElement captures the first element matching selector, scrolled
+// into view. The page is opened with opts, so WaitForSelector can hold the
+// capture until the element has rendered; a selector matching nothing then
+// fails with ErrElementNotFound rather than waiting out the timeout.
+func screenshotElement(opener pageOpener, ctx context.Context, url string, selector string, opts PageOptions) ([]byte, error) {
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	page, cleanup, err := opener.OpenPage(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	elements, err := queryElements(page, selector, opts.PierceShadow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", selector, err)
+	}
+	if len(elements) == 0 {
+		return nil, withFailureScreenshot(page, opts, fmt.Errorf("%w: %s", ErrElementNotFound, selector))
+	}
+
+	screenshot, err := elements[0].Screenshot(proto.PageCaptureScreenshotFormatPng, 0)
+	if err != nil {
+		return nil, withFailureScreenshot(page, opts, fmt.Errorf("failed to capture %s: %w", selector, err))
+	}
+
+	return screenshot, nil
+}
+
+func countElements(opener pageOpener, ctx context.Context, url string, selectors []string, opts PageOptions) (map[string]int, error) {
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	page, cleanup, err := opener.OpenPage(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	counts := make(map[string]int, len(selectors))
+	for _, selector := range selectors {
+		elements, err := queryElements(page, selector, opts.PierceShadow)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s: %w", selector, err)
+		}
+		counts[selector] = len(elements)
+	}
+
+	return counts, nil
+}
+
+func extractBySelectors(opener pageOpener, ctx context.Context, url string, selectors []string, opts PageOptions) (map[string][]string, error) {
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	page, cleanup, err := opener.OpenPage(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	// Selectors that match nothing keep an empty slice, not a missing key
+	values := make(map[string][]string, len(selectors))
+	for _, selector := range selectors {
+		elements, err := queryElements(page, selector, opts.PierceShadow)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s: %w", selector, err)
+		}
+
+		texts := make([]string, 0, len(elements))
+		for _, element := range elements {
+			text, err := element.Text()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read text of %s: %w", selector, err)
+			}
+			texts = append(texts, strings.TrimSpace(text))
+		}
+		values[selector] = texts
+	}
+
+	return values, nil
+}
+
+func extractAttributes(opener pageOpener, ctx context.Context, url string, queries []AttributeQuery, opts PageOptions) ([]AttributeResult, error) {
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	page, cleanup, err := opener.OpenPage(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	results := make([]AttributeResult, 0, len(queries))
+	for _, query := range queries {
+		elements, err := queryElements(page, query.Selector, opts.PierceShadow)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s: %w", query.Selector, err)
+		}
+
+		values := make([]*string, 0, len(elements))
+		for _, element := range elements {
+			value, err := element.Attribute(query.Attribute)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s of %s: %w", query.Attribute, query.Selector, err)
+			}
+			values = append(values, value)
+		}
+
+		results = append(results, AttributeResult{
+			Selector:  query.Selector,
+			Attribute: query.Attribute,
+			Values:    values,
+		})
+	}
+
+	return results, nil
+}
+
+func elementBoxes(opener pageOpener, ctx context.Context, url string, selectors []string, screenshots bool, opts PageOptions) ([]BoxResult, error) {
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	page, cleanup, err := opener.OpenPage(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	results := make([]BoxResult, 0, len(selectors))
+	for _, selector := range selectors {
+		elements, err := queryElements(page, selector, opts.PierceShadow)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s: %w", selector, err)
+		}
+
+		boxes := make([]*ElementBox, 0, len(elements))
+		for _, element := range elements {
+			// Elements without layout (display: none, detached) have no quads
+			shape, err := element.Shape()
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				boxes = append(boxes, nil)
+				continue
+			}
+			rect := shape.Box()
+			if rect == nil {
+				boxes = append(boxes, nil)
+				continue
+			}
+
+			box := &ElementBox{X: rect.X, Y: rect.Y, Width: rect.Width, Height: rect.Height}
+			if screenshots {
+				box.Screenshot, err = element.Screenshot(proto.PageCaptureScreenshotFormatPng, 0)
+				if err != nil {
+					return nil, fmt.Errorf("failed to capture %s: %w", selector, err)
+				}
+			}
+			boxes = append(boxes, box)
+		}
+
+		results = append(results, BoxResult{
+			Selector: selector,
+			Boxes:    boxes,
+		})
+	}
+
+	return results, nil
+}
+
 func getPageInfo(opener pageOpener, ctx context.Context, url string, opts PageOptions) (*PageResult, error) {
 	ctx, cancel := withTimeout(ctx, opts.Timeout)
 	defer cancel()
@@ -256,13 +886,13 @@ func getPageInfo(opener pageOpener, ctx context.Context, url string, opts PageOp
 		return nil, err
 	}
 	defer cleanup()
-	defer page.Close()
 
 	info := page.MustInfo()
 
 	return &PageResult{
-		URL:   info.URL,
-		Title: info.Title,
+		URL:          info.URL,
+		RequestedURL: url,
+		Title:        info.Title,
 	}, nil
 }
 
@@ -273,9 +903,198 @@ func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, c
 	return context.WithTimeout(ctx, timeout)
 }
 
+// preparePage applies options to a new page, navigates to the URL and waits
+// for it to load. The caller is responsible for closing the page on error.
+func preparePage(page *rod.Page, url string, opts PageOptions) error {
+	if err := applyPageOptions(page, url, opts); err != nil {
+		return err
+	}
+
+	var initialHTML func() string
+	if opts.initialHTML != nil {
+		initialHTML = captureInitialHTML(page)
+	}
+	if opts.console != nil {
+		captureConsole(page, opts.console)
+	}
+	var response func() *documentResponse
+	if opts.response != nil {
+		response = captureDocumentResponse(page)
+	}
+
+	if err := page.Navigate(url); err != nil {
+		return withFailureScreenshot(page, opts, fmt.Errorf("failed to navigate to %s: %w", url, err))
+	}
+	opts.reportPhase(PhaseNavigated)
+
+	if opts.WaitForLoad {
+		if err := page.WaitLoad(); err != nil {
+			return withFailureScreenshot(page, opts, fmt.Errorf("failed to wait for page load: %w", err))
+		}
+	}
+	if initialHTML != nil {
+		*opts.initialHTML = initialHTML()
+	}
+	if response != nil {
+		if resp := response(); resp != nil {
+			*opts.response = *resp
+		}
+	}
+	if opts.DismissConsent {
+		dismissConsent(page)
+	}
+	if opts.WaitForFunction != "" {
+		if err := waitForFunction(page, opts.WaitForFunction, opts.WaitPollInterval); err != nil {
+			return withFailureScreenshot(page, opts, err)
+		}
+	}
+	if len(opts.WaitForSelectors) > 0 {
+		if err := waitForSelectors(page, opts.WaitForSelectors, opts.WaitMode, opts.WaitPollInterval); err != nil {
+			return withFailureScreenshot(page, opts, err)
+		}
+	}
+	if opts.WaitForSelector != "" {
+		if err := waitForSelector(page, opts.WaitForSelector, opts.WaitForSelectorTimeout); err != nil {
+			return withFailureScreenshot(page, opts, err)
+		}
+	}
+	if opts.AutoScroll {
+		if err := autoScroll(page, opts.ScrollSteps, opts.ScrollDelay); err != nil {
+			return withFailureScreenshot(page, opts, err)
+		}
+	}
+	opts.reportPhase(PhaseLoaded)
+
+	return nil
+}
+
+const defaultWaitPollInterval = 100 * time.Millisecond
+
+// waitForFunction polls a JS predicate until it returns a truthy value or the
+// page context is done
+func waitForFunction(page *rod.Page, predicate string, interval time.Duration) error {
+	if interval <= 0 {
+		interval = defaultWaitPollInterval
+	}
+
+	js := fmt.Sprintf("() => !!(%s)()", strings.Trim(predicate, "\t\n\v\f\r ;"))
+	ctx := page.GetContext()
+
+	for {
+		res, err := page.Eval(js)
+		if err != nil {
+			return fmt.Errorf("failed to evaluate wait_for_function: %w", err)
+		}
+		if res.Value.Bool() {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for wait_for_function: %w", ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+// waitForSelectors polls until any or all selectors match an element
+func waitForSelectors(page *rod.Page, selectors []string, mode WaitMode, interval time.Duration) error {
+	if interval <= 0 {
+		interval = defaultWaitPollInterval
+	}
+
+	ctx := page.GetContext()
+	for {
+		matched, err := matchingSelectors(page, selectors)
+		if err != nil {
+			return err
+		}
+		if (mode == WaitAny && len(matched) > 0) || len(matched) == len(selectors) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for selectors %v (%s), matched %v: %w", selectors, mode, matched, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+// waitForSelector waits until selector matches an element, for at most
+// timeout when set
+func waitForSelector(page *rod.Page, selector string, timeout time.Duration) error {
+	ctx := page.GetContext()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if _, err := page.Context(ctx).Element(selector); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("wait_for_selector timed out: %s", selector)
+		}
+		return fmt.Errorf("failed to wait for selector %s: %w", selector, err)
+	}
+	return nil
+}
+
+// matchingSelectors returns the selectors that currently match an element
+func matchingSelectors(page *rod.Page, selectors []string) ([]string, error) {
+	res, err := page.Eval(`(sels) => sels.filter(s => {
+		try { return !!document.querySelector(s) } catch (e) { return false }
+	})`, selectors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query selectors: %w", err)
+	}
+
+	matched := []string{}
+	for _, value := range res.Value.Arr() {
+		matched = append(matched, value.Str())
+	}
+	return matched, nil
+}
+
 func applyPageOptions(page *rod.Page, targetURL string, opts PageOptions) error {
-	if opts.UserAgent != "" {
-		if err := page.SetUserAgent(&proto.NetworkSetUserAgentOverride{UserAgent: opts.UserAgent}); err != nil {
+	if !ValidWaitMode(opts.WaitMode) {
+		return fmt.Errorf("%w: unknown wait_mode %q (use any or all)", ErrInvalidOptions, opts.WaitMode)
+	}
+	if opts.WaitForSelectorTimeout < 0 {
+		return fmt.Errorf("%w: wait_for_selector_timeout must not be negative", ErrInvalidOptions)
+	}
+	if opts.ScriptTimeout < 0 {
+		return fmt.Errorf("%w: script_timeout must not be negative", ErrInvalidOptions)
+	}
+	if err := validateScroll(opts); err != nil {
+		return err
+	}
+
+	viewport, userAgent, err := resolveEmulation(opts)
+	if err != nil {
+		return err
+	}
+	if viewport != nil {
+		if err := emulateViewport(page, viewport); err != nil {
+			return err
+		}
+	}
+
+	if userAgent != "" || opts.AcceptLanguage != "" {
+		override := &proto.NetworkSetUserAgentOverride{
+			UserAgent:      userAgent,
+			AcceptLanguage: opts.AcceptLanguage,
+		}
+		// The override always replaces the user agent, so keep the
+		// browser default when only the language is requested.
+		if override.UserAgent == "" {
+			version, err := proto.BrowserGetVersion{}.Call(page)
+			if err != nil {
+				return fmt.Errorf("failed to get default user agent: %w", err)
+			}
+			override.UserAgent = version.UserAgent
+		}
+		if err := page.SetUserAgent(override); err != nil {
 			return fmt.Errorf("failed to set user agent: %w", err)
 		}
 	}
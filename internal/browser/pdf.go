@@ -0,0 +1,103 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Paper formats supported by RenderPDF
+const (
+	PaperA4     = "A4"
+	PaperLetter = "Letter"
+)
+
+// paperSizes holds width and height in inches per paper format
+var paperSizes = map[string][2]float64{
+	PaperA4:     {8.27, 11.69},
+	PaperLetter: {8.5, 11},
+}
+
+// PDFMargins are page margins in inches
+type PDFMargins struct {
+	Top    float64 `json:"top"`
+	Right  float64 `json:"right"`
+	Bottom float64 `json:"bottom"`
+	Left   float64 `json:"left"`
+}
+
+// PDFOptions controls how a page is printed to PDF
+type PDFOptions struct {
+	Landscape       bool        `json:"landscape,omitempty"`
+	Format          string      `json:"format,omitempty"`  // A4 (default) or Letter
+	Margins         *PDFMargins `json:"margins,omitempty"` // Chrome's default margins if unset
+	PrintBackground bool        `json:"print_background,omitempty"`
+}
+
+// Validate checks the paper format and margins
+func (o PDFOptions) Validate() error {
+	if _, ok := o.paperSize(); !ok {
+		return fmt.Errorf("%w: unknown pdf format %q (use A4 or Letter)", ErrInvalidOptions, o.Format)
+	}
+	if m := o.Margins; m != nil && (m.Top < 0 || m.Right < 0 || m.Bottom < 0 || m.Left < 0) {
+		return fmt.Errorf("%w: pdf margins must not be negative", ErrInvalidOptions)
+	}
+	return nil
+}
+
+// paperSize returns the width and height of the format in inches
+func (o PDFOptions) paperSize() ([2]float64, bool) {
+	if o.Format == "" {
+		return paperSizes[PaperA4], true
+	}
+	for name, size := range paperSizes {
+		if strings.EqualFold(name, o.Format) {
+			return size, true
+		}
+	}
+	return [2]float64{}, false
+}
+
+// renderPDF prints the page to PDF with Page.printToPDF
+func renderPDF(opener pageOpener, ctx context.Context, url string, pdfOpts PDFOptions, opts PageOptions) ([]byte, error) {
+	if err := pdfOpts.Validate(); err != nil {
+		return nil, err
+	}
+	size, _ := pdfOpts.paperSize()
+
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	page, cleanup, err := opener.OpenPage(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	req := &proto.PagePrintToPDF{
+		Landscape:       pdfOpts.Landscape,
+		PrintBackground: pdfOpts.PrintBackground,
+		PaperWidth:      &size[0],
+		PaperHeight:     &size[1],
+	}
+	if m := pdfOpts.Margins; m != nil {
+		req.MarginTop = &m.Top
+		req.MarginRight = &m.Right
+		req.MarginBottom = &m.Bottom
+		req.MarginLeft = &m.Left
+	}
+
+	stream, err := page.PDF(req)
+	if err != nil {
+		return nil, withFailureScreenshot(page, opts, fmt.Errorf("failed to render pdf: %w", err))
+	}
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pdf: %w", err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,266 @@
+package browser
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Page pool defaults
+const (
+	DefaultPoolMaxIdle = 5 * time.Minute
+	poolResetTimeout   = 5 * time.Second
+)
+
+// PoolStats counts page pool activity
+type PoolStats struct {
+	Size      int   `json:"size"`      // Maximum idle pages kept
+	Idle      int   `json:"idle"`      // Pages waiting to be reused
+	Hits      int64 `json:"hits"`      // Requests served by a pooled page
+	Misses    int64 `json:"misses"`    // Requests that had to create a page
+	Evictions int64 `json:"evictions"` // Pages closed after idling past the max idle time
+	Discards  int64 `json:"discards"`  // Pages closed because they failed or failed to reset
+}
+
+// pooledPage is a reusable page in its own incognito browser context, so
+// cookies and storage can be cleared without touching other pages
+type pooledPage struct {
+	page      *rod.Page    // Not bound to a request context
+	context   *rod.Browser // Incognito context owning the page
+	origins   []string     // Origins to clear on reset
+	emulated  bool         // Viewport or user agent overrides were applied
+	idleSince time.Time
+}
+
+// addOrigin records the origin of rawURL so its storage is cleared on reset
+func (p *pooledPage) addOrigin(rawURL string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return
+	}
+	origin := u.Scheme + "://" + u.Host
+	for _, o := range p.origins {
+		if o == origin {
+			return
+		}
+	}
+	p.origins = append(p.origins, origin)
+}
+
+// pagePool keeps up to size reset pages warm for reuse. Pages idle longer
+// than maxIdle are closed by the janitor.
+type pagePool struct {
+	size    int
+	maxIdle time.Duration
+
+	// reset and close are swapped out in tests
+	reset func(*pooledPage) error
+	close func(*pooledPage)
+
+	mu     sync.Mutex
+	idle   []*pooledPage // Most recently returned last
+	closed bool
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+	discards  atomic.Int64
+}
+
+func newPagePool(size int, maxIdle time.Duration) *pagePool {
+	if maxIdle <= 0 {
+		maxIdle = DefaultPoolMaxIdle
+	}
+	return &pagePool{
+		size:    size,
+		maxIdle: maxIdle,
+		reset:   resetPooledPage,
+		close:   closePooledPage,
+	}
+}
+
+// get returns the most recently used idle page, or nil on a miss
+func (p *pagePool) get() *pooledPage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if n := len(p.idle); n > 0 && !p.closed {
+		entry := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.hits.Add(1)
+		return entry
+	}
+	p.misses.Add(1)
+	return nil
+}
+
+// put resets entry and keeps it for reuse, closing it instead if the reset
+// fails or the pool is full or drained
+func (p *pagePool) put(entry *pooledPage) {
+	if err := p.reset(entry); err != nil {
+		log.Printf("Warning: discarding pooled page after failed reset: %v", err)
+		p.discard(entry)
+		return
+	}
+	entry.origins = nil
+	entry.emulated = false
+	entry.idleSince = time.Now()
+
+	p.mu.Lock()
+	if p.closed || len(p.idle) >= p.size {
+		p.mu.Unlock()
+		p.close(entry)
+		return
+	}
+	p.idle = append(p.idle, entry)
+	p.mu.Unlock()
+}
+
+// discard closes an entry that must not be reused
+func (p *pagePool) discard(entry *pooledPage) {
+	p.discards.Add(1)
+	p.close(entry)
+}
+
+// evict closes the pages idle since before now minus maxIdle
+func (p *pagePool) evict(now time.Time) {
+	p.mu.Lock()
+	var expired []*pooledPage
+	kept := p.idle[:0]
+	for _, entry := range p.idle {
+		if now.Sub(entry.idleSince) > p.maxIdle {
+			expired = append(expired, entry)
+		} else {
+			kept = append(kept, entry)
+		}
+	}
+	p.idle = kept
+	p.mu.Unlock()
+
+	for _, entry := range expired {
+		p.evictions.Add(1)
+		p.close(entry)
+	}
+}
+
+// drain closes every idle page. Pages returned afterwards are closed too
+// when closed is set.
+func (p *pagePool) drain(closed bool) {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = closed
+	p.mu.Unlock()
+
+	for _, entry := range idle {
+		p.close(entry)
+	}
+}
+
+// startJanitor evicts idle pages periodically. The returned func stops it.
+func (p *pagePool) startJanitor() func() {
+	stop := make(chan struct{})
+	interval := p.maxIdle / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				p.evict(now)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(stop) }) }
+}
+
+// stats returns the current pool counters
+func (p *pagePool) stats() *PoolStats {
+	p.mu.Lock()
+	idle := len(p.idle)
+	p.mu.Unlock()
+
+	return &PoolStats{
+		Size:      p.size,
+		Idle:      idle,
+		Hits:      p.hits.Load(),
+		Misses:    p.misses.Load(),
+		Evictions: p.evictions.Load(),
+		Discards:  p.discards.Load(),
+	}
+}
+
+// resetPooledPage returns a page to a blank state: about:blank, no cookies,
+// no storage for the origins it visited and no emulation overrides
+func resetPooledPage(entry *pooledPage) error {
+	page := entry.page.Timeout(poolResetTimeout)
+	defer page.CancelTimeout()
+
+	// Scripts and action sequences may have navigated away from the
+	// requested URL, so clear every origin in the page's history
+	if history, err := (proto.PageGetNavigationHistory{}).Call(page); err == nil {
+		for _, item := range history.Entries {
+			entry.addOrigin(item.URL)
+		}
+	}
+	if err := page.Navigate("about:blank"); err != nil {
+		return fmt.Errorf("failed to navigate to about:blank: %w", err)
+	}
+
+	if err := (proto.StorageClearCookies{BrowserContextID: entry.context.BrowserContextID}).Call(page); err != nil {
+		return fmt.Errorf("failed to clear cookies: %w", err)
+	}
+	for _, origin := range entry.origins {
+		clear := proto.StorageClearDataForOrigin{Origin: origin, StorageTypes: "all"}
+		if err := clear.Call(page); err != nil {
+			return fmt.Errorf("failed to clear storage for %s: %w", origin, err)
+		}
+	}
+
+	// Request interception may outlive a canceled request context
+	if err := (proto.FetchDisable{}).Call(page); err != nil {
+		return fmt.Errorf("failed to disable request interception: %w", err)
+	}
+	if err := (proto.NetworkSetExtraHTTPHeaders{Headers: proto.NetworkHeaders{}}).Call(page); err != nil {
+		return fmt.Errorf("failed to clear headers: %w", err)
+	}
+	if !entry.emulated {
+		return nil
+	}
+	if err := page.SetViewport(nil); err != nil {
+		return fmt.Errorf("failed to clear viewport: %w", err)
+	}
+	if err := (proto.EmulationSetTouchEmulationEnabled{Enabled: false}).Call(page); err != nil {
+		return fmt.Errorf("failed to disable touch emulation: %w", err)
+	}
+	version, err := proto.BrowserGetVersion{}.Call(page)
+	if err != nil {
+		return fmt.Errorf("failed to get default user agent: %w", err)
+	}
+	if err := page.SetUserAgent(&proto.NetworkSetUserAgentOverride{UserAgent: version.UserAgent}); err != nil {
+		return fmt.Errorf("failed to restore user agent: %w", err)
+	}
+	return nil
+}
+
+// closePooledPage closes the page and disposes of its browser context
+func closePooledPage(entry *pooledPage) {
+	_ = entry.page.Close()
+	if err := entry.context.Close(); err != nil {
+		log.Printf("Warning: failed to dispose pooled page context: %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package browser
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// testPool returns a pool whose reset fails for entries in failing and
+// which records closed entries
+func testPool(size int, maxIdle time.Duration, failing map[*pooledPage]bool) (*pagePool, *[]*pooledPage) {
+	closed := &[]*pooledPage{}
+	pool := newPagePool(size, maxIdle)
+	pool.reset = func(entry *pooledPage) error {
+		if failing[entry] {
+			return errors.New("reset failed")
+		}
+		return nil
+	}
+	pool.close = func(entry *pooledPage) {
+		*closed = append(*closed, entry)
+	}
+	return pool, closed
+}
+
+func TestPagePoolReusesAndCounts(t *testing.T) {
+	pool, closed := testPool(1, time.Minute, nil)
+
+	if pool.get() != nil {
+		t.Fatal("Expected a miss on an empty pool")
+	}
+
+	first, second := &pooledPage{}, &pooledPage{}
+	first.addOrigin("https://example.com/a")
+	first.emulated = true
+	pool.put(first)
+	pool.put(second) // pool is full
+
+	if got := pool.get(); got != first {
+		t.Fatal("Expected the pooled page to be reused")
+	}
+	if len(first.origins) != 0 || first.emulated {
+		t.Error("Expected reset state to be cleared on return")
+	}
+	if len(*closed) != 1 || (*closed)[0] != second {
+		t.Errorf("Expected the page beyond the pool size to be closed, got %d closed", len(*closed))
+	}
+
+	stats := pool.stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Idle != 0 || stats.Size != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestPagePoolDiscardsFailedReset(t *testing.T) {
+	broken := &pooledPage{}
+	pool, closed := testPool(2, time.Minute, map[*pooledPage]bool{broken: true})
+
+	pool.put(broken)
+	if len(*closed) != 1 || pool.stats().Discards != 1 {
+		t.Fatal("Expected a page that failed to reset to be discarded")
+	}
+	if pool.get() != nil {
+		t.Error("Expected the discarded page not to be reused")
+	}
+}
+
+func TestPagePoolEvictsIdlePages(t *testing.T) {
+	pool, closed := testPool(2, time.Minute, nil)
+
+	stale, fresh := &pooledPage{}, &pooledPage{}
+	pool.put(stale)
+	pool.put(fresh)
+	stale.idleSince = time.Now().Add(-2 * time.Minute)
+
+	pool.evict(time.Now())
+	if len(*closed) != 1 || (*closed)[0] != stale {
+		t.Fatalf("Expected only the stale page to be evicted, got %d closed", len(*closed))
+	}
+	if stats := pool.stats(); stats.Evictions != 1 || stats.Idle != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestPagePoolDrain(t *testing.T) {
+	pool, closed := testPool(2, time.Minute, nil)
+
+	pool.put(&pooledPage{})
+	pool.drain(true)
+	if len(*closed) != 1 {
+		t.Fatalf("Expected the idle page to be closed, got %d", len(*closed))
+	}
+
+	// Pages returned after the pool stopped are closed
+	pool.put(&pooledPage{})
+	if len(*closed) != 2 || pool.get() != nil {
+		t.Error("Expected a stopped pool to keep no pages")
+	}
+}
+
+func TestPooledPageAddOrigin(t *testing.T) {
+	entry := &pooledPage{}
+	entry.addOrigin("https://example.com/a?b=1")
+	entry.addOrigin("https://example.com/other")
+	entry.addOrigin("http://example.com:8080/")
+	entry.addOrigin("about:blank")
+
+	want := []string{"https://example.com", "http://example.com:8080"}
+	if len(entry.origins) != len(want) {
+		t.Fatalf("Expected origins %v, got %v", want, entry.origins)
+	}
+	for i := range want {
+		if entry.origins[i] != want[i] {
+			t.Errorf("Expected origin %q, got %q", want[i], entry.origins[i])
+		}
+	}
+}
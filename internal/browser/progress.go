@@ -0,0 +1,66 @@
+package browser
+
+import (
+	"fmt"
+
+	"github.com/go-rod/rod"
+	"github.com/ysmood/gson"
+)
+
+// ProgressFunction is the window function scripts call to report progress:
+// window.__scrqProgress(current, total, message)
+const ProgressFunction = "__scrqProgress"
+
+// maxProgressMessage caps the length of script progress messages
+const maxProgressMessage = 200
+
+// progressBinding is the rod binding behind ProgressFunction. rod passes a
+// single argument, so ProgressFunction packs its arguments into an array.
+const progressBinding = "__scrqProgressBinding"
+
+var progressWrapper = fmt.Sprintf(`() => {
+	window.%s = (current, total, message) => window.%s([current, total, message]);
+}`, ProgressFunction, progressBinding)
+
+// exposeProgress binds ProgressFunction on the page to onProgress, for the
+// current document and any it navigates to. The returned func unbinds it.
+func exposeProgress(page *rod.Page, onProgress func(current, total int, message string)) (func(), error) {
+	stopBinding, err := page.Expose(progressBinding, func(req gson.JSON) (interface{}, error) {
+		onProgress(parseProgress(req))
+		return nil, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to expose %s: %w", ProgressFunction, err)
+	}
+
+	removeWrapper, err := page.EvalOnNewDocument("(" + progressWrapper + ")()")
+	if err == nil {
+		_, err = page.Eval(progressWrapper)
+	}
+	if err != nil {
+		_ = stopBinding()
+		return nil, fmt.Errorf("failed to expose %s: %w", ProgressFunction, err)
+	}
+
+	return func() {
+		_ = removeWrapper()
+		_ = stopBinding()
+	}, nil
+}
+
+// parseProgress reads the [current, total, message] arguments of a
+// ProgressFunction call. Negative counts become zero and a missing message
+// is empty.
+func parseProgress(args gson.JSON) (int, int, string) {
+	current := max(args.Get("0").Int(), 0)
+	total := max(args.Get("1").Int(), 0)
+
+	var message string
+	if msg := args.Get("2"); !msg.Nil() {
+		message = msg.Str()
+	}
+	if runes := []rune(message); len(runes) > maxProgressMessage {
+		message = string(runes[:maxProgressMessage])
+	}
+	return current, total, message
+}
@@ -0,0 +1,38 @@
+package browser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ysmood/gson"
+)
+
+func TestParseProgress(t *testing.T) {
+	tests := []struct {
+		args    string
+		current int
+		total   int
+		message string
+	}{
+		{`[2, 10, "page 2"]`, 2, 10, "page 2"},
+		{`[3.7, 5]`, 3, 5, ""},
+		{`[-1, "x", null]`, 0, 0, ""},
+		{`[1, 2, 42]`, 1, 2, "42"},
+		{`[]`, 0, 0, ""},
+	}
+
+	for _, tt := range tests {
+		current, total, message := parseProgress(gson.NewFrom(tt.args))
+		if current != tt.current || total != tt.total || message != tt.message {
+			t.Errorf("%s: got (%d, %d, %q), want (%d, %d, %q)", tt.args, current, total, message, tt.current, tt.total, tt.message)
+		}
+	}
+}
+
+func TestParseProgressTruncatesMessage(t *testing.T) {
+	long := strings.Repeat("é", maxProgressMessage+10)
+	_, _, message := parseProgress(gson.New([]interface{}{1, 2, long}))
+	if len([]rune(message)) != maxProgressMessage {
+		t.Errorf("Expected the message to be cut to %d runes, got %d", maxProgressMessage, len([]rune(message)))
+	}
+}
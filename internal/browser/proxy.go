@@ -0,0 +1,65 @@
+package browser
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// proxySchemes lists the proxy URL schemes Chrome's --proxy-server accepts
+// and that we also support for static fetches
+var proxySchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"socks5": true,
+}
+
+// parseProxy validates a proxy URL and returns it in
+// scheme://[user:pass@]host:port form. A bare host:port is treated as an
+// HTTP proxy. Chrome ignores credentials in --proxy-server, so callers
+// launching Chrome pass proxyServer and answer the proxy's auth challenge
+// with proxyCredentials instead.
+func parseProxy(raw string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid proxy URL: %v", ErrInvalidOptions, err)
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if !proxySchemes[scheme] {
+		return nil, fmt.Errorf("%w: unsupported proxy scheme %q (use http, https or socks5)", ErrInvalidOptions, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("%w: proxy URL has no host", ErrInvalidOptions)
+	}
+	if u.User != nil && u.User.Username() == "" {
+		return nil, fmt.Errorf("%w: proxy credentials have no username", ErrInvalidOptions)
+	}
+
+	return &url.URL{Scheme: scheme, User: u.User, Host: u.Host}, nil
+}
+
+// proxyServer returns the proxy URL without credentials, as passed to
+// Chrome's --proxy-server
+func proxyServer(proxy *url.URL) string {
+	return (&url.URL{Scheme: proxy.Scheme, Host: proxy.Host}).String()
+}
+
+// proxyCredentials returns the credentials in the proxy URL, or nil
+func proxyCredentials(proxy *url.URL) *Credentials {
+	if proxy.User == nil {
+		return nil
+	}
+	password, _ := proxy.User.Password()
+	return &Credentials{Username: proxy.User.Username(), Password: password}
+}
+
+// HasProxyCredentials reports whether a proxy URL carries credentials
+func HasProxyCredentials(raw string) bool {
+	proxy, err := parseProxy(raw)
+	return err == nil && proxy.User != nil
+}
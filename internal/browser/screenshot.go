@@ -0,0 +1,71 @@
+package browser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Image formats supported by TakeScreenshot and ScreenshotElement
+const (
+	ImagePNG  = "png"
+	ImageJPEG = "jpeg"
+	ImageWebP = "webp"
+)
+
+// DefaultScreenshotQuality is the JPEG and WebP quality when none is set
+const DefaultScreenshotQuality = 80
+
+// ScreenshotOptions controls the image format of a screenshot
+type ScreenshotOptions struct {
+	Format  string `json:"format,omitempty"`  // png (default), jpeg or webp
+	Quality int    `json:"quality,omitempty"` // 1-100 for jpeg and webp (default 80); ignored for png
+}
+
+// Validate checks the format and, for lossy formats, the quality
+func (o ScreenshotOptions) Validate() error {
+	switch o.ImageFormat() {
+	case ImagePNG:
+		return nil
+	case ImageJPEG, ImageWebP:
+		if o.Quality < 0 || o.Quality > 100 {
+			return fmt.Errorf("%w: screenshot quality must be between 1 and 100", ErrInvalidOptions)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown screenshot format %q (use png, jpeg or webp)", ErrInvalidOptions, o.Format)
+	}
+}
+
+// ImageFormat returns the normalized format name, png when unset
+func (o ScreenshotOptions) ImageFormat() string {
+	format := strings.ToLower(o.Format)
+	switch format {
+	case "":
+		return ImagePNG
+	case "jpg":
+		return ImageJPEG
+	}
+	return format
+}
+
+// quality returns the quality passed to the capture, 0 for png
+func (o ScreenshotOptions) quality() int {
+	if o.ImageFormat() == ImagePNG {
+		return 0
+	}
+	if o.Quality == 0 {
+		return DefaultScreenshotQuality
+	}
+	return o.Quality
+}
+
+// capture returns the Page.captureScreenshot parameters for the options
+func (o ScreenshotOptions) capture() *proto.PageCaptureScreenshot {
+	req := &proto.PageCaptureScreenshot{Format: proto.PageCaptureScreenshotFormat(o.ImageFormat())}
+	if quality := o.quality(); quality > 0 {
+		req.Quality = &quality
+	}
+	return req
+}
@@ -0,0 +1,40 @@
+package browser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+func TestScreenshotOptions(t *testing.T) {
+	for _, tc := range []struct {
+		opts    ScreenshotOptions
+		format  proto.PageCaptureScreenshotFormat
+		quality int
+	}{
+		{opts: ScreenshotOptions{}, format: proto.PageCaptureScreenshotFormatPng},
+		{opts: ScreenshotOptions{Format: "png", Quality: 500}, format: proto.PageCaptureScreenshotFormatPng},
+		{opts: ScreenshotOptions{Format: "JPG"}, format: proto.PageCaptureScreenshotFormatJpeg, quality: DefaultScreenshotQuality},
+		{opts: ScreenshotOptions{Format: "webp", Quality: 40}, format: proto.PageCaptureScreenshotFormatWebp, quality: 40},
+	} {
+		if err := tc.opts.Validate(); err != nil {
+			t.Errorf("%+v: unexpected error %v", tc.opts, err)
+			continue
+		}
+		req := tc.opts.capture()
+		quality := 0
+		if req.Quality != nil {
+			quality = *req.Quality
+		}
+		if req.Format != tc.format || quality != tc.quality {
+			t.Errorf("%+v: expected %s at quality %d, got %s at %d", tc.opts, tc.format, tc.quality, req.Format, quality)
+		}
+	}
+
+	for _, opts := range []ScreenshotOptions{{Format: "gif"}, {Format: "jpeg", Quality: 101}, {Format: "webp", Quality: -1}} {
+		if err := opts.Validate(); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("%+v: expected ErrInvalidOptions, got %v", opts, err)
+		}
+	}
+}
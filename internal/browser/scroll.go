@@ -0,0 +1,70 @@
+package browser
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+// Bounds for auto-scrolling lazy-loaded pages
+const (
+	DefaultScrollSteps = 10
+	MaxScrollSteps     = 100
+	DefaultScrollDelay = 500 * time.Millisecond
+	MinScrollDelay     = 50 * time.Millisecond
+)
+
+// scrollToBottomJS scrolls to the end of the document and returns its height
+const scrollToBottomJS = `() => {
+	const height = Math.max(
+		document.body ? document.body.scrollHeight : 0,
+		document.documentElement.scrollHeight
+	);
+	window.scrollTo(0, height);
+	return height;
+}`
+
+// validateScroll checks the auto-scroll options
+func validateScroll(opts PageOptions) error {
+	if opts.ScrollSteps < 0 || opts.ScrollSteps > MaxScrollSteps {
+		return fmt.Errorf("%w: scroll_steps must be between 0 and %d", ErrInvalidOptions, MaxScrollSteps)
+	}
+	if opts.ScrollDelay != 0 && opts.ScrollDelay < MinScrollDelay {
+		return fmt.Errorf("%w: scroll_delay must be at least %s", ErrInvalidOptions, MinScrollDelay)
+	}
+	return nil
+}
+
+// autoScroll scrolls to the bottom of the page up to steps times, pausing
+// delay after each scroll, and stops early once the document height no
+// longer grows
+func autoScroll(page *rod.Page, steps int, delay time.Duration) error {
+	if steps <= 0 {
+		steps = DefaultScrollSteps
+	}
+	if delay <= 0 {
+		delay = DefaultScrollDelay
+	}
+
+	ctx := page.GetContext()
+	lastHeight := -1
+	for i := 0; i < steps; i++ {
+		res, err := page.Eval(scrollToBottomJS)
+		if err != nil {
+			return fmt.Errorf("failed to scroll page: %w", err)
+		}
+		height := res.Value.Int()
+		if height == lastHeight {
+			return nil
+		}
+		lastHeight = height
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out scrolling page after %d steps: %w", i+1, ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+	return nil
+}
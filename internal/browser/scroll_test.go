@@ -0,0 +1,31 @@
+package browser
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidateScroll(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  PageOptions
+		valid bool
+	}{
+		{"defaults", PageOptions{AutoScroll: true}, true},
+		{"explicit", PageOptions{AutoScroll: true, ScrollSteps: MaxScrollSteps, ScrollDelay: time.Second}, true},
+		{"negative steps", PageOptions{ScrollSteps: -1}, false},
+		{"too many steps", PageOptions{ScrollSteps: MaxScrollSteps + 1}, false},
+		{"delay too short", PageOptions{ScrollDelay: time.Millisecond}, false},
+	}
+
+	for _, tt := range tests {
+		err := validateScroll(tt.opts)
+		if tt.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("%s: expected ErrInvalidOptions, got %v", tt.name, err)
+		}
+	}
+}
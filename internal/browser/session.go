@@ -0,0 +1,246 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/google/uuid"
+)
+
+// Session defaults
+const (
+	DefaultSessionTTL  = 10 * time.Minute
+	DefaultMaxSessions = 50
+)
+
+// ErrSessionNotFound is returned for an unknown, closed or expired session
+var ErrSessionNotFound = errors.New("session not found")
+
+// ErrTooManySessions is returned when the session limit is reached
+var ErrTooManySessions = errors.New("too many sessions")
+
+// SessionInfo describes a browser session
+type SessionInfo struct {
+	ID         string    `json:"session_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"` // Closed if left idle until then
+}
+
+// session is a browser context shared by the requests passing its ID
+type session struct {
+	id        string
+	context   *rod.Browser
+	createdAt time.Time
+	lastUsed  time.Time
+	inUse     int  // Pages open in the session
+	closed    bool // Disposed of once the last page is closed
+}
+
+// SessionManager keeps incognito browser contexts alive between requests,
+// so cookies and storage set by one request are seen by the next. Sessions
+// left idle longer than the TTL are closed.
+type SessionManager struct {
+	max    int
+	create func() (*rod.Browser, error)
+
+	// dispose is swapped out in tests
+	dispose func(*rod.Browser)
+
+	mu       sync.Mutex
+	ttl      time.Duration
+	sessions map[string]*session
+	pending  int  // Sessions being created
+	janitor  bool // The janitor runs while sessions exist
+}
+
+// NewSessionManager creates a session manager that gets a new browser
+// context from create for each session
+func NewSessionManager(ttl time.Duration, max int, create func() (*rod.Browser, error)) *SessionManager {
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	if max <= 0 {
+		max = DefaultMaxSessions
+	}
+	return &SessionManager{
+		max:      max,
+		create:   create,
+		dispose:  disposeSession,
+		ttl:      ttl,
+		sessions: make(map[string]*session),
+	}
+}
+
+// SetTTL changes how long sessions may stay idle
+func (s *SessionManager) SetTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	s.mu.Lock()
+	s.ttl = ttl
+	s.mu.Unlock()
+}
+
+// Create starts a new session
+func (s *SessionManager) Create() (*SessionInfo, error) {
+	s.mu.Lock()
+	if len(s.sessions)+s.pending >= s.max {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: limit of %d reached", ErrTooManySessions, s.max)
+	}
+	s.pending++
+	s.mu.Unlock()
+
+	context, err := s.create()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending--
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	now := time.Now()
+	sess := &session{
+		id:        "sess_" + uuid.New().String(),
+		context:   context,
+		createdAt: now,
+		lastUsed:  now,
+	}
+	s.sessions[sess.id] = sess
+	if !s.janitor {
+		s.janitor = true
+		go s.runJanitor()
+	}
+	return s.info(sess), nil
+}
+
+// Close disposes of a session. Pages still open in it are closed first.
+func (s *SessionManager) Close(id string) error {
+	s.mu.Lock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	delete(s.sessions, id)
+	sess.closed = true
+	idle := sess.inUse == 0
+	s.mu.Unlock()
+
+	if idle {
+		s.dispose(sess.context)
+	}
+	return nil
+}
+
+// CloseAll disposes of every session
+func (s *SessionManager) CloseAll() {
+	s.mu.Lock()
+	var idle []*session
+	for id, sess := range s.sessions {
+		delete(s.sessions, id)
+		sess.closed = true
+		if sess.inUse == 0 {
+			idle = append(idle, sess)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, sess := range idle {
+		s.dispose(sess.context)
+	}
+}
+
+// acquire returns the browser context of a session and marks it in use
+// until the returned func is called
+func (s *SessionManager) acquire(id string) (*rod.Browser, func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	sess.inUse++
+	sess.lastUsed = time.Now()
+
+	var once sync.Once
+	return sess.context, func() { once.Do(func() { s.release(sess) }) }, nil
+}
+
+func (s *SessionManager) release(sess *session) {
+	s.mu.Lock()
+	sess.inUse--
+	sess.lastUsed = time.Now()
+	dispose := sess.closed && sess.inUse == 0
+	s.mu.Unlock()
+
+	if dispose {
+		s.dispose(sess.context)
+	}
+}
+
+// evict closes the sessions idle since before now minus the TTL
+func (s *SessionManager) evict(now time.Time) {
+	s.mu.Lock()
+	var expired []*session
+	for id, sess := range s.sessions {
+		if sess.inUse == 0 && now.Sub(sess.lastUsed) > s.ttl {
+			delete(s.sessions, id)
+			sess.closed = true
+			expired = append(expired, sess)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, sess := range expired {
+		log.Printf("Closing session %s after %s idle", sess.id, now.Sub(sess.lastUsed).Round(time.Second))
+		s.dispose(sess.context)
+	}
+}
+
+// runJanitor evicts idle sessions periodically, stopping once none are left
+func (s *SessionManager) runJanitor() {
+	for {
+		s.mu.Lock()
+		interval := s.ttl / 2
+		s.mu.Unlock()
+		if interval < time.Second {
+			interval = time.Second
+		}
+		time.Sleep(interval)
+
+		s.evict(time.Now())
+
+		s.mu.Lock()
+		if len(s.sessions) == 0 {
+			s.janitor = false
+			s.mu.Unlock()
+			return
+		}
+		s.mu.Unlock()
+	}
+}
+
+// info describes sess; the caller holds s.mu
+func (s *SessionManager) info(sess *session) *SessionInfo {
+	return &SessionInfo{
+		ID:         sess.id,
+		CreatedAt:  sess.createdAt,
+		LastUsedAt: sess.lastUsed,
+		ExpiresAt:  sess.lastUsed.Add(s.ttl),
+	}
+}
+
+// disposeSession closes a session's browser context and its pages
+func disposeSession(context *rod.Browser) {
+	if err := context.Close(); err != nil {
+		log.Printf("Warning: failed to dispose session context: %v", err)
+	}
+}
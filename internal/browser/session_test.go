@@ -0,0 +1,119 @@
+package browser
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+// testSessions returns a session manager handing out placeholder contexts
+// and recording the disposed ones
+func testSessions(ttl time.Duration, max int) (*SessionManager, *[]*rod.Browser) {
+	disposed := &[]*rod.Browser{}
+	sessions := NewSessionManager(ttl, max, func() (*rod.Browser, error) {
+		return &rod.Browser{}, nil
+	})
+	sessions.dispose = func(context *rod.Browser) {
+		*disposed = append(*disposed, context)
+	}
+	return sessions, disposed
+}
+
+func TestSessionManagerReusesContext(t *testing.T) {
+	sessions, disposed := testSessions(time.Minute, 2)
+
+	info, err := sessions.Create()
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !info.ExpiresAt.Equal(info.LastUsedAt.Add(time.Minute)) {
+		t.Errorf("Expected the session to expire a TTL after its last use, got %+v", info)
+	}
+
+	first, release, err := sessions.acquire(info.ID)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	release()
+	second, release, _ := sessions.acquire(info.ID)
+	release()
+	if first != second {
+		t.Error("Expected every request in a session to share its context")
+	}
+
+	if err := sessions.Close(info.ID); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if len(*disposed) != 1 || (*disposed)[0] != first {
+		t.Errorf("Expected the context to be disposed, got %d disposed", len(*disposed))
+	}
+	if _, _, err := sessions.acquire(info.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound after close, got %v", err)
+	}
+	if err := sessions.Close(info.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound closing twice, got %v", err)
+	}
+}
+
+func TestSessionManagerLimit(t *testing.T) {
+	sessions, _ := testSessions(time.Minute, 1)
+
+	if _, err := sessions.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := sessions.Create(); !errors.Is(err, ErrTooManySessions) {
+		t.Errorf("Expected ErrTooManySessions, got %v", err)
+	}
+
+	failing := NewSessionManager(time.Minute, 1, func() (*rod.Browser, error) {
+		return nil, errors.New("browser gone")
+	})
+	if _, err := failing.Create(); err == nil {
+		t.Fatal("Expected the create error")
+	}
+	if failing.pending != 0 || len(failing.sessions) != 0 {
+		t.Error("Expected a failed create to free its slot")
+	}
+}
+
+func TestSessionManagerEvictsIdle(t *testing.T) {
+	sessions, disposed := testSessions(time.Minute, 3)
+
+	idle, _ := sessions.Create()
+	busy, _ := sessions.Create()
+	_, release, _ := sessions.acquire(busy.ID)
+
+	sessions.evict(time.Now().Add(2 * time.Minute))
+	if len(*disposed) != 1 {
+		t.Fatalf("Expected only the idle session to be evicted, got %d disposed", len(*disposed))
+	}
+	if _, _, err := sessions.acquire(idle.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected the idle session to be gone, got %v", err)
+	}
+
+	// Closing a session in use waits for its last page
+	if err := sessions.Close(busy.ID); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if len(*disposed) != 1 {
+		t.Error("Expected the busy session to stay open until released")
+	}
+	release()
+	release() // Releasing twice is harmless
+	if len(*disposed) != 2 {
+		t.Errorf("Expected the session to be disposed on release, got %d disposed", len(*disposed))
+	}
+}
+
+func TestSessionManagerCloseAll(t *testing.T) {
+	sessions, disposed := testSessions(time.Minute, 3)
+	sessions.Create()
+	sessions.Create()
+
+	sessions.CloseAll()
+	if len(*disposed) != 2 || len(sessions.sessions) != 0 {
+		t.Errorf("Expected every session to be disposed, got %d disposed", len(*disposed))
+	}
+}
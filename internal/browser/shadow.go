@@ -0,0 +1,152 @@
+package browser
+
+import (
+	"fmt"
+
+	"github.com/go-rod/rod"
+)
+
+// maxShadowDepth bounds how many nested shadow roots are walked
+const maxShadowDepth = 32
+
+// deepQueryScript defines deepQueryAll(selector), which matches selector in
+// the document and, recursively, in every open shadow root. Roots are
+// visited once, so a host re-parented into its own tree cannot loop.
+const deepQueryScript = `
+const deepQueryAll = (selector) => {
+	const seen = new WeakSet()
+	const found = []
+	const walk = (root, depth) => {
+		if (!root || seen.has(root) || depth > %[1]d) return
+		seen.add(root)
+		found.push(...root.querySelectorAll(selector))
+		for (const el of root.querySelectorAll('*')) {
+			if (el.shadowRoot) walk(el.shadowRoot, depth + 1)
+		}
+	}
+	walk(document, 0)
+	return found
+}`
+
+// deepHTMLScript defines deepHTML(node), which serializes node with each
+// open shadow root inlined as a declarative <template shadowrootmode="open">
+const deepHTMLScript = `
+const deepHTML = (node) => {
+	const seen = new WeakSet()
+	const raw = new Set(['SCRIPT', 'STYLE', 'NOSCRIPT', 'XMP', 'IFRAME', 'NOEMBED', 'NOFRAMES', 'PLAINTEXT'])
+	const escape = (s) => s.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;')
+	const children = (parent, depth) => Array.from(parent.childNodes).map(n => serialize(n, depth)).join('')
+	const serialize = (n, depth) => {
+		switch (n.nodeType) {
+		case Node.TEXT_NODE:
+			return n.parentNode && raw.has(n.parentNode.nodeName) ? n.data : escape(n.data)
+		case Node.COMMENT_NODE:
+			return '<!--' + n.data + '-->'
+		case Node.ELEMENT_NODE: {
+			const shell = n.cloneNode(false).outerHTML
+			const close = shell.lastIndexOf('</')
+			if (close < 0) return shell
+			let inner = ''
+			if (n.shadowRoot && !seen.has(n.shadowRoot) && depth < %[1]d) {
+				seen.add(n.shadowRoot)
+				inner += '<template shadowrootmode="open">' + children(n.shadowRoot, depth + 1) + '</template>'
+			}
+			inner += n.nodeName === 'TEMPLATE' ? children(n.content, depth) : children(n, depth)
+			return shell.slice(0, close) + inner + shell.slice(close)
+		}
+		default:
+			return ''
+		}
+	}
+	return serialize(node, 0)
+}`
+
+// deepTextScript defines deepText(), the page's visible text followed by the
+// text rendered inside each open shadow root
+const deepTextScript = `
+const deepText = () => {
+	const seen = new WeakSet()
+	const parts = [document.body ? document.body.innerText : '']
+	const walk = (root, depth) => {
+		for (const el of root.querySelectorAll('*')) {
+			const shadow = el.shadowRoot
+			if (!shadow || seen.has(shadow) || depth > %[1]d) continue
+			seen.add(shadow)
+			for (const child of shadow.children) {
+				if (child.nodeName === 'STYLE' || child.nodeName === 'SCRIPT') continue
+				const text = (child.innerText || '').trim()
+				if (text) parts.push(text)
+			}
+			walk(shadow, depth + 1)
+		}
+	}
+	walk(document, 0)
+	return parts.join('\n')
+}`
+
+// shadowJS returns a helper definition followed by the function expression
+// body, bound to maxShadowDepth
+func shadowJS(helper string, body string) string {
+	return fmt.Sprintf(`function(...args) {`+helper+`
+	return (`+body+`)(...args)
+}`, maxShadowDepth)
+}
+
+// queryElement returns the first element matching selector, searching open
+// shadow roots when pierce is set. Like page.Element it retries until the
+// element appears or the page context ends.
+func queryElement(page *rod.Page, selector string, pierce bool) (*rod.Element, error) {
+	if !pierce {
+		return page.Element(selector)
+	}
+	return page.ElementByJS(rod.Eval(shadowJS(deepQueryScript, `(s) => deepQueryAll(s)[0] || null`), selector))
+}
+
+// queryElements returns all elements matching selector, including those in
+// open shadow roots when pierce is set
+func queryElements(page *rod.Page, selector string, pierce bool) (rod.Elements, error) {
+	if !pierce {
+		return page.Elements(selector)
+	}
+	return page.ElementsByJS(rod.Eval(shadowJS(deepQueryScript, `(s) => deepQueryAll(s)`), selector))
+}
+
+// pageHTML returns the document HTML, with open shadow roots inlined as
+// declarative shadow DOM templates when pierce is set
+func pageHTML(page *rod.Page, pierce bool) (string, error) {
+	if !pierce {
+		return page.HTML()
+	}
+	res, err := page.Eval(shadowJS(deepHTMLScript, `() => deepHTML(document.documentElement)`))
+	if err != nil {
+		return "", err
+	}
+	return res.Value.Str(), nil
+}
+
+// elementHTML returns the element's outer HTML, inlining open shadow roots
+// when pierce is set
+func elementHTML(element *rod.Element, pierce bool) (string, error) {
+	if !pierce {
+		return element.HTML()
+	}
+	res, err := element.Eval(shadowJS(deepHTMLScript, `function() { return deepHTML(this) }`))
+	if err != nil {
+		return "", err
+	}
+	return res.Value.Str(), nil
+}
+
+// pageText returns the page's visible text, including text rendered in open
+// shadow roots when pierce is set
+func pageText(page *rod.Page, pierce bool) (string, error) {
+	script := `() => document.body.innerText`
+	if pierce {
+		script = shadowJS(deepTextScript, `() => deepText()`)
+	}
+	res, err := page.Eval(script)
+	if err != nil {
+		return "", err
+	}
+	return res.Value.Str(), nil
+}
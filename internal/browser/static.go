@@ -0,0 +1,138 @@
+package browser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxStaticBytes bounds the response body read by FetchStatic
+const maxStaticBytes = 10 << 20
+
+// FetchStatic fetches a page with a plain HTTP GET instead of a browser.
+// No JavaScript runs, so the result is the HTML as served; title, text and
+// links are parsed from it. Headers, cookies, user agent, accept language,
+// proxy and timeout from opts are honored; browser-only options are ignored.
+func FetchStatic(ctx context.Context, targetURL string, opts PageOptions) (*PageResult, error) {
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	for key, value := range opts.Headers {
+		req.Header.Set(key, value)
+	}
+	if opts.UserAgent != "" {
+		req.Header.Set("User-Agent", opts.UserAgent)
+	}
+	if opts.AcceptLanguage != "" {
+		req.Header.Set("Accept-Language", opts.AcceptLanguage)
+	}
+	for _, cookie := range opts.Cookies {
+		req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	if opts.BasicAuth != nil {
+		if err := opts.BasicAuth.Validate(); err != nil {
+			return nil, err
+		}
+		// Not resent when a redirect leaves the host
+		req.SetBasicAuth(opts.BasicAuth.Username, opts.BasicAuth.Password)
+	}
+
+	client := http.DefaultClient
+	if opts.Proxy != "" {
+		proxyURL, err := parseProxy(opts.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		client = &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxStaticBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read page: %w", err)
+	}
+
+	result := &PageResult{
+		URL:          resp.Request.URL.String(),
+		FinalURL:     resp.Request.URL.String(),
+		RequestedURL: targetURL,
+		HTML:         string(body),
+		StatusCode:   resp.StatusCode,
+		Headers:      make(map[string]string, len(resp.Header)),
+	}
+	for key := range resp.Header {
+		result.Headers[key] = resp.Header.Get(key)
+	}
+	if opts.IncludeCookies {
+		// Only the final response's Set-Cookie headers; there is no cookie jar
+		result.Cookies = make([]CookieInfo, 0)
+		for _, cookie := range resp.Cookies() {
+			info := CookieInfo{
+				Name:     cookie.Name,
+				Value:    cookie.Value,
+				Domain:   cookie.Domain,
+				Path:     cookie.Path,
+				HTTPOnly: cookie.HttpOnly,
+				Secure:   cookie.Secure,
+			}
+			if !cookie.Expires.IsZero() {
+				info.Expires = cookie.Expires.Unix()
+			}
+			result.Cookies = append(result.Cookies, info)
+		}
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		// Not HTML; the raw body is still useful
+		return result, nil
+	}
+
+	result.Title = strings.TrimSpace(doc.Find("title").First().Text())
+	doc.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		ref, err := url.Parse(strings.TrimSpace(href))
+		if err != nil {
+			return
+		}
+		link := resp.Request.URL.ResolveReference(ref)
+		if link.Scheme == "http" || link.Scheme == "https" {
+			result.Links = append(result.Links, link.String())
+		}
+	})
+	doc.Find("script, style, noscript, template").Remove()
+	result.Text = strings.Join(textNodes(doc.Find("body"), nil), " ")
+
+	return result, nil
+}
+
+// textNodes appends the non-blank text nodes under sel in document order.
+// goquery's Text concatenates adjacent blocks without a separator.
+func textNodes(sel *goquery.Selection, parts []string) []string {
+	sel.Contents().Each(func(_ int, child *goquery.Selection) {
+		if goquery.NodeName(child) != "#text" {
+			parts = textNodes(child, parts)
+			return
+		}
+		if text := strings.Join(strings.Fields(child.Text()), " "); text != "" {
+			parts = append(parts, text)
+		}
+	})
+	return parts
+}
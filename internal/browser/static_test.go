@@ -0,0 +1,113 @@
+package browser
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchStatic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "scrq-test" || r.Header.Get("X-Extra") != "1" {
+			t.Errorf("Expected custom headers, got %v", r.Header)
+		}
+		if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "abc" {
+			t.Errorf("Expected session cookie, got %v", r.Cookies())
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = io.WriteString(w, `<html><head><title> Static </title><script>var x = 1</script></head>
+<body><h1>Hello</h1><p>world</p><a href="/next">next</a><a href="mailto:a@b.c">mail</a></body></html>`)
+	}))
+	defer server.Close()
+
+	opts := DefaultPageOptions()
+	opts.UserAgent = "scrq-test"
+	opts.Headers = map[string]string{"X-Extra": "1"}
+	opts.Cookies = []CookieParam{{Name: "session", Value: "abc"}}
+
+	result, err := FetchStatic(context.Background(), server.URL, opts)
+	if err != nil {
+		t.Fatalf("FetchStatic failed: %v", err)
+	}
+	if result.Title != "Static" || result.Text != "Hello world next mail" || result.StatusCode != http.StatusOK {
+		t.Errorf("Unexpected result: title=%q text=%q status=%d", result.Title, result.Text, result.StatusCode)
+	}
+	if len(result.Links) != 1 || result.Links[0] != server.URL+"/next" {
+		t.Errorf("Expected one resolved http link, got %v", result.Links)
+	}
+}
+
+func TestFetchStaticRedirect(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/missing", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Served-By", "test")
+		http.NotFound(w, r)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	result, err := FetchStatic(context.Background(), server.URL+"/old", DefaultPageOptions())
+	if err != nil {
+		t.Fatalf("FetchStatic failed: %v", err)
+	}
+	if result.StatusCode != http.StatusNotFound || result.FinalURL != server.URL+"/missing" {
+		t.Errorf("Expected 404 at the redirect target, got %d at %q", result.StatusCode, result.FinalURL)
+	}
+	if result.URL != server.URL+"/missing" || result.RequestedURL != server.URL+"/old" {
+		t.Errorf("Expected the landed and requested URLs, got %q and %q", result.URL, result.RequestedURL)
+	}
+	if result.Headers["X-Served-By"] != "test" {
+		t.Errorf("Expected response headers, got %v", result.Headers)
+	}
+}
+
+func TestPageResultProject(t *testing.T) {
+	result := &PageResult{URL: "https://example.com", Title: "Example", HTML: "<html></html>", Text: "hi"}
+
+	projected, err := result.Project([]string{"title", "text"})
+	if err != nil {
+		t.Fatalf("Project failed: %v", err)
+	}
+	fields := projected.(map[string]interface{})
+	if len(fields) != 2 || fields["title"] != "Example" || fields["text"] != "hi" {
+		t.Errorf("Unexpected projection: %v", fields)
+	}
+
+	if all, _ := result.Project(nil); all != result {
+		t.Errorf("Expected no fields to keep the result unchanged")
+	}
+	if err := ValidateResultFields([]string{"html", "bogus"}); err == nil {
+		t.Errorf("Expected unknown field to be rejected")
+	}
+}
+
+func TestFetchStaticIncludeCookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "xyz", Path: "/", HttpOnly: true})
+		_, _ = io.WriteString(w, "<title>Login</title>")
+	}))
+	defer server.Close()
+
+	opts := DefaultPageOptions()
+	result, err := FetchStatic(context.Background(), server.URL, opts)
+	if err != nil {
+		t.Fatalf("FetchStatic failed: %v", err)
+	}
+	if result.Cookies != nil {
+		t.Errorf("Expected no cookies unless requested, got %v", result.Cookies)
+	}
+
+	opts.IncludeCookies = true
+	result, err = FetchStatic(context.Background(), server.URL, opts)
+	if err != nil {
+		t.Fatalf("FetchStatic failed: %v", err)
+	}
+	if len(result.Cookies) != 1 || result.Cookies[0].Name != "session" || !result.Cookies[0].HTTPOnly {
+		t.Errorf("Unexpected cookies: %+v", result.Cookies)
+	}
+}
@@ -0,0 +1,91 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+)
+
+// maxTableColspan bounds how often one cell is repeated for its colspan
+const maxTableColspan = 100
+
+// Table is an HTML table as text cells. Every row has as many cells as
+// Headers; short rows are padded with empty strings.
+type Table struct {
+	Caption string     `json:"caption,omitempty"`
+	Headers []string   `json:"headers"`
+	Rows    [][]string `json:"rows"`
+}
+
+// tablesScript returns the text cells of each table matching the selector
+// (all tables when empty). A cell spanning several columns is repeated so
+// the columns line up; nested tables are returned separately.
+const tablesScript = `(selector, maxSpan) => {
+	const tables = Array.from(document.querySelectorAll(selector || 'table')).filter(t => t.tagName === 'TABLE');
+	const text = el => (el.innerText || el.textContent || '').replace(/\s+/g, ' ').trim();
+	return tables.map(t => ({
+		caption: t.caption ? text(t.caption) : '',
+		rows: Array.from(t.rows).map(r => Array.from(r.cells).flatMap(c =>
+			Array(Math.min(Math.max(c.colSpan || 1, 1), maxSpan)).fill(text(c)))),
+	}));
+}`
+
+// rawTable is what tablesScript returns per table
+type rawTable struct {
+	Caption string     `json:"caption"`
+	Rows    [][]string `json:"rows"`
+}
+
+func extractTables(opener pageOpener, ctx context.Context, url string, selector string, opts PageOptions) ([]Table, error) {
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	page, cleanup, err := opener.OpenPage(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	res, err := page.Eval(tablesScript, selector, maxTableColspan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract tables: %w", err)
+	}
+
+	var raw []rawTable
+	if err := res.Value.Unmarshal(&raw); err != nil {
+		return nil, fmt.Errorf("failed to read tables: %w", err)
+	}
+	if selector != "" && len(raw) == 0 {
+		return nil, fmt.Errorf("%w: no table matches %s", ErrElementNotFound, selector)
+	}
+
+	tables := make([]Table, 0, len(raw))
+	for _, table := range raw {
+		tables = append(tables, normalizeTable(table))
+	}
+	return tables, nil
+}
+
+// normalizeTable takes the first row as the headers, in <thead> when the
+// table has one, and pads every row to the widest one
+func normalizeTable(raw rawTable) Table {
+	table := Table{Caption: raw.Caption, Headers: []string{}, Rows: [][]string{}}
+	if len(raw.Rows) == 0 {
+		return table
+	}
+
+	width := 0
+	for _, row := range raw.Rows {
+		width = max(width, len(row))
+	}
+	pad := func(row []string) []string {
+		padded := make([]string, width)
+		copy(padded, row)
+		return padded
+	}
+
+	table.Headers = pad(raw.Rows[0])
+	for _, row := range raw.Rows[1:] {
+		table.Rows = append(table.Rows, pad(row))
+	}
+	return table
+}
@@ -0,0 +1,36 @@
+package browser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTable(t *testing.T) {
+	table := normalizeTable(rawTable{
+		Caption: "Standings",
+		Rows: [][]string{
+			{"Team", "Played", "Points"},
+			{"Reds", "10", "24", "*"},
+			{"Blues"},
+			{},
+		},
+	})
+
+	want := Table{
+		Caption: "Standings",
+		Headers: []string{"Team", "Played", "Points", ""},
+		Rows: [][]string{
+			{"Reds", "10", "24", "*"},
+			{"Blues", "", "", ""},
+			{"", "", "", ""},
+		},
+	}
+	if !reflect.DeepEqual(table, want) {
+		t.Errorf("Expected %+v, got %+v", want, table)
+	}
+
+	empty := normalizeTable(rawTable{})
+	if empty.Headers == nil || empty.Rows == nil || len(empty.Headers)+len(empty.Rows) != 0 {
+		t.Errorf("Expected empty, non-nil headers and rows, got %+v", empty)
+	}
+}
@@ -0,0 +1,153 @@
+package browser
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+// usageTTL is how long a resource usage sample is reused before the
+// process tree is scanned again
+const usageTTL = 10 * time.Second
+
+// clockTicks is the USER_HZ the kernel reports CPU times in
+const clockTicks = 100
+
+// ResourceUsage is a snapshot of a browser's process tree and open pages
+type ResourceUsage struct {
+	PID        int     `json:"pid"`
+	Processes  int     `json:"processes"`   // Browser process plus its children (renderers, GPU, ...)
+	RSSBytes   uint64  `json:"rss_bytes"`   // Resident memory of the whole tree
+	CPUSeconds float64 `json:"cpu_seconds"` // User + system CPU time of the whole tree
+	OpenPages  int     `json:"open_pages"`
+	SampledAt  int64   `json:"sampled_at"`
+
+	MemoryRestarts int64 `json:"memory_restarts"` // Restarts by the memory watchdog
+	PagesServed    int64 `json:"pages_served"`    // Pages opened since the last watchdog restart
+	PageRecycles   int64 `json:"page_recycles"`   // Restarts after serving --browser-max-pages pages
+
+	Pool *PoolStats `json:"pool,omitempty"` // Warm page pool, when enabled
+}
+
+// usageCache reuses the last sample for usageTTL so frequent stats polling
+// stays cheap
+type usageCache struct {
+	mu     sync.Mutex
+	sample *ResourceUsage
+}
+
+// get returns the cached sample or collects a new one
+func (c *usageCache) get(pid int, browser *rod.Browser) (*ResourceUsage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sample != nil && c.sample.PID == pid && time.Since(time.Unix(c.sample.SampledAt, 0)) < usageTTL {
+		return c.sample, nil
+	}
+
+	usage, err := collectUsage(pid, browser)
+	if err != nil {
+		return nil, err
+	}
+	c.sample = usage
+	return usage, nil
+}
+
+// collectUsage sums memory and CPU over the process tree rooted at pid and
+// counts the browser's open pages
+func collectUsage(pid int, browser *rod.Browser) (*ResourceUsage, error) {
+	if pid <= 0 {
+		return nil, fmt.Errorf("browser process is not running")
+	}
+
+	usage := &ResourceUsage{PID: pid, SampledAt: time.Now().Unix()}
+	for _, p := range processTree(pid) {
+		rss, cpu, err := processStats(p)
+		if err != nil {
+			// The process may have exited since the scan
+			continue
+		}
+		usage.Processes++
+		usage.RSSBytes += rss
+		usage.CPUSeconds += cpu
+	}
+	if usage.Processes == 0 {
+		return nil, fmt.Errorf("no process stats for pid %d (requires /proc)", pid)
+	}
+
+	if browser != nil {
+		if pages, err := browser.Pages(); err == nil {
+			usage.OpenPages = len(pages)
+		}
+	}
+
+	return usage, nil
+}
+
+// processTree returns root and all its descendants from /proc
+func processTree(root int) []int {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return []int{root}
+	}
+
+	children := map[int][]int{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		fields, err := statFields(pid)
+		if err != nil || len(fields) < 2 {
+			continue
+		}
+		if ppid, err := strconv.Atoi(fields[1]); err == nil {
+			children[ppid] = append(children[ppid], pid)
+		}
+	}
+
+	tree := []int{root}
+	for i := 0; i < len(tree); i++ {
+		tree = append(tree, children[tree[i]]...)
+	}
+	return tree
+}
+
+// processStats returns the resident memory and CPU time of one process
+func processStats(pid int) (uint64, float64, error) {
+	fields, err := statFields(pid)
+	if err != nil {
+		return 0, 0, err
+	}
+	// Fields after the command name: state(0) ppid(1) ... utime(11) stime(12) ... rss(21)
+	if len(fields) < 22 {
+		return 0, 0, fmt.Errorf("short stat for pid %d", pid)
+	}
+
+	utime, _ := strconv.ParseFloat(fields[11], 64)
+	stime, _ := strconv.ParseFloat(fields[12], 64)
+	rssPages, _ := strconv.ParseUint(fields[21], 10, 64)
+
+	return rssPages * uint64(os.Getpagesize()), (utime + stime) / clockTicks, nil
+}
+
+// statFields reads /proc/<pid>/stat and returns the fields after the
+// parenthesized command name, which may itself contain spaces
+func statFields(pid int) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return nil, err
+	}
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return nil, fmt.Errorf("malformed stat for pid %d", pid)
+	}
+	return strings.Fields(string(data[end+1:])), nil
+}
@@ -0,0 +1,25 @@
+package browser
+
+import (
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestCollectUsage(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("process stats are read from /proc")
+	}
+
+	usage, err := collectUsage(os.Getpid(), nil)
+	if err != nil {
+		t.Fatalf("Failed to collect usage: %v", err)
+	}
+	if usage.Processes < 1 || usage.RSSBytes == 0 {
+		t.Errorf("Expected own process to be measured, got %+v", usage)
+	}
+
+	if _, err := collectUsage(0, nil); err == nil {
+		t.Error("Expected an error without a process")
+	}
+}
@@ -0,0 +1,122 @@
+package browser
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+// idlePollInterval is how often a pending restart checks for open pages
+const idlePollInterval = time.Second
+
+// browserWatchdog restarts a browser whose process tree grows past a memory
+// limit or that has served a set number of pages. Restarts wait until no
+// page is open so running work is not interrupted.
+type browserWatchdog struct {
+	active   atomic.Int64 // pages currently open through OpenPage
+	served   atomic.Int64 // pages opened since the last watchdog restart
+	restarts atomic.Int64 // restarts triggered by the memory limit
+	recycles atomic.Int64 // restarts triggered by the page limit
+}
+
+// track counts an opened page as active until its cleanup runs
+func (w *browserWatchdog) track(page *rod.Page, cleanup func(), err error) (*rod.Page, func(), error) {
+	if err != nil {
+		return page, cleanup, err
+	}
+
+	w.served.Add(1)
+	w.active.Add(1)
+	var done atomic.Bool
+	return page, func() {
+		cleanup()
+		if done.CompareAndSwap(false, true) {
+			w.active.Add(-1)
+		}
+	}, nil
+}
+
+// start checks usage every interval and, once RSS exceeds limit, restarts
+// the browser as soon as it is idle. The returned func stops the watchdog.
+func (w *browserWatchdog) start(name string, limit uint64, interval time.Duration, usage func() (*ResourceUsage, error), restart func() error) func() {
+	stop := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			u, err := usage()
+			if err != nil || u.RSSBytes <= limit {
+				continue
+			}
+			log.Printf("%s RSS %d MB exceeds limit of %d MB, restarting once idle", name, u.RSSBytes>>20, limit>>20)
+
+			if !w.waitIdle(stop) {
+				return
+			}
+			if err := restart(); err != nil {
+				log.Printf("Failed to restart %s after memory limit: %v", name, err)
+				continue
+			}
+			w.served.Store(0)
+			log.Printf("%s restarted after exceeding memory limit (%d restarts)", name, w.restarts.Add(1))
+		}
+	}()
+
+	return func() { close(stop) }
+}
+
+// startRecycle restarts the browser, once idle, after it has served
+// maxPages pages. The returned func stops the recycler.
+func (w *browserWatchdog) startRecycle(name string, maxPages int64, restart func() error) func() {
+	stop := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(idlePollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			if w.served.Load() < maxPages || w.active.Load() > 0 {
+				continue
+			}
+			if err := restart(); err != nil {
+				log.Printf("Failed to recycle %s after %d pages: %v", name, maxPages, err)
+				continue
+			}
+			w.served.Store(0)
+			log.Printf("%s recycled after serving %d pages (%d recycles)", name, maxPages, w.recycles.Add(1))
+		}
+	}()
+
+	return func() { close(stop) }
+}
+
+// waitIdle blocks until no page is open. It returns false if stopped first.
+func (w *browserWatchdog) waitIdle(stop <-chan struct{}) bool {
+	ticker := time.NewTicker(idlePollInterval)
+	defer ticker.Stop()
+
+	for w.active.Load() > 0 {
+		select {
+		case <-stop:
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
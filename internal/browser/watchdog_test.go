@@ -0,0 +1,72 @@
+package browser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryWatchdogRestartsWhenIdle(t *testing.T) {
+	var w browserWatchdog
+
+	// An open page delays the restart
+	_, cleanup, _ := w.track(nil, noopCleanup, nil)
+
+	restarted := make(chan struct{}, 1)
+	stop := w.start("test", 100, 5*time.Millisecond, func() (*ResourceUsage, error) {
+		return &ResourceUsage{RSSBytes: 200}, nil
+	}, func() error {
+		restarted <- struct{}{}
+		return nil
+	})
+	defer stop()
+
+	select {
+	case <-restarted:
+		t.Fatal("Expected no restart while a page is open")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cleanup()
+	cleanup() // repeated cleanup must not double count
+
+	select {
+	case <-restarted:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Expected a restart once idle")
+	}
+	if w.active.Load() != 0 {
+		t.Errorf("Expected no active pages, got %d", w.active.Load())
+	}
+}
+
+func TestPageRecyclerRestartsAfterMaxPages(t *testing.T) {
+	var w browserWatchdog
+
+	restarted := make(chan struct{}, 1)
+	stop := w.startRecycle("test", 2, func() error {
+		restarted <- struct{}{}
+		return nil
+	})
+	defer stop()
+
+	_, first, _ := w.track(nil, noopCleanup, nil)
+	first()
+	_, second, _ := w.track(nil, noopCleanup, nil)
+
+	select {
+	case <-restarted:
+		t.Fatal("Expected no recycle while a page is open")
+	case <-time.After(1500 * time.Millisecond):
+	}
+
+	second()
+	select {
+	case <-restarted:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Expected a recycle once idle")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if w.served.Load() != 0 || w.recycles.Load() != 1 {
+		t.Errorf("Expected counter reset and one recycle, got served=%d recycles=%d", w.served.Load(), w.recycles.Load())
+	}
+}
@@ -4,12 +4,21 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 )
 
-const (
+// Build information, injected via -ldflags "-X ...".
+var (
 	// Version is the current version of Scrq
 	Version = "1"
+	// Commit is the git commit the binary was built from
+	Commit = ""
+	// BuildDate is the time the binary was built
+	BuildDate = ""
+)
+
+const (
 	// AppName is the application name
 	AppName = "Scrq Server"
 )
@@ -21,13 +30,35 @@ type Config struct {
 	Port    int
 	BaseURL string // Full base URL for API responses (e.g., http://localhost:8000)
 
+	PathPrefix string // Path a gateway adds in front of /scrq in client-facing URLs (e.g., /scraper)
+
+	ShutdownTimeout time.Duration // Force exit if graceful shutdown takes longer
+	DrainTimeout    time.Duration // Requeue jobs still running this long into shutdown
+
+	MaxBodyBytes int // Larger request bodies are rejected with 413
+
+	Compress bool // Compress /scrq responses for clients sending Accept-Encoding
+
 	// Browser (Lightpanda CDP)
 	BrowserHost string
 	BrowserPort int
 
 	// Chrome
-	WithChrome     bool
-	ChromeRevision int
+	WithChrome        bool
+	ChromeRevision    int
+	ChromePoolSize    int           // Warm pages kept for reuse; 0 disables the pool
+	ChromePoolMaxIdle time.Duration // Close pooled pages idle longer than this
+	ChromeSessionTTL  time.Duration // Close browser sessions idle longer than this
+
+	// Memory watchdog (restart a browser once idle when over the limit)
+	BrowserMaxRSS      int           // Megabytes; 0 disables the watchdog
+	BrowserMemoryCheck time.Duration // How often browser memory is checked
+	BrowserMaxPages    int           // Recycle a browser once idle after this many pages; 0 disables
+
+	// Page limits (shared by Lightpanda and Chrome)
+	MaxConcurrentPages int           // 0 means unlimited
+	PageWaitTimeout    time.Duration // Max wait for a free page slot before 503
+	MaxBatchPages      int           // Pages all sync batch scrapes may have in flight together (0 = unlimited)
 
 	// Queue (NATS JetStream)
 	WithNats   bool
@@ -35,6 +66,17 @@ type Config struct {
 	NatsStore  string
 	NatsAutoDL bool
 	NatsBin    string
+	NatsConfig string
+
+	EventBufferSize int // Per-subscriber SSE/WebSocket event buffer
+	NatsReplicas    int // JetStream stream replicas (external clusters only)
+	Workers         int // Jobs processed at once
+
+	QueueMaxAge     time.Duration // Queued jobs not picked up by then are dropped
+	QueueMaxMsgs    int64         // Most messages in the job stream (0 = unlimited)
+	QueueMaxBytes   int64         // Most bytes in the job stream (0 = unlimited)
+	QueueStorage    string        // Job stream, job store and dead letter storage: file or memory
+	QueueMaxDeliver int           // Deliveries of a job message before JetStream gives up
 
 	// Security
 	RateLimitRequests int           // requests per window
@@ -42,9 +84,30 @@ type Config struct {
 	IdempotencyTTL    time.Duration // TTL for idempotency keys
 	ResultTTL         time.Duration // TTL for job results
 	MaxJobTimeout     time.Duration // Maximum allowed job timeout
+	MaxSyncTimeout    time.Duration // Maximum page timeout on synchronous endpoints
 	MaxRetries        int           // Maximum retries per job
+	AdminToken        string        // Token required for admin endpoints (disabled if empty)
+	AllowedIPs        []string      // Client IPs or CIDR ranges allowed on the job routes (all if empty)
+	TrustProxy        bool          // Take the client IP from X-Forwarded-For for AllowedIPs
+
+	RouteContentTypes []string // "prefix=type" entries accepted besides JSON on routes under prefix
+
+	RequireIdempotencyKey bool          // Reject job submissions without an idempotency key
+	ContentDedupWindow    time.Duration // Dedupe identical keyless job submissions within this window (0 = disabled)
+
+	// Polling
+	PollInterval time.Duration // Status polling interval suggested to clients
+	SSEKeepalive time.Duration // Keepalive comment interval on idle SSE streams
+	WSPing       time.Duration // Ping interval on job WebSockets
+
+	// Recipes
+	RecipesFile string // JSON file of named extraction recipes loaded at startup
+
+	// Tracing
+	OTLPEndpoint string // OTLP/HTTP collector receiving trace spans (disabled if empty)
 
 	// Flags
+	ConfigFile  string // YAML or JSON file loaded before flags are applied
 	ShowVersion bool
 	ShowHelp    bool
 }
@@ -52,68 +115,181 @@ type Config struct {
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	return &Config{
-		Host:              "0.0.0.0",
-		Port:              8000,
-		BaseURL:           "", // Will be auto-generated if empty
-		BrowserHost:       "127.0.0.1",
-		BrowserPort:       9222,
-		WithChrome:        false,
-		ChromeRevision:    0,
-		WithNats:          true,
-		NatsURL:           "nats://127.0.0.1:4222",
-		NatsStore:         "./data/nats",
-		NatsAutoDL:        true,
-		NatsBin:           "./bin/nats-server",
-		RateLimitRequests: 100,
-		RateLimitWindow:   time.Minute,
-		IdempotencyTTL:    24 * time.Hour,
-		ResultTTL:         7 * 24 * time.Hour, // 7 days
-		MaxJobTimeout:     5 * time.Minute,
-		MaxRetries:        5,
-		ShowVersion:       false,
-		ShowHelp:          false,
+		Host:               "0.0.0.0",
+		Port:               8000,
+		BaseURL:            "", // Will be auto-generated if empty
+		ShutdownTimeout:    30 * time.Second,
+		DrainTimeout:       20 * time.Second,
+		MaxBodyBytes:       10 * 1024 * 1024,
+		Compress:           true,
+		BrowserHost:        "127.0.0.1",
+		BrowserPort:        9222,
+		WithChrome:         false,
+		ChromeRevision:     0,
+		ChromePoolMaxIdle:  5 * time.Minute,
+		ChromeSessionTTL:   10 * time.Minute,
+		PageWaitTimeout:    10 * time.Second,
+		MaxBatchPages:      20,
+		BrowserMemoryCheck: 30 * time.Second,
+		WithNats:           true,
+		NatsURL:            "nats://127.0.0.1:4222",
+		NatsStore:          "./data/nats",
+		NatsAutoDL:         true,
+		NatsBin:            "./bin/nats-server",
+		EventBufferSize:    32,
+		NatsReplicas:       1,
+		Workers:            1,
+		QueueMaxAge:        24 * time.Hour,
+		QueueStorage:       "file",
+		QueueMaxDeliver:    3,
+		RateLimitRequests:  100,
+		RateLimitWindow:    time.Minute,
+		IdempotencyTTL:     24 * time.Hour,
+		ResultTTL:          7 * 24 * time.Hour, // 7 days
+		MaxJobTimeout:      5 * time.Minute,
+		MaxSyncTimeout:     2 * time.Minute,
+		MaxRetries:         5,
+		PollInterval:       2 * time.Second,
+		SSEKeepalive:       15 * time.Second,
+		WSPing:             30 * time.Second,
+		ShowVersion:        false,
+		ShowHelp:           false,
 	}
 }
 
-// ParseFlags parses command line flags and returns the config
+// ParseFlags parses command line flags and returns the config. With
+// --config the file is loaded first and the flags given on the command line
+// are applied on top of it.
 func ParseFlags() *Config {
 	cfg := DefaultConfig()
+	registerFlags(flag.CommandLine, cfg)
+
+	// Custom usage function
+	flag.Usage = func() {
+		PrintHelp()
+	}
+
+	flag.Parse()
+
+	if cfg.ConfigFile != "" {
+		fileCfg, err := LoadFile(cfg.ConfigFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+			os.Exit(2)
+		}
 
+		// Flags win over the file
+		overrides := flag.NewFlagSet("overrides", flag.ContinueOnError)
+		registerFlags(overrides, fileCfg)
+		flag.Visit(func(f *flag.Flag) {
+			_ = overrides.Set(f.Name, f.Value.String())
+		})
+		cfg = fileCfg
+	}
+
+	normalize(cfg)
+	return cfg
+}
+
+// registerFlags defines every command line flag on fs, bound to cfg
+func registerFlags(fs *flag.FlagSet, cfg *Config) {
 	// Server flags
-	flag.StringVar(&cfg.Host, "host", cfg.Host, "Host address to bind the server")
-	flag.IntVar(&cfg.Port, "port", cfg.Port, "Port number for the server")
-	flag.StringVar(&cfg.BaseURL, "base-url", cfg.BaseURL, "Base URL for API responses (e.g., http://localhost:8000)")
+	fs.StringVar(&cfg.Host, "host", cfg.Host, "Host address to bind the server")
+	fs.IntVar(&cfg.Port, "port", cfg.Port, "Port number for the server")
+	fs.StringVar(&cfg.BaseURL, "base-url", cfg.BaseURL, "Base URL for API responses (e.g., http://localhost:8000)")
+	fs.StringVar(&cfg.PathPrefix, "path-prefix", cfg.PathPrefix, "Path prefix added by a gateway in front of /scrq in returned URLs (e.g., /scraper)")
+	fs.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "Maximum time to drain jobs and close connections before forcing exit")
+	fs.DurationVar(&cfg.DrainTimeout, "drain-timeout", cfg.DrainTimeout, "Grace period for running jobs on shutdown before they are requeued")
+	fs.IntVar(&cfg.MaxBodyBytes, "max-body-bytes", cfg.MaxBodyBytes, "Maximum request body size in bytes")
+	fs.BoolVar(&cfg.Compress, "compress", cfg.Compress, "Compress responses for clients sending Accept-Encoding (gzip, deflate or br)")
 
 	// Browser flags
-	flag.StringVar(&cfg.BrowserHost, "browser-host", cfg.BrowserHost, "Lightpanda browser CDP host")
-	flag.IntVar(&cfg.BrowserPort, "browser-port", cfg.BrowserPort, "Lightpanda browser CDP port")
+	fs.StringVar(&cfg.BrowserHost, "browser-host", cfg.BrowserHost, "Lightpanda browser CDP host")
+	fs.IntVar(&cfg.BrowserPort, "browser-port", cfg.BrowserPort, "Lightpanda browser CDP port")
 
 	// Chrome flags
-	flag.BoolVar(&cfg.WithChrome, "with-chrome", cfg.WithChrome, "Download Chrome and enable Chrome-backed endpoints")
-	flag.IntVar(&cfg.ChromeRevision, "chrome-revision", cfg.ChromeRevision, "Chromium revision to download (0 uses default)")
+	fs.BoolVar(&cfg.WithChrome, "with-chrome", cfg.WithChrome, "Download Chrome and enable Chrome-backed endpoints")
+	fs.IntVar(&cfg.ChromeRevision, "chrome-revision", cfg.ChromeRevision, "Chromium revision to download (0 uses default)")
+	fs.IntVar(&cfg.ChromePoolSize, "chrome-pool-size", cfg.ChromePoolSize, "Warm Chrome pages kept for reuse between requests (0 = no pool)")
+	fs.DurationVar(&cfg.ChromePoolMaxIdle, "chrome-pool-max-idle", cfg.ChromePoolMaxIdle, "Close pooled Chrome pages idle longer than this")
+	fs.DurationVar(&cfg.ChromeSessionTTL, "chrome-session-ttl", cfg.ChromeSessionTTL, "Close Chrome browser sessions idle longer than this")
+
+	// Memory watchdog flags
+	fs.IntVar(&cfg.BrowserMaxRSS, "browser-max-rss", cfg.BrowserMaxRSS, "Restart a browser once idle when its memory exceeds this many MB (0 = never)")
+	fs.DurationVar(&cfg.BrowserMemoryCheck, "browser-memory-check", cfg.BrowserMemoryCheck, "How often browser memory is checked against --browser-max-rss")
+	fs.IntVar(&cfg.BrowserMaxPages, "browser-max-pages", cfg.BrowserMaxPages, "Restart a browser once idle after it has served this many pages (0 = never)")
+
+	// Page limit flags
+	fs.IntVar(&cfg.MaxConcurrentPages, "max-concurrent-pages", cfg.MaxConcurrentPages, "Maximum pages open at once across all engines (0 = unlimited)")
+	fs.DurationVar(&cfg.PageWaitTimeout, "page-wait-timeout", cfg.PageWaitTimeout, "Maximum time to wait for a free page slot before returning 503")
+	fs.IntVar(&cfg.MaxBatchPages, "max-batch-pages", cfg.MaxBatchPages, "Maximum pages in flight across all synchronous batch scrapes (0 = unlimited)")
 
 	// NATS flags
-	flag.BoolVar(&cfg.WithNats, "with-nats", cfg.WithNats, "Enable NATS JetStream for job queue")
-	flag.StringVar(&cfg.NatsURL, "nats-url", cfg.NatsURL, "NATS server URL")
-	flag.StringVar(&cfg.NatsStore, "nats-store", cfg.NatsStore, "NATS JetStream storage directory")
-	flag.BoolVar(&cfg.NatsAutoDL, "nats-autodl", cfg.NatsAutoDL, "Auto-download NATS server binary")
-	flag.StringVar(&cfg.NatsBin, "nats-bin", cfg.NatsBin, "Path to NATS server binary")
+	fs.BoolVar(&cfg.WithNats, "with-nats", cfg.WithNats, "Enable NATS JetStream for job queue")
+	fs.StringVar(&cfg.NatsURL, "nats-url", cfg.NatsURL, "NATS server URL")
+	fs.StringVar(&cfg.NatsStore, "nats-store", cfg.NatsStore, "NATS JetStream storage directory")
+	fs.BoolVar(&cfg.NatsAutoDL, "nats-autodl", cfg.NatsAutoDL, "Auto-download NATS server binary")
+	fs.StringVar(&cfg.NatsBin, "nats-bin", cfg.NatsBin, "Path to NATS server binary")
+	fs.StringVar(&cfg.NatsConfig, "nats-config", cfg.NatsConfig, "Path to a nats-server config file (overrides built-in NATS flags)")
+	fs.IntVar(&cfg.NatsReplicas, "nats-replicas", cfg.NatsReplicas, "JetStream stream replicas when using an external NATS cluster (1-5)")
+	fs.IntVar(&cfg.EventBufferSize, "event-buffer", cfg.EventBufferSize, "Events buffered per SSE/WebSocket subscriber before drops")
+	fs.IntVar(&cfg.Workers, "workers", cfg.Workers, "Queue workers, i.e. jobs processed at once")
+	fs.DurationVar(&cfg.QueueMaxAge, "queue-max-age", cfg.QueueMaxAge, "Drop queued jobs not picked up within this age (at least 24h)")
+	fs.Int64Var(&cfg.QueueMaxMsgs, "queue-max-msgs", cfg.QueueMaxMsgs, "Most messages the job stream holds, new jobs are refused beyond it (0 = unlimited)")
+	fs.Int64Var(&cfg.QueueMaxBytes, "queue-max-bytes", cfg.QueueMaxBytes, "Most bytes the job stream holds, new jobs are refused beyond it (0 = unlimited)")
+	fs.StringVar(&cfg.QueueStorage, "queue-storage", cfg.QueueStorage, "Storage of the job stream, job store and dead letters: file or memory")
+	fs.IntVar(&cfg.QueueMaxDeliver, "queue-max-deliver", cfg.QueueMaxDeliver, "Deliveries of a job message before JetStream gives up on it (at least 2)")
 
 	// Security flags
-	flag.IntVar(&cfg.RateLimitRequests, "rate-limit", cfg.RateLimitRequests, "Rate limit requests per minute")
-	flag.IntVar(&cfg.MaxRetries, "max-retries", cfg.MaxRetries, "Maximum retries per job (1-10)")
+	fs.IntVar(&cfg.RateLimitRequests, "rate-limit", cfg.RateLimitRequests, "Rate limit requests per window")
+	fs.DurationVar(&cfg.RateLimitWindow, "rate-limit-window", cfg.RateLimitWindow, "Time window for --rate-limit")
+	fs.DurationVar(&cfg.IdempotencyTTL, "idempotency-ttl", cfg.IdempotencyTTL, "How long idempotency keys are remembered")
+	fs.IntVar(&cfg.MaxRetries, "max-retries", cfg.MaxRetries, "Maximum retries per job (1-10)")
+	fs.DurationVar(&cfg.MaxJobTimeout, "max-job-timeout", cfg.MaxJobTimeout, "Maximum timeout clients may request for jobs and synchronous endpoints (0 = no limit)")
+	fs.DurationVar(&cfg.MaxSyncTimeout, "max-sync-timeout", cfg.MaxSyncTimeout, "Maximum page timeout clients may request on synchronous endpoints (0 = no limit)")
+	fs.StringVar(&cfg.AdminToken, "admin-token", cfg.AdminToken, "Token required for admin endpoints (disabled if empty)")
+	fs.Var((*stringList)(&cfg.AllowedIPs), "allowed-ips", "Comma-separated client IPs or CIDR ranges allowed on the job routes (all if empty)")
+	fs.BoolVar(&cfg.TrustProxy, "trust-proxy", cfg.TrustProxy, "Take the client IP for --allowed-ips from the last X-Forwarded-For entry")
+	fs.Var((*stringList)(&cfg.RouteContentTypes), "route-content-types", "Comma-separated prefix=type entries accepting request bodies besides JSON on routes under prefix, e.g. /scrq/uploads=multipart/form-data")
+	fs.BoolVar(&cfg.RequireIdempotencyKey, "require-idempotency-key", cfg.RequireIdempotencyKey, "Reject job submissions without an idempotency key")
+	fs.DurationVar(&cfg.ContentDedupWindow, "content-dedup-window", cfg.ContentDedupWindow, "Treat identical job submissions without an idempotency key within this window as duplicates (0 = disabled)")
+
+	// Polling flags
+	fs.DurationVar(&cfg.PollInterval, "poll-interval", cfg.PollInterval, "Status polling interval suggested to clients without SSE/WebSocket")
+	fs.DurationVar(&cfg.SSEKeepalive, "sse-keepalive", cfg.SSEKeepalive, "Interval of keepalive comments on idle SSE event streams")
+	fs.DurationVar(&cfg.WSPing, "ws-ping", cfg.WSPing, "Ping interval on job WebSockets; peers missing two pongs are dropped")
+
+	// Recipe flags
+	fs.StringVar(&cfg.RecipesFile, "recipes", cfg.RecipesFile, "JSON file of named extraction recipes to load at startup")
+
+	// Tracing flags
+	fs.StringVar(&cfg.OTLPEndpoint, "otlp-endpoint", cfg.OTLPEndpoint, "OTLP/HTTP collector base URL for trace spans, e.g. http://localhost:4318 (tracing disabled if empty)")
 
 	// Other flags
-	flag.BoolVar(&cfg.ShowVersion, "version", cfg.ShowVersion, "Show version information")
-	flag.BoolVar(&cfg.ShowHelp, "help", cfg.ShowHelp, "Show help message")
+	fs.StringVar(&cfg.ConfigFile, "config", cfg.ConfigFile, "YAML or JSON config file; flags given on the command line override it")
+	fs.BoolVar(&cfg.ShowVersion, "version", cfg.ShowVersion, "Show version information")
+	fs.BoolVar(&cfg.ShowHelp, "help", cfg.ShowHelp, "Show help message")
+}
 
-	// Custom usage function
-	flag.Usage = func() {
-		PrintHelp()
-	}
+// stringList is a comma-separated list flag
+type stringList []string
 
-	flag.Parse()
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
 
+func (l *stringList) Set(value string) error {
+	*l = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// normalize fills derived settings and bounds invalid values
+func normalize(cfg *Config) {
 	// Auto-generate BaseURL if not provided
 	if cfg.BaseURL == "" {
 		host := cfg.Host
@@ -122,6 +298,12 @@ func ParseFlags() *Config {
 		}
 		cfg.BaseURL = fmt.Sprintf("http://%s:%d", host, cfg.Port)
 	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+
+	// Normalize the path prefix to "/a/b" form
+	if cfg.PathPrefix = strings.Trim(cfg.PathPrefix, "/"); cfg.PathPrefix != "" {
+		cfg.PathPrefix = "/" + cfg.PathPrefix
+	}
 
 	// Validate
 	if cfg.MaxRetries < 1 {
@@ -133,8 +315,9 @@ func ParseFlags() *Config {
 	if cfg.RateLimitRequests < 1 {
 		cfg.RateLimitRequests = 100
 	}
-
-	return cfg
+	if cfg.MaxBodyBytes < 1 {
+		cfg.MaxBodyBytes = DefaultConfig().MaxBodyBytes
+	}
 }
 
 // PrintVersion prints version information
@@ -153,6 +336,11 @@ Server:
   --host            %s
   --port            %d
   --base-url        %s (auto-generated if empty)
+  --path-prefix     %s (gateway path in front of /scrq in returned URLs)
+  --shutdown-timeout %s (force exit after this)
+  --drain-timeout   %s (requeue running jobs after this)
+  --max-body-bytes  %d (larger request bodies get 413)
+  --compress        %v (gzip/deflate/br responses on Accept-Encoding)
 
 Browser (Lightpanda CDP):
   --browser-host    %s
@@ -161,6 +349,19 @@ Browser (Lightpanda CDP):
 Chrome:
   --with-chrome     %v
   --chrome-revision %d
+  --chrome-pool-size     %d (0 = no pool)
+  --chrome-pool-max-idle %s
+  --chrome-session-ttl   %s
+
+Memory watchdog:
+  --browser-max-rss      %d MB (0 = disabled)
+  --browser-memory-check %s
+  --browser-max-pages    %d (0 = disabled)
+
+Pages:
+  --max-concurrent-pages %d (0 = unlimited)
+  --page-wait-timeout    %s
+  --max-batch-pages      %d (0 = unlimited)
 
 Queue (NATS JetStream):
   --with-nats        %v
@@ -168,21 +369,58 @@ Queue (NATS JetStream):
   --nats-store       %s
   --nats-autodl      %v
   --nats-bin         %s
+  --nats-config      %s (custom nats-server config file)
+  --nats-replicas    %d (stream replicas, external cluster only)
+  --event-buffer     %d (events buffered per subscriber)
+  --workers          %d (jobs processed at once)
+  --queue-max-age    %s (drop jobs queued longer, at least 24h)
+  --queue-max-msgs   %d (0 = unlimited)
+  --queue-max-bytes  %d (0 = unlimited)
+  --queue-storage    %s (file or memory)
+  --queue-max-deliver %d (deliveries per job message, at least 2)
 
 Security:
-  --rate-limit       %d (requests per minute)
+  --rate-limit       %d (requests per window)
+  --rate-limit-window %s
+  --idempotency-ttl  %s
   --max-retries      %d (max retries per job)
+  --max-job-timeout  %s (cap on job and sync endpoint timeouts)
+  --max-sync-timeout %s (cap on sync endpoint timeouts)
+  --admin-token      %s (admin endpoints disabled if empty)
+  --allowed-ips      %s (comma-separated IPs/CIDRs for job routes, all if empty)
+  --trust-proxy      %v (client IP from X-Forwarded-For)
+  --route-content-types %s (comma-separated prefix=type bodies accepted besides JSON)
+  --require-idempotency-key %v
+  --content-dedup-window %s (0 = disabled)
+
+Polling:
+  --poll-interval    %s (suggested to clients without SSE/WebSocket)
+  --sse-keepalive    %s (keepalive comments on idle SSE streams)
+  --ws-ping          %s (WebSocket ping interval, two missed pongs drop the peer)
+
+Recipes:
+  --recipes          %s (JSON file of named recipes)
+
+Tracing:
+  --otlp-endpoint    %s (OTLP/HTTP collector, tracing disabled if empty)
 
 Other:
+  --config          YAML or JSON config file (flags override it)
   --version         show version
   --help            show this help
 
 `, AppName, Version,
-		"0.0.0.0", 8000, "http://localhost:8000",
+		"0.0.0.0", 8000, "http://localhost:8000", `""`, "30s", "20s", 10*1024*1024, true,
 		"127.0.0.1", 9222,
-		false, 0,
-		true, "nats://127.0.0.1:4222", "./data/nats", true, "./bin/nats-server",
-		100, 5)
+		false, 0, 0, "5m0s", "10m0s",
+		0, "30s", 0,
+		0, "10s", 20,
+		true, "nats://127.0.0.1:4222", "./data/nats", true, "./bin/nats-server", `""`, 1, 32, 1,
+		"24h0m0s", 0, 0, "file", 3,
+		100, "1m0s", "24h0m0s", 5, "5m0s", "2m0s", `""`, `""`, false, `""`, false, "0s",
+		"2s", "15s", "30s",
+		`""`,
+		`""`)
 }
 
 // HandleFlags handles version and help flags, exits if needed
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// cliOnlyFlags are flags that may only be given on the command line
+var cliOnlyFlags = map[string]bool{
+	"config":  true,
+	"help":    true,
+	"version": true,
+}
+
+// LoadFile reads a YAML or JSON config file on top of the defaults. Keys are
+// the flag names without dashes, e.g. "max-job-timeout: 5m"; nested
+// sections are joined with a dash, so "nats: {url: ...}" sets --nats-url.
+// Underscores may be used instead of dashes. Durations are strings such as
+// "30s" or "5m". Lists, e.g. of allowed IPs, may be YAML or JSON lists or
+// comma-separated strings. The result is not normalized yet; ParseFlags does that
+// after applying command line flags.
+func LoadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// YAML is a superset of JSON, so one parser handles both
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	settings, lists := map[string]string{}, map[string]bool{}
+	if err := flattenSettings("", raw, settings, lists); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	cfg := DefaultConfig()
+	fs := flag.NewFlagSet(path, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	registerFlags(fs, cfg)
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := fs.Lookup(name)
+		if f == nil || cliOnlyFlags[name] {
+			return nil, fmt.Errorf("config file %s: unknown setting %q", path, name)
+		}
+		if _, isList := f.Value.(*stringList); lists[name] && !isList {
+			return nil, fmt.Errorf("config file %s: setting %q must be a single value, not a list", path, name)
+		}
+		if err := fs.Set(name, settings[name]); err != nil {
+			return nil, fmt.Errorf("config file %s: invalid value %q for %s: %w", path, settings[name], name, err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// flattenSettings turns nested sections into flag names and values. Lists
+// are joined with commas and their names recorded in lists.
+func flattenSettings(prefix string, values map[string]interface{}, settings map[string]string, lists map[string]bool) error {
+	for key, value := range values {
+		name := strings.ReplaceAll(strings.ToLower(key), "_", "-")
+		if prefix != "" {
+			name = prefix + "-" + name
+		}
+
+		switch v := value.(type) {
+		case map[interface{}]interface{}:
+			section := make(map[string]interface{}, len(v))
+			for k, inner := range v {
+				section[fmt.Sprint(k)] = inner
+			}
+			if err := flattenSettings(name, section, settings, lists); err != nil {
+				return err
+			}
+		case map[string]interface{}:
+			if err := flattenSettings(name, v, settings, lists); err != nil {
+				return err
+			}
+		case []interface{}:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				switch item.(type) {
+				case nil, []interface{}, map[interface{}]interface{}, map[string]interface{}:
+					return fmt.Errorf("setting %q must be a list of single values", name)
+				}
+				items = append(items, fmt.Sprint(item))
+			}
+			settings[name] = strings.Join(items, ",")
+			lists[name] = true
+		case nil:
+			return fmt.Errorf("setting %q has no value", name)
+		default:
+			settings[name] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
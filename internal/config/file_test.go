@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadFileYAML(t *testing.T) {
+	path := writeConfig(t, "scrq.yaml", `
+host: 127.0.0.1
+port: 9000
+with-chrome: true
+nats:
+  url: nats://nats:4222
+  replicas: 3
+rate_limit: 50
+rate-limit-window: 30s
+max-job-timeout: 10m
+allowed-ips: 10.0.0.0/8, 127.0.0.1
+route_content_types:
+  - /scrq/uploads=multipart/form-data
+  - /scrq/feeds=application/xml
+`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if cfg.Host != "127.0.0.1" || cfg.Port != 9000 || !cfg.WithChrome {
+		t.Errorf("Unexpected server settings: %s:%d chrome=%v", cfg.Host, cfg.Port, cfg.WithChrome)
+	}
+	if cfg.NatsURL != "nats://nats:4222" || cfg.NatsReplicas != 3 {
+		t.Errorf("Unexpected nats settings: %s replicas=%d", cfg.NatsURL, cfg.NatsReplicas)
+	}
+	if cfg.RateLimitRequests != 50 || cfg.RateLimitWindow != 30*time.Second || cfg.MaxJobTimeout != 10*time.Minute {
+		t.Errorf("Unexpected limits: %d per %s, job timeout %s", cfg.RateLimitRequests, cfg.RateLimitWindow, cfg.MaxJobTimeout)
+	}
+	if len(cfg.AllowedIPs) != 2 || cfg.AllowedIPs[0] != "10.0.0.0/8" || cfg.AllowedIPs[1] != "127.0.0.1" {
+		t.Errorf("Unexpected allowed IPs: %q", cfg.AllowedIPs)
+	}
+	if len(cfg.RouteContentTypes) != 2 || cfg.RouteContentTypes[1] != "/scrq/feeds=application/xml" {
+		t.Errorf("Expected a YAML list for a list flag, got %q", cfg.RouteContentTypes)
+	}
+	if cfg.MaxSyncTimeout != DefaultConfig().MaxSyncTimeout {
+		t.Errorf("Expected unset fields to keep their defaults, got max sync timeout %s", cfg.MaxSyncTimeout)
+	}
+}
+
+func TestLoadFileJSON(t *testing.T) {
+	path := writeConfig(t, "scrq.json", `{"port": 8100, "chrome": {"pool-size": 4}, "poll-interval": "5s", "allowed-ips": ["10.0.0.0/8", "::1"]}`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if cfg.Port != 8100 || cfg.ChromePoolSize != 4 || cfg.PollInterval != 5*time.Second {
+		t.Errorf("Unexpected config: port=%d pool=%d poll=%s", cfg.Port, cfg.ChromePoolSize, cfg.PollInterval)
+	}
+	if len(cfg.AllowedIPs) != 2 || cfg.AllowedIPs[1] != "::1" {
+		t.Errorf("Expected a JSON list for a list flag, got %q", cfg.AllowedIPs)
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown setting":  "no-such-setting: 1",
+		"invalid duration": "max-job-timeout: 30",
+		"invalid number":   "port: eighty",
+		"list value":       "host: [a, b]",
+		"nested list":      "allowed-ips: [[10.0.0.1]]",
+		"config in config": "config: other.yaml",
+	} {
+		if _, err := LoadFile(writeConfig(t, "scrq.yaml", content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
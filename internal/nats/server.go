@@ -26,6 +26,8 @@ type Server struct {
 	js        jetstream.JetStream
 	mu        sync.Mutex
 	isRunning bool
+
+	configFile string
 }
 
 // ServerConfig holds configuration for the NATS server
@@ -34,6 +36,11 @@ type ServerConfig struct {
 	StoreDir string
 	URL      string
 	AutoDL   bool
+
+	// ConfigFile, when set, launches nats-server with "-c <file>" instead
+	// of the built-in flag set. The file must enable JetStream and listen
+	// on URL.
+	ConfigFile string
 }
 
 // NewServer creates a new NATS server manager
@@ -44,10 +51,21 @@ func NewServer(cfg ServerConfig) (*Server, error) {
 		return nil, fmt.Errorf("failed to ensure NATS binary: %w", err)
 	}
 
+	if cfg.ConfigFile != "" {
+		info, err := os.Stat(cfg.ConfigFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid NATS config file: %w", err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("invalid NATS config file: %s is a directory", cfg.ConfigFile)
+		}
+	}
+
 	return &Server{
-		binPath:  binPath,
-		storeDir: cfg.StoreDir,
-		url:      cfg.URL,
+		binPath:    binPath,
+		storeDir:   cfg.StoreDir,
+		url:        cfg.URL,
+		configFile: cfg.ConfigFile,
 	}, nil
 }
 
@@ -66,29 +84,13 @@ func (s *Server) Start(ctx context.Context) error {
 		return s.connect()
 	}
 
-	// Create store directory
-	absStoreDir, err := filepath.Abs(s.storeDir)
-	if err != nil {
-		return fmt.Errorf("failed to get absolute path for store dir: %w", err)
-	}
-
-	if err := os.MkdirAll(absStoreDir, 0755); err != nil {
-		return fmt.Errorf("failed to create store directory: %w", err)
-	}
-
-	// Parse host and port from URL
-	host, port, err := parseNatsURL(s.url)
+	args, err := s.serverArgs()
 	if err != nil {
-		return fmt.Errorf("failed to parse NATS URL: %w", err)
+		return err
 	}
 
 	// Start NATS server with JetStream
-	s.cmd = exec.CommandContext(ctx, s.binPath,
-		"-js",
-		"-sd", absStoreDir,
-		"-a", host,
-		"-p", port,
-	)
+	s.cmd = exec.CommandContext(ctx, s.binPath, args...)
 	s.cmd.Stdout = os.Stdout
 	s.cmd.Stderr = os.Stderr
 
@@ -147,6 +149,15 @@ func (s *Server) IsRunning() bool {
 	return s.isRunning
 }
 
+// IsEmbedded reports whether this process launched nats-server itself with
+// the built-in single-node flags, rather than connecting to an existing
+// server or using a custom config file
+func (s *Server) IsEmbedded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cmd != nil && s.configFile == ""
+}
+
 // GetConnection returns the NATS connection
 func (s *Server) GetConnection() *nats.Conn {
 	s.mu.Lock()
@@ -161,6 +172,41 @@ func (s *Server) GetJetStream() jetstream.JetStream {
 	return s.js
 }
 
+// serverArgs returns the nats-server command line. A custom config file
+// replaces the built-in flag set entirely.
+func (s *Server) serverArgs() ([]string, error) {
+	if s.configFile != "" {
+		absConfig, err := filepath.Abs(s.configFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path for config file: %w", err)
+		}
+		return []string{"-c", absConfig}, nil
+	}
+
+	// Create store directory
+	absStoreDir, err := filepath.Abs(s.storeDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for store dir: %w", err)
+	}
+
+	if err := os.MkdirAll(absStoreDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+
+	// Parse host and port from URL
+	host, port, err := parseNatsURL(s.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse NATS URL: %w", err)
+	}
+
+	return []string{
+		"-js",
+		"-sd", absStoreDir,
+		"-a", host,
+		"-p", port,
+	}, nil
+}
+
 func (s *Server) isReachable() bool {
 	host, port, err := parseNatsURL(s.url)
 	if err != nil {
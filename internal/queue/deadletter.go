@@ -0,0 +1,182 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	// DeadLetterStreamName is the stream holding jobs that exhausted their retries
+	DeadLetterStreamName = "SCRQ_JOBS_DLQ"
+	// DeadLetterSubjectPrefix prefixes the per-job dead-letter subjects
+	DeadLetterSubjectPrefix = "scrq.dlq"
+	// DeadLetterMaxAge is how long dead-lettered jobs are kept
+	DeadLetterMaxAge = 7 * 24 * time.Hour
+)
+
+// ErrNotDeadLettered is returned when requeueing a job that is not in the
+// dead-letter stream
+var ErrNotDeadLettered = errors.New("job is not in the dead-letter queue")
+
+// errDeadLetterUnavailable is returned when the manager has no dead-letter stream
+var errDeadLetterUnavailable = errors.New("dead-letter queue is not available")
+
+// deadLetterSubject returns the subject a failed job is dead-lettered on.
+// One subject per job lets a requeue look the job up directly.
+func deadLetterSubject(jobID string) string {
+	return DeadLetterSubjectPrefix + "." + jobID
+}
+
+// setupDeadLetterStream creates or updates the dead-letter stream. Only the
+// latest failure of a job is kept.
+func (m *Manager) setupDeadLetterStream(ctx context.Context, config QueueConfig) error {
+	stream, err := m.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:              DeadLetterStreamName,
+		Description:       "Scrq jobs that exhausted their retries",
+		Subjects:          []string{DeadLetterSubjectPrefix + ".>"},
+		Retention:         jetstream.LimitsPolicy,
+		MaxAge:            DeadLetterMaxAge,
+		MaxMsgsPerSubject: 1,
+		Storage:           config.storageType(),
+		Replicas:          config.Replicas,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create dead-letter stream: %w", err)
+	}
+	m.dlq = stream
+	return nil
+}
+
+// deadLetter publishes a job that failed for good, with its final error, to
+// the dead-letter stream. A failed publish is logged; the job stays failed
+// in the store either way.
+func (m *Manager) deadLetter(job *Job) {
+	data, err := job.ToJSON()
+	if err != nil {
+		log.Printf("Failed to serialize job %s for the dead-letter queue: %v", job.ID, err)
+		return
+	}
+
+	msg := jobMsg(job, data)
+	msg.Subject = deadLetterSubject(job.ID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := m.js.PublishMsg(ctx, msg); err != nil {
+		log.Printf("Failed to dead-letter job %s%s: %v", job.ID, job.Request.TraceContext, err)
+	}
+}
+
+// DeadLetters returns a page of dead-lettered jobs, most recently failed
+// first, and how many jobs the dead-letter stream holds in total
+func (m *Manager) DeadLetters(limit, offset int) ([]*Job, int, error) {
+	if m.dlq == nil {
+		return nil, 0, errDeadLetterUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	info, err := m.dlq.Info(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read dead-letter stream: %w", err)
+	}
+	state := info.State
+
+	jobs := make([]*Job, 0)
+	skipped := 0
+	for seq := state.LastSeq; seq >= state.FirstSeq && seq > 0 && len(jobs) < limit; seq-- {
+		msg, err := m.dlq.GetMsg(ctx, seq)
+		if errors.Is(err, jetstream.ErrMsgNotFound) {
+			// Replaced by a later failure of the same job, or requeued
+			continue
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read dead-lettered job: %w", err)
+		}
+		if skipped < offset {
+			skipped++
+			continue
+		}
+		job, err := FromJSON(msg.Data)
+		if err != nil {
+			log.Printf("Skipping dead-lettered message %d: %v", seq, err)
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, int(state.Msgs), nil
+}
+
+// Requeue moves a dead-lettered job back into the job stream. The job starts
+// over as queued with its retry count reset, and leaves the dead-letter
+// stream once it has been published.
+func (m *Manager) Requeue(jobID string) (*Job, error) {
+	if m.dlq == nil {
+		return nil, errDeadLetterUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	msg, err := m.dlq.GetLastMsgForSubject(ctx, deadLetterSubject(jobID))
+	if errors.Is(err, jetstream.ErrMsgNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrNotDeadLettered, jobID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead-lettered job: %w", err)
+	}
+
+	job, err := FromJSON(msg.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode dead-lettered job: %w", err)
+	}
+	job.Requeue()
+
+	// The stored copy may have expired since the job failed, so it is
+	// saved again rather than updated
+	if err := m.store.Save(job); err != nil {
+		return nil, fmt.Errorf("failed to save job: %w", err)
+	}
+
+	data, err := job.ToJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize job: %w", err)
+	}
+	if err := m.publish(job, data); err != nil {
+		job.SetError(fmt.Sprintf("failed to requeue: %v (last error: %s)", err, job.LastError))
+		_ = m.store.Update(job)
+		return nil, fmt.Errorf("failed to publish job: %w", err)
+	}
+
+	if err := m.dlq.DeleteMsg(ctx, msg.Sequence); err != nil && !errors.Is(err, jetstream.ErrMsgNotFound) {
+		log.Printf("Failed to remove requeued job %s from the dead-letter queue: %v", jobID, err)
+	}
+
+	m.events.Emit(job.ID, Event{
+		JobID:   job.ID,
+		Status:  job.Status,
+		Message: "Job requeued from the dead-letter queue",
+	})
+
+	return job, nil
+}
+
+// purgeDeadLetter removes a job from the dead-letter stream, if it is there
+func (m *Manager) purgeDeadLetter(jobID string) {
+	if m.dlq == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := m.dlq.Purge(ctx, jetstream.WithPurgeSubject(deadLetterSubject(jobID))); err != nil {
+		log.Printf("Failed to remove deleted job %s from the dead-letter queue: %v", jobID, err)
+	}
+}
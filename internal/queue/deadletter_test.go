@@ -0,0 +1,125 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// memStream is an in-memory dead-letter stream
+type memStream struct {
+	jetstream.Stream
+	msgs map[uint64]*jetstream.RawStreamMsg
+	last uint64
+}
+
+func (s *memStream) add(msg *nats.Msg) {
+	s.last++
+	for seq, old := range s.msgs {
+		if old.Subject == msg.Subject {
+			delete(s.msgs, seq) // MaxMsgsPerSubject: 1
+		}
+	}
+	s.msgs[s.last] = &jetstream.RawStreamMsg{Subject: msg.Subject, Sequence: s.last, Data: msg.Data}
+}
+
+func (s *memStream) Info(ctx context.Context, opts ...jetstream.StreamInfoOpt) (*jetstream.StreamInfo, error) {
+	return &jetstream.StreamInfo{State: jetstream.StreamState{Msgs: uint64(len(s.msgs)), FirstSeq: 1, LastSeq: s.last}}, nil
+}
+
+func (s *memStream) GetMsg(ctx context.Context, seq uint64, opts ...jetstream.GetMsgOpt) (*jetstream.RawStreamMsg, error) {
+	if msg, ok := s.msgs[seq]; ok {
+		return msg, nil
+	}
+	return nil, jetstream.ErrMsgNotFound
+}
+
+func (s *memStream) GetLastMsgForSubject(ctx context.Context, subject string) (*jetstream.RawStreamMsg, error) {
+	for _, msg := range s.msgs {
+		if msg.Subject == subject {
+			return msg, nil
+		}
+	}
+	return nil, jetstream.ErrMsgNotFound
+}
+
+func (s *memStream) DeleteMsg(ctx context.Context, seq uint64) error {
+	delete(s.msgs, seq)
+	return nil
+}
+
+// dlqJetStream sends dead-letter publishes to a memStream and records the rest
+type dlqJetStream struct {
+	jetstream.JetStream
+	dlq      *memStream
+	subjects []string
+}
+
+func (d *dlqJetStream) PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	if strings.HasPrefix(msg.Subject, DeadLetterSubjectPrefix+".") {
+		d.dlq.add(msg)
+	} else {
+		d.subjects = append(d.subjects, msg.Subject)
+	}
+	return &jetstream.PubAck{}, nil
+}
+
+// failingProcessor always fails
+type failingProcessor struct{}
+
+func (failingProcessor) Process(ctx context.Context, job *Job, progress func(int, string)) (interface{}, error) {
+	return nil, errors.New("upstream unavailable")
+}
+
+func TestDeadLetterAndRequeue(t *testing.T) {
+	dlq := &memStream{msgs: make(map[uint64]*jetstream.RawStreamMsg)}
+	js := &dlqJetStream{dlq: dlq}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := &Manager{js: js, dlq: dlq, store: NewStore(), events: NewEventHub(0), ctx: ctx, cancel: cancel, active: make(map[string]context.CancelFunc)}
+	defer m.store.Stop()
+
+	if _, err := m.Requeue("job_missing"); !errors.Is(err, ErrNotDeadLettered) {
+		t.Fatalf("Expected ErrNotDeadLettered, got %v", err)
+	}
+
+	job := NewJob(JobRequest{Type: JobTypeScrape, URL: "https://example.com"})
+	job.RetryCount = job.MaxRetries
+	if err := m.store.Save(job); err != nil {
+		t.Fatalf("Failed to store job: %v", err)
+	}
+	data, _ := job.ToJSON()
+	m.processMessage(&ackMsg{data: data, acked: make(chan struct{})}, failingProcessor{})
+
+	jobs, total, err := m.DeadLetters(10, 0)
+	if err != nil {
+		t.Fatalf("DeadLetters: %v", err)
+	}
+	if total != 1 || len(jobs) != 1 || jobs[0].ID != job.ID {
+		t.Fatalf("Expected the failed job in the dead-letter queue, got %d of %d", len(jobs), total)
+	}
+	if jobs[0].Status != JobStatusFailed || !strings.Contains(jobs[0].Error, "upstream unavailable") {
+		t.Errorf("Expected the final error to be kept, got %s %q", jobs[0].Status, jobs[0].Error)
+	}
+
+	requeued, err := m.Requeue(job.ID)
+	if err != nil {
+		t.Fatalf("Requeue: %v", err)
+	}
+	if requeued.Status != JobStatusQueued || requeued.RetryCount != 0 || requeued.Error != "" {
+		t.Errorf("Expected a fresh queued job, got %s with %d retries and error %q", requeued.Status, requeued.RetryCount, requeued.Error)
+	}
+	if len(js.subjects) != 1 || js.subjects[0] != SubjectName {
+		t.Errorf("Expected the job to be republished on %s, got %v", SubjectName, js.subjects)
+	}
+	if stored, _ := m.GetJob(job.ID); stored.Status != JobStatusQueued {
+		t.Errorf("Expected the stored job to be queued, got %s", stored.Status)
+	}
+	if _, total, _ := m.DeadLetters(10, 0); total != 0 {
+		t.Errorf("Expected the dead-letter queue to be empty, got %d", total)
+	}
+}
@@ -0,0 +1,91 @@
+package queue
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/ahrdadan/scrq/internal/browser"
+)
+
+// contentKey lists the request fields that decide what a job fetches and
+// returns. Two requests with equal content keys produce the same result, so
+// they are duplicates for content-based idempotency. Delivery and scheduling
+// fields (notify, retry, priority, timeout, result_ttl, deadline, run_at,
+// delay_seconds, trace context, idempotency_key) are deliberately left out.
+type contentKey struct {
+	Type             JobType              `json:"type"`
+	URL              string               `json:"url"`
+	URLs             []string             `json:"urls"`
+	Engine           string               `json:"engine"`
+	Script           string               `json:"script"`
+	Selectors        []string             `json:"selectors"`
+	WaitForLoad      bool                 `json:"wait_for_load"`
+	WaitForFunction  string               `json:"wait_for_function"`
+	WaitForSelectors []string             `json:"wait_for_selectors"`
+	WaitForSelector  string               `json:"wait_for_selector"`
+	AutoScroll       bool                 `json:"auto_scroll"`
+	ScrollSteps      int                  `json:"scroll_steps"`
+	ScrollDelay      int                  `json:"scroll_delay"`
+	BlockResources   []string             `json:"block_resources"`
+	WaitMode         string               `json:"wait_mode"`
+	UserAgent        string               `json:"user_agent"`
+	AcceptLanguage   string               `json:"accept_language"`
+	Headers          map[string]string    `json:"headers"`
+	Cookies          []CookieParam        `json:"cookies"`
+	Proxy            string               `json:"proxy"`
+	DismissConsent   bool                 `json:"dismiss_consent"`
+	DownloadSelector string               `json:"download_selector"`
+	PierceShadow     bool                 `json:"pierce_shadow"`
+	IncludeCookies   bool                 `json:"include_cookies"`
+	CaptureConsole   bool                 `json:"capture_console"`
+	Render           bool                 `json:"render"`
+	Fields           []string             `json:"fields"`
+	PDF              *browser.PDFOptions  `json:"pdf"`
+	BasicAuth        *browser.Credentials `json:"basic_auth"`
+}
+
+// ContentHash returns a SHA-256 hex digest of the fields that determine the
+// job's result (see contentKey). Call it after Normalize so equivalent
+// requests hash the same; defaults such as wait_mode are filled in here.
+func (r *JobRequest) ContentHash() string {
+	key := contentKey{
+		Type:             r.Type,
+		URL:              r.URL,
+		URLs:             r.URLs,
+		Engine:           r.Engine,
+		Script:           r.Script,
+		Selectors:        r.Selectors,
+		WaitForLoad:      r.WaitForLoad,
+		WaitForFunction:  r.WaitForFunction,
+		WaitForSelectors: r.WaitForSelectors,
+		WaitForSelector:  r.WaitForSelector,
+		AutoScroll:       r.AutoScroll,
+		ScrollSteps:      r.ScrollSteps,
+		ScrollDelay:      r.ScrollDelay,
+		BlockResources:   r.BlockResources,
+		WaitMode:         r.WaitMode,
+		UserAgent:        r.UserAgent,
+		AcceptLanguage:   r.AcceptLanguage,
+		Headers:          r.Headers,
+		Cookies:          r.Cookies,
+		Proxy:            r.Proxy,
+		DismissConsent:   r.DismissConsent,
+		DownloadSelector: r.DownloadSelector,
+		PierceShadow:     r.PierceShadow,
+		IncludeCookies:   r.IncludeCookies,
+		CaptureConsole:   r.CaptureConsole,
+		Render:           !r.Static(),
+		Fields:           r.Fields,
+		PDF:              r.PDF,
+		BasicAuth:        r.BasicAuth,
+	}
+	if key.WaitMode == "" {
+		key.WaitMode = string(browser.WaitAll)
+	}
+
+	// Map keys are marshaled in sorted order, so the encoding is stable
+	data, _ := json.Marshal(key)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -12,16 +12,27 @@ type Event struct {
 	Message  string    `json:"message,omitempty"`
 }
 
+// DefaultEventBufferSize is the per-subscriber event buffer used when none
+// is configured
+const DefaultEventBufferSize = 32
+
 // EventHub manages event subscriptions
 type EventHub struct {
 	subscribers map[string][]chan Event
 	mu          sync.RWMutex
+	bufSize     int
 }
 
-// NewEventHub creates a new event hub
-func NewEventHub() *EventHub {
+// NewEventHub creates a new event hub. Each subscriber gets a channel
+// buffering bufSize events; a slow subscriber whose buffer is full misses
+// events, so larger buffers trade memory for fewer drops.
+func NewEventHub(bufSize int) *EventHub {
+	if bufSize <= 0 {
+		bufSize = DefaultEventBufferSize
+	}
 	return &EventHub{
 		subscribers: make(map[string][]chan Event),
+		bufSize:     bufSize,
 	}
 }
 
@@ -30,7 +41,7 @@ func (h *EventHub) Subscribe(jobID string) <-chan Event {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	ch := make(chan Event, 10)
+	ch := make(chan Event, h.bufSize)
 	h.subscribers[jobID] = append(h.subscribers[jobID], ch)
 	return ch
 }
@@ -68,6 +79,26 @@ func (h *EventHub) Emit(jobID string, event Event) {
 	}
 }
 
+// SubscriberCount returns the number of active subscribers for a job
+func (h *EventHub) SubscriberCount(jobID string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.subscribers[jobID])
+}
+
+// SubscriberCounts returns the number of active subscribers per job
+func (h *EventHub) SubscriberCounts() map[string]int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	counts := make(map[string]int, len(h.subscribers))
+	for jobID, subs := range h.subscribers {
+		counts[jobID] = len(subs)
+	}
+	return counts
+}
+
 // Close closes all subscriptions
 func (h *EventHub) Close() {
 	h.mu.Lock()
@@ -1,8 +1,12 @@
 package queue
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/ahrdadan/scrq/internal/browser"
 	"github.com/google/uuid"
 )
 
@@ -13,25 +17,70 @@ const (
 	DefaultResultTTL  = 7 * 24 * time.Hour // 7 days
 	DefaultRetryDelay = 5 * time.Second
 	MaxRetryDelay     = 5 * time.Minute
+
+	DefaultBatchConcurrency = 3  // URLs of a batch job scraped at once
+	MaxBatchConcurrency     = 10 // Upper bound for JobRequest.Concurrent
+
+	// MaxScheduleDelay bounds how far ahead a job can be scheduled. It stays
+	// under the job stream's MaxAge, past which the message is dropped;
+	// MinQueueMaxAge keeps the configured MaxAge above it.
+	MaxScheduleDelay = 23 * time.Hour
 )
 
+// ErrDeadlineExceeded is returned when a job cannot finish before the
+// deadline set in its request
+var ErrDeadlineExceeded = errors.New("job deadline exceeded")
+
+// ErrNotCancelable is returned when a job is in a status that cannot move
+// to canceled and has not finished either
+var ErrNotCancelable = errors.New("job cannot be canceled")
+
+// ErrJobNotFinished is returned when deleting a job that is still queued,
+// scheduled, running or retrying
+var ErrJobNotFinished = errors.New("job has not finished")
+
 // JobStatus represents the status of a job
 type JobStatus string
 
 const (
 	JobStatusQueued    JobStatus = "queued"
+	JobStatusScheduled JobStatus = "scheduled"
 	JobStatusRunning   JobStatus = "running"
 	JobStatusSucceeded JobStatus = "succeeded"
 	JobStatusFailed    JobStatus = "failed"
 	JobStatusCanceled  JobStatus = "canceled"
 	JobStatusRetrying  JobStatus = "retrying"
+	JobStatusExpired   JobStatus = "expired"
+	JobStatusDeleted   JobStatus = "deleted"
 )
 
+// IsTerminal reports whether no further events will follow this status
+func (s JobStatus) IsTerminal() bool {
+	switch s {
+	case JobStatusSucceeded, JobStatusFailed, JobStatusCanceled, JobStatusExpired, JobStatusDeleted:
+		return true
+	}
+	return false
+}
+
+// Cancelable reports whether a job in this status can still be canceled.
+// Scheduled and retrying jobs are waiting for redelivery, which skips
+// canceled jobs.
+func (s JobStatus) Cancelable() bool {
+	switch s {
+	case JobStatusQueued, JobStatusScheduled, JobStatusRunning, JobStatusRetrying:
+		return true
+	}
+	return false
+}
+
 // JobType represents the type of job
 type JobType string
 
 const (
 	JobTypeScrape JobType = "scrape"
+	JobTypeBatch  JobType = "batch"
+	JobTypePDF    JobType = "pdf" // Print a single page to PDF (chrome only)
 )
 
 // NotifyConfig holds notification settings for a job
@@ -39,6 +88,16 @@ type NotifyConfig struct {
 	WebhookURL    string `json:"webhook_url,omitempty"`
 	WebhookSecret string `json:"webhook_secret,omitempty"` // For HMAC signature
 	WebSocket     bool   `json:"websocket,omitempty"`
+	NotifyOnStart bool   `json:"notify_on_start,omitempty"` // Also send a webhook when the job starts running
+	NotifyOnRetry bool   `json:"notify_on_retry,omitempty"` // Also send a webhook on each retry
+
+	Timeout            int  `json:"timeout,omitempty"`              // Delivery timeout in seconds (default 30, max 120)
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"` // Skip TLS verification for self-signed internal receivers
+
+	Retry *RetryConfig `json:"retry,omitempty"` // Webhook delivery retries (default 3 retries, 2s delay, factor 2)
+
+	Headers  map[string]string      `json:"headers,omitempty"`  // Extra headers on each delivery, e.g. for an auth gateway
+	Metadata map[string]interface{} `json:"metadata,omitempty"` // Included verbatim in the payload as metadata
 }
 
 // RetryConfig holds retry settings for a job
@@ -83,15 +142,225 @@ type JobRequest struct {
 	Timeout        int               `json:"timeout"`        // seconds (default: 30)
 	WaitForLoad    bool              `json:"wait_for_load"`
 	Script         string            `json:"script,omitempty"`
+	Selectors      []string          `json:"selectors,omitempty"` // Return the text of elements matching each selector
 	UserAgent      string            `json:"user_agent,omitempty"`
 	Headers        map[string]string `json:"headers,omitempty"`
 	Cookies        []CookieParam     `json:"cookies,omitempty"`
-	Proxy          string            `json:"proxy,omitempty"` // only for chrome engine
+	Proxy          string            `json:"proxy,omitempty"` // only for chrome engine; may carry user:pass@
 	Notify         *NotifyConfig     `json:"notify,omitempty"`
 	Retry          *RetryConfig      `json:"retry,omitempty"`
 	IdempotencyKey string            `json:"idempotency_key,omitempty"` // Client-provided idempotency key
 	Priority       int               `json:"priority,omitempty"`        // Job priority (higher = more urgent)
 	ResultTTL      int               `json:"result_ttl,omitempty"`      // Result TTL in seconds (default: 7 days)
+
+	ScreenshotOnFailure bool   `json:"screenshot_on_failure,omitempty"` // Capture the page when the job errors
+	AcceptLanguage      string `json:"accept_language,omitempty"`       // Accept-Language header and navigator.languages
+	PartialOnTimeout    bool   `json:"partial_on_timeout,omitempty"`    // Return results collected so far when a batch job times out
+	WaitForFunction     string `json:"wait_for_function,omitempty"`     // JS predicate polled after load until truthy
+	WaitPollInterval    int    `json:"wait_poll_interval,omitempty"`    // Poll interval in milliseconds (default: 100)
+
+	WaitForSelectors []string `json:"wait_for_selectors,omitempty"` // Selectors to wait for after load
+	WaitMode         string   `json:"wait_mode,omitempty"`          // any or all (default)
+	DismissConsent   bool     `json:"dismiss_consent,omitempty"`    // Click cookie consent accept buttons after load
+	DownloadSelector string   `json:"download_selector,omitempty"`  // Click this element and capture the downloaded file (chrome only)
+	PierceShadow     bool     `json:"pierce_shadow,omitempty"`      // Include open shadow DOM content in HTML, text and selectors
+	IncludeCookies   bool     `json:"include_cookies,omitempty"`    // Return the page's cookies after navigation in the result
+	CaptureConsole   bool     `json:"capture_console,omitempty"`    // Return console messages and page errors in the result
+	Render           *bool    `json:"render,omitempty"`             // false fetches over plain HTTP without a browser (no JavaScript)
+	Fields           []string `json:"fields,omitempty"`             // Page result fields to keep (default: all)
+
+	WaitForSelector        string `json:"wait_for_selector,omitempty"`         // Selector to wait for after load
+	WaitForSelectorTimeout int    `json:"wait_for_selector_timeout,omitempty"` // Milliseconds (default: the job timeout)
+	ScriptTimeout          int    `json:"script_timeout,omitempty"`            // Milliseconds the script may run (default: the job timeout)
+
+	AutoScroll  bool `json:"auto_scroll,omitempty"`  // Scroll to the bottom after load until the page stops growing
+	ScrollSteps int  `json:"scroll_steps,omitempty"` // Maximum scrolls (default: 10, max: 100)
+	ScrollDelay int  `json:"scroll_delay,omitempty"` // Milliseconds between scrolls (default: 500)
+
+	BlockResources []string `json:"block_resources,omitempty"` // Resource types to abort: image, font, stylesheet, media
+
+	BasicAuth *browser.Credentials `json:"basic_auth,omitempty"` // Answers the site's HTTP basic auth challenges
+
+	Concurrent int `json:"concurrent,omitempty"` // Batch jobs: URLs scraped at once (default: 3, max: 10)
+
+	PDF *browser.PDFOptions `json:"pdf,omitempty"` // Paper and margin options for pdf jobs
+
+	Deadline int64 `json:"deadline,omitempty"` // Unix time the job must finish by; later runs fail without scraping
+
+	RunAt        int64 `json:"run_at,omitempty"`        // Unix time to run the job at (max 23h ahead)
+	DelaySeconds int   `json:"delay_seconds,omitempty"` // Run the job this many seconds after submission instead
+
+	Recipe        string `json:"recipe,omitempty"`         // Name of the recipe the job was built from
+	RecipeVersion int    `json:"recipe_version,omitempty"` // Version of that recipe
+
+	TraceContext // trace_id / traceparent propagated to NATS headers, webhooks and logs
+}
+
+// Normalize validates the request and applies type precedence. Exactly one
+// of URL or URLs must be set; when URLs is set the job is always a batch job.
+func (r *JobRequest) Normalize() error {
+	hasURL := r.URL != ""
+	hasURLs := len(r.URLs) > 0
+
+	switch {
+	case hasURL && hasURLs:
+		return errors.New("only one of url or urls may be provided")
+	case !hasURL && !hasURLs:
+		return errors.New("url or urls is required")
+	case hasURLs && r.Type == JobTypePDF:
+		return errors.New("pdf jobs take a single url")
+	case hasURLs:
+		for _, u := range r.URLs {
+			if u == "" {
+				return errors.New("urls must not contain empty values")
+			}
+		}
+		r.Type = JobTypeBatch
+	case r.Type == JobTypeBatch:
+		return errors.New("batch jobs require urls")
+	case r.Type == "":
+		r.Type = JobTypeScrape
+	}
+
+	if r.Type == JobTypePDF {
+		if err := r.normalizePDF(); err != nil {
+			return err
+		}
+	} else if r.PDF != nil {
+		return errors.New("pdf options require type pdf")
+	}
+
+	if !browser.ValidWaitMode(browser.WaitMode(r.WaitMode)) {
+		return fmt.Errorf("unknown wait_mode %q (use any or all)", r.WaitMode)
+	}
+
+	if r.Static() && (r.Script != "" || r.DownloadSelector != "" || len(r.Selectors) > 0) {
+		return errors.New("script, selectors and download_selector require render")
+	}
+
+	if len(r.Selectors) > 0 && (r.Script != "" || r.DownloadSelector != "") {
+		return errors.New("selectors cannot be combined with script or download_selector")
+	}
+
+	if r.Concurrent < 0 || r.Concurrent > MaxBatchConcurrency {
+		return fmt.Errorf("concurrent must be between 0 and %d", MaxBatchConcurrency)
+	}
+
+	if r.Notify != nil && (r.Notify.Timeout < 0 || r.Notify.Timeout > int(MaxWebhookTimeout/time.Second)) {
+		return fmt.Errorf("notify.timeout must be between 0 and %d seconds", int(MaxWebhookTimeout/time.Second))
+	}
+	if r.Notify != nil && r.Notify.Retry != nil {
+		retry := r.Notify.Retry
+		if retry.MaxRetries < 0 || retry.MaxRetries > MaxWebhookRetries {
+			return fmt.Errorf("notify.retry.max_retries must be between 0 and %d", MaxWebhookRetries)
+		}
+		if retry.RetryDelay < 0 || retry.BackoffFactor < 0 {
+			return errors.New("notify.retry.retry_delay and backoff_factor must not be negative")
+		}
+	}
+	if r.Notify != nil {
+		for name, value := range r.Notify.Headers {
+			if name == "" || strings.ContainsAny(name, " \t\r\n:") || strings.ContainsAny(value, "\r\n") {
+				return fmt.Errorf("notify.headers: invalid header %q", name)
+			}
+		}
+	}
+
+	if err := browser.ValidateBlockResources(r.BlockResources); err != nil {
+		return err
+	}
+	if err := r.BasicAuth.Validate(); err != nil {
+		return err
+	}
+
+	if len(r.Fields) > 0 {
+		if r.Script != "" || r.DownloadSelector != "" || len(r.Selectors) > 0 {
+			return errors.New("fields only apply to page content, not script, selector or download results")
+		}
+		if err := browser.ValidateResultFields(r.Fields); err != nil {
+			return err
+		}
+	}
+
+	if r.Deadline > 0 && r.Deadline <= time.Now().Unix() {
+		return errors.New("deadline is already in the past")
+	}
+
+	if err := r.normalizeSchedule(time.Now()); err != nil {
+		return err
+	}
+
+	if err := r.TraceContext.Normalize(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// normalizePDF checks a pdf job. PDFs need Chrome, so the engine defaults to
+// chrome and content options that replace the page output are rejected.
+func (r *JobRequest) normalizePDF() error {
+	switch r.Engine {
+	case "":
+		r.Engine = "chrome"
+	case "chrome":
+	default:
+		return fmt.Errorf("pdf jobs require the chrome engine, not %s", r.Engine)
+	}
+	if r.Static() {
+		return errors.New("pdf jobs require render")
+	}
+	if r.Script != "" || len(r.Selectors) > 0 || r.DownloadSelector != "" || len(r.Fields) > 0 {
+		return errors.New("pdf jobs cannot use script, selectors, download_selector or fields")
+	}
+	if r.PDF != nil {
+		if err := r.PDF.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeSchedule checks run_at and delay_seconds. A scheduled job must
+// start within MaxScheduleDelay and before its deadline.
+func (r *JobRequest) normalizeSchedule(now time.Time) error {
+	maxDelay := int(MaxScheduleDelay / time.Second)
+	switch {
+	case r.RunAt != 0 && r.DelaySeconds != 0:
+		return errors.New("only one of run_at or delay_seconds may be provided")
+	case r.DelaySeconds < 0 || r.DelaySeconds > maxDelay:
+		return fmt.Errorf("delay_seconds must be between 0 and %d", maxDelay)
+	case r.RunAt < 0 || (r.RunAt > 0 && r.RunAt <= now.Unix()):
+		return errors.New("run_at is already in the past")
+	case r.RunAt > now.Add(MaxScheduleDelay).Unix():
+		return fmt.Errorf("run_at must be within %d seconds from now", maxDelay)
+	}
+
+	if runAt := r.scheduledAt(now); runAt > 0 && r.Deadline > 0 && r.Deadline <= runAt {
+		return errors.New("deadline must be after the scheduled run time")
+	}
+	return nil
+}
+
+// scheduledAt returns the Unix time a request submitted at now should run,
+// or 0 to run it right away
+func (r *JobRequest) scheduledAt(now time.Time) int64 {
+	if r.DelaySeconds > 0 {
+		return now.Add(time.Duration(r.DelaySeconds) * time.Second).Unix()
+	}
+	return r.RunAt
+}
+
+// Static reports whether the request opted out of browser rendering
+func (r *JobRequest) Static() bool {
+	return r.Render != nil && !*r.Render
+}
+
+// BatchItemResult holds the outcome of a single URL in a batch job
+type BatchItemResult struct {
+	URL    string      `json:"url"`
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
 }
 
 // Job represents a queued job
@@ -114,11 +383,22 @@ type Job struct {
 	RetryCount     int           `json:"retry_count"`
 	MaxRetries     int           `json:"max_retries"`
 	NextRetryAt    int64         `json:"next_retry_at,omitempty"`
+	RunAt          int64         `json:"run_at,omitempty"` // When a scheduled job runs
 	LastError      string        `json:"last_error,omitempty"`
 	IdempotencyKey string        `json:"idempotency_key,omitempty"`
 	Priority       int           `json:"priority"`
 	UserID         string        `json:"user_id,omitempty"` // For rate limiting
 	Timeout        int           `json:"timeout"`           // Job timeout in seconds
+
+	ErrorScreenshot string `json:"error_screenshot,omitempty"` // Base64 JPEG of the page at the last failure
+	Partial         bool   `json:"partial,omitempty"`          // Result is incomplete (e.g. batch timed out)
+	Warning         string `json:"warning,omitempty"`          // Why the result is partial
+
+	ErrorConsole []browser.ConsoleMessage `json:"error_console,omitempty"` // Console messages up to the last failure
+
+	// Traceparent of the span that enqueued the job, so the span processing
+	// it joins the request's trace although it runs later on a worker
+	Traceparent string `json:"traceparent,omitempty"`
 }
 
 // NewJob creates a new job from a request
@@ -131,28 +411,32 @@ func NewJob(req JobRequest) *Job {
 		timeout = int(DefaultJobTimeout.Seconds())
 	}
 
+	// Scheduled jobs wait for their run time, and keep their result for
+	// the full TTL after it
+	status := JobStatusQueued
+	start := time.Now()
+	runAt := req.scheduledAt(start)
+	if runAt > 0 {
+		status = JobStatusScheduled
+		start = time.Unix(runAt, 0)
+	}
+
 	// Set default max retries
 	maxRetries := DefaultMaxRetries
 	if req.Retry != nil && req.Retry.MaxRetries > 0 {
 		maxRetries = req.Retry.MaxRetries
 	}
 
-	// Calculate expiry time
-	resultTTL := DefaultResultTTL
-	if req.ResultTTL > 0 {
-		resultTTL = time.Duration(req.ResultTTL) * time.Second
-	}
-	expiresAt := time.Now().Add(resultTTL).Unix()
-
 	return &Job{
 		ID:             generateJobID(),
 		Type:           req.Type,
-		Status:         JobStatusQueued,
+		Status:         status,
 		Progress:       0,
 		Request:        req,
 		CreatedAt:      now,
 		UpdatedAt:      now,
-		ExpiresAt:      expiresAt,
+		ExpiresAt:      resultExpiry(req, start),
+		RunAt:          runAt,
 		Notify:         req.Notify,
 		MaxRetries:     maxRetries,
 		RetryCount:     0,
@@ -162,6 +446,37 @@ func NewJob(req JobRequest) *Job {
 	}
 }
 
+// resultExpiry returns when the result of a job starting at start is deleted
+func resultExpiry(req JobRequest, start time.Time) int64 {
+	resultTTL := DefaultResultTTL
+	if req.ResultTTL > 0 {
+		resultTTL = time.Duration(req.ResultTTL) * time.Second
+	}
+	return start.Add(resultTTL).Unix()
+}
+
+// Requeue resets a failed job so it runs again from the start, with its
+// retries and result TTL renewed. LastError keeps the previous failure.
+func (j *Job) Requeue() {
+	j.Status = JobStatusQueued
+	j.Progress = 0
+	j.ProgressInfo = nil
+	j.Message = ""
+	j.Result = nil
+	j.Error = ""
+	j.ErrorScreenshot = ""
+	j.ErrorConsole = nil
+	j.Partial = false
+	j.Warning = ""
+	j.RetryCount = 0
+	j.NextRetryAt = 0
+	j.StartedAt = 0
+	j.CompletedAt = 0
+	j.RunAt = 0
+	j.ExpiresAt = resultExpiry(j.Request, time.Now())
+	j.UpdatedAt = time.Now().Unix()
+}
+
 // SetStatus updates the job status
 func (j *Job) SetStatus(status JobStatus) {
 	j.Status = status
@@ -221,7 +536,7 @@ func (j *Job) SetError(err string) {
 
 // CanRetry returns true if the job can be retried
 func (j *Job) CanRetry() bool {
-	return j.RetryCount < j.MaxRetries
+	return j.RetryCount < j.MaxRetries && !j.DeadlinePassed(time.Now())
 }
 
 // PrepareRetry prepares the job for retry
@@ -271,6 +586,24 @@ func (j *Job) GetTimeoutDuration() time.Duration {
 	return time.Duration(j.Timeout) * time.Second
 }
 
+// DeadlinePassed reports whether the request deadline, if any, is at or
+// before now
+func (j *Job) DeadlinePassed(now time.Time) bool {
+	return j.Request.Deadline > 0 && now.Unix() >= j.Request.Deadline
+}
+
+// RunTimeout returns how long the job may run from now: its timeout, cut
+// short by the request deadline when that comes first
+func (j *Job) RunTimeout(now time.Time) time.Duration {
+	timeout := j.GetTimeoutDuration()
+	if j.Request.Deadline > 0 {
+		if remaining := time.Unix(j.Request.Deadline, 0).Sub(now); remaining < timeout {
+			timeout = remaining
+		}
+	}
+	return timeout
+}
+
 // JobStatusResponse represents a job status response
 type JobStatusResponse struct {
 	JobID     string    `json:"job_id"`
@@ -281,12 +614,42 @@ type JobStatusResponse struct {
 	UpdatedAt int64     `json:"updated_at"`
 }
 
+// JobSummary describes a job in a job listing
+type JobSummary struct {
+	JobID     string    `json:"job_id"`
+	Type      JobType   `json:"type"`
+	Status    JobStatus `json:"status"`
+	Progress  int       `json:"progress"`
+	URL       string    `json:"url,omitempty"`
+	URLCount  int       `json:"url_count,omitempty"` // Batch jobs
+	Error     string    `json:"error,omitempty"`
+	CreatedAt int64     `json:"created_at"`
+	UpdatedAt int64     `json:"updated_at"`
+	StatusURL string    `json:"status_url"`
+}
+
+// JobListResponse represents a page of jobs
+type JobListResponse struct {
+	Jobs   []JobSummary `json:"jobs"`
+	Total  int          `json:"total"` // Jobs matching the filters across all pages
+	Limit  int          `json:"limit"`
+	Offset int          `json:"offset"`
+}
+
 // JobResultResponse represents a job result response
 type JobResultResponse struct {
-	JobID  string      `json:"job_id"`
-	Status JobStatus   `json:"status"`
-	Result interface{} `json:"result,omitempty"`
-	Error  string      `json:"error,omitempty"`
+	JobID           string      `json:"job_id"`
+	Status          JobStatus   `json:"status"`
+	Result          interface{} `json:"result,omitempty"`
+	Error           string      `json:"error,omitempty"`
+	ErrorScreenshot string      `json:"error_screenshot,omitempty"`
+	Partial         bool        `json:"partial,omitempty"`
+	Warning         string      `json:"warning,omitempty"`
+
+	ErrorConsole []browser.ConsoleMessage `json:"error_console,omitempty"`
+
+	// Compressed is set when the result's HTML fields are base64 gzip
+	Compressed bool `json:"compressed,omitempty"`
 }
 
 // JobCreatedResponse represents the response when a job is created
@@ -303,6 +666,18 @@ type JobCreatedResponse struct {
 		WSURL      string `json:"ws_url"`
 		WSURLFull  string `json:"ws_url_full"`
 	} `json:"events"`
+
+	// PollInterval is the suggested status polling interval in seconds
+	// for clients that cannot use SSE or WebSocket
+	PollInterval int `json:"poll_interval,omitempty"`
+
+	// Duplicate is set when the request replayed an earlier submission;
+	// CreatedAt is then the original job's creation time
+	Duplicate bool  `json:"duplicate"`
+	CreatedAt int64 `json:"created_at"`
+
+	// TraceID echoes the trace ID the job was submitted with
+	TraceID string `json:"trace_id,omitempty"`
 }
 
 func generateJobID() string {
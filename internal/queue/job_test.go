@@ -0,0 +1,190 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ahrdadan/scrq/internal/browser"
+)
+
+func TestJobRequestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      JobRequest
+		wantType JobType
+		wantErr  bool
+	}{
+		{name: "single url", req: JobRequest{URL: "https://example.com"}, wantType: JobTypeScrape},
+		{name: "urls become batch", req: JobRequest{Type: JobTypeScrape, URLs: []string{"https://a.com", "https://b.com"}}, wantType: JobTypeBatch},
+		{name: "both set", req: JobRequest{URL: "https://example.com", URLs: []string{"https://a.com"}}, wantErr: true},
+		{name: "neither set", req: JobRequest{}, wantErr: true},
+		{name: "empty url in urls", req: JobRequest{URLs: []string{"https://a.com", ""}}, wantErr: true},
+		{name: "batch without urls", req: JobRequest{Type: JobTypeBatch, URL: "https://example.com"}, wantErr: true},
+		{name: "any wait mode", req: JobRequest{URL: "https://example.com", WaitMode: "any"}, wantType: JobTypeScrape},
+		{name: "unknown wait mode", req: JobRequest{URL: "https://example.com", WaitMode: "some"}, wantErr: true},
+		{name: "block resources", req: JobRequest{URL: "https://example.com", BlockResources: []string{"image", "font"}}, wantType: JobTypeScrape},
+		{name: "unknown block resource", req: JobRequest{URL: "https://example.com", BlockResources: []string{"script"}}, wantErr: true},
+		{name: "batch concurrency", req: JobRequest{URLs: []string{"https://example.com"}, Concurrent: 5}, wantType: JobTypeBatch},
+		{name: "batch concurrency too high", req: JobRequest{URLs: []string{"https://example.com"}, Concurrent: 11}, wantErr: true},
+		{name: "future deadline", req: JobRequest{URL: "https://example.com", Deadline: time.Now().Add(time.Hour).Unix()}, wantType: JobTypeScrape},
+		{name: "past deadline", req: JobRequest{URL: "https://example.com", Deadline: time.Now().Add(-time.Minute).Unix()}, wantErr: true},
+		{name: "run at", req: JobRequest{URL: "https://example.com", RunAt: time.Now().Add(time.Hour).Unix()}, wantType: JobTypeScrape},
+		{name: "run at in the past", req: JobRequest{URL: "https://example.com", RunAt: time.Now().Add(-time.Minute).Unix()}, wantErr: true},
+		{name: "run at too far ahead", req: JobRequest{URL: "https://example.com", RunAt: time.Now().Add(48 * time.Hour).Unix()}, wantErr: true},
+		{name: "delay", req: JobRequest{URL: "https://example.com", DelaySeconds: 60}, wantType: JobTypeScrape},
+		{name: "run at and delay", req: JobRequest{URL: "https://example.com", RunAt: time.Now().Add(time.Hour).Unix(), DelaySeconds: 60}, wantErr: true},
+		{name: "deadline before run at", req: JobRequest{URL: "https://example.com", DelaySeconds: 3600, Deadline: time.Now().Add(time.Minute).Unix()}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			err := req.Normalize()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Normalize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && req.Type != tt.wantType {
+				t.Errorf("Expected type %s, got %s", tt.wantType, req.Type)
+			}
+		})
+	}
+}
+
+func TestJobRunTimeout(t *testing.T) {
+	now := time.Now()
+
+	job := &Job{Timeout: 60}
+	if got := job.RunTimeout(now); got != time.Minute {
+		t.Errorf("Expected timeout without deadline to be 1m, got %v", got)
+	}
+
+	job.Request.Deadline = now.Add(10 * time.Second).Unix()
+	if got := job.RunTimeout(now); got > 10*time.Second || got <= 9*time.Second {
+		t.Errorf("Expected deadline to cap timeout near 10s, got %v", got)
+	}
+	if job.DeadlinePassed(now) {
+		t.Error("Expected deadline not to have passed")
+	}
+
+	job.MaxRetries = 3
+	if !job.DeadlinePassed(now.Add(time.Minute)) {
+		t.Error("Expected deadline to have passed")
+	}
+	job.Request.Deadline = now.Add(-time.Second).Unix()
+	if job.CanRetry() {
+		t.Error("Expected no retries past the deadline")
+	}
+}
+
+func TestJobStatusCancelable(t *testing.T) {
+	for _, status := range []JobStatus{JobStatusQueued, JobStatusRunning, JobStatusRetrying} {
+		if !status.Cancelable() {
+			t.Errorf("Expected %s to be cancelable", status)
+		}
+	}
+	for _, status := range []JobStatus{JobStatusSucceeded, JobStatusFailed, JobStatusCanceled, JobStatusExpired} {
+		if status.Cancelable() {
+			t.Errorf("Expected %s not to be cancelable", status)
+		}
+		if !status.IsTerminal() {
+			t.Errorf("Expected %s to be terminal", status)
+		}
+	}
+}
+
+func TestTraceContextNormalize(t *testing.T) {
+	trace := TraceContext{Traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+	if err := trace.Normalize(); err != nil {
+		t.Fatalf("Expected valid traceparent, got %v", err)
+	}
+	if trace.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected trace ID derived from traceparent, got %q", trace.TraceID)
+	}
+	if header := trace.Header(); header.Get(HeaderTraceparent) != trace.Traceparent || header.Get(HeaderTraceID) != trace.TraceID {
+		t.Errorf("Unexpected NATS headers: %v", header)
+	}
+
+	for _, invalid := range []TraceContext{
+		{Traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7"},
+		{Traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{Traceparent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{TraceID: "has space"},
+	} {
+		if err := invalid.Normalize(); err == nil {
+			t.Errorf("Expected %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestJobRequestNotifyTimeout(t *testing.T) {
+	req := JobRequest{URL: "https://example.com", Notify: &NotifyConfig{WebhookURL: "https://hooks.example.com", Timeout: 600}}
+	if err := req.Normalize(); err == nil {
+		t.Error("Expected webhook timeout above the maximum to be rejected")
+	}
+	req.Notify.Timeout = 60
+	if err := req.Normalize(); err != nil {
+		t.Errorf("Expected valid webhook timeout, got %v", err)
+	}
+
+	req.Notify.Retry = &RetryConfig{MaxRetries: MaxWebhookRetries + 1}
+	if err := req.Normalize(); err == nil {
+		t.Error("Expected webhook retries above the maximum to be rejected")
+	}
+	req.Notify.Retry = &RetryConfig{MaxRetries: 5, RetryDelay: -1}
+	if err := req.Normalize(); err == nil {
+		t.Error("Expected a negative webhook retry delay to be rejected")
+	}
+	req.Notify.Retry = nil
+
+	req.Notify.Headers = map[string]string{"X-Token": "a\r\nX-Injected: b"}
+	if err := req.Normalize(); err == nil {
+		t.Error("Expected a webhook header value with a line break to be rejected")
+	}
+	req.Notify.Headers = map[string]string{"X-Token": "abc"}
+	if err := req.Normalize(); err != nil {
+		t.Errorf("Expected valid webhook headers, got %v", err)
+	}
+}
+
+func TestJobRequestContentHash(t *testing.T) {
+	base := JobRequest{URL: "https://example.com", Headers: map[string]string{"A": "1", "B": "2"}}
+	same := JobRequest{URL: "https://example.com", Headers: map[string]string{"B": "2", "A": "1"}, WaitMode: "all", Priority: 9, Notify: &NotifyConfig{WebhookURL: "https://hooks.example.com"}}
+	if base.ContentHash() != same.ContentHash() {
+		t.Error("Expected delivery fields and defaults not to change the hash")
+	}
+
+	other := base
+	other.Script = "document.title"
+	if base.ContentHash() == other.ContentHash() {
+		t.Error("Expected script to change the hash")
+	}
+
+	authed := base
+	authed.BasicAuth = &browser.Credentials{Username: "admin", Password: "secret"}
+	if base.ContentHash() == authed.ContentHash() {
+		t.Error("Expected basic auth to change the hash")
+	}
+}
+
+func TestJobRequestNormalizePDF(t *testing.T) {
+	req := JobRequest{Type: JobTypePDF, URL: "https://example.com"}
+	if err := req.Normalize(); err != nil {
+		t.Fatalf("Expected pdf job to be valid, got %v", err)
+	}
+	if req.Engine != "chrome" {
+		t.Errorf("Expected pdf job to default to chrome, got %q", req.Engine)
+	}
+
+	invalid := []JobRequest{
+		{Type: JobTypePDF, URLs: []string{"https://example.com"}},
+		{Type: JobTypePDF, URL: "https://example.com", Engine: "lightpanda"},
+		{Type: JobTypePDF, URL: "https://example.com", Script: "1"},
+		{Type: JobTypePDF, URL: "https://example.com", PDF: &browser.PDFOptions{Format: "A3"}},
+		{URL: "https://example.com", PDF: &browser.PDFOptions{}},
+	}
+	for _, req := range invalid {
+		if err := req.Normalize(); err == nil {
+			t.Errorf("Expected %+v to be rejected", req)
+		}
+	}
+}
@@ -3,11 +3,16 @@ package queue
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/ahrdadan/scrq/internal/tracing"
+	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
 )
 
@@ -16,81 +21,359 @@ const (
 	StreamName = "SCRQ_JOBS"
 	// SubjectName is the subject for job messages
 	SubjectName = "scrq.jobs"
+	// HighPrioritySubjectName is the subject for jobs at or above
+	// HighPriorityThreshold; the worker drains it before SubjectName
+	HighPrioritySubjectName = "scrq.jobs.high"
+	// HighPriorityThreshold is the lowest priority routed to the high subject
+	HighPriorityThreshold = 8
 	// ConsumerName is the name of the durable consumer
 	ConsumerName = "scrq-worker"
+	// HighPriorityConsumerName is the name of the high-priority consumer
+	HighPriorityConsumerName = "scrq-worker-high"
+	// JobStoreBucket is the KV bucket that persists job metadata and results
+	JobStoreBucket = "SCRQ_JOB_STORE"
+	// MaxReplicas is the highest stream replication factor JetStream allows
+	MaxReplicas = 5
+	// publishAttempts is how often a job message is published before giving up
+	publishAttempts = 3
 )
 
+// publishRetryDelay is the base delay between publish attempts
+var publishRetryDelay = 200 * time.Millisecond
+
+// drainRequeueWait bounds how long Drain waits for interrupted jobs to hand
+// their messages back once the grace period is over
+var drainRequeueWait = 5 * time.Second
+
+// ackWait is how long JetStream waits for a message to be acked before
+// redelivering it. Workers extend it while a job runs longer.
+const ackWait = 5 * time.Minute
+
+// inProgressInterval is how often a running job's message is marked in
+// progress, resetting its ack wait
+var inProgressInterval = ackWait / 2
+
+// normalFetchWait bounds how long the worker waits on the normal subject,
+// and so how long a newly queued high-priority job can sit idle
+var normalFetchWait = time.Second
+
 // Manager manages the job queue
 type Manager struct {
-	js        jetstream.JetStream
-	store     *Store
-	events    *EventHub
-	stream    jetstream.Stream
-	consumer  jetstream.Consumer
-	mu        sync.Mutex
+	js       jetstream.JetStream
+	store    *Store
+	events   *EventHub
+	tracer   *tracing.Tracer
+	stream   jetstream.Stream
+	dlq      jetstream.Stream
+	consumer jetstream.Consumer
+	high     jetstream.Consumer
+	mu       sync.Mutex
+
+	// maxDeliver bounds deliveries of each job message
+	maxDeliver int
+
 	isRunning bool
+	paused    atomic.Bool
 	ctx       context.Context
 	cancel    context.CancelFunc
+
+	// active tracks jobs currently being processed, with the func
+	// canceling each one's context. inFlight counts them for Drain, which
+	// waits on idle; draining is set by Drain and, unlike paused, cannot be
+	// cleared by ResumeFetching. paused and draining change under activeMu
+	// so no job is admitted once either is seen set.
+	activeMu sync.Mutex
+	active   map[string]context.CancelFunc
+	inFlight int
+	idle     *sync.Cond
+	draining atomic.Bool
+
+	// interrupted is set when shutdown cuts running jobs short, so they are
+	// handed back to the queue instead of being retried or failed
+	interrupted atomic.Bool
+
+	statsCache consumerStatsCache
+}
+
+// ManagerConfig holds queue manager settings
+type ManagerConfig struct {
+	EventBufferSize int // Per-subscriber event buffer (default 32)
+
+	QueueConfig // Job stream retention and limits
+
+	Tracer *tracing.Tracer // Records a span per processed job (nil = no tracing)
+}
+
+// DefaultManagerConfig returns the default manager configuration
+func DefaultManagerConfig() ManagerConfig {
+	return ManagerConfig{
+		EventBufferSize: DefaultEventBufferSize,
+		QueueConfig:     DefaultQueueConfig(),
+	}
+}
+
+// Job stream storage types
+const (
+	StorageFile   = "file"
+	StorageMemory = "memory"
+)
+
+// Lower bounds of QueueConfig. A scheduled or retrying job's message is
+// delivered once to be held back until its run time and again to run, and
+// must not expire in between.
+const (
+	MinQueueMaxAge     = MaxScheduleDelay + time.Hour
+	MinQueueMaxDeliver = 2
+)
+
+// QueueConfig holds the retention and limits of the job stream. Zero values
+// take the defaults.
+type QueueConfig struct {
+	MaxAge     time.Duration // Queued jobs not picked up by then are dropped (default 24h)
+	MaxMsgs    int64         // Most messages the stream holds (0 = unlimited)
+	MaxBytes   int64         // Most bytes the stream holds (0 = unlimited)
+	Storage    string        // file (default) or memory, for the job stream, job store and dead letters
+	Replicas   int           // Stream replicas on a NATS cluster (1-5, default 1)
+	MaxDeliver int           // Deliveries of a job message before JetStream gives up on it (default 3)
+}
+
+// DefaultQueueConfig returns the default job stream settings
+func DefaultQueueConfig() QueueConfig {
+	return QueueConfig{
+		MaxAge:     24 * time.Hour,
+		Storage:    StorageFile,
+		Replicas:   1,
+		MaxDeliver: 3,
+	}
+}
+
+// normalize fills in defaults and rejects invalid settings
+func (c *QueueConfig) normalize() error {
+	defaults := DefaultQueueConfig()
+	if c.MaxAge == 0 {
+		c.MaxAge = defaults.MaxAge
+	}
+	if c.Storage == "" {
+		c.Storage = defaults.Storage
+	}
+	if c.Replicas <= 0 {
+		c.Replicas = defaults.Replicas
+	}
+	if c.MaxDeliver == 0 {
+		c.MaxDeliver = defaults.MaxDeliver
+	}
+
+	switch {
+	case c.MaxAge < MinQueueMaxAge:
+		return fmt.Errorf("queue max age must be at least %s to outlast the longest schedule delay, got %s", MinQueueMaxAge, c.MaxAge)
+	case c.MaxMsgs < 0 || c.MaxBytes < 0:
+		return errors.New("queue max messages and max bytes must not be negative")
+	case c.Storage != StorageFile && c.Storage != StorageMemory:
+		return fmt.Errorf("queue storage must be %s or %s, got %q", StorageFile, StorageMemory, c.Storage)
+	case c.Replicas > MaxReplicas:
+		return fmt.Errorf("stream replicas must be between 1 and %d, got %d", MaxReplicas, c.Replicas)
+	case c.MaxDeliver < MinQueueMaxDeliver:
+		return fmt.Errorf("queue max deliver must be at least %d so held back jobs can run, got %d", MinQueueMaxDeliver, c.MaxDeliver)
+	}
+	return nil
+}
+
+// streamConfig returns the job stream configuration. Unlimited counts are
+// -1 to JetStream. When a limit is hit new jobs are refused rather than
+// queued ones dropped.
+func (c QueueConfig) streamConfig() jetstream.StreamConfig {
+	unlimited := func(n int64) int64 {
+		if n == 0 {
+			return -1
+		}
+		return n
+	}
+	return jetstream.StreamConfig{
+		Name:        StreamName,
+		Description: "Scrq job queue",
+		Subjects:    []string{SubjectName, HighPrioritySubjectName},
+		Retention:   jetstream.WorkQueuePolicy,
+		Discard:     jetstream.DiscardNew,
+		MaxAge:      c.MaxAge,
+		MaxMsgs:     unlimited(c.MaxMsgs),
+		MaxBytes:    unlimited(c.MaxBytes),
+		Storage:     c.storageType(),
+		Replicas:    c.Replicas,
+	}
+}
+
+// storageType returns the JetStream storage type of the job stream, the job
+// store bucket and the dead-letter stream
+func (c QueueConfig) storageType() jetstream.StorageType {
+	if c.Storage == StorageMemory {
+		return jetstream.MemoryStorage
+	}
+	return jetstream.FileStorage
+}
+
+// validateWorkQueueSubjects checks that no two consumer filter subjects
+// overlap. A work queue stream delivers each message to one consumer only,
+// so JetStream rejects consumers whose filters could match the same subject.
+func validateWorkQueueSubjects(subjects ...string) error {
+	for i, a := range subjects {
+		for _, b := range subjects[i+1:] {
+			if subjectsOverlap(a, b) {
+				return fmt.Errorf("work queue subjects %q and %q overlap; give each consumer a distinct subject", a, b)
+			}
+		}
+	}
+	return nil
+}
+
+// subjectsOverlap reports whether some subject matches both a and b, which
+// may contain * and > wildcards
+func subjectsOverlap(a, b string) bool {
+	at, bt := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(at) && i < len(bt); i++ {
+		if at[i] == ">" || bt[i] == ">" {
+			return true
+		}
+		if at[i] != bt[i] && at[i] != "*" && bt[i] != "*" {
+			return false
+		}
+	}
+	return len(at) == len(bt)
 }
 
 // NewManager creates a new queue manager
 func NewManager(js jetstream.JetStream) (*Manager, error) {
+	return NewManagerWithConfig(js, DefaultManagerConfig())
+}
+
+// NewManagerWithConfig creates a new queue manager with custom settings
+func NewManagerWithConfig(js jetstream.JetStream, config ManagerConfig) (*Manager, error) {
+	if err := config.QueueConfig.normalize(); err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	m := &Manager{
-		js:     js,
-		store:  NewStore(),
-		events: NewEventHub(),
-		ctx:    ctx,
-		cancel: cancel,
+		js:         js,
+		events:     NewEventHub(config.EventBufferSize),
+		tracer:     config.Tracer,
+		maxDeliver: config.MaxDeliver,
+		ctx:        ctx,
+		cancel:     cancel,
+		active:     make(map[string]context.CancelFunc),
 	}
 
-	if err := m.setupStream(); err != nil {
+	if err := m.setupStream(config.QueueConfig); err != nil {
 		cancel()
 		return nil, fmt.Errorf("failed to setup stream: %w", err)
 	}
 
+	store, err := m.setupStore(config.QueueConfig)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to setup job store: %w", err)
+	}
+	m.store = store
+
+	// Close any open streams for jobs whose result TTL elapsed
+	m.store.SetExpireHandler(func(job *Job) {
+		m.events.Emit(job.ID, Event{
+			JobID:    job.ID,
+			Status:   JobStatusExpired,
+			Progress: job.Progress,
+			Message:  "Job result expired",
+		})
+	})
+
 	return m, nil
 }
 
 // setupStream creates or updates the JetStream stream
-func (m *Manager) setupStream() error {
+func (m *Manager) setupStream(config QueueConfig) error {
+	streamConfig := config.streamConfig()
+	if err := validateWorkQueueSubjects(streamConfig.Subjects...); err != nil {
+		return err
+	}
+	replicas := config.Replicas
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	// Create or update stream
-	stream, err := m.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
-		Name:        StreamName,
-		Description: "Scrq job queue",
-		Subjects:    []string{SubjectName},
-		Retention:   jetstream.WorkQueuePolicy,
-		MaxAge:      24 * time.Hour,
-		Storage:     jetstream.FileStorage,
-	})
+	stream, err := m.js.CreateOrUpdateStream(ctx, streamConfig)
 	if err != nil {
+		if replicas > 1 {
+			return fmt.Errorf("failed to create stream with %d replicas (needs a cluster with at least %d JetStream servers): %w", replicas, replicas, err)
+		}
 		return fmt.Errorf("failed to create stream: %w", err)
 	}
 	m.stream = stream
 
-	// Create or update consumer
+	// Create or update one consumer per subject. A work queue stream needs
+	// non-overlapping filters, so the normal consumer is narrowed first.
+	consumer, err := m.setupConsumer(ctx, ConsumerName, SubjectName)
+	if err != nil {
+		return err
+	}
+	m.consumer = consumer
+
+	high, err := m.setupConsumer(ctx, HighPriorityConsumerName, HighPrioritySubjectName)
+	if err != nil {
+		return err
+	}
+	m.high = high
+
+	return m.setupDeadLetterStream(ctx, config)
+}
+
+// setupConsumer creates or updates a durable consumer for one subject
+func (m *Manager) setupConsumer(ctx context.Context, name, subject string) (jetstream.Consumer, error) {
 	consumer, err := m.js.CreateOrUpdateConsumer(ctx, StreamName, jetstream.ConsumerConfig{
-		Name:          ConsumerName,
-		Durable:       ConsumerName,
+		Name:          name,
+		Durable:       name,
+		FilterSubject: subject,
 		AckPolicy:     jetstream.AckExplicitPolicy,
 		DeliverPolicy: jetstream.DeliverAllPolicy,
-		MaxDeliver:    3,
-		AckWait:       5 * time.Minute,
+		MaxDeliver:    m.maxDeliver,
+		AckWait:       ackWait,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to create consumer: %w", err)
+		return nil, fmt.Errorf("failed to create consumer %s: %w", name, err)
 	}
-	m.consumer = consumer
+	return consumer, nil
+}
 
-	return nil
+// setupStore creates or updates the KV bucket backing the job store and
+// loads the jobs it holds
+func (m *Manager) setupStore(config QueueConfig) (*Store, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	kv, err := m.js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:      JobStoreBucket,
+		Description: "Scrq job store",
+		Storage:     config.storageType(),
+		Replicas:    config.Replicas,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kv bucket: %w", err)
+	}
+
+	return NewStoreWithKV(kv)
 }
 
-// Start starts processing jobs from the queue
+// Start starts processing jobs from the queue, one at a time
 func (m *Manager) Start(processor JobProcessor) error {
+	return m.StartN(processor, 1)
+}
+
+// StartN starts concurrency workers sharing the consumers, so up to that
+// many jobs are processed at once
+func (m *Manager) StartN(processor JobProcessor, concurrency int) error {
+	if concurrency < 1 {
+		return fmt.Errorf("worker concurrency must be at least 1, got %d", concurrency)
+	}
+
 	m.mu.Lock()
 	if m.isRunning {
 		m.mu.Unlock()
@@ -99,27 +382,54 @@ func (m *Manager) Start(processor JobProcessor) error {
 	m.isRunning = true
 	m.mu.Unlock()
 
-	log.Println("Starting job queue worker...")
+	log.Printf("Starting %d job queue worker(s)...", concurrency)
 
-	go func() {
-		for {
-			select {
-			case <-m.ctx.Done():
-				return
-			default:
-				msgs, err := m.consumer.Fetch(1, jetstream.FetchMaxWait(5*time.Second))
-				if err != nil {
-					continue
-				}
+	for i := 0; i < concurrency; i++ {
+		go m.work(processor)
+	}
+	return nil
+}
 
-				for msg := range msgs.Messages() {
-					m.processMessage(msg, processor)
-				}
+// work fetches and processes one message at a time until the manager stops
+func (m *Manager) work(processor JobProcessor) {
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		default:
+			if m.fetchStopped() {
+				time.Sleep(time.Second)
+				continue
 			}
+
+			// High-priority jobs always go first; the normal subject
+			// is only polled when none are waiting
+			if msgs, err := m.high.FetchNoWait(1); err == nil && m.processBatch(msgs, processor) > 0 {
+				continue
+			}
+
+			msgs, err := m.consumer.Fetch(1, jetstream.FetchMaxWait(normalFetchWait))
+			if err != nil {
+				continue
+			}
+			m.processBatch(msgs, processor)
 		}
-	}()
+	}
+}
 
-	return nil
+// processBatch processes fetched messages and returns how many there were
+func (m *Manager) processBatch(msgs jetstream.MessageBatch, processor JobProcessor) int {
+	n := 0
+	for msg := range msgs.Messages() {
+		n++
+		// Hand back messages fetched while pausing or draining
+		if m.fetchStopped() {
+			_ = msg.Nak()
+			continue
+		}
+		m.processMessage(msg, processor)
+	}
+	return n
 }
 
 // Stop stops the queue manager
@@ -131,11 +441,237 @@ func (m *Manager) Stop() {
 		return
 	}
 
+	// Jobs still running are requeued rather than left running
+	m.interrupted.Store(true)
 	m.cancel()
 	m.isRunning = false
 	log.Println("Job queue worker stopped")
 }
 
+// StopFetching pauses the worker loop without tearing down NATS. Jobs can
+// still be enqueued and are processed once fetching resumes.
+func (m *Manager) StopFetching() {
+	m.activeMu.Lock()
+	defer m.activeMu.Unlock()
+	if !m.paused.Swap(true) {
+		log.Println("Job queue worker paused")
+	}
+}
+
+// ResumeFetching resumes a paused worker loop. It has no effect once the
+// manager is draining.
+func (m *Manager) ResumeFetching() {
+	m.activeMu.Lock()
+	defer m.activeMu.Unlock()
+	if !m.paused.Swap(false) {
+		return
+	}
+	if m.draining.Load() {
+		log.Println("Job queue worker stays paused while draining")
+		return
+	}
+	log.Println("Job queue worker resumed")
+}
+
+// IsPaused reports whether the worker loop is paused or draining
+func (m *Manager) IsPaused() bool {
+	return m.fetchStopped()
+}
+
+// fetchStopped reports whether new jobs are held back
+func (m *Manager) fetchStopped() bool {
+	return m.paused.Load() || m.draining.Load()
+}
+
+// Drain stops fetching new jobs and waits for in-flight jobs to finish. Jobs
+// still running when ctx is done are interrupted and Nak'd back to the queue
+// for redelivery, and the context error is returned.
+func (m *Manager) Drain(ctx context.Context) error {
+	m.activeMu.Lock()
+	if !m.draining.Swap(true) {
+		log.Println("Job queue worker draining")
+	}
+	m.activeMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.activeMu.Lock()
+		for m.inFlight > 0 {
+			m.idleCond().Wait()
+		}
+		m.activeMu.Unlock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	ids := m.interruptActive()
+	log.Printf("Drain grace period over, requeueing jobs %v", ids)
+	select {
+	case <-done:
+	case <-time.After(drainRequeueWait):
+		log.Printf("Jobs still in flight after requeue: %v", m.InFlightJobs())
+	}
+	return ctx.Err()
+}
+
+// interruptActive cancels every job being processed so it is requeued, and
+// returns their IDs
+func (m *Manager) interruptActive() []string {
+	m.interrupted.Store(true)
+
+	m.activeMu.Lock()
+	defer m.activeMu.Unlock()
+
+	ids := make([]string, 0, len(m.active))
+	for id, cancel := range m.active {
+		cancel()
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// InFlightJobs returns the IDs of jobs currently being processed
+func (m *Manager) InFlightJobs() []string {
+	m.activeMu.Lock()
+	defer m.activeMu.Unlock()
+
+	ids := make([]string, 0, len(m.active))
+	for id := range m.active {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// trackActive marks a job as being processed until the returned func is
+// called. cancel interrupts the job's processing when it is canceled. It
+// returns false, tracking nothing, when fetching is paused or draining; the
+// check and the in-flight count share activeMu so Drain never misses a job.
+func (m *Manager) trackActive(jobID string, cancel context.CancelFunc) (func(), bool) {
+	m.activeMu.Lock()
+	defer m.activeMu.Unlock()
+	if m.fetchStopped() {
+		return nil, false
+	}
+	m.active[jobID] = cancel
+	m.inFlight++
+
+	return func() {
+		m.activeMu.Lock()
+		defer m.activeMu.Unlock()
+		delete(m.active, jobID)
+		m.inFlight--
+		if m.inFlight == 0 {
+			m.idleCond().Broadcast()
+		}
+	}, true
+}
+
+// idleCond returns the condition broadcast when no job is in flight.
+// Callers hold activeMu.
+func (m *Manager) idleCond() *sync.Cond {
+	if m.idle == nil {
+		m.idle = sync.NewCond(&m.activeMu)
+	}
+	return m.idle
+}
+
+// consumerStatsTTL is how long consumer info from JetStream is reused, so
+// frequent stats polls do not each cost a NATS round trip
+const consumerStatsTTL = 2 * time.Second
+
+// QueueStats holds queue-level statistics
+type QueueStats struct {
+	Paused bool              `json:"paused"`
+	Jobs   map[JobStatus]int `json:"jobs"`
+
+	// Backlog across both consumers, and per consumer name
+	Pending     uint64                   `json:"pending"`     // Messages not yet delivered to a worker
+	AckPending  int                      `json:"ack_pending"` // Delivered and not yet acknowledged
+	Redelivered int                      `json:"redelivered"` // Delivered more than once and not yet acknowledged
+	Consumers   map[string]ConsumerStats `json:"consumers,omitempty"`
+	StatsError  string                   `json:"stats_error,omitempty"` // Set when JetStream could not be queried
+
+	CheckedAt time.Time `json:"checked_at"` // When the consumer stats were read
+}
+
+// ConsumerStats holds the backlog of one JetStream consumer
+type ConsumerStats struct {
+	Pending     uint64 `json:"pending"`
+	AckPending  int    `json:"ack_pending"`
+	Redelivered int    `json:"redelivered"`
+}
+
+// consumerStatsCache holds the last consumer stats read from JetStream
+type consumerStatsCache struct {
+	mu        sync.Mutex
+	stats     map[string]ConsumerStats
+	err       error
+	checkedAt time.Time
+}
+
+// Stats returns queue-level statistics: job counts from the store and the
+// consumers' backlog from JetStream, cached for consumerStatsTTL
+func (m *Manager) Stats() QueueStats {
+	stats := QueueStats{
+		Paused: m.IsPaused(),
+		Jobs:   m.store.CountByStatus(),
+	}
+
+	consumers, checkedAt, err := m.consumerStats()
+	if err != nil {
+		stats.StatsError = err.Error()
+	}
+	for _, consumer := range consumers {
+		stats.Pending += consumer.Pending
+		stats.AckPending += consumer.AckPending
+		stats.Redelivered += consumer.Redelivered
+	}
+	stats.Consumers = consumers
+	stats.CheckedAt = checkedAt
+	return stats
+}
+
+// consumerStats reads the backlog of each consumer, reusing the last read
+// while it is younger than consumerStatsTTL
+func (m *Manager) consumerStats() (map[string]ConsumerStats, time.Time, error) {
+	cache := &m.statsCache
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if !cache.checkedAt.IsZero() && time.Since(cache.checkedAt) < consumerStatsTTL {
+		return cache.stats, cache.checkedAt, cache.err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	stats := make(map[string]ConsumerStats, 2)
+	var err error
+	for name, consumer := range map[string]jetstream.Consumer{ConsumerName: m.consumer, HighPriorityConsumerName: m.high} {
+		if consumer == nil {
+			continue
+		}
+		info, infoErr := consumer.Info(ctx)
+		if infoErr != nil {
+			err = fmt.Errorf("failed to read consumer %s: %w", name, infoErr)
+			continue
+		}
+		stats[name] = ConsumerStats{
+			Pending:     info.NumPending,
+			AckPending:  info.NumAckPending,
+			Redelivered: info.NumRedelivered,
+		}
+	}
+
+	cache.stats, cache.err, cache.checkedAt = stats, err, time.Now()
+	return stats, cache.checkedAt, err
+}
+
 // Enqueue adds a job to the queue
 func (m *Manager) Enqueue(job *Job) error {
 	// Save job to store
@@ -143,34 +679,63 @@ func (m *Manager) Enqueue(job *Job) error {
 		return fmt.Errorf("failed to save job: %w", err)
 	}
 
-	// Publish to JetStream
+	// Publish to JetStream. Without a message the job would never run, so
+	// a failed publish removes it again instead of leaving it queued.
 	data, err := job.ToJSON()
 	if err != nil {
+		_ = m.store.Delete(job.ID)
 		return fmt.Errorf("failed to serialize job: %w", err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if _, err := m.js.Publish(ctx, SubjectName, data); err != nil {
+	if err := m.publish(job, data); err != nil {
+		_ = m.store.Delete(job.ID)
 		return fmt.Errorf("failed to publish job: %w", err)
 	}
 
 	// Emit event
+	message := "Job queued"
+	if job.Status == JobStatusScheduled {
+		message = "Job scheduled for " + time.Unix(job.RunAt, 0).UTC().Format(time.RFC3339)
+	}
 	m.events.Emit(job.ID, Event{
 		JobID:   job.ID,
 		Status:  job.Status,
-		Message: "Job queued",
+		Message: message,
 	})
 
 	return nil
 }
 
+// publish sends a job message, retrying transient failures up to
+// publishAttempts times with a growing delay
+func (m *Manager) publish(job *Job, data []byte) error {
+	var err error
+	for attempt := 1; attempt <= publishAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_, err = m.js.PublishMsg(ctx, jobMsg(job, data))
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempt < publishAttempts {
+			log.Printf("Publishing job %s failed (attempt %d/%d)%s: %v", job.ID, attempt, publishAttempts, job.Request.TraceContext, err)
+			time.Sleep(time.Duration(attempt) * publishRetryDelay)
+		}
+	}
+	return fmt.Errorf("%d attempts: %w", publishAttempts, err)
+}
+
 // GetJob retrieves a job by ID
 func (m *Manager) GetJob(jobID string) (*Job, error) {
 	return m.store.Get(jobID)
 }
 
+// ListJobs returns a page of stored jobs matching the filter, newest first,
+// and the number of jobs matching in total
+func (m *Manager) ListJobs(filter ListFilter) ([]*Job, int, error) {
+	return m.store.List(filter)
+}
+
 // UpdateJob updates a job and emits an event
 func (m *Manager) UpdateJob(job *Job) error {
 	if err := m.store.Update(job); err != nil {
@@ -184,23 +749,60 @@ func (m *Manager) UpdateJob(job *Job) error {
 		Message:  job.Message,
 	})
 
+	if job.Status.IsTerminal() {
+		notifyFinished(job)
+	}
+
 	return nil
 }
 
-// CancelJob cancels a job
-func (m *Manager) CancelJob(jobID string) (*Job, error) {
+// notifyFinished delivers the webhook for a job that reached a terminal
+// status (succeeded, failed or canceled). Every terminal transition goes
+// through UpdateJob or CancelJob, so this is the one place they notify.
+func notifyFinished(job *Job) {
+	notify := job.Notify
+	if notify == nil || notify.WebhookURL == "" {
+		return
+	}
+
+	var extra map[string]interface{}
+	if job.Status == JobStatusFailed {
+		extra = map[string]interface{}{
+			"error":       job.Error,
+			"retry_count": job.RetryCount,
+		}
+	}
+	sendWebhook(job.ID, *notify, job.Request.TraceContext, job.Status, extra)
+}
+
+// CancelJob cancels a job. A job that has already finished is returned
+// unchanged with canceled false, so cancelling is safe to repeat.
+func (m *Manager) CancelJob(jobID string) (*Job, bool, error) {
 	job, err := m.store.Get(jobID)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	if job.Status != JobStatusQueued && job.Status != JobStatusRunning {
-		return nil, fmt.Errorf("cannot cancel job with status: %s", job.Status)
+	if job.Status.IsTerminal() {
+		return job, false, nil
+	}
+	if !job.Status.Cancelable() {
+		return nil, false, fmt.Errorf("%w: status %s", ErrNotCancelable, job.Status)
 	}
 
 	job.SetStatus(JobStatusCanceled)
 	if err := m.store.Update(job); err != nil {
-		return nil, err
+		return nil, false, err
+	}
+
+	// Stop a running job's browser work now rather than at its timeout.
+	// The status is set first, so the worker sees the job as canceled and
+	// acks it without a retry.
+	m.activeMu.Lock()
+	cancel := m.active[jobID]
+	m.activeMu.Unlock()
+	if cancel != nil {
+		cancel()
 	}
 
 	m.events.Emit(job.ID, Event{
@@ -208,6 +810,35 @@ func (m *Manager) CancelJob(jobID string) (*Job, error) {
 		Status:  job.Status,
 		Message: "Job canceled",
 	})
+	notifyFinished(job)
+
+	return job, true, nil
+}
+
+// DeleteJob removes a finished job with its result and idempotency key, and
+// its dead-letter entry if it failed. Unfinished jobs must be canceled
+// first. Open event streams receive a final deleted event.
+func (m *Manager) DeleteJob(jobID string) (*Job, error) {
+	job, err := m.store.Get(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if !job.Status.IsTerminal() {
+		return nil, fmt.Errorf("%w: status %s", ErrJobNotFinished, job.Status)
+	}
+
+	if err := m.store.Delete(jobID); err != nil {
+		return nil, err
+	}
+	if job.Status == JobStatusFailed {
+		m.purgeDeadLetter(jobID)
+	}
+
+	m.events.Emit(job.ID, Event{
+		JobID:   job.ID,
+		Status:  JobStatusDeleted,
+		Message: "Job deleted",
+	})
 
 	return job, nil
 }
@@ -222,6 +853,16 @@ func (m *Manager) Unsubscribe(jobID string, ch <-chan Event) {
 	m.events.Unsubscribe(jobID, ch)
 }
 
+// SubscriberCount returns the number of SSE/WS subscribers for a job
+func (m *Manager) SubscriberCount(jobID string) int {
+	return m.events.SubscriberCount(jobID)
+}
+
+// SubscriberCounts returns the number of SSE/WS subscribers per job
+func (m *Manager) SubscriberCounts() map[string]int {
+	return m.events.SubscriberCounts()
+}
+
 // GetEventHub returns the event hub
 func (m *Manager) GetEventHub() *EventHub {
 	return m.events
@@ -270,6 +911,15 @@ func (m *Manager) processMessage(msg jetstream.Msg, processor JobProcessor) {
 		return
 	}
 
+	// Hold scheduled jobs until their run time
+	if storedJob.Status == JobStatusScheduled && storedJob.RunAt > 0 {
+		runAt := time.Unix(storedJob.RunAt, 0)
+		if time.Now().Before(runAt) {
+			_ = msg.NakWithDelay(time.Until(runAt))
+			return
+		}
+	}
+
 	// Check if we need to wait for retry delay
 	if storedJob.Status == JobStatusRetrying && storedJob.NextRetryAt > 0 {
 		waitUntil := time.Unix(storedJob.NextRetryAt, 0)
@@ -280,15 +930,60 @@ func (m *Manager) processMessage(msg jetstream.Msg, processor JobProcessor) {
 		}
 	}
 
+	// Past the deadline the result is no longer wanted; fail without running
+	if storedJob.DeadlinePassed(time.Now()) {
+		storedJob.SetError(fmt.Sprintf("%v: deadline %s passed before the job started",
+			ErrDeadlineExceeded, time.Unix(storedJob.Request.Deadline, 0).UTC().Format(time.RFC3339)))
+		_ = m.UpdateJob(storedJob)
+		_ = msg.Ack()
+		return
+	}
+
+	// Create context with timeout, shortened by the deadline if any.
+	// CancelJob cancels it to interrupt the browser work.
+	timeout := storedJob.RunTimeout(time.Now())
+	ctx, cancel := context.WithTimeout(m.ctx, timeout)
+	defer cancel()
+
+	finish, ok := m.trackActive(storedJob.ID, cancel)
+	if !ok {
+		// Paused or draining since the message was fetched
+		_ = msg.Nak()
+		return
+	}
+	defer finish()
+
+	// Jobs may run past the ack wait; without this another worker would be
+	// handed the same job while it is still running
+	defer keepInProgress(msg)()
+
+	// Canceled before its cancel func was registered
+	if storedJob.Status == JobStatusCanceled {
+		_ = msg.Ack()
+		return
+	}
+
 	// Update status to running
 	storedJob.SetStatus(JobStatusRunning)
 	storedJob.SetProgress(0, "Processing started")
 	_ = m.UpdateJob(storedJob)
 
-	// Create context with timeout
-	timeout := storedJob.GetTimeoutDuration()
-	ctx, cancel := context.WithTimeout(m.ctx, timeout)
-	defer cancel()
+	if notify := storedJob.Notify; notify != nil && notify.WebhookURL != "" && notify.NotifyOnStart {
+		sendWebhook(storedJob.ID, *notify, storedJob.Request.TraceContext, JobStatusRunning, map[string]interface{}{
+			"retry_count": storedJob.RetryCount,
+		})
+	}
+
+	// The processing span joins the trace of the request that enqueued the job
+	parent := storedJob.Traceparent
+	if parent == "" {
+		parent = storedJob.Request.Traceparent
+	}
+	ctx, span := m.tracer.Start(ctx, "scrq.job.process", tracing.SpanKindConsumer, parent)
+	defer span.End()
+	span.SetAttribute("scrq.job.id", storedJob.ID)
+	span.SetAttribute("scrq.job.type", string(storedJob.Type))
+	span.SetAttribute("scrq.job.retry_count", storedJob.RetryCount)
 
 	// Process the job with progress callback that supports page X/Y
 	result, err := processor.Process(ctx, storedJob, func(progress int, message string) {
@@ -296,7 +991,27 @@ func (m *Manager) processMessage(msg jetstream.Msg, processor JobProcessor) {
 		_ = m.UpdateJob(storedJob)
 	})
 
+	// A job canceled while it ran stays canceled; its webhook already went out
+	if storedJob.Status == JobStatusCanceled {
+		_ = msg.Ack()
+		return
+	}
+
+	// Cut short by shutdown: hand the message back so another worker, or
+	// this one after a restart, runs the job again from the start
+	if err != nil && m.interrupted.Load() && ctx.Err() == context.Canceled {
+		log.Printf("Job %s interrupted by shutdown, requeueing%s", storedJob.ID, storedJob.Request.TraceContext)
+		span.AddEvent("requeued on shutdown")
+		storedJob.SetStatus(JobStatusQueued)
+		storedJob.SetProgress(0, "Requeued on shutdown")
+		_ = m.UpdateJob(storedJob)
+		_ = msg.Nak()
+		return
+	}
+
 	if err != nil {
+		span.RecordError(err)
+
 		// Check if we can retry
 		if storedJob.CanRetry() {
 			storedJob.LastError = err.Error()
@@ -311,21 +1026,33 @@ func (m *Manager) processMessage(msg jetstream.Msg, processor JobProcessor) {
 				Message:  fmt.Sprintf("Retrying (%d/%d): %s", storedJob.RetryCount, storedJob.MaxRetries, err.Error()),
 			})
 
-			// Re-enqueue for retry
-			data, _ := storedJob.ToJSON()
-			retryCtx, retryCancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer retryCancel()
+			if notify := storedJob.Notify; notify != nil && notify.WebhookURL != "" && notify.NotifyOnRetry {
+				sendWebhook(storedJob.ID, *notify, storedJob.Request.TraceContext, JobStatusRetrying, map[string]interface{}{
+					"retry_count":   storedJob.RetryCount,
+					"max_retries":   storedJob.MaxRetries,
+					"next_retry_at": storedJob.NextRetryAt,
+					"error":         err.Error(),
+				})
+			}
 
-			if _, pubErr := m.js.Publish(retryCtx, SubjectName, data); pubErr != nil {
-				log.Printf("Failed to re-enqueue job for retry: %v", pubErr)
+			// Re-enqueue for retry; a job that cannot be re-published fails
+			// rather than waiting in retrying forever
+			data, _ := storedJob.ToJSON()
+			if pubErr := m.publish(storedJob, data); pubErr != nil {
+				log.Printf("Failed to re-enqueue job %s for retry%s: %v", storedJob.ID, storedJob.Request.TraceContext, pubErr)
+				storedJob.SetError(fmt.Sprintf("failed to schedule retry: %v (last error: %v)", pubErr, err))
+				_ = m.UpdateJob(storedJob)
+				m.deadLetter(storedJob)
 			}
 
 			_ = msg.Ack()
 			return
 		}
 
+		log.Printf("Job %s failed%s: %v", storedJob.ID, storedJob.Request.TraceContext, err)
 		storedJob.SetError(err.Error())
 		_ = m.UpdateJob(storedJob)
+		m.deadLetter(storedJob)
 		_ = msg.Ack()
 		return
 	}
@@ -335,6 +1062,50 @@ func (m *Manager) processMessage(msg jetstream.Msg, processor JobProcessor) {
 	_ = msg.Ack()
 }
 
+// keepInProgress marks msg in progress every inProgressInterval until the
+// returned func is called
+func keepInProgress(msg jetstream.Msg) func() {
+	stop, done := make(chan struct{}), make(chan struct{})
+	ticker := time.NewTicker(inProgressInterval)
+	go func() {
+		defer close(done)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := msg.InProgress(); err != nil {
+					log.Printf("Failed to extend ack wait: %v", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+// jobMsg builds the JetStream message for a job, carrying its trace
+// context as headers so consumers can correlate it without decoding the body
+func jobMsg(job *Job, data []byte) *nats.Msg {
+	msg := nats.NewMsg(jobSubject(job))
+	msg.Data = data
+	if !job.Request.TraceContext.IsZero() {
+		msg.Header = job.Request.TraceContext.Header()
+	}
+	return msg
+}
+
+// jobSubject returns the subject a job is published on
+func jobSubject(job *Job) string {
+	if job.Priority >= HighPriorityThreshold {
+		return HighPrioritySubjectName
+	}
+	return SubjectName
+}
+
 // JobProcessor defines the interface for processing jobs
 type JobProcessor interface {
 	Process(ctx context.Context, job *Job, progress func(int, string)) (interface{}, error)
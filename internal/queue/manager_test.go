@@ -0,0 +1,637 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// failingJetStream is a JetStream whose publishes always fail
+type failingJetStream struct {
+	jetstream.JetStream
+	attempts int
+}
+
+func (f *failingJetStream) PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	f.attempts++
+	return nil, errors.New("nats: no responders available for request")
+}
+
+func TestEnqueuePublishFailureRemovesJob(t *testing.T) {
+	defer func(delay time.Duration) { publishRetryDelay = delay }(publishRetryDelay)
+	publishRetryDelay = time.Millisecond
+
+	js := &failingJetStream{}
+	m := &Manager{js: js, store: NewStore(), events: NewEventHub(0)}
+	defer m.store.Stop()
+
+	job := NewJob(JobRequest{Type: JobTypeScrape, URL: "https://example.com"})
+	job.IdempotencyKey = "key-1"
+
+	if _, _, err := m.EnqueueWithIdempotency(job); err == nil {
+		t.Fatal("Expected enqueue to fail")
+	}
+	if js.attempts != publishAttempts {
+		t.Errorf("Expected %d publish attempts, got %d", publishAttempts, js.attempts)
+	}
+	if _, err := m.GetJob(job.ID); err == nil {
+		t.Error("Expected no orphaned queued job after a failed publish")
+	}
+	if _, exists := m.store.GetByIdempotencyKey("key-1"); exists {
+		t.Error("Expected idempotency key to be released")
+	}
+	if counts := m.store.CountByStatus(); counts[JobStatusQueued] != 0 {
+		t.Errorf("Expected no queued jobs, got %d", counts[JobStatusQueued])
+	}
+}
+
+// recordingJetStream is a JetStream that records published subjects
+type recordingJetStream struct {
+	jetstream.JetStream
+	subjects []string
+}
+
+func (r *recordingJetStream) PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	r.subjects = append(r.subjects, msg.Subject)
+	return &jetstream.PubAck{}, nil
+}
+
+func TestEnqueueRoutesByPriority(t *testing.T) {
+	js := &recordingJetStream{}
+	m := &Manager{js: js, store: NewStore(), events: NewEventHub(0)}
+	defer m.store.Stop()
+
+	for _, priority := range []int{5, HighPriorityThreshold, 10} {
+		job := NewJob(JobRequest{Type: JobTypeScrape, URL: "https://example.com"})
+		job.Priority = priority
+		if err := m.Enqueue(job); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+
+	want := []string{SubjectName, HighPrioritySubjectName, HighPrioritySubjectName}
+	if len(js.subjects) != len(want) {
+		t.Fatalf("Expected %d publishes, got %v", len(want), js.subjects)
+	}
+	for i := range want {
+		if js.subjects[i] != want[i] {
+			t.Errorf("Publish %d: expected subject %s, got %s", i, want[i], js.subjects[i])
+		}
+	}
+}
+
+func TestTerminalWebhooks(t *testing.T) {
+	events := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		events <- r.Header.Get("X-Scrq-Event")
+	}))
+	defer server.Close()
+
+	m := &Manager{store: NewStore(), events: NewEventHub(0)}
+	defer m.store.Stop()
+
+	newJob := func() *Job {
+		job := NewJob(JobRequest{Type: JobTypeScrape, URL: "https://example.com"})
+		job.Notify = &NotifyConfig{WebhookURL: server.URL}
+		if err := m.store.Save(job); err != nil {
+			t.Fatalf("Failed to store job: %v", err)
+		}
+		return job
+	}
+	receive := func() string {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for webhook")
+			return ""
+		}
+	}
+
+	failed := newJob()
+	failed.SetError("boom")
+	if err := m.UpdateJob(failed); err != nil {
+		t.Fatalf("UpdateJob failed: %v", err)
+	}
+	if event := receive(); event != "job.failed" {
+		t.Errorf("Expected job.failed, got %q", event)
+	}
+
+	if _, canceled, err := m.CancelJob(newJob().ID); err != nil || !canceled {
+		t.Fatalf("CancelJob failed: canceled=%v err=%v", canceled, err)
+	}
+	if event := receive(); event != "job.canceled" {
+		t.Errorf("Expected job.canceled, got %q", event)
+	}
+
+	succeeded := newJob()
+	succeeded.SetProgress(50, "halfway")
+	_ = m.UpdateJob(succeeded)
+	succeeded.SetResult("ok")
+	_ = m.UpdateJob(succeeded)
+	if event := receive(); event != "job.succeeded" {
+		t.Errorf("Expected only job.succeeded, got %q", event)
+	}
+}
+
+// ackMsg is a JetStream message that records acks and naks
+type ackMsg struct {
+	jetstream.Msg
+	data  []byte
+	acked chan struct{}
+	naks  int
+	delay time.Duration
+}
+
+func (a *ackMsg) Data() []byte { return a.data }
+func (a *ackMsg) Ack() error   { close(a.acked); return nil }
+func (a *ackMsg) Nak() error   { a.naks++; return nil }
+
+func (a *ackMsg) NakWithDelay(delay time.Duration) error {
+	a.naks++
+	a.delay = delay
+	return nil
+}
+
+// blockingProcessor runs until its context is done
+type blockingProcessor struct{ started chan struct{} }
+
+func (p blockingProcessor) Process(ctx context.Context, job *Job, progress func(int, string)) (interface{}, error) {
+	close(p.started)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestCancelJobInterruptsRunningJob(t *testing.T) {
+	js := &recordingJetStream{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := &Manager{js: js, store: NewStore(), events: NewEventHub(0), ctx: ctx, cancel: cancel, active: make(map[string]context.CancelFunc)}
+	defer m.store.Stop()
+
+	job := NewJob(JobRequest{Type: JobTypeScrape, URL: "https://example.com"})
+	job.Timeout = 300
+	if err := m.store.Save(job); err != nil {
+		t.Fatalf("Failed to store job: %v", err)
+	}
+	data, _ := job.ToJSON()
+	msg := &ackMsg{data: data, acked: make(chan struct{})}
+
+	processor := blockingProcessor{started: make(chan struct{})}
+	done := make(chan struct{})
+	go func() {
+		m.processMessage(msg, processor)
+		close(done)
+	}()
+
+	<-processor.started
+	if _, canceled, err := m.CancelJob(job.ID); err != nil || !canceled {
+		t.Fatalf("CancelJob failed: canceled=%v err=%v", canceled, err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the running job to stop once canceled")
+	}
+	select {
+	case <-msg.acked:
+	default:
+		t.Error("Expected the message to be acked")
+	}
+	if msg.naks != 0 || len(js.subjects) != 0 {
+		t.Errorf("Expected no redelivery or retry, got %d naks and %d publishes", msg.naks, len(js.subjects))
+	}
+	if stored, _ := m.GetJob(job.ID); stored.Status != JobStatusCanceled {
+		t.Errorf("Expected status canceled, got %s", stored.Status)
+	}
+	if len(m.InFlightJobs()) != 0 {
+		t.Errorf("Expected no in-flight jobs, got %v", m.InFlightJobs())
+	}
+}
+
+func TestDeleteJob(t *testing.T) {
+	m := &Manager{store: NewStore(), events: NewEventHub(0)}
+	defer m.store.Stop()
+
+	job := NewJob(JobRequest{Type: JobTypeScrape, URL: "https://example.com", IdempotencyKey: "key-1"})
+	if err := m.store.Save(job); err != nil {
+		t.Fatalf("Failed to store job: %v", err)
+	}
+	if _, err := m.DeleteJob(job.ID); !errors.Is(err, ErrJobNotFinished) {
+		t.Fatalf("Expected a queued job to be kept, got %v", err)
+	}
+
+	events := m.Subscribe(job.ID)
+	job.SetResult("secret")
+	if _, err := m.DeleteJob(job.ID); err != nil {
+		t.Fatalf("DeleteJob failed: %v", err)
+	}
+	if event := <-events; event.Status != JobStatusDeleted || !event.Status.IsTerminal() {
+		t.Errorf("Expected a final deleted event, got %+v", event)
+	}
+	if _, err := m.GetJob(job.ID); err == nil {
+		t.Error("Expected the job to be gone")
+	}
+	if _, ok := m.store.GetByIdempotencyKey("key-1"); ok {
+		t.Error("Expected the idempotency key to be released")
+	}
+	if _, err := m.DeleteJob(job.ID); err == nil {
+		t.Error("Expected deleting twice to fail")
+	}
+}
+
+// infoConsumer reports a fixed backlog and counts Info calls
+type infoConsumer struct {
+	jetstream.Consumer
+	info  jetstream.ConsumerInfo
+	calls int
+}
+
+func (c *infoConsumer) Info(ctx context.Context) (*jetstream.ConsumerInfo, error) {
+	c.calls++
+	info := c.info
+	return &info, nil
+}
+
+func TestStatsReportsConsumerBacklog(t *testing.T) {
+	normal := &infoConsumer{info: jetstream.ConsumerInfo{NumPending: 7, NumAckPending: 2, NumRedelivered: 1}}
+	high := &infoConsumer{info: jetstream.ConsumerInfo{NumPending: 3, NumAckPending: 1}}
+	m := &Manager{store: NewStore(), events: NewEventHub(0), consumer: normal, high: high}
+	defer m.store.Stop()
+
+	if err := m.store.Save(NewJob(JobRequest{Type: JobTypeScrape, URL: "https://example.com"})); err != nil {
+		t.Fatalf("Failed to store job: %v", err)
+	}
+
+	stats := m.Stats()
+	if stats.Pending != 10 || stats.AckPending != 3 || stats.Redelivered != 1 || stats.StatsError != "" {
+		t.Errorf("Expected the backlog summed across consumers, got %+v", stats)
+	}
+	if stats.Consumers[HighPriorityConsumerName].Pending != 3 || stats.Jobs[JobStatusQueued] != 1 {
+		t.Errorf("Expected per-consumer and per-status counts, got %+v", stats)
+	}
+
+	// A poll within the cache TTL does not query JetStream again
+	normal.info.NumPending = 100
+	if stats := m.Stats(); stats.Pending != 10 || normal.calls != 1 {
+		t.Errorf("Expected cached consumer stats, got pending %d after %d calls", stats.Pending, normal.calls)
+	}
+
+	m.statsCache.checkedAt = time.Now().Add(-consumerStatsTTL)
+	if stats := m.Stats(); stats.Pending != 103 || normal.calls != 2 {
+		t.Errorf("Expected fresh consumer stats once the cache expired, got pending %d", stats.Pending)
+	}
+}
+
+func TestQueueConfig(t *testing.T) {
+	var config QueueConfig
+	if err := config.normalize(); err != nil {
+		t.Fatalf("Expected the zero config to take the defaults, got %v", err)
+	}
+	if config != DefaultQueueConfig() {
+		t.Errorf("Expected the defaults, got %+v", config)
+	}
+	stream := config.streamConfig()
+	if stream.MaxAge != 24*time.Hour || stream.Storage != jetstream.FileStorage || stream.Retention != jetstream.WorkQueuePolicy || stream.MaxMsgs != -1 || stream.MaxBytes != -1 {
+		t.Errorf("Expected today's stream settings, got %+v", stream)
+	}
+
+	config = QueueConfig{MaxMsgs: 1000, MaxBytes: 1 << 20, Storage: StorageMemory, Replicas: 3, MaxDeliver: 5}
+	if err := config.normalize(); err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	stream = config.streamConfig()
+	if stream.MaxMsgs != 1000 || stream.MaxBytes != 1<<20 || stream.Storage != jetstream.MemoryStorage || stream.Replicas != 3 || stream.Discard != jetstream.DiscardNew {
+		t.Errorf("Expected the configured limits, got %+v", stream)
+	}
+	if config.storageType() != jetstream.MemoryStorage || DefaultQueueConfig().storageType() != jetstream.FileStorage {
+		t.Error("Expected the configured storage for the job store and dead letters too")
+	}
+
+	for _, invalid := range []QueueConfig{
+		{MaxAge: -time.Hour},
+		{MaxAge: MaxScheduleDelay},
+		{MaxDeliver: 1},
+		{MaxMsgs: -1},
+		{MaxBytes: -1},
+		{Storage: "disk"},
+		{Replicas: MaxReplicas + 1},
+		{MaxDeliver: -1},
+	} {
+		if err := invalid.normalize(); err == nil {
+			t.Errorf("Expected %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestValidateWorkQueueSubjects(t *testing.T) {
+	if err := validateWorkQueueSubjects(SubjectName, HighPrioritySubjectName); err != nil {
+		t.Errorf("Expected the job subjects not to overlap, got %v", err)
+	}
+
+	cases := []struct {
+		a, b    string
+		overlap bool
+	}{
+		{"scrq.jobs", "scrq.jobs", true},
+		{"scrq.jobs", "scrq.jobs.high", false},
+		{"scrq.*", "scrq.jobs", true},
+		{"scrq.*", "scrq.jobs.high", false},
+		{"scrq.>", "scrq.jobs.high", true},
+		{"scrq.jobs.>", "scrq.jobs", false},
+		{"scrq.*.high", "scrq.jobs.*", true},
+		{"scrq.jobs.low", "scrq.jobs.high", false},
+	}
+	for _, tc := range cases {
+		if got := subjectsOverlap(tc.a, tc.b); got != tc.overlap {
+			t.Errorf("subjectsOverlap(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.overlap)
+		}
+	}
+	if err := validateWorkQueueSubjects("scrq.jobs", "scrq.jobs.high", "scrq.>"); err == nil {
+		t.Error("Expected overlapping subjects to be rejected")
+	}
+}
+
+func TestDrainRequeuesJobAfterGracePeriod(t *testing.T) {
+	js := &recordingJetStream{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := &Manager{js: js, store: NewStore(), events: NewEventHub(0), ctx: ctx, cancel: cancel, active: make(map[string]context.CancelFunc)}
+	defer m.store.Stop()
+
+	job := NewJob(JobRequest{Type: JobTypeScrape, URL: "https://example.com"})
+	job.Timeout = 300
+	if err := m.store.Save(job); err != nil {
+		t.Fatalf("Failed to store job: %v", err)
+	}
+	data, _ := job.ToJSON()
+	msg := &ackMsg{data: data, acked: make(chan struct{})}
+
+	processor := blockingProcessor{started: make(chan struct{})}
+	done := make(chan struct{})
+	go func() {
+		m.processMessage(msg, processor)
+		close(done)
+	}()
+	<-processor.started
+
+	grace, cancelGrace := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelGrace()
+	if err := m.Drain(grace); err != context.DeadlineExceeded {
+		t.Errorf("Expected the grace period to run out, got %v", err)
+	}
+	<-done
+
+	if !m.IsPaused() {
+		t.Error("Expected fetching to stop")
+	}
+	if msg.naks != 1 || len(js.subjects) != 0 {
+		t.Errorf("Expected the message to be Nak'd for redelivery, got %d naks and %d publishes", msg.naks, len(js.subjects))
+	}
+	select {
+	case <-msg.acked:
+		t.Error("Expected the interrupted job not to be acked")
+	default:
+	}
+	if stored, _ := m.GetJob(job.ID); stored.Status != JobStatusQueued || stored.RetryCount != 0 {
+		t.Errorf("Expected the job back in queued without a retry, got %s with %d retries", stored.Status, stored.RetryCount)
+	}
+	if len(m.InFlightJobs()) != 0 {
+		t.Errorf("Expected no in-flight jobs, got %v", m.InFlightJobs())
+	}
+}
+
+func TestDrainWaitsForRunningJob(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := &Manager{ctx: ctx, cancel: cancel, active: make(map[string]context.CancelFunc)}
+
+	finish, ok := m.trackActive("job_1", func() {})
+	if !ok {
+		t.Fatal("Expected the job to be admitted before draining")
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		finish()
+	}()
+
+	grace, cancelGrace := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelGrace()
+	if err := m.Drain(grace); err != nil {
+		t.Errorf("Expected the job to finish within the grace period, got %v", err)
+	}
+	if m.interrupted.Load() {
+		t.Error("Expected a job finishing in time not to be interrupted")
+	}
+}
+
+func TestDrainRejectsLateJobsAndIgnoresResume(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := &Manager{ctx: ctx, cancel: cancel, active: make(map[string]context.CancelFunc)}
+
+	finish, _ := m.trackActive("job_1", func() {})
+	drained := make(chan error, 1)
+	go func() { drained <- m.Drain(context.Background()) }()
+	for !m.IsPaused() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A job fetched before the drain began is handed back, not started
+	if _, ok := m.trackActive("job_2", func() {}); ok {
+		t.Error("Expected a job to be rejected while draining")
+	}
+	m.StopFetching()
+	m.ResumeFetching()
+	if !m.IsPaused() {
+		t.Error("Expected resume not to undo a drain in progress")
+	}
+	select {
+	case err := <-drained:
+		t.Fatalf("Expected drain to wait for the running job, got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	finish()
+	if err := <-drained; err != nil {
+		t.Errorf("Expected drain to finish once the job did, got %v", err)
+	}
+}
+
+// queuedConsumer is a consumer handing out the messages sent on msgs
+type queuedConsumer struct {
+	jetstream.Consumer
+	msgs chan jetstream.Msg
+}
+
+// queuedBatch is a fetched batch of at most one message
+type queuedBatch struct {
+	jetstream.MessageBatch
+	msgs chan jetstream.Msg
+}
+
+func (b queuedBatch) Messages() <-chan jetstream.Msg { return b.msgs }
+
+func (c *queuedConsumer) fetch(wait time.Duration) jetstream.MessageBatch {
+	batch := queuedBatch{msgs: make(chan jetstream.Msg, 1)}
+	select {
+	case msg := <-c.msgs:
+		batch.msgs <- msg
+	case <-time.After(wait):
+	}
+	close(batch.msgs)
+	return batch
+}
+
+func (c *queuedConsumer) Fetch(n int, opts ...jetstream.FetchOpt) (jetstream.MessageBatch, error) {
+	return c.fetch(10 * time.Millisecond), nil
+}
+
+func (c *queuedConsumer) FetchNoWait(n int) (jetstream.MessageBatch, error) {
+	return c.fetch(0), nil
+}
+
+// gatedProcessor reports each job it starts and holds it until release
+// is closed
+type gatedProcessor struct {
+	started chan string
+	release chan struct{}
+}
+
+func (p gatedProcessor) Process(ctx context.Context, job *Job, progress func(int, string)) (interface{}, error) {
+	p.started <- job.ID
+	<-p.release
+	return "done", nil
+}
+
+func TestStartNProcessesJobsConcurrently(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := &Manager{
+		store:    NewStore(),
+		events:   NewEventHub(0),
+		consumer: &queuedConsumer{msgs: make(chan jetstream.Msg, 3)},
+		high:     &queuedConsumer{msgs: make(chan jetstream.Msg)},
+		ctx:      ctx,
+		cancel:   cancel,
+		active:   make(map[string]context.CancelFunc),
+	}
+	defer m.store.Stop()
+
+	var msgs []*ackMsg
+	for i := 0; i < 3; i++ {
+		job := NewJob(JobRequest{Type: JobTypeScrape, URL: "https://example.com"})
+		if err := m.store.Save(job); err != nil {
+			t.Fatalf("Failed to store job: %v", err)
+		}
+		data, _ := job.ToJSON()
+		msg := &ackMsg{data: data, acked: make(chan struct{})}
+		msgs = append(msgs, msg)
+		m.consumer.(*queuedConsumer).msgs <- msg
+	}
+
+	if err := m.StartN(gatedProcessor{}, 0); err == nil {
+		t.Fatal("Expected an error for zero workers")
+	}
+
+	processor := gatedProcessor{started: make(chan string, 3), release: make(chan struct{})}
+	if err := m.StartN(processor, 3); err != nil {
+		t.Fatalf("StartN: %v", err)
+	}
+	defer m.Stop()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-processor.started:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected 3 jobs running at once, got %d", i)
+		}
+	}
+	if n := len(m.InFlightJobs()); n != 3 {
+		t.Errorf("Expected 3 in-flight jobs, got %d", n)
+	}
+
+	close(processor.release)
+	for _, msg := range msgs {
+		select {
+		case <-msg.acked:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected every job to be acked")
+		}
+	}
+}
+
+func TestKeepInProgressExtendsAckWait(t *testing.T) {
+	defer func(interval time.Duration) { inProgressInterval = interval }(inProgressInterval)
+	inProgressInterval = 5 * time.Millisecond
+
+	msg := &progressMsg{}
+	stop := keepInProgress(msg)
+	time.Sleep(30 * time.Millisecond)
+	stop()
+
+	n := msg.count.Load()
+	if n == 0 {
+		t.Fatal("Expected the message to be marked in progress")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if msg.count.Load() != n {
+		t.Error("Expected no progress marks after stopping")
+	}
+}
+
+// progressMsg counts InProgress calls
+type progressMsg struct {
+	jetstream.Msg
+	count atomic.Int32
+}
+
+func (p *progressMsg) InProgress() error {
+	p.count.Add(1)
+	return nil
+}
+
+func TestScheduledJobWaitsForRunTime(t *testing.T) {
+	js := &recordingJetStream{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := &Manager{js: js, store: NewStore(), events: NewEventHub(0), ctx: ctx, cancel: cancel, active: make(map[string]context.CancelFunc)}
+	defer m.store.Stop()
+
+	job := NewJob(JobRequest{Type: JobTypeScrape, URL: "https://example.com", DelaySeconds: 3600})
+	if job.Status != JobStatusScheduled || job.RunAt == 0 {
+		t.Fatalf("Expected a scheduled job with a run time, got %s at %d", job.Status, job.RunAt)
+	}
+	if err := m.Enqueue(job); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	data, _ := job.ToJSON()
+	msg := &ackMsg{data: data, acked: make(chan struct{})}
+	m.processMessage(msg, failingProcessor{})
+	if msg.naks != 1 || msg.delay < 59*time.Minute {
+		t.Errorf("Expected one nak delayed until the run time, got %d naks with %v", msg.naks, msg.delay)
+	}
+	if stored, _ := m.GetJob(job.ID); stored.Status != JobStatusScheduled {
+		t.Errorf("Expected the job to stay scheduled, got %s", stored.Status)
+	}
+
+	if _, canceled, err := m.CancelJob(job.ID); err != nil || !canceled {
+		t.Fatalf("CancelJob failed: canceled=%v err=%v", canceled, err)
+	}
+	msg = &ackMsg{data: data, acked: make(chan struct{})}
+	m.processMessage(msg, failingProcessor{})
+	select {
+	case <-msg.acked:
+	default:
+		t.Error("Expected the canceled scheduled job to be acked")
+	}
+}
@@ -1,15 +1,15 @@
 package queue
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"encoding/base64"
+	"errors"
 	"fmt"
-	"log"
-	"net/http"
+	"sync"
 	"time"
 
 	"github.com/ahrdadan/scrq/internal/browser"
+	"github.com/ahrdadan/scrq/internal/tracing"
 )
 
 // ScrapeProcessor processes scrape jobs
@@ -99,58 +99,20 @@ func (p *ScrapeProcessor) Process(ctx context.Context, job *Job, progress func(i
 	reporter := NewProgressReporter(job, progress)
 	reporter.SetStage("initialization")
 
-	// Select browser client based on engine
+	// Static requests never touch a browser, so no engine is needed
 	var client browser.Client
-	switch req.Engine {
-	case "chrome":
-		if p.chrome == nil {
-			return nil, fmt.Errorf("chrome engine not available")
-		}
-		client = p.chrome
-	case "lightpanda", "":
-		if p.lightpanda == nil {
-			return nil, fmt.Errorf("lightpanda engine not available")
-		}
-		client = p.lightpanda
-		if req.Proxy != "" {
-			return nil, fmt.Errorf("proxy is only supported with chrome engine")
+	var err error
+	if !req.Static() {
+		client, err = p.selectClient(req)
+		if err != nil {
+			return nil, err
 		}
-	default:
-		return nil, fmt.Errorf("unknown engine: %s", req.Engine)
 	}
 
-	reporter.Report(10, "Initializing browser")
+	reporter.Report(5, "Initializing browser")
 	reporter.SetStage("browser_ready")
 
-	// Build page options
-	opts := browser.DefaultPageOptions()
-	if req.Timeout > 0 {
-		opts.Timeout = time.Duration(req.Timeout) * time.Second
-	}
-	opts.WaitForLoad = req.WaitForLoad
-	opts.UserAgent = req.UserAgent
-	opts.Headers = req.Headers
-	opts.Proxy = req.Proxy
-
-	// Convert cookies
-	for _, c := range req.Cookies {
-		opts.Cookies = append(opts.Cookies, browser.CookieParam{
-			Name:     c.Name,
-			Value:    c.Value,
-			URL:      c.URL,
-			Domain:   c.Domain,
-			Path:     c.Path,
-			Expires:  c.Expires,
-			HTTPOnly: c.HTTPOnly,
-			Secure:   c.Secure,
-		})
-	}
-
-	reporter.SetStage("fetching")
-	reporter.SetPageProgress(1, 1, "Fetching page")
-
-	var result interface{}
-	var err error
+	opts := buildPageOptions(req)
 
 	// Check context before processing
 	select {
@@ -159,16 +121,36 @@ func (p *ScrapeProcessor) Process(ctx context.Context, job *Job, progress func(i
 	default:
 	}
 
-	if req.Script != "" {
-		reporter.SetStage("script_execution")
-		reporter.Report(50, "Executing script")
-		result, err = client.EvaluateScript(ctx, req.URL, req.Script, opts)
+	var result interface{}
+	if job.Type == JobTypeBatch {
+		result, err = p.processBatch(ctx, job, client, opts, reporter)
 	} else {
-		result, err = client.FetchPage(ctx, req.URL, opts)
+		opts.OnPhase = func(phase browser.PagePhase) {
+			reportPagePhase(reporter, phase, req.Script != "")
+		}
+		// Scripts report their own progress via window.__scrqProgress
+		if req.Script != "" {
+			opts.OnProgress = reporter.SetItemProgress
+		}
+		reporter.SetStage("navigating")
+		reporter.Report(10, "Navigating to page")
+
+		result, err = scrapeURL(ctx, client, req.URL, req, opts)
 	}
 
 	if err != nil {
+		if screenshot := browser.FailureScreenshot(err); len(screenshot) > 0 {
+			job.ErrorScreenshot = base64.StdEncoding.EncodeToString(screenshot)
+		}
+		if console := browser.ConsoleMessages(err); len(console) > 0 {
+			job.ErrorConsole = console
+		}
+
 		// Check if it's a timeout error
+		if ctx.Err() != nil && job.DeadlinePassed(time.Now()) {
+			return nil, fmt.Errorf("%w at %s: %w", ErrDeadlineExceeded,
+				time.Unix(job.Request.Deadline, 0).UTC().Format(time.RFC3339), ctx.Err())
+		}
 		if ctx.Err() != nil {
 			return nil, fmt.Errorf("job timed out after %v: %w", job.GetTimeoutDuration(), ctx.Err())
 		}
@@ -176,12 +158,7 @@ func (p *ScrapeProcessor) Process(ctx context.Context, job *Job, progress func(i
 	}
 
 	reporter.SetStage("processing")
-	reporter.Report(90, "Processing result")
-
-	// Send webhook if configured
-	if job.Notify != nil && job.Notify.WebhookURL != "" {
-		go sendWebhook(job.ID, job.Notify.WebhookURL, "succeeded")
-	}
+	reporter.Report(90, "Extraction complete")
 
 	reporter.SetStage("completed")
 	reporter.Report(100, "Job completed successfully")
@@ -189,42 +166,236 @@ func (p *ScrapeProcessor) Process(ctx context.Context, job *Job, progress func(i
 	return result, nil
 }
 
-// sendWebhook sends a webhook notification
-func sendWebhook(jobID, webhookURL, status string) {
-	payload := map[string]interface{}{
-		"job_id":      jobID,
-		"status":      status,
-		"result_url":  fmt.Sprintf("/scrq/jobs/%s/result", jobID),
-		"finished_at": time.Now().Unix(),
+// reportPagePhase maps page loading phases of a single-page job to progress
+func reportPagePhase(reporter *ProgressReporter, phase browser.PagePhase, hasScript bool) {
+	switch phase {
+	case browser.PhaseNavigated:
+		reporter.Report(40, "Navigation complete")
+	case browser.PhaseLoaded:
+		reporter.Report(60, "Page load complete")
+		if hasScript {
+			reporter.SetStage("script_execution")
+			reporter.Report(70, "Executing script")
+		} else {
+			reporter.SetStage("extracting")
+			reporter.Report(70, "Extracting content")
+		}
 	}
+}
 
-	data, err := json.Marshal(payload)
-	if err != nil {
-		log.Printf("Failed to marshal webhook payload: %v", err)
-		return
+// processBatch scrapes the URLs of a batch job, up to req.Concurrent at
+// once, and returns their results in request order. Per-URL failures are
+// recorded in the result instead of failing the whole job.
+func (p *ScrapeProcessor) processBatch(ctx context.Context, job *Job, client browser.Client, opts browser.PageOptions, reporter *ProgressReporter) ([]BatchItemResult, error) {
+	req := job.Request
+	reporter.SetStage("fetching")
+
+	concurrent := req.Concurrent
+	if concurrent <= 0 {
+		concurrent = DefaultBatchConcurrency
+	}
+	// Back off when the browser starts timing out, ramp up on success
+	limiter := browser.NewAdaptiveLimiter(concurrent)
+
+	results := make([]BatchItemResult, len(req.URLs))
+	finished := make([]bool, len(req.URLs))
+	var mu sync.Mutex
+	completed := 0
+
+	var wg sync.WaitGroup
+	for i, targetURL := range req.URLs {
+		wg.Add(1)
+		go func(idx int, targetURL string) {
+			defer wg.Done()
+			limiter.Acquire()
+			if ctx.Err() != nil {
+				limiter.Release(nil)
+				return
+			}
+
+			item := BatchItemResult{URL: targetURL}
+			data, err := scrapeURL(ctx, client, targetURL, req, opts)
+			// A failure while the browser is down means it crashed or is restarting
+			if err != nil && client != nil && !client.IsRunning() {
+				limiter.Backoff()
+			}
+			limiter.Release(err)
+			// An item cut short by the job timeout is not a per-URL failure
+			if err != nil && ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				item.Error = err.Error()
+			} else {
+				item.Result = data
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[idx] = item
+			finished[idx] = true
+			completed++
+			reporter.SetItemProgress(completed, len(req.URLs), targetURL)
+		}(i, targetURL)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		done := make([]BatchItemResult, 0, completed)
+		for i, item := range results {
+			if finished[i] {
+				done = append(done, item)
+			}
+		}
+		return partialBatch(ctx, job, done)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	return results, nil
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(data))
-	if err != nil {
-		log.Printf("Failed to create webhook request: %v", err)
-		return
+// partialBatch handles a batch job whose context ended early. When the job
+// timed out and opted into partial results, the items completed so far are
+// returned and the job is flagged as partial; otherwise the context error is
+// returned and the collected results are discarded.
+func partialBatch(ctx context.Context, job *Job, results []BatchItemResult) ([]BatchItemResult, error) {
+	if !job.Request.PartialOnTimeout || len(results) == 0 || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, ctx.Err()
+	}
+
+	job.Partial = true
+	job.Warning = fmt.Sprintf("job timed out after %d of %d URLs; returning partial results", len(results), len(job.Request.URLs))
+	return results, nil
+}
+
+// scrapeURL scrapes one URL in a child span of the job's processing span,
+// with the page loading phases recorded as span events
+func scrapeURL(ctx context.Context, client browser.Client, targetURL string, req JobRequest, opts browser.PageOptions) (interface{}, error) {
+	ctx, span := tracing.Start(ctx, "scrq.page")
+	defer span.End()
+	span.SetAttribute("url.full", targetURL)
+
+	if span != nil {
+		onPhase := opts.OnPhase
+		opts.OnPhase = func(phase browser.PagePhase) {
+			span.AddEvent(string(phase))
+			if onPhase != nil {
+				onPhase(phase)
+			}
+		}
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Scrq-Event", "job."+status)
+	result, err := scrapeTarget(ctx, client, targetURL, req, opts)
+	span.RecordError(err)
+	return result, err
+}
+
+// scrapeTarget renders a PDF, runs the job script against a URL, captures a
+// download, extracts text by selector, or fetches the page content when none
+// is set
+func scrapeTarget(ctx context.Context, client browser.Client, targetURL string, req JobRequest, opts browser.PageOptions) (interface{}, error) {
+	if req.Type == JobTypePDF {
+		var pdfOpts browser.PDFOptions
+		if req.PDF != nil {
+			pdfOpts = *req.PDF
+		}
+		pdf, err := client.RenderPDF(ctx, targetURL, pdfOpts, opts)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"url":          targetURL,
+			"content_type": "application/pdf",
+			"size":         len(pdf),
+			"data":         pdf, // base64 in JSON
+		}, nil
+	}
+	if req.DownloadSelector != "" {
+		return client.DownloadFile(ctx, targetURL, req.DownloadSelector, opts)
+	}
+	if req.Script != "" {
+		return client.EvaluateScript(ctx, targetURL, req.Script, opts)
+	}
+	if len(req.Selectors) > 0 {
+		return client.ExtractBySelectors(ctx, targetURL, req.Selectors, opts)
+	}
 
-	resp, err := http.DefaultClient.Do(req)
+	var page *browser.PageResult
+	var err error
+	if req.Static() {
+		page, err = browser.FetchStatic(ctx, targetURL, opts)
+	} else {
+		page, err = client.FetchPage(ctx, targetURL, opts)
+	}
 	if err != nil {
-		log.Printf("Failed to send webhook: %v", err)
-		return
+		return nil, err
 	}
-	defer resp.Body.Close()
+	// Drop unrequested fields before the result is stored
+	return page.Project(req.Fields)
+}
 
-	if resp.StatusCode >= 400 {
-		log.Printf("Webhook returned error status: %d", resp.StatusCode)
+// selectClient returns the browser client for the requested engine
+func (p *ScrapeProcessor) selectClient(req JobRequest) (browser.Client, error) {
+	switch req.Engine {
+	case "chrome":
+		if p.chrome == nil {
+			return nil, fmt.Errorf("chrome engine not available")
+		}
+		return p.chrome, nil
+	case "lightpanda", "":
+		if p.lightpanda == nil {
+			return nil, fmt.Errorf("lightpanda engine not available")
+		}
+		if req.Proxy != "" {
+			return nil, fmt.Errorf("proxy is only supported with chrome engine")
+		}
+		return p.lightpanda, nil
+	default:
+		return nil, fmt.Errorf("unknown engine: %s", req.Engine)
 	}
 }
 
+// buildPageOptions converts job request settings to browser page options
+func buildPageOptions(req JobRequest) browser.PageOptions {
+	opts := browser.DefaultPageOptions()
+	if req.Timeout > 0 {
+		opts.Timeout = time.Duration(req.Timeout) * time.Second
+	}
+	opts.WaitForLoad = req.WaitForLoad
+	opts.UserAgent = req.UserAgent
+	opts.Headers = req.Headers
+	opts.Proxy = req.Proxy
+	opts.ScreenshotOnFailure = req.ScreenshotOnFailure
+	opts.AcceptLanguage = req.AcceptLanguage
+	opts.WaitForFunction = req.WaitForFunction
+	opts.WaitPollInterval = time.Duration(req.WaitPollInterval) * time.Millisecond
+	opts.WaitForSelectors = req.WaitForSelectors
+	opts.WaitMode = browser.WaitMode(req.WaitMode)
+	opts.WaitForSelector = req.WaitForSelector
+	opts.WaitForSelectorTimeout = time.Duration(req.WaitForSelectorTimeout) * time.Millisecond
+	opts.ScriptTimeout = time.Duration(req.ScriptTimeout) * time.Millisecond
+	opts.AutoScroll = req.AutoScroll
+	opts.ScrollSteps = req.ScrollSteps
+	opts.ScrollDelay = time.Duration(req.ScrollDelay) * time.Millisecond
+	opts.BlockResources = req.BlockResources
+	opts.DismissConsent = req.DismissConsent
+	opts.PierceShadow = req.PierceShadow
+	opts.IncludeCookies = req.IncludeCookies
+	opts.CaptureConsole = req.CaptureConsole
+	opts.BasicAuth = req.BasicAuth
+
+	// Convert cookies
+	for _, c := range req.Cookies {
+		opts.Cookies = append(opts.Cookies, browser.CookieParam{
+			Name:     c.Name,
+			Value:    c.Value,
+			URL:      c.URL,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  c.Expires,
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+		})
+	}
+
+	return opts
+}
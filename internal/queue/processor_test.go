@@ -0,0 +1,172 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ahrdadan/scrq/internal/browser/browsertest"
+)
+
+func TestPartialBatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	collected := []BatchItemResult{{URL: "https://example.com/1"}}
+
+	job := NewJob(JobRequest{Type: JobTypeBatch, URLs: []string{"https://example.com/1", "https://example.com/2"}})
+	if _, err := partialBatch(ctx, job, collected); err == nil {
+		t.Errorf("Expected timeout error when partial results are not enabled")
+	}
+
+	job.Request.PartialOnTimeout = true
+	results, err := partialBatch(ctx, job, collected)
+	if err != nil {
+		t.Fatalf("Expected partial results, got error: %v", err)
+	}
+	if len(results) != 1 || !job.Partial || job.Warning == "" {
+		t.Errorf("Expected job flagged partial with 1 result, got %d results, partial=%v", len(results), job.Partial)
+	}
+
+	canceled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	if _, err := partialBatch(canceled, NewJob(job.Request), collected); err == nil {
+		t.Errorf("Expected cancellation to discard partial results")
+	}
+}
+
+func TestScrapeProcessorWithMockClient(t *testing.T) {
+	lightpanda := browsertest.NewMockClient()
+	chrome := browsertest.NewMockClient()
+	chrome.ScriptResult = "Example"
+	processor := NewScrapeProcessor(lightpanda, chrome)
+
+	job := NewJob(JobRequest{URL: "https://example.com", Engine: "chrome", Script: "document.title", UserAgent: "scrq-test"})
+	result, err := processor.Process(context.Background(), job, func(int, string) {})
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if result != "Example" {
+		t.Errorf("Expected script result, got %v", result)
+	}
+	call, ok := chrome.LastCall(browsertest.MethodEvaluateScript)
+	if !ok || call.URL != "https://example.com" || call.Opts.UserAgent != "scrq-test" || call.Args[0] != "document.title" {
+		t.Errorf("Unexpected chrome call: %+v", call)
+	}
+	if len(lightpanda.Calls()) != 0 {
+		t.Errorf("Expected lightpanda to be unused, got %v", lightpanda.Calls())
+	}
+	if call.Opts.OnProgress == nil {
+		t.Fatal("Expected script jobs to receive a progress callback")
+	}
+	var lastMessage string
+	job = NewJob(JobRequest{URL: "https://example.com", Engine: "chrome", Script: "document.title"})
+	if _, err := processor.Process(context.Background(), job, func(_ int, message string) { lastMessage = message }); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	call, _ = chrome.LastCall(browsertest.MethodEvaluateScript)
+	call.Opts.OnProgress(3, 4, "page 3")
+	if lastMessage != "[Item 3/4] page 3" || job.ProgressInfo.CurrentItem != 3 || job.ProgressInfo.TotalItems != 4 {
+		t.Errorf("Expected script progress to be reported as item progress, got %q %+v", lastMessage, job.ProgressInfo)
+	}
+
+	lightpanda.ErrorFunc = func(method, url string) error {
+		if url == "https://example.com/2" {
+			return errors.New("boom")
+		}
+		return nil
+	}
+	batch := NewJob(JobRequest{Type: JobTypeBatch, URLs: []string{"https://example.com/1", "https://example.com/2"}})
+	result, err = processor.Process(context.Background(), batch, func(int, string) {})
+	if err != nil {
+		t.Fatalf("Process batch failed: %v", err)
+	}
+	items := result.([]BatchItemResult)
+	if len(items) != 2 || items[0].Error != "" || items[1].Error != "boom" {
+		t.Errorf("Expected second item to fail, got %+v", items)
+	}
+	if calls := lightpanda.CallsTo(browsertest.MethodFetchPage); len(calls) != 2 {
+		t.Errorf("Expected 2 fetches, got %d", len(calls))
+	}
+}
+
+func TestScrapeProcessorBatchConcurrency(t *testing.T) {
+	lightpanda := browsertest.NewMockClient()
+	processor := NewScrapeProcessor(lightpanda, browsertest.NewMockClient())
+
+	var inFlight, peak atomic.Int32
+	lightpanda.ErrorFunc = func(method, url string) error {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		if url == "https://example.com/3" {
+			return errors.New("boom")
+		}
+		return nil
+	}
+
+	urls := []string{"https://example.com/1", "https://example.com/2", "https://example.com/3", "https://example.com/4", "https://example.com/5"}
+	job := NewJob(JobRequest{Type: JobTypeBatch, URLs: urls, Concurrent: 2})
+
+	var mu sync.Mutex
+	var messages []string
+	result, err := processor.Process(context.Background(), job, func(_ int, message string) {
+		mu.Lock()
+		messages = append(messages, message)
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatalf("Process batch failed: %v", err)
+	}
+
+	if peak.Load() > 2 {
+		t.Errorf("Expected at most 2 URLs in flight, got %d", peak.Load())
+	}
+	items := result.([]BatchItemResult)
+	if len(items) != len(urls) {
+		t.Fatalf("Expected %d results, got %d", len(urls), len(items))
+	}
+	for i, item := range items {
+		if item.URL != urls[i] {
+			t.Errorf("Result %d: expected %s, got %s", i, urls[i], item.URL)
+		}
+		if failed := item.Error != ""; failed != (i == 2) {
+			t.Errorf("Result %d: unexpected error %q", i, item.Error)
+		}
+	}
+
+	itemProgress := 0
+	for _, message := range messages {
+		if strings.HasPrefix(message, "[Item ") {
+			itemProgress++
+		}
+	}
+	if itemProgress != len(urls) {
+		t.Errorf("Expected %d item progress reports, got %v", len(urls), messages)
+	}
+}
+
+func TestScrapeProcessorWaitForSelector(t *testing.T) {
+	lightpanda := browsertest.NewMockClient()
+	processor := NewScrapeProcessor(lightpanda, browsertest.NewMockClient())
+
+	job := NewJob(JobRequest{URL: "https://example.com", WaitForSelector: ".results", WaitForSelectorTimeout: 1500})
+	if _, err := processor.Process(context.Background(), job, func(int, string) {}); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	call, ok := lightpanda.LastCall(browsertest.MethodFetchPage)
+	if !ok || call.Opts.WaitForSelector != ".results" || call.Opts.WaitForSelectorTimeout != 1500*time.Millisecond {
+		t.Errorf("Unexpected fetch call: %+v", call)
+	}
+}
@@ -0,0 +1,163 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrRecipeNotFound is returned when no recipe (or recipe version) exists
+// under the requested name
+var ErrRecipeNotFound = errors.New("recipe not found")
+
+// Recipe is a named, versioned extraction template. Request holds every job
+// option except the target URL(s), which are supplied when the recipe runs.
+type Recipe struct {
+	Name        string     `json:"name"`
+	Version     int        `json:"version"`
+	Description string     `json:"description,omitempty"`
+	Request     JobRequest `json:"request"`
+	CreatedAt   int64      `json:"created_at"`
+}
+
+// Validate checks that the recipe is a usable template
+func (r *Recipe) Validate() error {
+	if r.Name == "" {
+		return errors.New("recipe name is required")
+	}
+	if r.Request.URL != "" || len(r.Request.URLs) > 0 {
+		return errors.New("recipe request must not contain url or urls")
+	}
+
+	// Normalize a copy with a placeholder URL to catch invalid options
+	probe := r.Request
+	probe.URL = "https://example.com"
+	if r.Request.Type == JobTypeBatch {
+		probe.URL = ""
+		probe.URLs = []string{"https://example.com"}
+	}
+	if err := probe.Normalize(); err != nil {
+		return fmt.Errorf("invalid recipe request: %w", err)
+	}
+	return nil
+}
+
+// Build returns the job request for running the recipe against urls. A
+// single URL yields a scrape job, several a batch job.
+func (r *Recipe) Build(urls []string) JobRequest {
+	req := r.Request
+	if len(urls) == 1 && req.Type != JobTypeBatch {
+		req.URL = urls[0]
+	} else {
+		req.URLs = urls
+	}
+	req.Recipe = r.Name
+	req.RecipeVersion = r.Version
+	return req
+}
+
+// RecipeStore keeps every version of each recipe in memory
+type RecipeStore struct {
+	recipes map[string][]Recipe // name -> versions, oldest first
+	mu      sync.RWMutex
+}
+
+// NewRecipeStore creates an empty recipe store
+func NewRecipeStore() *RecipeStore {
+	return &RecipeStore{
+		recipes: make(map[string][]Recipe),
+	}
+}
+
+// LoadRecipeFile creates a store from a JSON file holding an array of
+// recipes. Each recipe becomes version 1.
+func LoadRecipeFile(path string) (*RecipeStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recipes: %w", err)
+	}
+
+	var recipes []Recipe
+	if err := json.Unmarshal(data, &recipes); err != nil {
+		return nil, fmt.Errorf("failed to parse recipes: %w", err)
+	}
+
+	store := NewRecipeStore()
+	for _, recipe := range recipes {
+		if _, err := store.Put(recipe); err != nil {
+			return nil, fmt.Errorf("recipe %q: %w", recipe.Name, err)
+		}
+	}
+	return store, nil
+}
+
+// Put validates the recipe and stores it as the next version of its name
+func (s *RecipeStore) Put(recipe Recipe) (Recipe, error) {
+	if err := recipe.Validate(); err != nil {
+		return Recipe{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := s.recipes[recipe.Name]
+	recipe.Version = len(versions) + 1
+	recipe.CreatedAt = time.Now().Unix()
+	s.recipes[recipe.Name] = append(versions, recipe)
+	return recipe, nil
+}
+
+// Get returns a recipe version, or the latest version if version is 0
+func (s *RecipeStore) Get(name string, version int) (Recipe, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	versions := s.recipes[name]
+	if len(versions) == 0 {
+		return Recipe{}, fmt.Errorf("%w: %s", ErrRecipeNotFound, name)
+	}
+	if version == 0 {
+		return versions[len(versions)-1], nil
+	}
+	if version < 0 || version > len(versions) {
+		return Recipe{}, fmt.Errorf("%w: %s version %d", ErrRecipeNotFound, name, version)
+	}
+	return versions[version-1], nil
+}
+
+// Versions returns every version of a recipe, oldest first
+func (s *RecipeStore) Versions(name string) []Recipe {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]Recipe(nil), s.recipes[name]...)
+}
+
+// List returns the latest version of every recipe, sorted by name
+func (s *RecipeStore) List() []Recipe {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]Recipe, 0, len(s.recipes))
+	for _, versions := range s.recipes {
+		list = append(list, versions[len(versions)-1])
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Delete removes a recipe and all its versions
+func (s *RecipeStore) Delete(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.recipes[name]; !exists {
+		return false
+	}
+	delete(s.recipes, name)
+	return true
+}
@@ -0,0 +1,63 @@
+package queue
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecipeStoreVersions(t *testing.T) {
+	store := NewRecipeStore()
+
+	first, err := store.Put(Recipe{Name: "product", Request: JobRequest{Script: "() => document.title"}})
+	if err != nil {
+		t.Fatalf("Failed to put recipe: %v", err)
+	}
+	second, err := store.Put(Recipe{Name: "product", Request: JobRequest{Script: "() => location.href"}})
+	if err != nil {
+		t.Fatalf("Failed to put recipe: %v", err)
+	}
+	if first.Version != 1 || second.Version != 2 {
+		t.Fatalf("Expected versions 1 and 2, got %d and %d", first.Version, second.Version)
+	}
+
+	latest, err := store.Get("product", 0)
+	if err != nil || latest.Request.Script != "() => location.href" {
+		t.Errorf("Expected latest version, got %+v (%v)", latest, err)
+	}
+	if old, err := store.Get("product", 1); err != nil || old.Request.Script != "() => document.title" {
+		t.Errorf("Expected version 1, got %+v (%v)", old, err)
+	}
+	if _, err := store.Get("product", 3); !errors.Is(err, ErrRecipeNotFound) {
+		t.Errorf("Expected ErrRecipeNotFound, got %v", err)
+	}
+
+	req := latest.Build([]string{"https://example.com"})
+	if req.URL != "https://example.com" || req.Recipe != "product" || req.RecipeVersion != 2 {
+		t.Errorf("Unexpected built request: %+v", req)
+	}
+
+	if _, err := store.Put(Recipe{Name: "bad", Request: JobRequest{URL: "https://example.com"}}); err == nil {
+		t.Error("Expected a recipe with a URL to be rejected")
+	}
+	if _, err := store.Put(Recipe{Name: "bad", Request: JobRequest{WaitMode: "some"}}); err == nil {
+		t.Error("Expected a recipe with invalid options to be rejected")
+	}
+}
+
+func TestLoadRecipeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recipes.json")
+	data := `[{"name": "headlines", "description": "Front page headlines", "request": {"script": "() => [...document.querySelectorAll('h2')].map(h => h.innerText)"}}]`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("Failed to write recipes: %v", err)
+	}
+
+	store, err := LoadRecipeFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load recipes: %v", err)
+	}
+	if list := store.List(); len(list) != 1 || list[0].Name != "headlines" || list[0].Version != 1 {
+		t.Errorf("Unexpected recipes: %+v", list)
+	}
+}
@@ -1,20 +1,45 @@
 package queue
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
 )
 
-// Store is an in-memory job store with TTL support
+// kvTimeout bounds each read or write against the backing KV bucket
+const kvTimeout = 5 * time.Second
+
+// removedSeq marks the writer of a removed job so no later write lands
+const removedSeq = ^uint64(0)
+
+// jobWriter orders the KV writes of one job. Each write takes the next
+// sequence under the store lock; a write older than the last one to land
+// is dropped so a slow Put cannot regress the stored job.
+type jobWriter struct {
+	next    uint64 // Last sequence handed out, guarded by Store.mu
+	mu      sync.Mutex
+	written uint64 // Sequence of the last write to land, guarded by mu
+}
+
+// Store is an in-memory job store with TTL support. With a KV bucket
+// (see NewStoreWithKV) every write goes through to the bucket so jobs
+// survive restarts.
 type Store struct {
 	jobs           map[string]*Job
 	idempotencyMap map[string]string // idempotency_key -> job_id
+	kv             jetstream.KeyValue
+	writers        map[string]*jobWriter
 	mu             sync.RWMutex
 	cleanupTicker  *time.Ticker
 	stopCleanup    chan struct{}
+	onExpire       func(job *Job)
 }
 
 // NewStore creates a new job store
@@ -31,6 +56,174 @@ func NewStore() *Store {
 	return s
 }
 
+// NewStoreWithKV creates a job store backed by a JetStream KV bucket. Jobs
+// already in the bucket are loaded, rebuilding the idempotency index;
+// expired ones are removed from the bucket instead.
+func NewStoreWithKV(kv jetstream.KeyValue) (*Store, error) {
+	s := &Store{
+		jobs:           make(map[string]*Job),
+		idempotencyMap: make(map[string]string),
+		kv:             kv,
+		writers:        make(map[string]*jobWriter),
+		stopCleanup:    make(chan struct{}),
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	s.startCleanup()
+
+	return s, nil
+}
+
+// load rehydrates the in-memory indexes from the KV bucket
+func (s *Store) load() error {
+	ctx, cancel := context.WithTimeout(context.Background(), kvTimeout)
+	defer cancel()
+
+	lister, err := s.kv.ListKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list stored jobs: %w", err)
+	}
+	defer lister.Stop()
+
+	var keys []string
+	for key := range lister.Keys() {
+		keys = append(keys, key)
+	}
+
+	loaded := 0
+	for _, key := range keys {
+		job, err := s.fetch(ctx, key)
+		if err != nil {
+			log.Printf("Skipping stored job %s: %v", key, err)
+			continue
+		}
+		if job.IsExpired() {
+			s.remove(key, nil)
+			continue
+		}
+		s.jobs[job.ID] = job
+		if job.IdempotencyKey != "" {
+			s.idempotencyMap[job.IdempotencyKey] = job.ID
+		}
+		loaded++
+	}
+
+	if loaded > 0 {
+		log.Printf("Loaded %d jobs from the job store", loaded)
+	}
+	return nil
+}
+
+// fetch reads a single job from the KV bucket
+func (s *Store) fetch(ctx context.Context, jobID string) (*Job, error) {
+	entry, err := s.kv.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return FromJSON(entry.Value())
+}
+
+// loadJob reads a job from the KV bucket and caches it in memory
+func (s *Store) loadJob(jobID string) (*Job, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), kvTimeout)
+	defer cancel()
+
+	job, err := s.fetch(ctx, jobID)
+	if err != nil {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cached, ok := s.jobs[jobID]; ok {
+		return cached, true
+	}
+	s.jobs[job.ID] = job
+	if job.IdempotencyKey != "" {
+		s.idempotencyMap[job.IdempotencyKey] = job.ID
+	}
+	return job, true
+}
+
+// pendingWrite is a job snapshot waiting to be written to the KV bucket
+type pendingWrite struct {
+	jobID  string
+	data   []byte
+	seq    uint64
+	writer *jobWriter
+}
+
+// snapshot serializes a job for persist and assigns it the next sequence
+// of the job's writer. It returns nil without a KV bucket. Callers hold
+// s.mu, so the job cannot change while it is copied.
+func (s *Store) snapshot(job *Job) (*pendingWrite, error) {
+	if s.kv == nil {
+		return nil, nil
+	}
+	data, err := job.ToJSON()
+	if err != nil {
+		return nil, err
+	}
+	w, ok := s.writers[job.ID]
+	if !ok {
+		w = &jobWriter{}
+		s.writers[job.ID] = w
+	}
+	w.next++
+	return &pendingWrite{jobID: job.ID, data: data, seq: w.next, writer: w}, nil
+}
+
+// persist writes a snapshot to the KV bucket unless a newer one of the
+// same job already landed. It must be called without holding s.mu.
+func (s *Store) persist(write *pendingWrite) error {
+	if write == nil {
+		return nil
+	}
+	w := write.writer
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if write.seq <= w.written {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), kvTimeout)
+	defer cancel()
+	if _, err := s.kv.Put(ctx, write.jobID, write.data); err != nil {
+		return err
+	}
+	w.written = write.seq
+	return nil
+}
+
+// takeWriter detaches the writer of a job being removed. Callers hold s.mu.
+func (s *Store) takeWriter(jobID string) *jobWriter {
+	w := s.writers[jobID]
+	delete(s.writers, jobID)
+	return w
+}
+
+// remove deletes a job from the KV bucket, if any. The job's writer, when
+// given, is retired first so writes still in flight are dropped. It must
+// be called without holding s.mu.
+func (s *Store) remove(jobID string, w *jobWriter) {
+	if s.kv == nil {
+		return
+	}
+	if w != nil {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.written = removedSeq
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), kvTimeout)
+	defer cancel()
+	if err := s.kv.Delete(ctx, jobID); err != nil && !errors.Is(err, jetstream.ErrKeyNotFound) {
+		log.Printf("Failed to remove job %s from the job store: %v", jobID, err)
+	}
+}
+
 // startCleanup starts the background TTL cleanup
 func (s *Store) startCleanup() {
 	s.cleanupTicker = time.NewTicker(1 * time.Hour)
@@ -48,13 +241,21 @@ func (s *Store) startCleanup() {
 	}()
 }
 
+// SetExpireHandler registers a callback invoked for each job removed by the
+// TTL cleanup. The callback runs outside the store lock.
+func (s *Store) SetExpireHandler(fn func(job *Job)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onExpire = fn
+}
+
 // cleanupExpired removes expired jobs
 func (s *Store) cleanupExpired() {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 
 	now := time.Now().Unix()
-	deleted := 0
+	var expired []*Job
+	writers := make(map[string]*jobWriter)
 
 	for jobID, job := range s.jobs {
 		if job.IsExpired() {
@@ -63,12 +264,26 @@ func (s *Store) cleanupExpired() {
 				delete(s.idempotencyMap, job.IdempotencyKey)
 			}
 			delete(s.jobs, jobID)
-			deleted++
+			writers[jobID] = s.takeWriter(jobID)
+			expired = append(expired, job)
 		}
 	}
+	onExpire := s.onExpire
 
-	if deleted > 0 {
-		log.Printf("Cleaned up %d expired jobs (now: %d)", deleted, now)
+	s.mu.Unlock()
+
+	for _, job := range expired {
+		s.remove(job.ID, writers[job.ID])
+	}
+
+	if len(expired) > 0 {
+		log.Printf("Cleaned up %d expired jobs (now: %d)", len(expired), now)
+	}
+
+	if onExpire != nil {
+		for _, job := range expired {
+			onExpire(job)
+		}
 	}
 }
 
@@ -77,11 +292,26 @@ func (s *Store) Stop() {
 	close(s.stopCleanup)
 }
 
-// Save saves a job to the store
+// Save saves a job to the store. The job is written to the KV bucket, if
+// any, before it becomes visible; a failed write leaves the store as it was.
 func (s *Store) Save(job *Job) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	write, err := s.snapshot(job)
+	s.mu.Unlock()
+	if err == nil {
+		err = s.persist(write)
+	}
+	if err != nil {
+		s.mu.Lock()
+		if _, ok := s.jobs[job.ID]; !ok {
+			delete(s.writers, job.ID)
+		}
+		s.mu.Unlock()
+		return fmt.Errorf("failed to persist job: %w", err)
+	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.jobs[job.ID] = job
 
 	// Save idempotency mapping if key provided
@@ -115,12 +345,16 @@ func (s *Store) GetByIdempotencyKey(key string) (*Job, bool) {
 	return job, true
 }
 
-// Get retrieves a job by ID
+// Get retrieves a job by ID. Jobs missing from memory are read from the
+// KV bucket, if any.
 func (s *Store) Get(jobID string) (*Job, error) {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
-
 	job, ok := s.jobs[jobID]
+	s.mu.RUnlock()
+
+	if !ok && s.kv != nil {
+		job, ok = s.loadJob(jobID)
+	}
 	if !ok {
 		return nil, fmt.Errorf("job not found: %s", jobID)
 	}
@@ -133,34 +367,100 @@ func (s *Store) Get(jobID string) (*Job, error) {
 	return job, nil
 }
 
-// Update updates a job in the store
+// Update updates a job in the store. The KV bucket is written after the
+// store lock is released so readers never wait on it; a failed write is
+// logged and the in-memory copy stays authoritative.
 func (s *Store) Update(job *Job) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	if _, ok := s.jobs[job.ID]; !ok {
+		s.mu.Unlock()
 		return fmt.Errorf("job not found: %s", job.ID)
 	}
 	s.jobs[job.ID] = job
+	write, err := s.snapshot(job)
+	s.mu.Unlock()
+
+	if err == nil {
+		err = s.persist(write)
+	}
+	if err != nil {
+		log.Printf("Failed to persist job %s: %v", job.ID, err)
+	}
 	return nil
 }
 
 // Delete removes a job from the store
 func (s *Store) Delete(jobID string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	if job, ok := s.jobs[jobID]; ok && job.IdempotencyKey != "" && s.idempotencyMap[job.IdempotencyKey] == jobID {
+		delete(s.idempotencyMap, job.IdempotencyKey)
+	}
 	delete(s.jobs, jobID)
+	w := s.takeWriter(jobID)
+	s.mu.Unlock()
+
+	s.remove(jobID, w)
 	return nil
 }
 
-// List returns all jobs
-func (s *Store) List() ([]*Job, error) {
+// List returns the page of unexpired jobs selected by filter, newest first,
+// and how many jobs match in total. Only matching jobs are collected.
+func (s *Store) List(filter ListFilter) ([]*Job, int, error) {
+	s.mu.RLock()
+	matched := make([]*Job, 0)
+	for _, job := range s.jobs {
+		if filter.matches(job) {
+			matched = append(matched, job)
+		}
+	}
+	s.mu.RUnlock()
+
+	// Newest first; IDs break ties so pages are stable
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].CreatedAt != matched[j].CreatedAt {
+			return matched[i].CreatedAt > matched[j].CreatedAt
+		}
+		return matched[i].ID < matched[j].ID
+	})
+
+	total := len(matched)
+	if filter.Offset >= total {
+		return []*Job{}, total, nil
+	}
+	matched = matched[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(matched) {
+		matched = matched[:filter.Limit]
+	}
+	return matched, total, nil
+}
+
+// ListFilter selects and pages the jobs returned by Store.List
+type ListFilter struct {
+	Status JobStatus // Only jobs with this status (any if empty)
+	Type   JobType   // Only jobs of this type (any if empty)
+	Limit  int       // Maximum jobs returned (no limit if 0)
+	Offset int       // Matching jobs skipped before the first returned
+}
+
+func (f ListFilter) matches(job *Job) bool {
+	if job.IsExpired() {
+		return false
+	}
+	if f.Status != "" && job.Status != f.Status {
+		return false
+	}
+	return f.Type == "" || job.Type == f.Type
+}
+
+// CountByStatus returns the number of stored jobs per status
+func (s *Store) CountByStatus() map[JobStatus]int {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	jobs := make([]*Job, 0, len(s.jobs))
+	counts := make(map[JobStatus]int)
 	for _, job := range s.jobs {
-		jobs = append(jobs, job)
+		counts[job.Status]++
 	}
-	return jobs, nil
+	return counts
 }
 
 // ToJSON serializes a job to JSON
@@ -0,0 +1,293 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func TestCleanupExpiredNotifiesHandler(t *testing.T) {
+	store := NewStore()
+	defer store.Stop()
+
+	job := NewJob(JobRequest{Type: JobTypeScrape, URL: "https://example.com"})
+	job.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	if err := store.Save(job); err != nil {
+		t.Fatalf("Failed to save job: %v", err)
+	}
+
+	var expired []string
+	store.SetExpireHandler(func(j *Job) {
+		expired = append(expired, j.ID)
+	})
+
+	store.cleanupExpired()
+
+	if len(expired) != 1 || expired[0] != job.ID {
+		t.Errorf("Expected handler to be called for %s, got %v", job.ID, expired)
+	}
+
+	if _, err := store.Get(job.ID); err == nil {
+		t.Errorf("Expected expired job to be removed")
+	}
+}
+
+func TestExpiredEventClosesSubscriber(t *testing.T) {
+	hub := NewEventHub(0)
+	store := NewStore()
+	defer store.Stop()
+
+	store.SetExpireHandler(func(j *Job) {
+		hub.Emit(j.ID, Event{JobID: j.ID, Status: JobStatusExpired})
+	})
+
+	job := NewJob(JobRequest{Type: JobTypeScrape, URL: "https://example.com"})
+	job.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	_ = store.Save(job)
+
+	events := hub.Subscribe(job.ID)
+	defer hub.Unsubscribe(job.ID, events)
+
+	store.cleanupExpired()
+
+	select {
+	case event := <-events:
+		if !event.Status.IsTerminal() {
+			t.Errorf("Expected terminal status, got %s", event.Status)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected expired event")
+	}
+}
+
+func TestSubscriberCount(t *testing.T) {
+	hub := NewEventHub(0)
+
+	first := hub.Subscribe("job-1")
+	hub.Subscribe("job-1")
+	hub.Subscribe("job-2")
+
+	if n := hub.SubscriberCount("job-1"); n != 2 {
+		t.Errorf("Expected 2 subscribers for job-1, got %d", n)
+	}
+
+	hub.Unsubscribe("job-1", first)
+	counts := hub.SubscriberCounts()
+	if counts["job-1"] != 1 || counts["job-2"] != 1 {
+		t.Errorf("Unexpected subscriber counts: %v", counts)
+	}
+
+	if n := hub.SubscriberCount("missing"); n != 0 {
+		t.Errorf("Expected 0 subscribers for unknown job, got %d", n)
+	}
+}
+
+func TestListFiltersSortsAndPages(t *testing.T) {
+	s := NewStore()
+	defer s.Stop()
+
+	for i, status := range []JobStatus{JobStatusRunning, JobStatusSucceeded, JobStatusRunning, JobStatusRunning} {
+		job := NewJob(JobRequest{Type: JobTypeScrape, URL: "https://example.com"})
+		job.ID = string(rune('a' + i))
+		job.Status = status
+		job.CreatedAt = int64(100 + i)
+		s.Save(job)
+	}
+	batch := NewJob(JobRequest{Type: JobTypeBatch, URLs: []string{"https://example.com"}})
+	batch.Status = JobStatusRunning
+	s.Save(batch)
+	expired := NewJob(JobRequest{Type: JobTypeScrape, URL: "https://example.com"})
+	expired.Status = JobStatusRunning
+	expired.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	s.Save(expired)
+
+	jobs, total, err := s.List(ListFilter{Status: JobStatusRunning, Type: JobTypeScrape, Limit: 2})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("Expected total 3, got %d", total)
+	}
+	if len(jobs) != 2 || jobs[0].ID != "d" || jobs[1].ID != "c" {
+		t.Fatalf("Expected jobs d, c newest first, got %v", jobs)
+	}
+
+	jobs, total, _ = s.List(ListFilter{Status: JobStatusRunning, Type: JobTypeScrape, Limit: 2, Offset: 2})
+	if total != 3 || len(jobs) != 1 || jobs[0].ID != "a" {
+		t.Fatalf("Expected last page with job a, got %v (total %d)", jobs, total)
+	}
+
+	jobs, total, _ = s.List(ListFilter{Offset: 10})
+	if total != 5 || len(jobs) != 0 {
+		t.Errorf("Expected empty page of 5 jobs, got %d of %d", len(jobs), total)
+	}
+}
+
+// memKV is an in-memory stand-in for a JetStream KV bucket
+type memKV struct {
+	jetstream.KeyValue
+	data map[string][]byte
+}
+
+type memEntry struct {
+	jetstream.KeyValueEntry
+	value []byte
+}
+
+func (e memEntry) Value() []byte { return e.value }
+
+type memLister struct{ keys chan string }
+
+func (l memLister) Keys() <-chan string { return l.keys }
+func (l memLister) Stop() error         { return nil }
+
+func (kv *memKV) Get(ctx context.Context, key string) (jetstream.KeyValueEntry, error) {
+	value, ok := kv.data[key]
+	if !ok {
+		return nil, jetstream.ErrKeyNotFound
+	}
+	return memEntry{value: value}, nil
+}
+
+func (kv *memKV) Put(ctx context.Context, key string, value []byte) (uint64, error) {
+	kv.data[key] = value
+	return uint64(len(kv.data)), nil
+}
+
+func (kv *memKV) Delete(ctx context.Context, key string, opts ...jetstream.KVDeleteOpt) error {
+	delete(kv.data, key)
+	return nil
+}
+
+func (kv *memKV) ListKeys(ctx context.Context, opts ...jetstream.WatchOpt) (jetstream.KeyLister, error) {
+	keys := make(chan string, len(kv.data))
+	for key := range kv.data {
+		keys <- key
+	}
+	close(keys)
+	return memLister{keys: keys}, nil
+}
+
+func TestStoreWithKVSurvivesRestart(t *testing.T) {
+	kv := &memKV{data: make(map[string][]byte)}
+
+	s, err := NewStoreWithKV(kv)
+	if err != nil {
+		t.Fatalf("NewStoreWithKV: %v", err)
+	}
+	job := NewJob(JobRequest{Type: JobTypeScrape, URL: "https://example.com"})
+	job.IdempotencyKey = "key-1"
+	s.Save(job)
+	job.SetStatus(JobStatusSucceeded)
+	s.Update(job)
+	expired := NewJob(JobRequest{Type: JobTypeScrape, URL: "https://example.com"})
+	expired.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	s.Save(expired)
+	s.Stop()
+
+	restarted, err := NewStoreWithKV(kv)
+	if err != nil {
+		t.Fatalf("NewStoreWithKV after restart: %v", err)
+	}
+	defer restarted.Stop()
+
+	got, err := restarted.Get(job.ID)
+	if err != nil {
+		t.Fatalf("Expected job to survive restart: %v", err)
+	}
+	if got.Status != JobStatusSucceeded {
+		t.Errorf("Expected status succeeded, got %s", got.Status)
+	}
+	if byKey, ok := restarted.GetByIdempotencyKey("key-1"); !ok || byKey.ID != job.ID {
+		t.Errorf("Expected idempotency key to be rehydrated")
+	}
+	if _, ok := kv.data[expired.ID]; ok {
+		t.Errorf("Expected expired job to be removed from the bucket")
+	}
+
+	restarted.Delete(job.ID)
+	if _, ok := kv.data[job.ID]; ok {
+		t.Errorf("Expected delete to remove the job from the bucket")
+	}
+}
+
+// blockingKV is a memKV whose Puts wait until release is closed
+type blockingKV struct {
+	*memKV
+	started chan struct{}
+	release chan struct{}
+}
+
+func (kv *blockingKV) Put(ctx context.Context, key string, value []byte) (uint64, error) {
+	kv.started <- struct{}{}
+	<-kv.release
+	return kv.memKV.Put(ctx, key, value)
+}
+
+func TestStoreUpdateDoesNotBlockReaders(t *testing.T) {
+	kv := &memKV{data: make(map[string][]byte)}
+	s, err := NewStoreWithKV(kv)
+	if err != nil {
+		t.Fatalf("NewStoreWithKV: %v", err)
+	}
+	defer s.Stop()
+	job := NewJob(JobRequest{Type: JobTypeScrape, URL: "https://example.com"})
+	s.Save(job)
+
+	blocking := &blockingKV{memKV: kv, started: make(chan struct{}), release: make(chan struct{})}
+	s.kv = blocking
+	done := make(chan struct{})
+	go func() {
+		job.SetProgress(50, "halfway")
+		s.Update(job)
+		close(done)
+	}()
+	<-blocking.started
+
+	if _, err := s.Get(job.ID); err != nil {
+		t.Errorf("Expected Get during a pending write to succeed: %v", err)
+	}
+	if _, total, _ := s.List(ListFilter{}); total != 1 {
+		t.Errorf("Expected List during a pending write to see 1 job, got %d", total)
+	}
+	close(blocking.release)
+	<-done
+}
+
+func TestStoreDropsStaleWrites(t *testing.T) {
+	kv := &memKV{data: make(map[string][]byte)}
+	s, err := NewStoreWithKV(kv)
+	if err != nil {
+		t.Fatalf("NewStoreWithKV: %v", err)
+	}
+	defer s.Stop()
+	job := NewJob(JobRequest{Type: JobTypeScrape, URL: "https://example.com"})
+	s.Save(job)
+
+	// Two snapshots whose writes land out of order
+	s.mu.Lock()
+	job.SetStatus(JobStatusRunning)
+	older, _ := s.snapshot(job)
+	job.SetStatus(JobStatusSucceeded)
+	newer, _ := s.snapshot(job)
+	s.mu.Unlock()
+
+	s.persist(newer)
+	s.persist(older)
+	stored, _ := FromJSON(kv.data[job.ID])
+	if stored.Status != JobStatusSucceeded {
+		t.Errorf("Expected the newer write to win, got status %s", stored.Status)
+	}
+
+	// A write still in flight when the job is deleted is dropped
+	s.mu.Lock()
+	late, _ := s.snapshot(job)
+	s.mu.Unlock()
+	s.Delete(job.ID)
+	s.persist(late)
+	if _, ok := kv.data[job.ID]; ok {
+		t.Error("Expected a write after delete not to recreate the job")
+	}
+}
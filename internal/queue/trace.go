@@ -0,0 +1,100 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Header names used to propagate trace context on NATS messages and webhooks
+const (
+	HeaderTraceparent = "traceparent"
+	HeaderTraceID     = "X-Trace-Id"
+)
+
+// maxTraceIDLength bounds client supplied trace IDs
+const maxTraceIDLength = 128
+
+// TraceContext carries a client's distributed tracing identifiers through
+// the queue. Traceparent follows the W3C Trace Context format.
+type TraceContext struct {
+	TraceID     string `json:"trace_id,omitempty"`
+	Traceparent string `json:"traceparent,omitempty"`
+}
+
+// ParseTraceparent returns the trace ID of a W3C traceparent header value
+// ("00-<32 hex trace id>-<16 hex parent id>-<2 hex flags>")
+func ParseTraceparent(value string) (string, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return "", false
+	}
+	for _, part := range parts {
+		if !isLowerHex(part) {
+			return "", false
+		}
+	}
+	if parts[0] == "ff" || strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// Normalize validates the trace context and derives TraceID from
+// Traceparent when only the latter is given
+func (t *TraceContext) Normalize() error {
+	if t.Traceparent != "" {
+		traceID, ok := ParseTraceparent(t.Traceparent)
+		if !ok {
+			return fmt.Errorf("invalid traceparent %q", t.Traceparent)
+		}
+		if t.TraceID == "" {
+			t.TraceID = traceID
+		}
+	}
+	if len(t.TraceID) > maxTraceIDLength {
+		return fmt.Errorf("trace_id must be at most %d characters", maxTraceIDLength)
+	}
+	for _, r := range t.TraceID {
+		if r < 0x21 || r > 0x7e {
+			return errors.New("trace_id must be printable ASCII without spaces")
+		}
+	}
+	return nil
+}
+
+// IsZero reports whether no trace context was supplied
+func (t TraceContext) IsZero() bool {
+	return t.TraceID == "" && t.Traceparent == ""
+}
+
+// Header returns the trace context as NATS message headers
+func (t TraceContext) Header() nats.Header {
+	header := nats.Header{}
+	if t.Traceparent != "" {
+		header.Set(HeaderTraceparent, t.Traceparent)
+	}
+	if t.TraceID != "" {
+		header.Set(HeaderTraceID, t.TraceID)
+	}
+	return header
+}
+
+// String formats the trace context for log lines, empty when unset
+func (t TraceContext) String() string {
+	if t.TraceID == "" {
+		return ""
+	}
+	return " trace_id=" + t.TraceID
+}
+
+func isLowerHex(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
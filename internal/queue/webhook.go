@@ -0,0 +1,257 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ahrdadan/scrq/internal/security"
+)
+
+// Webhook delivery timeouts
+const (
+	DefaultWebhookTimeout = 30 * time.Second
+	MaxWebhookTimeout     = 2 * time.Minute
+)
+
+// Webhook delivery retries, configured per job with notify.retry
+const (
+	DefaultWebhookRetries    = 3
+	MaxWebhookRetries        = 10
+	DefaultWebhookRetryDelay = 2 * time.Second
+	MaxWebhookRetryDelay     = time.Minute
+)
+
+// HeaderDeliveryAttempt numbers webhook delivery attempts from 1
+const HeaderDeliveryAttempt = "X-Scrq-Delivery-Attempt"
+
+// The webhook queue is drained by a few senders so slow receivers never
+// hold up job processing
+const (
+	webhookSenders   = 4
+	webhookQueueSize = 1024
+)
+
+// Webhook deliveries use dedicated clients so per-job settings never touch
+// http.DefaultClient. The insecure client is only used when a job opts in.
+var (
+	webhookClient         = &http.Client{Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}}
+	insecureWebhookClient = &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+)
+
+// reservedWebhookHeaders are set by scrq only; custom headers with these
+// names are dropped even when scrq leaves the header out
+var reservedWebhookHeaders = map[string]bool{
+	"Content-Type":        true,
+	"Traceparent":         true,
+	"X-Scrq-Event":        true,
+	"X-Scrq-Signature":    true,
+	HeaderDeliveryAttempt: true,
+}
+
+// webhooks is the process-wide webhook delivery queue
+var webhooks = newWebhookQueue(webhookSenders, webhookQueueSize)
+
+// webhookDelivery is a webhook and the attempts made to deliver it
+type webhookDelivery struct {
+	jobID   string
+	notify  NotifyConfig
+	trace   TraceContext
+	event   string
+	data    []byte
+	attempt int // Attempts made so far
+}
+
+// maxRetries returns how often a failed delivery is retried
+func (d *webhookDelivery) maxRetries() int {
+	if retry := d.notify.Retry; retry != nil && retry.MaxRetries > 0 {
+		return retry.MaxRetries
+	}
+	return DefaultWebhookRetries
+}
+
+// retryDelay returns the backoff before the next attempt:
+// retry_delay * backoff_factor ^ (attempt - 1), capped at MaxWebhookRetryDelay
+func (d *webhookDelivery) retryDelay() time.Duration {
+	delay, factor := DefaultWebhookRetryDelay, 2.0
+	if retry := d.notify.Retry; retry != nil {
+		if retry.RetryDelay > 0 {
+			delay = time.Duration(retry.RetryDelay) * time.Second
+		}
+		if retry.BackoffFactor > 0 {
+			factor = retry.BackoffFactor
+		}
+	}
+	for i := 1; i < d.attempt && delay < MaxWebhookRetryDelay; i++ {
+		delay = time.Duration(float64(delay) * factor)
+	}
+	return min(delay, MaxWebhookRetryDelay)
+}
+
+// webhookQueue delivers webhooks in the background. Failed deliveries are
+// put back on the queue once their backoff has passed.
+type webhookQueue struct {
+	deliveries chan *webhookDelivery
+	senders    int
+	start      sync.Once
+
+	// post and after are swapped out in tests
+	post  func(*webhookDelivery) (retryable bool, err error)
+	after func(time.Duration, func())
+}
+
+func newWebhookQueue(senders, size int) *webhookQueue {
+	return &webhookQueue{
+		deliveries: make(chan *webhookDelivery, size),
+		senders:    senders,
+		post:       postWebhook,
+		after:      func(delay time.Duration, f func()) { time.AfterFunc(delay, f) },
+	}
+}
+
+// enqueue queues a delivery attempt, dropping it if the queue is full
+func (q *webhookQueue) enqueue(d *webhookDelivery) {
+	q.start.Do(func() {
+		for i := 0; i < q.senders; i++ {
+			go q.send()
+		}
+	})
+
+	select {
+	case q.deliveries <- d:
+	default:
+		log.Printf("Dropping webhook %s for job %s: delivery queue full%s", d.event, d.jobID, d.trace)
+	}
+}
+
+func (q *webhookQueue) send() {
+	for d := range q.deliveries {
+		q.attempt(d)
+	}
+}
+
+// attempt makes one delivery attempt and schedules a retry when it fails
+// in a way worth retrying
+func (q *webhookQueue) attempt(d *webhookDelivery) {
+	d.attempt++
+	retryable, err := q.post(d)
+	if err == nil {
+		return
+	}
+
+	if !retryable || d.attempt > d.maxRetries() {
+		log.Printf("Webhook %s for job %s failed after %d attempt(s)%s: %v", d.event, d.jobID, d.attempt, d.trace, err)
+		return
+	}
+
+	delay := d.retryDelay()
+	log.Printf("Webhook %s for job %s failed (attempt %d), retrying in %s%s: %v", d.event, d.jobID, d.attempt, delay, d.trace, err)
+	q.after(delay, func() { q.enqueue(d) })
+}
+
+// sendWebhook queues a webhook notification. Extra fields are merged into
+// the payload. Deliveries are signed when the job has a webhook secret and
+// carry the job's trace context.
+func sendWebhook(jobID string, notify NotifyConfig, trace TraceContext, status JobStatus, extra map[string]interface{}) {
+	payload := map[string]interface{}{
+		"job_id":     jobID,
+		"status":     status,
+		"result_url": fmt.Sprintf("/scrq/jobs/%s/result", jobID),
+	}
+	if trace.TraceID != "" {
+		payload["trace_id"] = trace.TraceID
+	}
+	if status.IsTerminal() {
+		payload["finished_at"] = time.Now().Unix()
+	} else {
+		payload["timestamp"] = time.Now().Unix()
+	}
+	for key, value := range extra {
+		payload[key] = value
+	}
+	if len(notify.Metadata) > 0 {
+		payload["metadata"] = notify.Metadata
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to marshal webhook payload: %v", err)
+		return
+	}
+
+	if notify.InsecureSkipVerify {
+		log.Printf("Warning: delivering webhook for job %s to %s without TLS verification", jobID, notify.WebhookURL)
+	}
+
+	webhooks.enqueue(&webhookDelivery{
+		jobID:  jobID,
+		notify: notify,
+		trace:  trace,
+		event:  "job." + string(status),
+		data:   data,
+	})
+}
+
+// postWebhook makes one delivery attempt. Any 2xx is a success. Network
+// errors, timeouts, 408, 429 and 5xx are retryable; other statuses and TLS
+// verification failures are permanent.
+func postWebhook(d *webhookDelivery) (retryable bool, err error) {
+	timeout := DefaultWebhookTimeout
+	if d.notify.Timeout > 0 {
+		timeout = time.Duration(d.notify.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.notify.WebhookURL, bytes.NewReader(d.data))
+	if err != nil {
+		return false, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+
+	for name, value := range d.notify.Headers {
+		if !reservedWebhookHeaders[http.CanonicalHeaderKey(name)] {
+			req.Header.Set(name, value)
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Scrq-Event", d.event)
+	req.Header.Set(HeaderDeliveryAttempt, strconv.Itoa(d.attempt))
+	if d.trace.Traceparent != "" {
+		req.Header.Set(HeaderTraceparent, d.trace.Traceparent)
+	}
+	if d.notify.WebhookSecret != "" {
+		req.Header.Set("X-Scrq-Signature", "sha256="+security.GenerateWebhookSignature(d.data, d.notify.WebhookSecret))
+	}
+
+	client := webhookClient
+	if d.notify.InsecureSkipVerify {
+		client = insecureWebhookClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		var certErr *tls.CertificateVerificationError
+		return !errors.As(err, &certErr), err
+	}
+	defer resp.Body.Close()
+
+	switch code := resp.StatusCode; {
+	case code >= 200 && code < 300:
+		return false, nil
+	case code == http.StatusRequestTimeout, code == http.StatusTooManyRequests, code >= 500:
+		return true, fmt.Errorf("webhook returned status %d", code)
+	default:
+		return false, fmt.Errorf("webhook returned status %d", code)
+	}
+}
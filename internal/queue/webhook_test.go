@@ -0,0 +1,226 @@
+package queue
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ahrdadan/scrq/internal/security"
+)
+
+func TestSendWebhookSigned(t *testing.T) {
+	type delivery struct {
+		event, signature, attempt string
+		body                      []byte
+	}
+	received := make(chan delivery, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(HeaderTraceparent) != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+			t.Errorf("Expected traceparent header, got %q", r.Header.Get(HeaderTraceparent))
+		}
+		received <- delivery{r.Header.Get("X-Scrq-Event"), r.Header.Get("X-Scrq-Signature"), r.Header.Get(HeaderDeliveryAttempt), body}
+	}))
+	defer server.Close()
+
+	notify := NotifyConfig{WebhookURL: server.URL, WebhookSecret: "secret"}
+	trace := TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", Traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+	sendWebhook("job-1", notify, trace, JobStatusRetrying, map[string]interface{}{"retry_count": 1})
+
+	d := <-received
+	if d.event != "job.retrying" || d.attempt != "1" {
+		t.Errorf("Expected event job.retrying on attempt 1, got %s on attempt %s", d.event, d.attempt)
+	}
+	if d.signature != "sha256="+security.GenerateWebhookSignature(d.body, "secret") {
+		t.Errorf("Unexpected signature %q", d.signature)
+	}
+	if !security.VerifyWebhookSignature(d.body, d.signature, "secret") || security.VerifyWebhookSignature(d.body, d.signature, "other") {
+		t.Error("Expected the signature header to verify with the secret only")
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(d.body, &payload); err != nil {
+		t.Fatalf("Failed to parse payload: %v", err)
+	}
+	if payload["retry_count"] != float64(1) || payload["finished_at"] != nil || payload["trace_id"] != trace.TraceID {
+		t.Errorf("Unexpected payload: %v", payload)
+	}
+}
+
+func TestSendWebhookInsecureSkipVerify(t *testing.T) {
+	received := make(chan struct{}, 2)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+	}))
+	defer server.Close()
+
+	// The test server's certificate is self-signed, so verification fails
+	// and is not worth retrying
+	d := &webhookDelivery{notify: NotifyConfig{WebhookURL: server.URL, Timeout: 5}, attempt: 1}
+	if retryable, err := postWebhook(d); err == nil || retryable {
+		t.Fatalf("Expected delivery to a self-signed receiver to fail permanently, got retryable=%v err=%v", retryable, err)
+	}
+
+	sendWebhook("job-1", NotifyConfig{WebhookURL: server.URL, Timeout: 5, InsecureSkipVerify: true}, TraceContext{}, JobStatusSucceeded, nil)
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected delivery with insecure_skip_verify")
+	}
+	if len(received) != 0 {
+		t.Error("Expected a single delivery")
+	}
+}
+
+func TestSendWebhookHeadersAndMetadata(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+		received <- r
+	}))
+	defer server.Close()
+
+	notify := NotifyConfig{
+		WebhookURL:    server.URL,
+		WebhookSecret: "secret",
+		Headers: map[string]string{
+			"Authorization":    "Bearer gateway-token",
+			"content-type":     "text/plain",
+			"X-Scrq-Signature": "sha256=forged",
+			"Traceparent":      "forged",
+		},
+		Metadata: map[string]interface{}{"tag": "nightly", "attempt": 2},
+	}
+	sendWebhook("job-1", notify, TraceContext{}, JobStatusSucceeded, nil)
+
+	body, r := <-bodies, <-received
+	if r.Header.Get("Authorization") != "Bearer gateway-token" {
+		t.Errorf("Expected the custom header, got %q", r.Header.Get("Authorization"))
+	}
+	if r.Header.Get("Content-Type") != "application/json" || !security.VerifyWebhookSignature(body, r.Header.Get("X-Scrq-Signature"), "secret") {
+		t.Errorf("Expected custom headers not to replace scrq's, got %v", r.Header)
+	}
+	if r.Header.Get(HeaderTraceparent) != "" {
+		t.Errorf("Expected the custom traceparent to be dropped, got %q", r.Header.Get(HeaderTraceparent))
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("Failed to parse payload: %v", err)
+	}
+	metadata, _ := payload["metadata"].(map[string]interface{})
+	if metadata["tag"] != "nightly" || metadata["attempt"] != float64(2) {
+		t.Errorf("Expected the metadata verbatim, got %v", payload["metadata"])
+	}
+}
+
+func TestPostWebhookStatus(t *testing.T) {
+	cases := []struct {
+		status    int
+		ok        bool
+		retryable bool
+	}{
+		{http.StatusOK, true, false},
+		{http.StatusNoContent, true, false},
+		{http.StatusBadRequest, false, false},
+		{http.StatusNotFound, false, false},
+		{http.StatusRequestTimeout, false, true},
+		{http.StatusTooManyRequests, false, true},
+		{http.StatusInternalServerError, false, true},
+		{http.StatusBadGateway, false, true},
+	}
+	for _, tc := range cases {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.status)
+		}))
+		retryable, err := postWebhook(&webhookDelivery{notify: NotifyConfig{WebhookURL: server.URL}, attempt: 1})
+		server.Close()
+
+		if (err == nil) != tc.ok || retryable != tc.retryable {
+			t.Errorf("Status %d: expected ok=%v retryable=%v, got err=%v retryable=%v", tc.status, tc.ok, tc.retryable, err, retryable)
+		}
+	}
+}
+
+func TestWebhookQueueRetriesWithBackoff(t *testing.T) {
+	var attempts []string
+	var delays []time.Duration
+	statuses := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts = append(attempts, r.Header.Get(HeaderDeliveryAttempt))
+		w.WriteHeader(statuses[len(attempts)-1])
+	}))
+	defer server.Close()
+
+	// Retries run inline instead of after their backoff
+	q := newWebhookQueue(1, 1)
+	q.after = func(delay time.Duration, retry func()) {
+		delays = append(delays, delay)
+		q.attempt(<-retryDelivery(q, retry))
+	}
+
+	q.attempt(&webhookDelivery{notify: NotifyConfig{WebhookURL: server.URL, Retry: &RetryConfig{RetryDelay: 1, BackoffFactor: 3}}})
+
+	if len(attempts) != 3 || attempts[0] != "1" || attempts[1] != "2" || attempts[2] != "3" {
+		t.Errorf("Expected attempts 1, 2 and 3, got %v", attempts)
+	}
+	if len(delays) != 2 || delays[0] != time.Second || delays[1] != 3*time.Second {
+		t.Errorf("Expected backoff of 1s then 3s, got %v", delays)
+	}
+}
+
+func TestWebhookQueueGivesUp(t *testing.T) {
+	calls := 0
+	q := newWebhookQueue(1, 1)
+	q.after = func(delay time.Duration, retry func()) { q.attempt(<-retryDelivery(q, retry)) }
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	q.attempt(&webhookDelivery{notify: NotifyConfig{WebhookURL: server.URL, Retry: &RetryConfig{MaxRetries: 2}}})
+	if calls != 3 {
+		t.Errorf("Expected the first attempt and 2 retries, got %d attempts", calls)
+	}
+
+	// Client errors other than 408 and 429 are not retried
+	calls = 0
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}))
+	defer rejecting.Close()
+	q.attempt(&webhookDelivery{notify: NotifyConfig{WebhookURL: rejecting.URL}})
+	if calls != 1 {
+		t.Errorf("Expected a 422 to end delivery, got %d attempts", calls)
+	}
+}
+
+// retryDelivery runs a scheduled retry and returns the delivery it queued.
+// The queue's senders are never started, so the test drives every attempt.
+func retryDelivery(q *webhookQueue, retry func()) <-chan *webhookDelivery {
+	q.start.Do(func() {})
+	retry()
+	return q.deliveries
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	d := &webhookDelivery{}
+	for attempt, want := range []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second} {
+		d.attempt = attempt + 1
+		if got := d.retryDelay(); got != want {
+			t.Errorf("Attempt %d: expected %s, got %s", d.attempt, want, got)
+		}
+	}
+
+	d.attempt = 20
+	if got := d.retryDelay(); got != MaxWebhookRetryDelay {
+		t.Errorf("Expected the delay to be capped at %s, got %s", MaxWebhookRetryDelay, got)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 )
@@ -118,8 +119,10 @@ func GenerateWebhookSignature(payload []byte, secret string) string {
 	return hex.EncodeToString(mac.Sum(nil))
 }
 
-// VerifyWebhookSignature verifies a webhook signature
+// VerifyWebhookSignature verifies a webhook signature. The signature may be
+// the bare hex digest or the X-Scrq-Signature header value ("sha256=<hex>").
 func VerifyWebhookSignature(payload []byte, signature, secret string) bool {
+	signature = strings.TrimPrefix(signature, "sha256=")
 	expected := GenerateWebhookSignature(payload, secret)
 	return hmac.Equal([]byte(expected), []byte(signature))
 }
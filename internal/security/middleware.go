@@ -1,6 +1,12 @@
 package security
 
 import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -108,22 +114,104 @@ func SecurityHeadersMiddleware() fiber.Handler {
 	}
 }
 
+// DefaultMaxBodyBytes is the request body limit when none is configured
+const DefaultMaxBodyBytes = 10 * 1024 * 1024
+
+// ValidationConfig configures RequestValidationMiddleware
+type ValidationConfig struct {
+	// AllowedContentTypes are accepted on every route
+	AllowedContentTypes []string
+	// RouteContentTypes adds accepted types for routes under a path prefix,
+	// e.g. "multipart/form-data" for upload routes
+	RouteContentTypes map[string][]string
+	// MaxBodyBytes rejects larger request bodies (DefaultMaxBodyBytes if 0)
+	MaxBodyBytes int
+}
+
+// DefaultValidationConfig returns a config that accepts only JSON bodies
+// up to DefaultMaxBodyBytes
+func DefaultValidationConfig() ValidationConfig {
+	return ValidationConfig{
+		AllowedContentTypes: []string{fiber.MIMEApplicationJSON},
+		MaxBodyBytes:        DefaultMaxBodyBytes,
+	}
+}
+
+// acceptedContentTypes returns the content types accepted for a path,
+// sorted and without duplicates
+func (v ValidationConfig) acceptedContentTypes(path string) []string {
+	seen := make(map[string]bool)
+	var accepted []string
+	add := func(types []string) {
+		for _, t := range types {
+			if t = strings.ToLower(t); !seen[t] {
+				seen[t] = true
+				accepted = append(accepted, t)
+			}
+		}
+	}
+
+	add(v.AllowedContentTypes)
+	for prefix, types := range v.RouteContentTypes {
+		if strings.HasPrefix(path, prefix) {
+			add(types)
+		}
+	}
+	sort.Strings(accepted)
+	return accepted
+}
+
+// ParseRouteContentTypes parses "prefix=type" entries into
+// ValidationConfig.RouteContentTypes. A prefix may be given more than once
+// to accept several types.
+func ParseRouteContentTypes(entries []string) (map[string][]string, error) {
+	routes := make(map[string][]string)
+	for _, entry := range entries {
+		prefix, contentType, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		prefix, contentType = strings.TrimSpace(prefix), strings.TrimSpace(contentType)
+		if !ok || !strings.HasPrefix(prefix, "/") || contentType == "" {
+			return nil, fmt.Errorf("invalid route content type %q, expected /path/prefix=type", entry)
+		}
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, fmt.Errorf("invalid content type %q for %s: %w", contentType, prefix, err)
+		}
+		routes[prefix] = append(routes[prefix], mediaType)
+	}
+	return routes, nil
+}
+
 // RequestValidationMiddleware validates incoming requests
 func RequestValidationMiddleware() fiber.Handler {
+	return RequestValidationMiddlewareWithConfig(DefaultValidationConfig())
+}
+
+// RequestValidationMiddlewareWithConfig validates incoming requests, accepting
+// the content types configured for each route
+func RequestValidationMiddlewareWithConfig(config ValidationConfig) fiber.Handler {
+	maxBody := config.MaxBodyBytes
+	if maxBody <= 0 {
+		maxBody = DefaultMaxBodyBytes
+	}
+
 	return func(c *fiber.Ctx) error {
 		// Check content type for POST/PUT/PATCH requests
 		if c.Method() == fiber.MethodPost || c.Method() == fiber.MethodPut || c.Method() == fiber.MethodPatch {
 			contentType := c.Get("Content-Type")
-			if contentType != "" && !strings.HasPrefix(contentType, "application/json") {
-				return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
-					"success": false,
-					"error":   "Content-Type must be application/json",
-				})
+			if contentType != "" {
+				accepted := config.acceptedContentTypes(c.Path())
+				if !contentTypeAllowed(contentType, accepted) {
+					return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+						"success":  false,
+						"error":    "Unsupported Content-Type, expected one of: " + strings.Join(accepted, ", "),
+						"accepted": accepted,
+					})
+				}
 			}
 		}
 
-		// Limit request body size (10MB max)
-		if len(c.Body()) > 10*1024*1024 {
+		// Limit request body size
+		if len(c.Body()) > maxBody {
 			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
 				"success": false,
 				"error":   "Request body too large",
@@ -134,20 +222,85 @@ func RequestValidationMiddleware() fiber.Handler {
 	}
 }
 
+// contentTypeAllowed reports whether the media type of contentType (ignoring
+// parameters such as charset or boundary) is in accepted
+func contentTypeAllowed(contentType string, accepted []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range accepted {
+		if strings.EqualFold(mediaType, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// IPWhitelistConfig configures IPWhitelistMiddlewareWithConfig
+type IPWhitelistConfig struct {
+	// AllowedIPs are client IPs or CIDR ranges such as "10.0.0.0/8"; all
+	// clients are allowed when empty
+	AllowedIPs []string
+	// TrustProxy takes the client IP from the last X-Forwarded-For entry,
+	// the one appended by the proxy in front of scrq. Without a proxy that
+	// sets the header, clients could spoof it.
+	TrustProxy bool
+}
+
+// ParseAllowedIPs parses IPs and CIDR ranges into networks. A single IP
+// becomes a network holding only that address.
+func ParseAllowedIPs(entries []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if strings.Contains(entry, "/") {
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR range %q: %w", entry, err)
+			}
+			networks = append(networks, network)
+			continue
+		}
+
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", entry)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return networks, nil
+}
+
 // IPWhitelistMiddleware creates an IP whitelist middleware
 func IPWhitelistMiddleware(allowedIPs []string) fiber.Handler {
-	ipSet := make(map[string]bool)
-	for _, ip := range allowedIPs {
-		ipSet[ip] = true
+	return IPWhitelistMiddlewareWithConfig(IPWhitelistConfig{AllowedIPs: allowedIPs})
+}
+
+// IPWhitelistMiddlewareWithConfig rejects clients outside the allowed IPs
+// and ranges with 403. Invalid entries are logged and match no client;
+// validate them up front with ParseAllowedIPs.
+func IPWhitelistMiddlewareWithConfig(config IPWhitelistConfig) fiber.Handler {
+	var networks []*net.IPNet
+	for _, entry := range config.AllowedIPs {
+		parsed, err := ParseAllowedIPs([]string{entry})
+		if err != nil {
+			log.Printf("Ignoring allowed IP entry: %v", err)
+			continue
+		}
+		networks = append(networks, parsed...)
 	}
 
 	return func(c *fiber.Ctx) error {
-		if len(allowedIPs) == 0 {
+		if len(config.AllowedIPs) == 0 {
 			return c.Next()
 		}
 
-		clientIP := c.IP()
-		if !ipSet[clientIP] {
+		if !ipAllowed(clientIP(c, config.TrustProxy), networks) {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"success": false,
 				"error":   "Access denied",
@@ -157,3 +310,58 @@ func IPWhitelistMiddleware(allowedIPs []string) fiber.Handler {
 		return c.Next()
 	}
 }
+
+// clientIP returns the address of the client, taken from the last
+// X-Forwarded-For entry when the proxy is trusted
+func clientIP(c *fiber.Ctx, trustProxy bool) net.IP {
+	if trustProxy {
+		if forwarded := c.Get(fiber.HeaderXForwardedFor); forwarded != "" {
+			entries := strings.Split(forwarded, ",")
+			return net.ParseIP(strings.TrimSpace(entries[len(entries)-1]))
+		}
+	}
+	return net.ParseIP(c.IP())
+}
+
+// ipAllowed reports whether ip is in one of the networks
+func ipAllowed(ip net.IP, networks []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminAuthMiddleware restricts access to requests carrying the admin token.
+// The token is read from the X-Admin-Token header or an Authorization bearer
+// token. If no token is configured, admin endpoints are disabled entirely.
+func AdminAuthMiddleware(token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token == "" {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"success": false,
+				"error":   "Admin endpoints are disabled",
+			})
+		}
+
+		provided := c.Get("X-Admin-Token")
+		if provided == "" {
+			if auth := c.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+				provided = strings.TrimPrefix(auth, "Bearer ")
+			}
+		}
+
+		if provided == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"success": false,
+				"error":   "Invalid admin token",
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,237 @@
+package security_test
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ahrdadan/scrq/internal/security"
+	"github.com/gofiber/fiber/v2"
+)
+
+func setupAdminApp(token string) *fiber.App {
+	app := fiber.New()
+	app.Get("/admin", security.AdminAuthMiddleware(token), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	return app
+}
+
+func TestAdminAuthNotConfigured(t *testing.T) {
+	app := setupAdminApp("")
+
+	req := httptest.NewRequest("GET", "/admin", nil)
+	req.Header.Set("X-Admin-Token", "anything")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+
+	if resp.StatusCode != 403 {
+		t.Errorf("Expected status 403, got %d", resp.StatusCode)
+	}
+}
+
+func TestAdminAuthWrongToken(t *testing.T) {
+	app := setupAdminApp("secret")
+
+	req := httptest.NewRequest("GET", "/admin", nil)
+	req.Header.Set("X-Admin-Token", "wrong")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+
+	if resp.StatusCode != 401 {
+		t.Errorf("Expected status 401, got %d", resp.StatusCode)
+	}
+}
+
+func TestAdminAuthNonBearerScheme(t *testing.T) {
+	app := setupAdminApp("secret")
+
+	req := httptest.NewRequest("GET", "/admin", nil)
+	req.Header.Set("Authorization", "Basic secret")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+
+	if resp.StatusCode != 401 {
+		t.Errorf("Expected status 401, got %d", resp.StatusCode)
+	}
+}
+
+func TestAdminAuthHeaderToken(t *testing.T) {
+	app := setupAdminApp("secret")
+
+	req := httptest.NewRequest("GET", "/admin", nil)
+	req.Header.Set("X-Admin-Token", "secret")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestAdminAuthBearerToken(t *testing.T) {
+	app := setupAdminApp("secret")
+
+	req := httptest.NewRequest("GET", "/admin", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+func doValidationRequest(t *testing.T, path, contentType string) int {
+	t.Helper()
+	app := fiber.New()
+	app.Use(security.RequestValidationMiddlewareWithConfig(security.ValidationConfig{
+		AllowedContentTypes: []string{fiber.MIMEApplicationJSON},
+		RouteContentTypes: map[string][]string{
+			"/upload": {fiber.MIMEMultipartForm},
+		},
+	}))
+	app.Post("/*", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	// A minimal body that is also a valid (empty) multipart message
+	req := httptest.NewRequest("POST", path, strings.NewReader("--x--\r\n"))
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	return resp.StatusCode
+}
+
+func TestRequestValidationContentTypes(t *testing.T) {
+	tests := []struct {
+		path, contentType string
+		status            int
+	}{
+		{"/jobs", "application/json", 200},
+		{"/jobs", "application/json; charset=utf-8", 200},
+		{"/jobs", "text/plain", 415},
+		{"/jobs", "multipart/form-data; boundary=x", 415},
+		{"/upload", "multipart/form-data; boundary=x", 200},
+		{"/upload", "application/json", 200},
+		{"/upload", "application/xml", 415},
+	}
+
+	for _, tt := range tests {
+		if status := doValidationRequest(t, tt.path, tt.contentType); status != tt.status {
+			t.Errorf("POST %s with %s: expected status %d, got %d", tt.path, tt.contentType, tt.status, status)
+		}
+	}
+}
+
+func TestRequestValidationListsAcceptedTypesSorted(t *testing.T) {
+	app := fiber.New()
+	app.Use(security.RequestValidationMiddlewareWithConfig(security.ValidationConfig{
+		AllowedContentTypes: []string{fiber.MIMEApplicationJSON},
+		RouteContentTypes: map[string][]string{
+			"/upload":        {fiber.MIMEMultipartForm, fiber.MIMEApplicationJSON},
+			"/upload/images": {"image/png"},
+		},
+	}))
+
+	req := httptest.NewRequest("POST", "/upload/images", strings.NewReader("x"))
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	want := "expected one of: application/json, image/png, multipart/form-data"
+	if resp.StatusCode != 415 || !strings.Contains(string(body), want) {
+		t.Errorf("Expected 415 listing %q, got %d: %s", want, resp.StatusCode, body)
+	}
+}
+
+func TestParseRouteContentTypes(t *testing.T) {
+	routes, err := security.ParseRouteContentTypes([]string{
+		"/scrq/uploads=multipart/form-data",
+		" /scrq/uploads = application/octet-stream ",
+		"/scrq/feeds=application/xml; charset=utf-8",
+	})
+	if err != nil {
+		t.Fatalf("ParseRouteContentTypes: %v", err)
+	}
+	if len(routes["/scrq/uploads"]) != 2 || routes["/scrq/uploads"][1] != "application/octet-stream" || routes["/scrq/feeds"][0] != "application/xml" {
+		t.Errorf("Unexpected routes: %v", routes)
+	}
+
+	for _, invalid := range []string{"multipart/form-data", "scrq=text/plain", "/scrq=", "/scrq=not a type"} {
+		if _, err := security.ParseRouteContentTypes([]string{invalid}); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestIPWhitelistRanges(t *testing.T) {
+	tests := []struct {
+		trustProxy bool
+		forwarded  string
+		status     int
+	}{
+		{forwarded: "", status: 200},                           // 0.0.0.0 from app.Test is in 0.0.0.0/8
+		{forwarded: "192.168.1.1", status: 200},                // header ignored without a trusted proxy
+		{trustProxy: true, forwarded: "10.1.2.3", status: 200}, // in 10.0.0.0/8
+		{trustProxy: true, forwarded: "192.168.1.1", status: 403},
+		{trustProxy: true, forwarded: "10.1.2.3, 192.168.1.1", status: 403}, // spoofed first entry
+		{trustProxy: true, forwarded: "192.168.1.1, 2001:db8::1", status: 200},
+		{trustProxy: true, forwarded: "not-an-ip", status: 403},
+	}
+
+	for _, tt := range tests {
+		app := fiber.New()
+		app.Use(security.IPWhitelistMiddlewareWithConfig(security.IPWhitelistConfig{
+			AllowedIPs: []string{"0.0.0.0/8", "10.0.0.0/8", "2001:db8::1"},
+			TrustProxy: tt.trustProxy,
+		}))
+		app.Get("/", func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusOK)
+		})
+
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("trust_proxy=%v X-Forwarded-For %q: expected status %d, got %d", tt.trustProxy, tt.forwarded, tt.status, resp.StatusCode)
+		}
+	}
+}
+
+func TestParseAllowedIPs(t *testing.T) {
+	if _, err := security.ParseAllowedIPs([]string{"127.0.0.1", "10.0.0.0/8", "::1"}); err != nil {
+		t.Errorf("Expected valid entries to parse, got %v", err)
+	}
+	for _, entry := range []string{"10.0.0.0/33", "localhost", ""} {
+		if _, err := security.ParseAllowedIPs([]string{entry}); err == nil {
+			t.Errorf("Expected %q to be rejected", entry)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// Middleware starts a server span for each request, continuing the trace
+// of an incoming traceparent header. Handlers reach the span through
+// c.UserContext().
+func Middleware(t *Tracer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if t == nil {
+			return c.Next()
+		}
+
+		ctx, span := t.Start(c.UserContext(), c.Method()+" "+c.Path(), SpanKindServer, c.Get(HeaderTraceparent))
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		// Errors reach the error handler after this returns, so the
+		// status they will produce is taken from the error itself
+		status := c.Response().StatusCode()
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			status = fiberErr.Code
+		} else if err != nil {
+			status = fiber.StatusInternalServerError
+		}
+
+		span.SetName(c.Method() + " " + c.Route().Path)
+		span.SetAttribute("http.request.method", c.Method())
+		span.SetAttribute("http.route", c.Route().Path)
+		span.SetAttribute("url.path", c.Path())
+		span.SetAttribute("http.response.status_code", status)
+		if status >= fiber.StatusInternalServerError {
+			if err != nil {
+				span.RecordError(err)
+			} else {
+				span.RecordError(errors.New(utils.StatusMessage(status)))
+			}
+		}
+		return err
+	}
+}
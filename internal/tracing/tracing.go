@@ -0,0 +1,275 @@
+// Package tracing records OpenTelemetry spans across the job lifecycle and
+// exports them to an OTLP/HTTP collector. A nil *Tracer is a no-op, as are
+// the methods of a nil *Span, so call sites need no checks when tracing is
+// not configured.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HeaderTraceparent is the W3C Trace Context header
+const HeaderTraceparent = "traceparent"
+
+// SpanKind is the OTLP span kind
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+	SpanKindProducer SpanKind = 4
+	SpanKindConsumer SpanKind = 5
+)
+
+// Config holds tracer settings
+type Config struct {
+	Endpoint      string        // OTLP/HTTP collector base URL, e.g. http://localhost:4318 (disabled if empty)
+	ServiceName   string        // service.name resource attribute (default "scrq")
+	BatchSize     int           // Spans per export request (default 256)
+	FlushInterval time.Duration // Longest a finished span waits to be exported (default 5s)
+}
+
+// Tracer starts spans and hands finished ones to the exporter
+type Tracer struct {
+	exporter *exporter
+}
+
+// NewTracer creates a tracer exporting to config.Endpoint. It returns nil,
+// a no-op tracer, when no endpoint is configured.
+func NewTracer(config Config) *Tracer {
+	if config.Endpoint == "" {
+		return nil
+	}
+	if config.ServiceName == "" {
+		config.ServiceName = "scrq"
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 256
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = 5 * time.Second
+	}
+	return &Tracer{exporter: newExporter(config)}
+}
+
+// Shutdown exports the spans still buffered and stops the exporter
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	return t.exporter.shutdown(ctx)
+}
+
+// spanContext identifies a span within a trace
+type spanContext struct {
+	traceID [16]byte
+	spanID  [8]byte
+	sampled bool
+}
+
+// traceparent formats the span context as a W3C traceparent value
+func (sc spanContext) traceparent() string {
+	flags := "00"
+	if sc.sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%x-%x-%s", sc.traceID, sc.spanID, flags)
+}
+
+// parseTraceparent decodes a W3C traceparent value
+// ("00-<32 hex trace id>-<16 hex parent id>-<2 hex flags>")
+func parseTraceparent(value string) (spanContext, bool) {
+	var sc spanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if parts[0] == "ff" || strings.ToLower(value) != value {
+		return sc, false
+	}
+	var version, flags [1]byte
+	if _, err := hex.Decode(version[:], []byte(parts[0])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.traceID[:], []byte(parts[1])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.spanID[:], []byte(parts[2])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return sc, false
+	}
+	if sc.traceID == ([16]byte{}) || sc.spanID == ([8]byte{}) {
+		return sc, false
+	}
+	sc.sampled = flags[0]&1 == 1
+	return sc, true
+}
+
+// attribute is a span or resource attribute
+type attribute struct {
+	key   string
+	value interface{}
+}
+
+// event is a timestamped annotation on a span
+type event struct {
+	name string
+	time time.Time
+}
+
+// Span is an operation being traced. It is exported once ended.
+type Span struct {
+	tracer   *Tracer
+	kind     SpanKind
+	sc       spanContext
+	parentID [8]byte
+	start    time.Time
+
+	mu     sync.Mutex
+	name   string
+	end    time.Time
+	attrs  []attribute
+	events []event
+	failed bool
+	errMsg string
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx carrying span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span carried by ctx, nil if none
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Start starts a span continuing the trace of traceparent, so work that
+// runs elsewhere (another process, or a queued job) links to the span that
+// produced the traceparent. A new trace is started when traceparent is
+// empty or malformed.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind, traceparent string) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	parent, ok := parseTraceparent(traceparent)
+	return t.start(ctx, name, kind, parent, ok)
+}
+
+// Start starts a child of the span carried by ctx. It is a no-op returning
+// a nil span when ctx carries none.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return parent.tracer.start(ctx, name, SpanKindInternal, parent.sc, true)
+}
+
+func (t *Tracer) start(ctx context.Context, name string, kind SpanKind, parent spanContext, hasParent bool) (context.Context, *Span) {
+	span := &Span{tracer: t, kind: kind, name: name, start: time.Now()}
+	span.sc.sampled = true
+	if hasParent {
+		span.sc.traceID = parent.traceID
+		span.sc.sampled = parent.sampled
+		span.parentID = parent.spanID
+	} else {
+		_, _ = rand.Read(span.sc.traceID[:])
+	}
+	_, _ = rand.Read(span.sc.spanID[:])
+	return ContextWithSpan(ctx, span), span
+}
+
+// Traceparent returns the span's W3C traceparent, empty for a nil span
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	return s.sc.traceparent()
+}
+
+// TraceID returns the span's hex trace ID, empty for a nil span
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.sc.traceID[:])
+}
+
+// SetName renames the span, e.g. once the matched route is known
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.name = name
+	s.mu.Unlock()
+}
+
+// SetAttribute sets a string, bool, integer or float attribute
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.attrs {
+		if s.attrs[i].key == key {
+			s.attrs[i].value = value
+			return
+		}
+	}
+	s.attrs = append(s.attrs, attribute{key: key, value: value})
+}
+
+// AddEvent records a named point in time on the span
+func (s *Span) AddEvent(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.events = append(s.events, event{name: name, time: time.Now()})
+	s.mu.Unlock()
+}
+
+// RecordError marks the span as failed with err; a nil err is ignored
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.failed = true
+	s.errMsg = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes the span and queues it for export. Later calls do nothing.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	ended := !s.end.IsZero()
+	if !ended {
+		s.end = time.Now()
+	}
+	s.mu.Unlock()
+	if !ended && s.sc.sampled {
+		s.tracer.exporter.enqueue(s)
+	}
+}
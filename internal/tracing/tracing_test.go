@@ -0,0 +1,164 @@
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ahrdadan/scrq/internal/tracing"
+	"github.com/gofiber/fiber/v2"
+)
+
+// collectedSpan is the part of an OTLP JSON span the tests check
+type collectedSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Kind         int    `json:"kind"`
+	Attributes   []struct {
+		Key   string `json:"key"`
+		Value struct {
+			StringValue string `json:"stringValue"`
+			IntValue    string `json:"intValue"`
+		} `json:"value"`
+	} `json:"attributes"`
+	Status struct {
+		Code int `json:"code"`
+	} `json:"status"`
+}
+
+// collector is an OTLP/HTTP receiver keeping the spans it is sent by name
+type collector struct {
+	mu    sync.Mutex
+	spans map[string]collectedSpan
+}
+
+func newCollector(t *testing.T) (*collector, *httptest.Server) {
+	c := &collector{spans: make(map[string]collectedSpan)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected export to %s as %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []collectedSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode export: %v", err)
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					c.spans[span.Name] = span
+				}
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return c, srv
+}
+
+func TestSpansLinkAcrossRequestAndJob(t *testing.T) {
+	col, srv := newCollector(t)
+	tracer := tracing.NewTracer(tracing.Config{Endpoint: srv.URL})
+
+	var jobTraceparent string
+	app := fiber.New()
+	app.Use(tracing.Middleware(tracer))
+	app.Post("/jobs", func(c *fiber.Ctx) error {
+		_, span := tracing.Start(c.UserContext(), "enqueue")
+		jobTraceparent = span.Traceparent()
+		span.End()
+		return c.SendStatus(fiber.StatusAccepted)
+	})
+
+	req := httptest.NewRequest("POST", "/jobs", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if _, err := app.Test(req); err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+
+	// The worker picks the job up later, from the traceparent it carries
+	_, process := tracer.Start(context.Background(), "process", tracing.SpanKindConsumer, jobTraceparent)
+	process.RecordError(errors.New("upstream unavailable"))
+	process.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracer.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	col.mu.Lock()
+	defer col.mu.Unlock()
+	server, enqueue, job := col.spans["POST /jobs"], col.spans["enqueue"], col.spans["process"]
+	if server.SpanID == "" || enqueue.SpanID == "" || job.SpanID == "" {
+		t.Fatalf("Expected the server, enqueue and process spans, got %v", col.spans)
+	}
+	for _, span := range []collectedSpan{server, enqueue, job} {
+		if span.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("%s: expected the incoming trace ID, got %s", span.Name, span.TraceID)
+		}
+	}
+	if server.ParentSpanID != "00f067aa0ba902b7" || server.Kind != int(tracing.SpanKindServer) {
+		t.Errorf("Expected a server span under the incoming parent, got kind %d under %q", server.Kind, server.ParentSpanID)
+	}
+	if enqueue.ParentSpanID != server.SpanID {
+		t.Errorf("Expected the enqueue span under the server span, got %q", enqueue.ParentSpanID)
+	}
+	if job.ParentSpanID != enqueue.SpanID || job.Status.Code != 2 {
+		t.Errorf("Expected a failed process span under the enqueue span, got status %d under %q", job.Status.Code, job.ParentSpanID)
+	}
+
+	var status string
+	for _, attr := range server.Attributes {
+		if attr.Key == "http.response.status_code" {
+			status = attr.Value.IntValue
+		}
+	}
+	if status != "202" {
+		t.Errorf("Expected http.response.status_code 202, got %q", status)
+	}
+}
+
+func TestTracingDisabledWithoutEndpoint(t *testing.T) {
+	tracer := tracing.NewTracer(tracing.Config{})
+	if tracer != nil {
+		t.Fatal("Expected no tracer without an endpoint")
+	}
+
+	app := fiber.New()
+	app.Use(tracing.Middleware(tracer))
+	app.Get("/", func(c *fiber.Ctx) error {
+		_, span := tracing.Start(c.UserContext(), "child")
+		span.SetAttribute("key", "value")
+		span.RecordError(errors.New("ignored"))
+		span.End()
+		return c.SendString(span.Traceparent())
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	body := make([]byte, 64)
+	n, _ := resp.Body.Read(body)
+	if resp.StatusCode != 200 || strings.TrimSpace(string(body[:n])) != "" {
+		t.Errorf("Expected an untraced 200, got %d with %q", resp.StatusCode, body[:n])
+	}
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown: %v", err)
+	}
+}